@@ -1,14 +1,40 @@
 package main
 
 import (
+	"context"
 	_ "flicker/docs"
+	"flicker/internal/aicache"
+	aicachepsql "flicker/internal/aicache/psql"
 	"flicker/internal/auth/jwt"
 	"flicker/internal/auth/psql"
+	cardspsql "flicker/internal/cards/psql"
 	"flicker/internal/config"
+	documentspsql "flicker/internal/documents/psql"
+	"flicker/internal/ingest"
+	ingestpsql "flicker/internal/ingest/psql"
+	"flicker/internal/lecture"
+	"flicker/internal/metrics"
+	"flicker/internal/n8n"
 	"flicker/internal/net"
+	notespsql "flicker/internal/notes/psql"
+	"flicker/internal/prompt"
+	promptpsql "flicker/internal/prompt/psql"
+	"flicker/internal/quiz"
+	quizpsql "flicker/internal/quiz/psql"
+	"flicker/internal/ratelimit"
+	ratelimitpsql "flicker/internal/ratelimit/psql"
+	"flicker/internal/reqid"
+	"flicker/internal/summary"
+	"flicker/internal/transcript"
+	"flicker/internal/upload"
+	"flicker/internal/usage"
+	usagepsql "flicker/internal/usage/psql"
+	"flicker/internal/webhook"
+	webhookpsql "flicker/internal/webhook/psql"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/autumnterror/breezynotes/pkg/log"
 )
@@ -30,15 +56,102 @@ func main() {
 	cfg := config.MustSetup()
 
 	db := psql.MustConnect(cfg)
+	// queries are marked with id of request they are made for
+	repo := reqid.SQL(db.Driver)
+	metrics.RegisterDB(db.Driver, "flicker")
 
-	e := net.New(cfg, psql.NewDriver(db.Driver), jwt.NewWithConfig(cfg))
+	n8nAPI := n8n.New(cfg)
+	transcriber := transcript.NewClient(cfg)
+	summarizer := summary.New(n8nAPI, cfg)
+
+	var store ingest.Store = ingest.NewMemoryStore()
+	if cfg.VectorStore == ingest.StorePgvector {
+		store = ingestpsql.NewDriver(repo)
+	}
+
+	uploads := upload.MustNewStore(cfg.UploadDir, cfg.UploadMaxSize, cfg.UploadTTL)
+	sweepCtx, stopSweep := context.WithCancel(context.Background())
+	go uploads.Sweep(sweepCtx, time.Hour)
+
+	var cache aicache.Cache
+	switch cfg.AICache {
+	case aicache.StorePostgres:
+		cache = aicachepsql.NewDriver(repo)
+	case aicache.StoreMemory:
+		cache = aicache.NewMemory(cfg.AICacheSize)
+	}
+	if cache != nil {
+		go aicache.Sweep(sweepCtx, cache, time.Hour)
+	}
+
+	var meter *usage.Meter
+	switch cfg.UsageStore {
+	case usage.StorePostgres:
+		meter = usage.New(usagepsql.NewDriver(repo), cfg)
+	case usage.StoreMemory:
+		meter = usage.New(usage.NewMemory(), cfg)
+	}
+
+	var limits ratelimit.Store
+	switch cfg.RateLimitStore {
+	case ratelimit.StorePostgres:
+		limits = ratelimitpsql.NewDriver(repo)
+	case ratelimit.StoreMemory:
+		limits = ratelimit.NewMemory()
+	}
+	if limits != nil {
+		go ratelimit.Sweep(sweepCtx, limits, 10*time.Minute)
+	}
+
+	var prompts *prompt.Library
+	switch cfg.PromptStore {
+	case prompt.StorePostgres:
+		prompts = prompt.New(promptpsql.NewDriver(repo), cfg)
+	case prompt.StoreMemory:
+		prompts = prompt.New(prompt.NewMemory(), cfg)
+	}
+
+	var webhooks *webhook.Dispatcher
+	switch cfg.WebhookStore {
+	case webhook.StorePostgres:
+		webhooks = webhook.New(webhookpsql.NewDriver(repo), cfg)
+	case webhook.StoreMemory:
+		webhooks = webhook.New(webhook.NewMemory(), cfg)
+	}
+
+	e := net.New(
+		cfg,
+		psql.NewDriver(repo),
+		jwt.NewWithConfig(cfg),
+		notespsql.NewDriver(repo),
+		n8nAPI,
+		quizpsql.NewDriver(repo),
+		quiz.NewGrader(cfg.QuizGrader, n8nAPI),
+		cardspsql.NewDriver(repo),
+		ingest.New(ingest.NewEmbedder(cfg), store, cfg.ChunkSize, cfg.ChunkOverlap),
+		documentspsql.NewDriver(repo),
+		uploads,
+		transcriber,
+		summarizer,
+		lecture.New(transcriber, summarizer, n8nAPI),
+		cache,
+		meter,
+		limits,
+		prompts,
+		webhooks,
+	)
+	e.InvalidateCache(sweepCtx)
 	go e.MustRun()
 
 	sign := wait()
+	stopSweep()
 
 	if err := e.Stop(); err != nil {
 		log.Error(op, "stop echo", err)
 	}
+	if webhooks != nil {
+		webhooks.Stop()
+	}
 
 	if err := db.Disconnect(); err != nil {
 		log.Error(op, "db disconnect", err)
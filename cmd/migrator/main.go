@@ -5,6 +5,7 @@ import (
 	"errors"
 	"flag"
 	"flicker/internal/config"
+	"flicker/internal/ingest"
 	"fmt"
 	"log"
 
@@ -23,47 +24,35 @@ func main() {
 	}
 }
 
+// pgvectorTable is version table of migrations/pgvector. They need pgvector extension and are
+// applied only when vector_store is "pgvector", so plain postgres works with memory store
+const pgvectorTable = "schema_migrations_pgvector"
+
 func executeMigrate(TYPE string) error {
 	const op = "migrator.executeMigrate"
 
 	cfg := config.MustSetup()
-	db, err := sql.Open("postgres", cfg.Uri)
-	if err != nil {
-		return format.Error(op, err)
-	}
-	defer func(db *sql.DB) {
-		err := db.Close()
-		if err != nil {
-			log.Fatal(format.Error(op, err))
-		}
-	}(db)
-
-	driver, err := postgres.WithInstance(db, &postgres.Config{})
-	if err != nil {
-		return format.Error(op, err)
-	}
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://migrations",
-		"postgres", driver)
-	if err != nil {
-		return format.Error(op, err)
-	}
-	defer func(m *migrate.Migrate) {
-		err, _ := m.Close()
-		if err != nil {
-			log.Fatal(format.Error(op, err))
-		}
-	}(m)
 
 	switch TYPE {
 	case "up":
-		err = m.Up()
-		if err != nil {
-			log.Fatal(format.Error(op, err))
+		if err := up(cfg.Uri, "migrations", postgres.DefaultMigrationsTable); err != nil {
+			return format.Error(op, err)
+		}
+		if cfg.VectorStore == ingest.StorePgvector {
+			if err := up(cfg.Uri, "migrations/pgvector", pgvectorTable); err != nil {
+				return format.Error(op, err)
+			}
 		}
 		log.Println("Migrations applied successfully!")
 		return nil
 	case "down":
+		// only main migrations are rolled back, tables of pgvector stay
+		m, err := open(cfg.Uri, "migrations", postgres.DefaultMigrationsTable)
+		if err != nil {
+			return format.Error(op, err)
+		}
+		defer closeMigrate(m)
+
 		if err := m.Steps(-1); err != nil && !errors.Is(err, migrate.ErrNoChange) {
 			return format.Error(op, err)
 		}
@@ -77,3 +66,39 @@ func executeMigrate(TYPE string) error {
 	}
 
 }
+
+// up apply all migrations of dir, which versions are kept in table
+func up(uri, dir, table string) error {
+	m, err := open(uri, dir, table)
+	if err != nil {
+		return err
+	}
+	defer closeMigrate(m)
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
+
+// open migrations of dir with own connection, closing of migrate closes it
+func open(uri, dir, table string) (*migrate.Migrate, error) {
+	db, err := sql.Open("postgres", uri)
+	if err != nil {
+		return nil, err
+	}
+	driver, err := postgres.WithInstance(db, &postgres.Config{MigrationsTable: table})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return migrate.NewWithDatabaseInstance("file://"+dir, "postgres", driver)
+}
+
+func closeMigrate(m *migrate.Migrate) {
+	const op = "migrator.closeMigrate"
+
+	if err, _ := m.Close(); err != nil {
+		log.Fatal(format.Error(op, err))
+	}
+}
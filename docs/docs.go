@@ -20,37 +20,34 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/ai/file2db": {
-            "post": {
-                "description": "Принимает файл, отправляет его в n8n webhook file2db, который сохраняет данные во векторную БД",
-                "consumes": [
-                    "multipart/form-data"
-                ],
+        "/api/admin/prompts": {
+            "get": {
+                "description": "Возвращает активные версии шаблонов промптов. Шаблоны есть у webhook generatemd, reducemd, gentest, flashcards и ask; webhook без активного шаблона использует промпт своего n8n workflow. Доступно администраторам",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "ai"
-                ],
-                "summary": "Upload file to vector DB",
-                "parameters": [
-                    {
-                        "type": "file",
-                        "description": "File to index",
-                        "name": "file",
-                        "in": "formData",
-                        "required": true
-                    }
+                    "admin"
                 ],
+                "summary": "List active prompt templates",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/views.File2DBResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/views.PromptTemplate"
+                            }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/views.SWGError"
                         }
@@ -64,25 +61,29 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/ai/file2dbtest": {
-            "post": {
-                "description": "Принимает файл, отправляет его в n8n webhook file2db, который сохраняет данные во векторную БД",
-                "consumes": [
-                    "multipart/form-data"
-                ],
+        "/api/admin/prompts/{name}": {
+            "get": {
+                "description": "Возвращает все версии шаблона, новые первыми. Доступно администраторам",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "ai"
+                    "admin"
                 ],
-                "summary": "Upload file to vector DB",
+                "summary": "List versions of prompt template",
                 "parameters": [
                     {
-                        "type": "file",
-                        "description": "File to index",
-                        "name": "file",
-                        "in": "formData",
+                        "enum": [
+                            "generatemd",
+                            "reducemd",
+                            "gentest",
+                            "flashcards",
+                            "ask"
+                        ],
+                        "type": "string",
+                        "description": "Template name",
+                        "name": "name",
+                        "in": "path",
                         "required": true
                     }
                 ],
@@ -90,11 +91,26 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/views.File2DBResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/views.PromptTemplate"
+                            }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/views.SWGError"
                         }
@@ -106,11 +122,9 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/api/ai/generatemd": {
+            },
             "post": {
-                "description": "Принимает текст и отправляет его в n8n webhook, который генерирует Markdown-конспект через LLM",
+                "description": "Сохраняет новую версию шаблона Go text/template. В шаблоне доступны переменные запроса {{.Language}}, {{.Detail}}, {{.Audience}}, {{.Format}} и поля запроса к webhook {{.Input.\u003cполе\u003e}}, например {{.Input.content}}. Отрисованный шаблон отправляется в webhook полем prompt, номер версии — полем prompt_version. При activate=true версия сразу становится активной, закешированные результаты других версий удаляются. Доступно администраторам",
                 "consumes": [
                     "application/json"
                 ],
@@ -118,25 +132,39 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "ai"
+                    "admin"
                 ],
-                "summary": "Generate Markdown summary",
+                "summary": "Create version of prompt template",
                 "parameters": [
                     {
-                        "description": "Text content to summarize",
-                        "name": "Content",
+                        "enum": [
+                            "generatemd",
+                            "reducemd",
+                            "gentest",
+                            "flashcards",
+                            "ask"
+                        ],
+                        "type": "string",
+                        "description": "Template name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Template",
+                        "name": "Template",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/views.GenerateMDRequest"
+                            "$ref": "#/definitions/views.PromptTemplateRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/views.MarkdownResponse"
+                            "$ref": "#/definitions/views.PromptTemplate"
                         }
                     },
                     "400": {
@@ -145,6 +173,24 @@ const docTemplate = `{
                             "$ref": "#/definitions/views.SWGError"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
                     "502": {
                         "description": "Bad Gateway",
                         "schema": {
@@ -154,36 +200,44 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/ai/generatemd-test": {
-            "post": {
-                "description": "Принимает текст и отправляет его в n8n webhook, который генерирует Markdown-конспект через LLM",
+        "/api/admin/prompts/{name}/active": {
+            "put": {
+                "description": "Делает версию шаблона активной, версия 0 отключает шаблон, и webhook использует промпт своего n8n workflow. Закешированные результаты других версий удаляются. Доступно администраторам",
                 "consumes": [
                     "application/json"
                 ],
-                "produces": [
-                    "application/json"
-                ],
                 "tags": [
-                    "ai"
+                    "admin"
                 ],
-                "summary": "Generate Markdown summary",
+                "summary": "Activate version of prompt template",
                 "parameters": [
                     {
-                        "description": "Text content to summarize",
-                        "name": "Content",
+                        "enum": [
+                            "generatemd",
+                            "reducemd",
+                            "gentest",
+                            "flashcards",
+                            "ask"
+                        ],
+                        "type": "string",
+                        "description": "Template name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Version",
+                        "name": "Version",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/views.GenerateMDRequest"
+                            "$ref": "#/definitions/views.PromptActivateRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/views.MarkdownResponse"
-                        }
+                    "204": {
+                        "description": "No Content"
                     },
                     "400": {
                         "description": "Bad Request",
@@ -191,6 +245,24 @@ const docTemplate = `{
                             "$ref": "#/definitions/views.SWGError"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
                     "502": {
                         "description": "Bad Gateway",
                         "schema": {
@@ -200,9 +272,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/ai/gentest": {
+        "/api/admin/prompts/{name}/preview": {
             "post": {
-                "description": "Принимает контекст/промт и отправляет его в n8n webhook, который генерирует задания (тесты, вопросы) в формате Markdown на основе этого контекста",
+                "description": "Отрисовывает шаблон без вызова LLM: переданный body, чтобы проверить ещё не сохранённый шаблон, иначе версию version или активную версию. Переменные берутся из prompt, поля запроса к webhook — из input. Доступно администраторам",
                 "consumes": [
                     "application/json"
                 ],
@@ -210,17 +282,31 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "ai"
+                    "admin"
                 ],
-                "summary": "Generate tasks in Markdown",
+                "summary": "Preview prompt template",
                 "parameters": [
                     {
-                        "description": "Context and/or prompt for tasks generation",
-                        "name": "Content",
+                        "enum": [
+                            "generatemd",
+                            "reducemd",
+                            "gentest",
+                            "flashcards",
+                            "ask"
+                        ],
+                        "type": "string",
+                        "description": "Template name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Template and its data",
+                        "name": "Preview",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/views.GenerateTasksRequest"
+                            "$ref": "#/definitions/views.PromptPreviewRequest"
                         }
                     }
                 ],
@@ -228,7 +314,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/views.TasksMarkdownResponse"
+                            "$ref": "#/definitions/views.PromptPreview"
                         }
                     },
                     "400": {
@@ -237,6 +323,24 @@ const docTemplate = `{
                             "$ref": "#/definitions/views.SWGError"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
                     "502": {
                         "description": "Bad Gateway",
                         "schema": {
@@ -246,25 +350,36 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/ai/transcribe": {
-            "post": {
-                "description": "Принимает аудио-файл, отправляет его в сервис транскрипции и возвращает текст",
-                "consumes": [
-                    "multipart/form-data"
-                ],
+        "/api/admin/prompts/{name}/versions/{version}": {
+            "get": {
+                "description": "Возвращает версию шаблона, версия 0 — активная. Доступно администраторам",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "ai"
+                    "admin"
                 ],
-                "summary": "Transcribe audio file",
+                "summary": "Get version of prompt template",
                 "parameters": [
                     {
-                        "type": "file",
-                        "description": "Audio file",
-                        "name": "file",
-                        "in": "formData",
+                        "enum": [
+                            "generatemd",
+                            "reducemd",
+                            "gentest",
+                            "flashcards",
+                            "ask"
+                        ],
+                        "type": "string",
+                        "description": "Template name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Version, 0 is active one",
+                        "name": "version",
+                        "in": "path",
                         "required": true
                     }
                 ],
@@ -272,7 +387,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/views.TranscribeResponse"
+                            "$ref": "#/definitions/views.PromptTemplate"
                         }
                     },
                     "400": {
@@ -281,6 +396,24 @@ const docTemplate = `{
                             "$ref": "#/definitions/views.SWGError"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
                     "502": {
                         "description": "Bad Gateway",
                         "schema": {
@@ -290,9 +423,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/auth": {
+        "/api/ai/ask": {
             "post": {
-                "description": "Authenticates user and returns access/refresh tokens",
+                "description": "Ищет top_k фрагментов, наиболее близких к вопросу, среди документов пользователя, проиндексированных через file2db, и отправляет их вместе с вопросом в n8n webhook ask. Ответ ссылается на фрагменты номерами [N], для каждого номера возвращается файл и смещения фрагмента в нём",
                 "consumes": [
                     "application/json"
                 ],
@@ -300,17 +433,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "ai"
                 ],
-                "summary": "Authorize user",
+                "summary": "Ask question about documents",
                 "parameters": [
                     {
-                        "description": "Login or Email and Password",
-                        "name": "User",
+                        "description": "Question",
+                        "name": "Request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/views.AuthRequest"
+                            "$ref": "#/definitions/views.AskRequest"
                         }
                     }
                 ],
@@ -318,7 +451,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/views.Tokens"
+                            "$ref": "#/definitions/views.AskResponse"
                         }
                     },
                     "400": {
@@ -333,100 +466,125 @@ const docTemplate = `{
                             "$ref": "#/definitions/views.SWGError"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds until quota is reset"
+                            }
+                        }
+                    },
                     "502": {
                         "description": "Bad Gateway",
                         "schema": {
                             "$ref": "#/definitions/views.SWGError"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds until upstream is called again"
+                            }
+                        }
                     }
                 }
             }
         },
-        "/api/auth/reg": {
+        "/api/ai/file2db": {
             "post": {
-                "description": "Validates registration data, creates user and returns tokens",
+                "description": "Сохраняет файл в библиотеку документов (/api/documents), извлекает текст из PDF, DOCX, TXT или MD, режет его на перекрывающиеся фрагменты, считает эмбеддинги и сохраняет их в векторное хранилище текущего пользователя. По этим фрагментам отвечает /api/ai/ask. Вместо файла можно передать id завершённой загрузки /api/uploads. Содержимое файла должно соответствовать расширению. По завершении webhook пользователя получают событие document.indexed или document.failed",
                 "consumes": [
-                    "application/json"
+                    "multipart/form-data"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "ai"
                 ],
-                "summary": "Register new user",
+                "summary": "Index file for search",
                 "parameters": [
                     {
-                        "description": "Reg data",
-                        "name": "User",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/views.UserRegister"
-                        }
+                        "type": "file",
+                        "description": "File to index",
+                        "name": "file",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Id of completed upload, used instead of file",
+                        "name": "upload_id",
+                        "in": "formData"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/views.SWGError"
+                            "$ref": "#/definitions/views.IngestReport"
                         }
                     },
-                    "302": {
-                        "description": "Found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/views.SWGError"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/views.SWGError"
                         }
                     },
-                    "502": {
-                        "description": "Bad Gateway",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/views.SWGError"
                         }
-                    }
-                }
-            }
-        },
-        "/api/auth/token": {
-            "get": {
-                "description": "Checks access token from cookie, tries to refresh if expired. If 410 (GONE) need to re-auth",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "auth"
-                ],
-                "summary": "Validate token (uses cookies)",
-                "responses": {
-                    "200": {
-                        "description": "OK",
+                    },
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
-                            "$ref": "#/definitions/views.SWGMessage"
+                            "$ref": "#/definitions/views.SWGError"
                         }
                     },
-                    "201": {
-                        "description": "Created",
+                    "413": {
+                        "description": "Request Entity Too Large",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/views.SWGError"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "415": {
+                        "description": "Unsupported Media Type",
                         "schema": {
                             "$ref": "#/definitions/views.SWGError"
                         }
                     },
-                    "410": {
-                        "description": "Gone",
+                    "429": {
+                        "description": "Too Many Requests",
                         "schema": {
                             "$ref": "#/definitions/views.SWGError"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds until quota is reset"
+                            }
                         }
                     },
                     "502": {
@@ -434,73 +592,4318 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/views.SWGError"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds until upstream is called again"
+                            }
+                        }
                     }
                 }
             }
         },
-        "/api/health": {
-            "get": {
-                "produces": [
+        "/api/ai/file2dbtest": {
+            "post": {
+                "description": "Принимает файл, отправляет его в n8n webhook file2db, который сохраняет данные во векторную БД",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "healthz"
+                    "ai"
+                ],
+                "summary": "Upload file to vector DB",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "File to index",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
                 ],
-                "summary": "check health of gateway",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/views.SWGMessage"
+                            "$ref": "#/definitions/views.File2DBResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds until quota is reset"
+                            }
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds until upstream is called again"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ai/generatemd": {
+            "post": {
+                "description": "Принимает текст и отправляет его в n8n webhook, который генерирует Markdown-конспект через LLM. Текст длиннее контекста LLM режется на части по summary_chunk_tokens токенов с перекрытием, части конспектируются параллельно, неудачные вызовы повторяются, затем конспекты частей сводятся в один (webhook reducemd) с единой структурой заголовков: # title, ## раздел, ### подраздел. Поле options задаёт язык конспекта (тег BCP 47), подробность (brief, standard, detailed) и стиль (outline — план, cornell — конспект Корнелла, mindmap — карта памяти); неизвестные значения отклоняются с 400, выбранные параметры передаются в n8n вместе с инструкциями для LLM. Если часть так и не удалось законспектировать, конспект собирается из остальных, а их номера возвращаются в failed_chunks; такой конспект не кешируется. При save=true конспект сохраняется как заметка текущего пользователя. Результат кешируется по хешу нормализованного текста, options, версии промпта и модели: повторный запрос отдаётся из кеша (заголовок X-Cache: HIT), Cache-Control: no-cache запрашивает новую генерацию, no-store ещё и не сохраняет её",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ai"
+                ],
+                "summary": "Generate Markdown summary",
+                "parameters": [
+                    {
+                        "description": "Text content to summarize",
+                        "name": "Content",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/views.GenerateMDRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "no-cache to generate again, no-store to also not cache result",
+                        "name": "Cache-Control",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/views.MarkdownResponse"
+                        },
+                        "headers": {
+                            "X-Cache": {
+                                "type": "string",
+                                "description": "HIT, MISS or BYPASS"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds until quota is reset"
+                            }
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds until upstream is called again"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ai/generatemd-test": {
+            "post": {
+                "description": "Принимает текст и отправляет его в n8n webhook, который генерирует Markdown-конспект через LLM",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ai"
+                ],
+                "summary": "Generate Markdown summary",
+                "parameters": [
+                    {
+                        "description": "Text content to summarize",
+                        "name": "Content",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/views.GenerateMDRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/views.MarkdownResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds until quota is reset"
+                            }
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds until upstream is called again"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ai/gentest": {
+            "post": {
+                "description": "Принимает контекст/промт и отправляет его в n8n webhook, который генерирует тест по JSON-схеме. Ответ LLM проверяется и чинится, при невалидном ответе запрос повторяется со списком ошибок. Поле options задаёт язык вопросов (тег BCP 47), число вопросов question_count (до 30), сложность (easy, medium, hard, mixed) и допустимые типы question_types; неизвестные значения отклоняются с 400, тест, не соответствующий options, запрашивается заново, лишние вопросы отбрасываются. При save=true тест сохраняется как заметка в формате Markdown, при save_quiz=true — как тест для прохождения в /api/quizzes. Результат кешируется по хешу нормализованного текста, options, версии промпта и модели: повторный запрос отдаётся из кеша (заголовок X-Cache: HIT), Cache-Control: no-cache запрашивает новую генерацию, no-store ещё и не сохраняет её",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ai"
+                ],
+                "summary": "Generate quiz",
+                "parameters": [
+                    {
+                        "description": "Context and/or prompt for tasks generation",
+                        "name": "Content",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/views.GenerateTasksRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "no-cache to generate again, no-store to also not cache result",
+                        "name": "Cache-Control",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/views.QuizResponse"
+                        },
+                        "headers": {
+                            "X-Cache": {
+                                "type": "string",
+                                "description": "HIT, MISS or BYPASS"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds until quota is reset"
+                            }
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds until upstream is called again"
+                            }
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "views.AuthRequest": {
+        },
+        "/api/ai/lecture": {
+            "post": {
+                "description": "Принимает запись лекции (файл или id завершённой загрузки /api/uploads), расшифровывает её, делит длинную расшифровку на части по summary_chunk_tokens токенов, параллельно конспектирует части через LLM (неудачные вызовы повторяются, часть, которую так и не удалось законспектировать, помечается failed) и собирает из них один Markdown-конспект с разделами по времени. Если quiz=true, по конспекту генерируется тест. С заголовком Accept: text/event-stream ход обработки приходит событиями progress (views.LectureStage), итог — событием result (views.LectureResponse) или error (views.SWGError); иначе возвращается JSON после завершения всех этапов. По завершении webhook пользователя получают событие lecture.completed (data — views.LectureResponse) или lecture.failed",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "ai"
+                ],
+                "summary": "Lecture recording to note",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Audio or video recording",
+                        "name": "file",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Id of completed upload, used instead of file",
+                        "name": "upload_id",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Title of note",
+                        "name": "title",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Generate quiz from note",
+                        "name": "quiz",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Save note",
+                        "name": "save",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Save generated quiz",
+                        "name": "save_quiz",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Prompt options as JSON object, see views.PromptOptions",
+                        "name": "prompt",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/views.LectureResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds until quota is reset"
+                            }
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds until upstream is called again"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ai/transcribe": {
+            "post": {
+                "description": "Принимает аудио- или видеофайл или id завершённой загрузки /api/uploads, отправляет его в сервис транскрипции и возвращает текст с сегментами по времени, субтитры SRT или WebVTT либо текст с метками времени. Тип файла определяется по содержимому, архивы и исполняемые файлы отклоняются. Если запрос с токеном, по завершении зарегистрированным webhook пользователя отправляется событие transcription.completed или transcription.failed",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/x-subrip",
+                    "text/vtt",
+                    "text/plain"
+                ],
+                "tags": [
+                    "ai"
+                ],
+                "summary": "Transcribe audio file",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Audio file",
+                        "name": "file",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Id of completed upload, used instead of file",
+                        "name": "upload_id",
+                        "in": "formData"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "srt",
+                            "vtt",
+                            "text"
+                        ],
+                        "type": "string",
+                        "description": "Response format: json with segments, srt or vtt subtitles, text with timestamps",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Save transcript as note",
+                        "name": "save",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Title of saved note",
+                        "name": "title",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/views.TranscribeResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds until quota is reset"
+                            }
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds until upstream is called again"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth": {
+            "post": {
+                "description": "Authenticates user and returns access/refresh tokens",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Authorize user",
+                "parameters": [
+                    {
+                        "description": "Login or Email and Password",
+                        "name": "User",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/views.AuthRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/views.Tokens"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds until next request is allowed"
+                            }
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/reg": {
+            "post": {
+                "description": "Validates registration data, creates user and returns tokens",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Register new user",
+                "parameters": [
+                    {
+                        "description": "Reg data",
+                        "name": "User",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/views.UserRegister"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "302": {
+                        "description": "Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds until next request is allowed"
+                            }
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/token": {
+            "get": {
+                "description": "Checks access token from cookie, tries to refresh if expired. If 410 (GONE) need to re-auth",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Validate token (uses cookies)",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGMessage"
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "410": {
+                        "description": "Gone",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/cards": {
+            "get": {
+                "description": "Returns all flashcards of current user, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cards"
+                ],
+                "summary": "List flashcards",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/views.Card"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Creates flashcard of current user, due immediately",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cards"
+                ],
+                "summary": "Create flashcard",
+                "parameters": [
+                    {
+                        "description": "Card",
+                        "name": "Card",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/views.CardRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/views.Card"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/cards/due": {
+            "get": {
+                "description": "Returns flashcards which should be reviewed now, most overdue first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cards"
+                ],
+                "summary": "Due flashcards",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Max number of cards, 20 by default, up to 100",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/views.Card"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/cards/export": {
+            "get": {
+                "description": "Returns all flashcards of current user as Anki deck. Cards keep their ids between exports, so reimport updates deck instead of duplicating",
+                "produces": [
+                    "application/apkg"
+                ],
+                "tags": [
+                    "cards"
+                ],
+                "summary": "Export flashcards",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/cards/generate": {
+            "post": {
+                "description": "Отправляет заметку пользователя (note_id) или текст (content) в n8n webhook flashcards, который генерирует карточки вопрос/ответ через LLM. Карточки сохраняются и сразу доступны для повторения",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cards"
+                ],
+                "summary": "Generate flashcards",
+                "parameters": [
+                    {
+                        "description": "Source of cards",
+                        "name": "Request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/views.GenerateCardsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/views.Card"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds until quota is reset"
+                            }
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds until upstream is called again"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/cards/{id}": {
+            "delete": {
+                "description": "Deletes flashcard of current user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cards"
+                ],
+                "summary": "Delete flashcard",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Card id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGMessage"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/cards/{id}/review": {
+            "post": {
+                "description": "Applies recall grade from 0 to 5 and schedules next review by SM-2 algorithm. Grade below 3 means card is forgotten and will be shown again tomorrow. Cards which are not due yet can't be reviewed",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cards"
+                ],
+                "summary": "Review flashcard",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Card id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Recall grade",
+                        "name": "Review",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/views.CardReview"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/views.Card"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/documents": {
+            "get": {
+                "description": "Returns documents uploaded by current user, newest first. Ingestion reports are omitted",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "documents"
+                ],
+                "summary": "List documents",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/views.Document"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/documents/{id}": {
+            "get": {
+                "description": "Returns document of current user with its last ingestion report",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "documents"
+                ],
+                "summary": "Get document",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Document id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/views.Document"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes document of current user and its chunks from vector store, so /api/ai/ask no longer cites it",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "documents"
+                ],
+                "summary": "Delete document",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Document id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGMessage"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/documents/{id}/reindex": {
+            "post": {
+                "description": "Indexes stored file again with current embedder and chunking settings, replacing its chunks in vector store. If indexing fails, previous chunks are kept and document gets failed status. Webhooks of owner get document.indexed or document.failed event",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "documents"
+                ],
+                "summary": "Reindex document",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Document id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/views.IngestReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds until quota is reset"
+                            }
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds until upstream is called again"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/export": {
+            "post": {
+                "description": "Рендерит markdown конспект или тест (например, результат generatemd или gentest) в PDF, DOCX или колоду Anki. В PDF и DOCX сохраняются заголовки, списки, таблицы и блоки кода. Колода Anki строится по разделам конспекта (заголовок — вопрос, содержимое — ответ) или по вопросам теста",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/pdf",
+                    "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+                    "application/apkg"
+                ],
+                "tags": [
+                    "export"
+                ],
+                "summary": "Export document",
+                "parameters": [
+                    {
+                        "enum": [
+                            "pdf",
+                            "docx",
+                            "apkg"
+                        ],
+                        "type": "string",
+                        "description": "Output format",
+                        "name": "format",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Markdown or quiz",
+                        "name": "Request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/views.ExportRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/health": {
+            "get": {
+                "description": "Возвращает состояние circuit breaker каждого внешнего сервиса. Пока breaker открыт, запросы к сервису не отправляются и AI-эндпоинты отвечают 503 с заголовком Retry-After, статус при этом degraded",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "healthz"
+                ],
+                "summary": "check health of gateway",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/views.Health"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/notes": {
+            "get": {
+                "description": "Returns all notes of current user, most recently updated first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "List notes",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/views.Note"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Saves markdown note of current user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Create note",
+                "parameters": [
+                    {
+                        "description": "Note",
+                        "name": "Note",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/views.NoteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/views.NoteId"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/notes/{id}": {
+            "get": {
+                "description": "Returns note of current user by id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Get note",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Note id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/views.Note"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replaces title and body of note and stores them as new revision. Source of note can't be changed",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Update note",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Note id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New title and body",
+                        "name": "Note",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/views.NoteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGMessage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes note of current user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Delete note",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Note id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGMessage"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/notes/{id}/diff": {
+            "get": {
+                "description": "Returns unified diff between two revisions. By default to is head revision and from is revision before it. With format=text diff returned as plain text",
+                "produces": [
+                    "application/json",
+                    "text/plain"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Diff two note revisions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Note id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Old revision number",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "New revision number",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "text"
+                        ],
+                        "type": "string",
+                        "description": "Response format",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/views.NoteDiff"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/notes/{id}/export": {
+            "get": {
+                "description": "Returns note of current user as PDF, DOCX or Anki deck with one card per section",
+                "produces": [
+                    "application/pdf",
+                    "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+                    "application/apkg"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Export note",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Note id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "pdf",
+                            "docx",
+                            "apkg"
+                        ],
+                        "type": "string",
+                        "description": "Output format",
+                        "name": "format",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/notes/{id}/revisions": {
+            "get": {
+                "description": "Returns revisions of note without bodies, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "List note revisions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Note id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/views.NoteRevision"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/notes/{id}/revisions/{rev}": {
+            "get": {
+                "description": "Returns one revision of note with body",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Get note revision",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Note id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Revision number",
+                        "name": "rev",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/views.NoteRevision"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/notes/{id}/revisions/{rev}/restore": {
+            "post": {
+                "description": "Copies old revision into note as new head revision. History is not rewritten",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notes"
+                ],
+                "summary": "Restore note revision",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Note id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Revision number to restore",
+                        "name": "rev",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/views.NoteRevision"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/quizzes": {
+            "get": {
+                "description": "Returns quizzes of current user without questions, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "quizzes"
+                ],
+                "summary": "List quizzes",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/views.SavedQuiz"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Validates quiz (e.g. returned by /api/ai/gentest) and saves it for current user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "quizzes"
+                ],
+                "summary": "Save quiz",
+                "parameters": [
+                    {
+                        "description": "Quiz",
+                        "name": "Quiz",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/views.Quiz"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/views.QuizId"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/quizzes/{id}": {
+            "get": {
+                "description": "Returns quiz of current user with questions and correct answers",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "quizzes"
+                ],
+                "summary": "Get quiz",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Quiz id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/views.SavedQuiz"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes quiz of current user with all attempts",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "quizzes"
+                ],
+                "summary": "Delete quiz",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Quiz id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGMessage"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/quizzes/{id}/attempts": {
+            "get": {
+                "description": "Returns history of attempts at quiz, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "quizzes"
+                ],
+                "summary": "List quiz attempts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Quiz id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/views.QuizAttempt"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Starts new attempt and returns questions without correct answers",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "quizzes"
+                ],
+                "summary": "Start quiz attempt",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Quiz id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/views.QuizAttempt"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/quizzes/{id}/attempts/{attempt}": {
+            "get": {
+                "description": "Returns attempt. Started attempt contains questions, submitted one contains results",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "quizzes"
+                ],
+                "summary": "Get quiz attempt",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Quiz id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Attempt id",
+                        "name": "attempt",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/views.QuizAttempt"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/quizzes/{id}/attempts/{attempt}/submit": {
+            "post": {
+                "description": "Grades answers and finishes attempt. Closed questions are scored exactly, open answers are scored by configured grader (LLM or keywords). Each question costs 1 point. Every open answer graded by LLM counts as one request of AI quota",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "quizzes"
+                ],
+                "summary": "Submit quiz attempt",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Quiz id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Attempt id",
+                        "name": "attempt",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Answers",
+                        "name": "Answers",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/views.AttemptSubmit"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/views.QuizAttempt"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds until quota is reset"
+                            }
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds until upstream is called again"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/quizzes/{id}/export": {
+            "get": {
+                "description": "Returns quiz of current user as printable PDF or DOCX with answer key, or as Anki deck with one card per question",
+                "produces": [
+                    "application/pdf",
+                    "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+                    "application/apkg"
+                ],
+                "tags": [
+                    "quizzes"
+                ],
+                "summary": "Export quiz",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Quiz id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "pdf",
+                            "docx",
+                            "apkg"
+                        ],
+                        "type": "string",
+                        "description": "Output format",
+                        "name": "format",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/quizzes/{id}/stats": {
+            "get": {
+                "description": "Returns best, worst, average and last score of submitted attempts and average score of every question, all in percents",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "quizzes"
+                ],
+                "summary": "Quiz statistics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Quiz id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/views.QuizStats"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/uploads": {
+            "post": {
+                "description": "Creates tus upload of Upload-Length bytes for current user and returns its URL in Location. Body with Content-Type application/offset+octet-stream is written as first chunk. Completed upload is passed to /api/ai/transcribe or /api/ai/file2db as upload_id",
+                "consumes": [
+                    "application/offset+octet-stream"
+                ],
+                "tags": [
+                    "uploads"
+                ],
+                "summary": "Create resumable upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "1.0.0",
+                        "description": "Protocol version",
+                        "name": "Tus-Resumable",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Size of file in bytes",
+                        "name": "Upload-Length",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated keys with base64 values, e.g. filename",
+                        "name": "Upload-Metadata",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Checksum of first chunk: algorithm and base64 digest",
+                        "name": "Upload-Checksum",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "460": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            },
+            "options": {
+                "description": "Returns supported tus version, extensions, checksum algorithms and maximum upload size in headers",
+                "tags": [
+                    "uploads"
+                ],
+                "summary": "Tus server capabilities",
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/api/uploads/{id}": {
+            "delete": {
+                "description": "Terminates upload of current user and removes received data",
+                "tags": [
+                    "uploads"
+                ],
+                "summary": "Delete upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Upload id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "1.0.0",
+                        "description": "Protocol version",
+                        "name": "Tus-Resumable",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "423": {
+                        "description": "Locked",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            },
+            "head": {
+                "description": "Returns in headers how many bytes of upload are received, so client resumes from Upload-Offset",
+                "tags": [
+                    "uploads"
+                ],
+                "summary": "Get upload offset",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Upload id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "1.0.0",
+                        "description": "Protocol version",
+                        "name": "Tus-Resumable",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Writes request body at Upload-Offset, which must be equal to current offset of upload. Chunk with Upload-Checksum is verified and discarded on mismatch with status 460",
+                "consumes": [
+                    "application/offset+octet-stream"
+                ],
+                "tags": [
+                    "uploads"
+                ],
+                "summary": "Append chunk to upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Upload id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "1.0.0",
+                        "description": "Protocol version",
+                        "name": "Tus-Resumable",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset of chunk",
+                        "name": "Upload-Offset",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Algorithm and base64 digest of chunk",
+                        "name": "Upload-Checksum",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "423": {
+                        "description": "Locked",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "460": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/me/usage": {
+            "get": {
+                "description": "Возвращает использование AI текущим пользователем за сутки и месяц (UTC): число запросов, объём входных данных, секунды аудио и оценку токенов, всего и по эндпоинтам, а также квоты его тарифа (0 — без ограничения). Ответы из кеша, запросы, отклонённые как некорректные, и запросы, не отправленные недоступному внешнему сервису (503), в квоту не засчитываются; выполняющиеся запросы засчитываются сразу. Запросы сверх квоты получают 429 с заголовком Retry-After до сброса квоты",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "AI usage of current user",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/views.UsageSummary"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/webhooks": {
+            "get": {
+                "description": "Returns webhooks of current user without secrets, oldest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List webhooks",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/views.Webhook"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Registers callback URL of current user. When subscribed job (transcription, document indexing, lecture) finishes or fails, POST with views.WebhookEvent is sent to URL. Request carries headers X-Flicker-Event, X-Flicker-Delivery, X-Flicker-Timestamp (unix seconds) and X-Flicker-Signature: \"sha256=\" and hex HMAC-SHA256 of timestamp, \".\" and body by secret of webhook. Secret is returned only in this response. Delivery without 2xx answer is retried with exponential backoff. Empty events subscribe to all events",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Register webhook",
+                "parameters": [
+                    {
+                        "description": "Callback URL and events",
+                        "name": "Webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/views.WebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/views.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/webhooks/{id}": {
+            "delete": {
+                "description": "Deletes webhook of current user with its delivery log. Deliveries already being retried are still sent",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Delete webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGMessage"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/webhooks/{id}/deliveries": {
+            "get": {
+                "description": "Returns last 50 deliveries of webhook of current user, newest first: payload, status (pending while retries go on), number of attempts, status code and error of last attempt",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List webhook deliveries",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/views.WebhookDelivery"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/webhooks/{id}/ping": {
+            "post": {
+                "description": "Sends signed ping event to webhook of current user once, without retries, and returns delivery with answer of receiver. Delivery is added to log",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Test webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/views.WebhookDelivery"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/views.SWGError"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "views.Answer": {
+            "type": "object",
+            "properties": {
+                "options": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        1
+                    ]
+                },
+                "question": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "text": {
+                    "type": "string",
+                    "example": "Наука о тепловых процессах"
+                }
+            }
+        },
+        "views.AskRequest": {
+            "type": "object",
+            "properties": {
+                "prompt": {
+                    "$ref": "#/definitions/views.PromptOptions"
+                },
+                "question": {
+                    "type": "string",
+                    "example": "Чем отличается изотермический процесс от адиабатного?"
+                },
+                "top_k": {
+                    "type": "integer",
+                    "maximum": 20,
+                    "minimum": 1,
+                    "example": 5
+                }
+            }
+        },
+        "views.AskResponse": {
+            "type": "object",
+            "properties": {
+                "answer": {
+                    "type": "string",
+                    "example": "Изотермический процесс идёт при постоянной температуре [1], а адиабатный — без теплообмена [2]."
+                },
+                "citations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/views.Citation"
+                    }
+                },
+                "prompt_version": {
+                    "description": "PromptVersion — версия промпта, которым получен ответ",
+                    "type": "string",
+                    "example": "ask=t1"
+                }
+            }
+        },
+        "views.AttemptQuestion": {
+            "type": "object",
+            "properties": {
+                "difficulty": {
+                    "type": "string",
+                    "enum": [
+                        "easy",
+                        "medium",
+                        "hard"
+                    ],
+                    "example": "easy"
+                },
+                "options": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "Тепловые процессы",
+                        "Движение планет"
+                    ]
+                },
+                "text": {
+                    "type": "string",
+                    "example": "Что изучает термодинамика?"
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "single",
+                        "multi",
+                        "open",
+                        "true_false"
+                    ],
+                    "example": "single"
+                }
+            }
+        },
+        "views.AttemptSubmit": {
+            "type": "object",
+            "properties": {
+                "answers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/views.Answer"
+                    }
+                }
+            }
+        },
+        "views.AuthRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "login": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "views.Card": {
+            "type": "object",
+            "properties": {
+                "back": {
+                    "type": "string",
+                    "example": "Тепловые процессы и превращения энергии"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "due_at": {
+                    "type": "string"
+                },
+                "ease": {
+                    "type": "number",
+                    "example": 2.5
+                },
+                "front": {
+                    "type": "string",
+                    "example": "Что изучает термодинамика?"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "d4c1b3c2-..."
+                },
+                "interval_days": {
+                    "type": "integer",
+                    "example": 6
+                },
+                "lapses": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "note_id": {
+                    "type": "string",
+                    "example": "d4c1b3c2-..."
+                },
+                "owner": {
+                    "type": "string"
+                },
+                "prompt_version": {
+                    "description": "PromptVersion — версия промпта, которым сгенерирована карточка",
+                    "type": "string",
+                    "example": "flashcards=t1"
+                },
+                "repetitions": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "reviewed_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "views.CardRequest": {
+            "type": "object",
+            "properties": {
+                "back": {
+                    "type": "string",
+                    "example": "Тепловые процессы и превращения энергии"
+                },
+                "front": {
+                    "type": "string",
+                    "example": "Что изучает термодинамика?"
+                }
+            }
+        },
+        "views.CardReview": {
+            "type": "object",
+            "properties": {
+                "grade": {
+                    "type": "integer",
+                    "maximum": 5,
+                    "minimum": 0,
+                    "example": 4
+                }
+            }
+        },
+        "views.Citation": {
+            "type": "object",
+            "properties": {
+                "chunk": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "document_id": {
+                    "type": "string",
+                    "example": "d4c1b3c2-..."
+                },
+                "end": {
+                    "type": "integer",
+                    "example": 5400
+                },
+                "n": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "score": {
+                    "type": "number",
+                    "example": 0.83
+                },
+                "source": {
+                    "type": "string",
+                    "example": "lecture.pdf"
+                },
+                "start": {
+                    "type": "integer",
+                    "example": 4200
+                },
+                "text": {
+                    "type": "string",
+                    "example": "Изотермический процесс протекает при постоянной температуре..."
+                }
+            }
+        },
+        "views.Document": {
+            "type": "object",
+            "properties": {
+                "checksum": {
+                    "type": "string",
+                    "example": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+                },
+                "chunks": {
+                    "type": "integer",
+                    "example": 48
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string",
+                    "example": "no text in file"
+                },
+                "filename": {
+                    "type": "string",
+                    "example": "lecture.pdf"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "d4c1b3c2-..."
+                },
+                "indexed_at": {
+                    "type": "string"
+                },
+                "mime_type": {
+                    "type": "string",
+                    "example": "application/pdf"
+                },
+                "owner": {
+                    "type": "string"
+                },
+                "report": {
+                    "$ref": "#/definitions/views.IngestReport"
+                },
+                "size": {
+                    "type": "integer",
+                    "example": 183204
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "indexing",
+                        "indexed",
+                        "failed"
+                    ],
+                    "example": "indexed"
+                }
+            }
+        },
+        "views.ExportRequest": {
+            "type": "object",
+            "properties": {
+                "markdown": {
+                    "type": "string",
+                    "example": "# Конспект ..."
+                },
+                "quiz": {
+                    "$ref": "#/definitions/views.Quiz"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Лекция 1. Введение"
+                }
+            }
+        },
+        "views.File2DBResponse": {
+            "type": "object",
+            "additionalProperties": true
+        },
+        "views.GenerateCardsRequest": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string",
+                    "example": "# Конспект ..."
+                },
+                "count": {
+                    "type": "integer",
+                    "maximum": 50,
+                    "minimum": 1,
+                    "example": 10
+                },
+                "note_id": {
+                    "type": "string",
+                    "example": "d4c1b3c2-..."
+                },
+                "prompt": {
+                    "$ref": "#/definitions/views.PromptOptions"
+                }
+            }
+        },
+        "views.GenerateMDRequest": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string",
+                    "example": "Текст документа, который нужно законспектировать"
+                },
+                "options": {
+                    "$ref": "#/definitions/views.SummaryOptions"
+                },
+                "prompt": {
+                    "$ref": "#/definitions/views.PromptOptions"
+                },
+                "save": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "source_ref": {
+                    "type": "string",
+                    "example": "lecture.mp3"
+                },
+                "source_type": {
+                    "type": "string",
+                    "enum": [
+                        "text",
+                        "file",
+                        "transcript"
+                    ],
+                    "example": "text"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Лекция 1. Введение"
+                }
+            }
+        },
+        "views.GenerateTasksRequest": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string",
+                    "example": "Контекст и/или промт для генерации заданий"
+                },
+                "options": {
+                    "$ref": "#/definitions/views.QuizOptions"
+                },
+                "prompt": {
+                    "$ref": "#/definitions/views.PromptOptions"
+                },
+                "save": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "save_quiz": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "source_ref": {
+                    "type": "string",
+                    "example": "lecture.mp3"
+                },
+                "source_type": {
+                    "type": "string",
+                    "enum": [
+                        "text",
+                        "file",
+                        "transcript"
+                    ],
+                    "example": "text"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Лекция 1. Введение"
+                }
+            }
+        },
+        "views.Health": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "HEALTHZ"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "ok",
+                        "degraded"
+                    ],
+                    "example": "ok"
+                },
+                "upstreams": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/views.UpstreamState"
+                    }
+                }
+            }
+        },
+        "views.IngestReport": {
+            "type": "object",
+            "properties": {
+                "characters": {
+                    "type": "integer",
+                    "example": 48210
+                },
+                "chunk_overlap": {
+                    "type": "integer",
+                    "example": 200
+                },
+                "chunk_size": {
+                    "type": "integer",
+                    "example": 1200
+                },
+                "chunks": {
+                    "type": "integer",
+                    "example": 48
+                },
+                "dimensions": {
+                    "type": "integer",
+                    "example": 1536
+                },
+                "document_id": {
+                    "type": "string",
+                    "example": "d4c1b3c2-..."
+                },
+                "duration_ms": {
+                    "type": "integer",
+                    "example": 2350
+                },
+                "embedder": {
+                    "type": "string",
+                    "example": "openai:text-embedding-3-small"
+                },
+                "filename": {
+                    "type": "string",
+                    "example": "lecture.pdf"
+                },
+                "format": {
+                    "type": "string",
+                    "enum": [
+                        "pdf",
+                        "docx",
+                        "txt",
+                        "md"
+                    ],
+                    "example": "pdf"
+                },
+                "size": {
+                    "type": "integer",
+                    "example": 183204
+                },
+                "store": {
+                    "type": "string",
+                    "enum": [
+                        "pgvector",
+                        "memory"
+                    ],
+                    "example": "pgvector"
+                },
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "page 3 skipped: malformed content"
+                    ]
+                }
+            }
+        },
+        "views.LectureChunk": {
+            "type": "object",
+            "properties": {
+                "characters": {
+                    "type": "integer",
+                    "example": 11840
+                },
+                "end_sec": {
+                    "type": "number",
+                    "example": 1260
+                },
+                "failed": {
+                    "description": "Failed — часть не удалось законспектировать, в конспекте на её месте заглушка",
+                    "type": "boolean",
+                    "example": false
+                },
+                "index": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "start_sec": {
+                    "type": "number",
+                    "example": 0
+                },
+                "summary": {
+                    "type": "string",
+                    "example": "# Первое начало термодинамики ..."
+                }
+            }
+        },
+        "views.LectureResponse": {
+            "type": "object",
+            "properties": {
+                "chunks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/views.LectureChunk"
+                    }
+                },
+                "markdown": {
+                    "type": "string",
+                    "example": "# Лекция 3 ..."
+                },
+                "note_id": {
+                    "type": "string",
+                    "example": "d4c1b3c2-..."
+                },
+                "prompt_version": {
+                    "description": "PromptVersion — версии промптов, которыми сгенерированы конспект и тест",
+                    "type": "string",
+                    "example": "generatemd=t3;gentest=1"
+                },
+                "quiz": {
+                    "$ref": "#/definitions/views.Quiz"
+                },
+                "quiz_id": {
+                    "type": "string",
+                    "example": "d4c1b3c2-..."
+                },
+                "stages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/views.LectureStage"
+                    }
+                },
+                "transcript": {
+                    "$ref": "#/definitions/views.TranscribeResponse"
+                },
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "1 of 5 parts are not summarized"
+                    ]
+                }
+            }
+        },
+        "views.LectureStage": {
+            "type": "object",
+            "properties": {
+                "done": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "duration_ms": {
+                    "type": "integer",
+                    "example": 2350
+                },
+                "error": {
+                    "type": "string",
+                    "example": "n8n request error"
+                },
+                "name": {
+                    "type": "string",
+                    "enum": [
+                        "transcribe",
+                        "chunk",
+                        "summarize",
+                        "merge",
+                        "quiz",
+                        "save"
+                    ],
+                    "example": "summarize"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "running",
+                        "done",
+                        "failed"
+                    ],
+                    "example": "running"
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 5
+                }
+            }
+        },
+        "views.MarkdownResponse": {
+            "type": "object",
+            "properties": {
+                "chunks": {
+                    "description": "Chunks — на сколько частей был разрезан длинный текст, FailedChunks — части без конспекта",
+                    "type": "integer",
+                    "example": 4
+                },
+                "failed_chunks": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        2
+                    ]
+                },
+                "markdown": {
+                    "type": "string",
+                    "example": "# Конспект ..."
+                },
+                "note_id": {
+                    "type": "string",
+                    "example": "d4c1b3c2-..."
+                },
+                "prompt_version": {
+                    "description": "PromptVersion — версии промптов, которыми сгенерирован конспект",
+                    "type": "string",
+                    "example": "generatemd=t3;reducemd=1"
+                },
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "1 of 4 chunks are not summarized"
+                    ]
+                }
+            }
+        },
+        "views.Note": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "type": "string",
+                    "example": "# Конспект ..."
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "d4c1b3c2-..."
+                },
+                "owner": {
+                    "type": "string"
+                },
+                "prompt_version": {
+                    "description": "PromptVersion — версии промптов, которыми сгенерирована заметка",
+                    "type": "string",
+                    "example": "generatemd=t3;reducemd=1"
+                },
+                "revision": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "source_ref": {
+                    "type": "string",
+                    "example": "lecture.mp3"
+                },
+                "source_type": {
+                    "type": "string",
+                    "enum": [
+                        "text",
+                        "file",
+                        "transcript"
+                    ],
+                    "example": "text"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Лекция 1. Введение"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "views.NoteDiff": {
+            "type": "object",
+            "properties": {
+                "diff": {
+                    "type": "string",
+                    "example": "--- revision 1\n+++ revision 2\n@@ -1 +1 @@\n-# old\n+# new\n"
+                },
+                "from": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "to": {
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "views.NoteId": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "example": "d4c1b3c2-..."
+                }
+            }
+        },
+        "views.NoteRequest": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "type": "string",
+                    "example": "# Конспект ..."
+                },
+                "source_ref": {
+                    "type": "string",
+                    "example": "lecture.mp3"
+                },
+                "source_type": {
+                    "type": "string",
+                    "enum": [
+                        "text",
+                        "file",
+                        "transcript"
+                    ],
+                    "example": "text"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Лекция 1. Введение"
+                }
+            }
+        },
+        "views.NoteRevision": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "type": "string",
+                    "example": "# Конспект ..."
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "number": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "restored_from": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Лекция 1. Введение"
+                }
+            }
+        },
+        "views.PromptActivateRequest": {
+            "type": "object",
+            "properties": {
+                "version": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 3
+                }
+            }
+        },
+        "views.PromptOptions": {
+            "type": "object",
+            "properties": {
+                "audience": {
+                    "type": "string",
+                    "example": "first-year students"
+                },
+                "detail": {
+                    "type": "string",
+                    "example": "brief"
+                },
+                "format": {
+                    "type": "string",
+                    "example": "outline"
+                },
+                "language": {
+                    "type": "string",
+                    "example": "ru"
+                },
+                "templates": {
+                    "description": "Templates — версия шаблона по имени, для не указанных используется активная версия",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "views.PromptPreview": {
+            "type": "object",
+            "properties": {
+                "prompt": {
+                    "type": "string",
+                    "example": "Составь конспект на языке ru"
+                }
+            }
+        },
+        "views.PromptPreviewRequest": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "type": "string",
+                    "example": "Составь конспект на языке {{.Language}}"
+                },
+                "input": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "prompt": {
+                    "$ref": "#/definitions/views.PromptOptions"
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "views.PromptTemplate": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "author": {
+                    "type": "string",
+                    "example": "d4c1b3c2-..."
+                },
+                "body": {
+                    "type": "string",
+                    "example": "Составь конспект на языке {{.Language}} для {{.Audience}}"
+                },
+                "comment": {
+                    "type": "string",
+                    "example": "shorter sections"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "generatemd"
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "views.PromptTemplateRequest": {
+            "type": "object",
+            "properties": {
+                "activate": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "body": {
+                    "type": "string",
+                    "example": "Составь конспект на языке {{.Language}} для {{.Audience}}"
+                },
+                "comment": {
+                    "type": "string",
+                    "example": "shorter sections"
+                }
+            }
+        },
+        "views.Question": {
+            "type": "object",
+            "properties": {
+                "answer": {
+                    "type": "string",
+                    "example": "Тепловые процессы и превращения энергии"
+                },
+                "correct": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        0
+                    ]
+                },
+                "difficulty": {
+                    "type": "string",
+                    "enum": [
+                        "easy",
+                        "medium",
+                        "hard"
+                    ],
+                    "example": "easy"
+                },
+                "explanation": {
+                    "type": "string",
+                    "example": "Термодинамика — раздел физики о тепловых явлениях"
+                },
+                "keywords": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "тепло",
+                        "энергия"
+                    ]
+                },
+                "options": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "Тепловые процессы",
+                        "Движение планет"
+                    ]
+                },
+                "text": {
+                    "type": "string",
+                    "example": "Что изучает термодинамика?"
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "single",
+                        "multi",
+                        "open",
+                        "true_false"
+                    ],
+                    "example": "single"
+                }
+            }
+        },
+        "views.QuestionResult": {
+            "type": "object",
+            "properties": {
+                "answer": {
+                    "type": "string",
+                    "example": "Тепловые процессы и превращения энергии"
+                },
+                "correct": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "expected": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        0
+                    ]
+                },
+                "explanation": {
+                    "type": "string",
+                    "example": "Термодинамика — раздел физики о тепловых явлениях"
+                },
+                "feedback": {
+                    "type": "string",
+                    "example": "Не упомянута энергия"
+                },
+                "question": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "score": {
+                    "type": "number",
+                    "example": 1
+                }
+            }
+        },
+        "views.Quiz": {
+            "type": "object",
+            "properties": {
+                "questions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/views.Question"
+                    }
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Тест по термодинамике"
+                }
+            }
+        },
+        "views.QuizAttempt": {
             "type": "object",
             "properties": {
-                "email": {
-                    "type": "string"
+                "answers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/views.Answer"
+                    }
                 },
-                "login": {
+                "id": {
+                    "type": "string",
+                    "example": "d4c1b3c2-..."
+                },
+                "max_score": {
+                    "type": "number",
+                    "example": 10
+                },
+                "questions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/views.AttemptQuestion"
+                    }
+                },
+                "quiz_id": {
+                    "type": "string",
+                    "example": "d4c1b3c2-..."
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/views.QuestionResult"
+                    }
+                },
+                "score": {
+                    "type": "number",
+                    "example": 7.5
+                },
+                "started_at": {
                     "type": "string"
                 },
-                "password": {
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "started",
+                        "submitted"
+                    ],
+                    "example": "submitted"
+                },
+                "submitted_at": {
                     "type": "string"
                 }
             }
         },
-        "views.File2DBResponse": {
+        "views.QuizId": {
             "type": "object",
-            "additionalProperties": true
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "example": "d4c1b3c2-..."
+                }
+            }
         },
-        "views.GenerateMDRequest": {
+        "views.QuizOptions": {
             "type": "object",
             "properties": {
-                "content": {
+                "difficulty": {
                     "type": "string",
-                    "example": "Текст документа, который нужно законспектировать"
+                    "enum": [
+                        "easy",
+                        "medium",
+                        "hard",
+                        "mixed"
+                    ],
+                    "example": "mixed"
+                },
+                "language": {
+                    "description": "Language — язык вопросов, тег BCP 47",
+                    "type": "string",
+                    "example": "en"
+                },
+                "question_count": {
+                    "type": "integer",
+                    "maximum": 30,
+                    "minimum": 1,
+                    "example": 10
+                },
+                "question_types": {
+                    "type": "array",
+                    "items": {
+                        "type": "string",
+                        "enum": [
+                            "single",
+                            "multi",
+                            "open",
+                            "true_false"
+                        ]
+                    },
+                    "example": [
+                        "single",
+                        "open"
+                    ]
                 }
             }
         },
-        "views.GenerateTasksRequest": {
+        "views.QuizResponse": {
             "type": "object",
             "properties": {
-                "content": {
+                "note_id": {
                     "type": "string",
-                    "example": "Контекст и/или промт для генерации заданий"
+                    "example": "d4c1b3c2-..."
+                },
+                "prompt_version": {
+                    "description": "PromptVersion — версии промптов, которыми сгенерирован тест",
+                    "type": "string",
+                    "example": "gentest=t2"
+                },
+                "quiz": {
+                    "$ref": "#/definitions/views.Quiz"
+                },
+                "quiz_id": {
+                    "type": "string",
+                    "example": "d4c1b3c2-..."
                 }
             }
         },
-        "views.MarkdownResponse": {
+        "views.QuizStats": {
             "type": "object",
             "properties": {
-                "markdown": {
-                    "type": "string",
-                    "example": "# Конспект ..."
+                "attempts": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "average": {
+                    "type": "number",
+                    "example": 75
+                },
+                "best": {
+                    "type": "number",
+                    "example": 90
+                },
+                "last": {
+                    "type": "number",
+                    "example": 90
+                },
+                "per_question": {
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    },
+                    "example": [
+                        100,
+                        50
+                    ]
+                },
+                "submitted": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "worst": {
+                    "type": "number",
+                    "example": 60
                 }
             }
         },
@@ -522,12 +4925,66 @@ const docTemplate = `{
                 }
             }
         },
-        "views.TasksMarkdownResponse": {
+        "views.SavedQuiz": {
             "type": "object",
             "properties": {
-                "markdown": {
+                "count": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "d4c1b3c2-..."
+                },
+                "owner": {
+                    "type": "string"
+                },
+                "prompt_version": {
+                    "description": "PromptVersion — версии промптов, которыми сгенерирован тест",
+                    "type": "string",
+                    "example": "gentest=t2"
+                },
+                "questions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/views.Question"
+                    }
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Тест по термодинамике"
+                }
+            }
+        },
+        "views.SummaryOptions": {
+            "type": "object",
+            "properties": {
+                "detail": {
+                    "type": "string",
+                    "enum": [
+                        "brief",
+                        "standard",
+                        "detailed"
+                    ],
+                    "example": "brief"
+                },
+                "language": {
+                    "description": "Language — язык конспекта, тег BCP 47",
+                    "type": "string",
+                    "example": "en"
+                },
+                "style": {
+                    "description": "Style: outline — план с разделами, cornell — конспект Корнелла (вопросы, заметки, итог),\nmindmap — карта памяти вложенными списками Markdown",
                     "type": "string",
-                    "example": "1. Вопрос...\n   A) ...\n   B) ..."
+                    "enum": [
+                        "outline",
+                        "cornell",
+                        "mindmap"
+                    ],
+                    "example": "cornell"
                 }
             }
         },
@@ -561,12 +5018,162 @@ const docTemplate = `{
                     "type": "string",
                     "example": "nova-2-general"
                 },
+                "note_id": {
+                    "type": "string",
+                    "example": "d4c1b3c2-..."
+                },
+                "segments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/views.TranscriptSegment"
+                    }
+                },
                 "text": {
                     "type": "string",
                     "example": "полная расшифровка аудио"
                 }
             }
         },
+        "views.TranscriptSegment": {
+            "type": "object",
+            "properties": {
+                "end_sec": {
+                    "type": "number",
+                    "example": 600
+                },
+                "segment_index": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "start_sec": {
+                    "type": "number",
+                    "example": 0
+                },
+                "text": {
+                    "type": "string",
+                    "example": "Сегодня поговорим о термодинамике"
+                }
+            }
+        },
+        "views.UpstreamState": {
+            "type": "object",
+            "properties": {
+                "failures": {
+                    "description": "Failures — неудачных запросов подряд",
+                    "type": "integer",
+                    "example": 0
+                },
+                "in_flight": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "limit": {
+                    "type": "integer",
+                    "example": 16
+                },
+                "name": {
+                    "type": "string",
+                    "example": "n8n"
+                },
+                "open_until": {
+                    "description": "OpenUntil — до какого времени запросы к сервису сразу отклоняются с 503",
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string",
+                    "enum": [
+                        "closed",
+                        "open",
+                        "half_open"
+                    ],
+                    "example": "closed"
+                }
+            }
+        },
+        "views.UsageLimit": {
+            "type": "object",
+            "properties": {
+                "audio_seconds": {
+                    "type": "number",
+                    "example": 7200
+                },
+                "requests": {
+                    "type": "integer",
+                    "example": 100
+                },
+                "tokens": {
+                    "type": "integer",
+                    "example": 200000
+                }
+            }
+        },
+        "views.UsagePeriod": {
+            "type": "object",
+            "properties": {
+                "endpoints": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/views.UsageTotals"
+                    }
+                },
+                "limit": {
+                    "$ref": "#/definitions/views.UsageLimit"
+                },
+                "reset_at": {
+                    "type": "string"
+                },
+                "start": {
+                    "type": "string"
+                },
+                "used": {
+                    "$ref": "#/definitions/views.UsageTotals"
+                }
+            }
+        },
+        "views.UsageSummary": {
+            "type": "object",
+            "properties": {
+                "day": {
+                    "$ref": "#/definitions/views.UsagePeriod"
+                },
+                "month": {
+                    "$ref": "#/definitions/views.UsagePeriod"
+                },
+                "plan": {
+                    "type": "string",
+                    "example": "free"
+                }
+            }
+        },
+        "views.UsageTotals": {
+            "type": "object",
+            "properties": {
+                "audio_seconds": {
+                    "type": "number",
+                    "example": 5400
+                },
+                "endpoint": {
+                    "type": "string",
+                    "example": "/api/ai/generatemd"
+                },
+                "failed": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "input_bytes": {
+                    "type": "integer",
+                    "example": 480210
+                },
+                "requests": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "tokens": {
+                    "type": "integer",
+                    "example": 152000
+                }
+            }
+        },
         "views.UserRegister": {
             "type": "object",
             "properties": {
@@ -583,6 +5190,114 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "views.Webhook": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "lecture.completed",
+                        "lecture.failed"
+                    ]
+                },
+                "id": {
+                    "type": "string",
+                    "example": "d4c1b3c2-..."
+                },
+                "owner": {
+                    "type": "string"
+                },
+                "secret": {
+                    "type": "string",
+                    "example": "whsec_3f1c..."
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://lms.example.com/flicker/callback"
+                }
+            }
+        },
+        "views.WebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "delivered_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string",
+                    "example": "status 503"
+                },
+                "event": {
+                    "type": "string",
+                    "example": "document.indexed"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "d4c1b3c2-..."
+                },
+                "payload": {
+                    "type": "string",
+                    "example": "{\"id\":\"...\",\"event\":\"document.indexed\"}"
+                },
+                "response_status": {
+                    "type": "integer",
+                    "example": 200
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "pending",
+                        "delivered",
+                        "failed"
+                    ],
+                    "example": "delivered"
+                },
+                "webhook_id": {
+                    "type": "string",
+                    "example": "d4c1b3c2-..."
+                }
+            }
+        },
+        "views.WebhookRequest": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string",
+                        "enum": [
+                            "transcription.completed",
+                            "transcription.failed",
+                            "document.indexed",
+                            "document.failed",
+                            "lecture.completed",
+                            "lecture.failed"
+                        ]
+                    },
+                    "example": [
+                        "lecture.completed",
+                        "lecture.failed"
+                    ]
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://lms.example.com/flicker/callback"
+                }
+            }
         }
     }
 }`
@@ -0,0 +1,97 @@
+package aicache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "Первая строка\n\nвторая строка", Normalize("  Первая \t строка  \r\n\r\n\r\n  вторая   строка\n\n"))
+	// composed and decomposed й are the same text
+	assert.Equal(t, "\u0439", Normalize("\u0438\u0306"))
+	assert.Equal(t, "Регистр", Normalize("Регистр"))
+}
+
+func TestKey(t *testing.T) {
+	t.Parallel()
+
+	k := Key("generatemd", "1", "gpt", "заголовок", "текст лекции")
+	assert.Len(t, k, 64)
+	assert.Equal(t, k, Key("generatemd", "1", "gpt", " заголовок", "текст  лекции\n"))
+
+	assert.NotEqual(t, k, Key("generatemd", "2", "gpt", "заголовок", "текст лекции"), "prompt version")
+	assert.NotEqual(t, k, Key("generatemd", "1", "claude", "заголовок", "текст лекции"), "model")
+	assert.NotEqual(t, k, Key("gentest", "1", "gpt", "заголовок", "текст лекции"), "prompt")
+	assert.NotEqual(t, k, Key("generatemd", "1", "gpt", "заголовок текст", "лекции"), "parts are separated")
+}
+
+func TestMemory(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	m := NewMemory(3)
+	for i := range 3 {
+		assert.NoError(t, m.Set(ctx, fmt.Sprint(i), "p", "1", []byte{byte(i)}, time.Hour))
+	}
+
+	t.Run("get", func(t *testing.T) {
+		v, ok, err := m.Get(ctx, "0")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, []byte{0}, v)
+
+		_, ok, _ = m.Get(ctx, "nope")
+		assert.False(t, ok)
+	})
+
+	t.Run("least recently used is evicted", func(t *testing.T) {
+		// "0" was read above, so "1" is the oldest
+		assert.NoError(t, m.Set(ctx, "3", "p", "1", []byte{3}, time.Hour))
+		assert.Equal(t, 3, m.Len())
+		_, ok, _ := m.Get(ctx, "1")
+		assert.False(t, ok)
+		_, ok, _ = m.Get(ctx, "0")
+		assert.True(t, ok)
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		assert.NoError(t, m.Set(ctx, "3", "p", "1", []byte{33}, time.Hour))
+		v, _, _ := m.Get(ctx, "3")
+		assert.Equal(t, []byte{33}, v)
+		assert.Equal(t, 3, m.Len())
+	})
+
+	t.Run("ttl", func(t *testing.T) {
+		assert.NoError(t, m.Set(ctx, "short", "p", "1", []byte{1}, time.Millisecond))
+		time.Sleep(5 * time.Millisecond)
+		_, ok, _ := m.Get(ctx, "short")
+		assert.False(t, ok)
+
+		n, err := m.Expire(ctx, time.Now().Add(2*time.Hour))
+		assert.NoError(t, err)
+		assert.EqualValues(t, 2, n)
+		assert.Equal(t, 0, m.Len())
+	})
+
+	t.Run("invalidate", func(t *testing.T) {
+		assert.NoError(t, m.Set(ctx, "a", "generatemd", "1", nil, time.Hour))
+		assert.NoError(t, m.Set(ctx, "b", "generatemd", "2", nil, time.Hour))
+		assert.NoError(t, m.Set(ctx, "c", "gentest", "1", nil, time.Hour))
+
+		n, err := m.Invalidate(ctx, "generatemd", "2")
+		assert.NoError(t, err)
+		assert.EqualValues(t, 1, n)
+		_, ok, _ := m.Get(ctx, "a")
+		assert.False(t, ok)
+		_, ok, _ = m.Get(ctx, "b")
+		assert.True(t, ok)
+		_, ok, _ = m.Get(ctx, "c")
+		assert.True(t, ok)
+	})
+}
@@ -0,0 +1,88 @@
+// Package aicache keep results of LLM generation by hash of normalized input, prompt version and
+// model, so resubmitted text doesn't cost another LLM call
+package aicache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/autumnterror/breezynotes/pkg/log"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	StoreMemory   = "memory"
+	StorePostgres = "postgres"
+	StoreOff      = "off"
+)
+
+// Cache keep generated values by key until ttl is over
+type Cache interface {
+	// Get return value if it is stored and not expired
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set store value made by version of prompt
+	Set(ctx context.Context, key, prompt, version string, value []byte, ttl time.Duration) error
+	// Invalidate delete values of prompt made by other versions of it and return their number
+	Invalidate(ctx context.Context, prompt, version string) (int64, error)
+	// Expire delete values expired before now
+	Expire(ctx context.Context, now time.Time) (int64, error)
+	Name() string
+}
+
+// Key is sha256 of prompt, its version, model and normalized input parts
+func Key(prompt, version, model string, input ...string) string {
+	h := sha256.New()
+	for _, s := range append([]string{prompt, version, model}, input...) {
+		h.Write([]byte(Normalize(s)))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Normalize make texts which differ only by unicode composition, line endings and whitespace
+// equal: spaces inside line are collapsed, lines are trimmed and runs of blank lines become one
+func Normalize(s string) string {
+	s = norm.NFC.String(s)
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+
+	res := make([]string, 0, len(lines))
+	blank := false
+	for _, line := range lines {
+		line = strings.Join(strings.FieldsFunc(line, unicode.IsSpace), " ")
+		if line == "" {
+			blank = len(res) > 0
+			continue
+		}
+		if blank {
+			res = append(res, "")
+			blank = false
+		}
+		res = append(res, line)
+	}
+	return strings.Join(res, "\n")
+}
+
+// Sweep expire values of cache every interval until ctx is done
+func Sweep(ctx context.Context, c Cache, every time.Duration) {
+	const op = "aicache.Sweep"
+
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			if n, err := c.Expire(ctx, now); err != nil {
+				log.Error(op, "", err)
+			} else if n > 0 {
+				log.Info(op, fmt.Sprintf("%d expired entries removed", n))
+			}
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package aicache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory is LRU cache of at most size values kept in process memory
+type Memory struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List // front is most recently used
+}
+
+type memoryEntry struct {
+	key, prompt, version string
+	value                []byte
+	expires              time.Time
+}
+
+func NewMemory(size int) *Memory {
+	return &Memory{
+		size:    max(size, 1),
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+func (m *Memory) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	el, ok := m.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	e := el.Value.(*memoryEntry)
+	if !time.Now().Before(e.expires) {
+		m.remove(el)
+		return nil, false, nil
+	}
+	m.order.MoveToFront(el)
+	return e.value, true, nil
+}
+
+func (m *Memory) Set(_ context.Context, key, prompt, version string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e := &memoryEntry{key: key, prompt: prompt, version: version, value: value, expires: time.Now().Add(ttl)}
+	if el, ok := m.entries[key]; ok {
+		el.Value = e
+		m.order.MoveToFront(el)
+		return nil
+	}
+	m.entries[key] = m.order.PushFront(e)
+	for m.order.Len() > m.size {
+		m.remove(m.order.Back())
+	}
+	return nil
+}
+
+func (m *Memory) Invalidate(_ context.Context, prompt, version string) (int64, error) {
+	return m.removeIf(func(e *memoryEntry) bool {
+		return e.prompt == prompt && e.version != version
+	}), nil
+}
+
+func (m *Memory) Expire(_ context.Context, now time.Time) (int64, error) {
+	return m.removeIf(func(e *memoryEntry) bool {
+		return !now.Before(e.expires)
+	}), nil
+}
+
+func (m *Memory) Name() string {
+	return StoreMemory
+}
+
+// Len return number of stored values, expired ones included
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.order.Len()
+}
+
+func (m *Memory) removeIf(match func(*memoryEntry) bool) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	for el := m.order.Front(); el != nil; {
+		next := el.Next()
+		if match(el.Value.(*memoryEntry)) {
+			m.remove(el)
+			n++
+		}
+		el = next
+	}
+	return n
+}
+
+func (m *Memory) remove(el *list.Element) {
+	m.order.Remove(el)
+	delete(m.entries, el.Value.(*memoryEntry).key)
+}
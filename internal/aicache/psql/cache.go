@@ -0,0 +1,85 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flicker/internal/aicache"
+	"time"
+
+	"github.com/autumnterror/breezynotes/pkg/utils/format"
+)
+
+func (d *Driver) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	const op = "psql.aicache.Get"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	var value []byte
+	err := d.driver.QueryRowContext(ctx, `SELECT value FROM ai_cache WHERE key = $1 AND expires_at > now()`, key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, format.Error(op, err)
+	}
+	return value, true, nil
+}
+
+func (d *Driver) Set(ctx context.Context, key, prompt, version string, value []byte, ttl time.Duration) error {
+	const op = "psql.aicache.Set"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	query := `
+		INSERT INTO ai_cache (key, prompt, version, value, expires_at)
+		VALUES ($1, $2, $3, $4, now() + $5 * interval '1 millisecond')
+		ON CONFLICT (key) DO UPDATE
+		SET prompt = EXCLUDED.prompt, version = EXCLUDED.version, value = EXCLUDED.value,
+		    created_at = now(), expires_at = EXCLUDED.expires_at
+	`
+	if _, err := d.driver.ExecContext(ctx, query, key, prompt, version, value, ttl.Milliseconds()); err != nil {
+		return format.Error(op, err)
+	}
+	return nil
+}
+
+func (d *Driver) Invalidate(ctx context.Context, prompt, version string) (int64, error) {
+	const op = "psql.aicache.Invalidate"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	res, err := d.driver.ExecContext(ctx, `DELETE FROM ai_cache WHERE prompt = $1 AND version <> $2`, prompt, version)
+	if err != nil {
+		return 0, format.Error(op, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, format.Error(op, err)
+	}
+	return n, nil
+}
+
+func (d *Driver) Expire(ctx context.Context, now time.Time) (int64, error) {
+	const op = "psql.aicache.Expire"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	res, err := d.driver.ExecContext(ctx, `DELETE FROM ai_cache WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, format.Error(op, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, format.Error(op, err)
+	}
+	return n, nil
+}
+
+func (d *Driver) Name() string {
+	return aicache.StorePostgres
+}
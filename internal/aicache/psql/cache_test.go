@@ -0,0 +1,75 @@
+package psql
+
+import (
+	"context"
+	authpsql "flicker/internal/auth/psql"
+	"flicker/internal/config"
+	"testing"
+	"time"
+
+	"github.com/autumnterror/breezynotes/pkg/utils/id"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheOperations(t *testing.T) {
+	t.Parallel()
+
+	repo, cleanup := setupTestTx(t)
+	defer cleanup()
+
+	key, prompt := id.New(), id.New()
+
+	t.Run("miss", func(t *testing.T) {
+		_, ok, err := repo.Get(context.TODO(), key)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("set and get", func(t *testing.T) {
+		assert.NoError(t, repo.Set(context.TODO(), key, prompt, "1", []byte(`{"markdown":"# А"}`), time.Hour))
+		v, ok, err := repo.Get(context.TODO(), key)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, `{"markdown":"# А"}`, string(v))
+
+		assert.NoError(t, repo.Set(context.TODO(), key, prompt, "1", []byte(`{}`), time.Hour))
+		v, _, _ = repo.Get(context.TODO(), key)
+		assert.Equal(t, `{}`, string(v))
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		old := id.New()
+		assert.NoError(t, repo.Set(context.TODO(), old, prompt, "1", []byte(`{}`), -time.Second))
+		_, ok, err := repo.Get(context.TODO(), old)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+
+		n, err := repo.Expire(context.TODO(), time.Now())
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, n, int64(1))
+	})
+
+	t.Run("invalidate", func(t *testing.T) {
+		n, err := repo.Invalidate(context.TODO(), prompt, "1")
+		assert.NoError(t, err)
+		assert.EqualValues(t, 0, n)
+
+		n, err = repo.Invalidate(context.TODO(), prompt, "2")
+		assert.NoError(t, err)
+		assert.EqualValues(t, 1, n)
+		_, ok, _ := repo.Get(context.TODO(), key)
+		assert.False(t, ok)
+	})
+}
+
+func setupTestTx(t *testing.T) (*Driver, func()) {
+	pdb := authpsql.MustConnect(config.Test())
+
+	tx, err := pdb.Driver.Begin()
+	assert.NoError(t, err)
+
+	return NewDriver(tx), func() {
+		assert.NoError(t, tx.Rollback())
+		assert.NoError(t, pdb.Disconnect())
+	}
+}
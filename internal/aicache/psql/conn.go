@@ -0,0 +1,26 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type SqlRepo interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+const waitTime = 3 * time.Second
+
+// Driver is aicache.Cache on top of postgres, shared by all instances of service
+type Driver struct {
+	driver SqlRepo
+}
+
+func NewDriver(driver SqlRepo) *Driver {
+	return &Driver{
+		driver: driver,
+	}
+}
@@ -2,6 +2,7 @@ package jwt
 
 import (
 	"errors"
+	"flicker/internal/metrics"
 	"fmt"
 	"github.com/autumnterror/breezynotes/pkg/utils/format"
 	"github.com/golang-jwt/jwt/v5"
@@ -30,6 +31,7 @@ func (w *WithConfig) GenerateToken(id, _type string) (string, error) {
 	if err != nil {
 		return "", format.Error(op, err)
 	}
+	metrics.TokensIssued.WithLabelValues(_type).Inc()
 	return ts, nil
 }
 
@@ -101,6 +103,19 @@ func (w *WithConfig) GetTypeFromToken(token *jwt.Token) (string, error) {
 
 // Refresh check refresh token and if all ok return new access token
 func (w *WithConfig) Refresh(refreshToken string) (string, error) {
+	token, err := w.refresh(refreshToken)
+	switch {
+	case err == nil:
+		metrics.TokenRefreshes.WithLabelValues("ok").Inc()
+	case errors.Is(err, ErrTokenExpired):
+		metrics.TokenRefreshes.WithLabelValues("expired").Inc()
+	default:
+		metrics.TokenRefreshes.WithLabelValues("invalid").Inc()
+	}
+	return token, err
+}
+
+func (w *WithConfig) refresh(refreshToken string) (string, error) {
 	const op = "jwt.WithConfig.Refresh"
 
 	rawRefToken, err := w.VerifyToken(refreshToken)
@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"flicker/internal/metrics"
+	"time"
 
 	"github.com/autumnterror/breezynotes/pkg/utils/format"
 	"golang.org/x/crypto/bcrypt"
@@ -51,7 +53,7 @@ func (d *Driver) Authentication(ctx context.Context, email, login, password stri
 		return "", format.Error(op, err)
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password)); err != nil {
+	if err := comparePassword(hashed, password); err != nil {
 		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
 			return "", ErrPasswordIncorrect
 		}
@@ -60,3 +62,9 @@ func (d *Driver) Authentication(ctx context.Context, email, login, password stri
 
 	return id, nil
 }
+
+// comparePassword with bcrypt hash, duration is observed in metrics
+func comparePassword(hashed, password string) error {
+	defer metrics.Since(metrics.Bcrypt, "compare", time.Now())
+	return bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password))
+}
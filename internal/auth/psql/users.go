@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"flicker/internal/metrics"
 	"flicker/internal/views"
 	"strings"
+	"time"
 
 	"github.com/autumnterror/breezynotes/pkg/log"
 	"github.com/autumnterror/breezynotes/pkg/utils/format"
@@ -25,7 +27,7 @@ func (d *Driver) GetAll(ctx context.Context) ([]*views.User, error) {
 	defer done()
 
 	var ls []*views.User
-	rows, err := d.driver.QueryContext(ctx, `SELECT * FROM users`)
+	rows, err := d.driver.QueryContext(ctx, `SELECT id, login, email, about, password, photo FROM users`)
 	if err != nil {
 		return nil, format.Error(op, err)
 	}
@@ -55,7 +57,7 @@ func (d *Driver) Create(ctx context.Context, u *views.User) error {
 				VALUES ($1, $2, $3, $4, $5, $6)
 			`
 
-	hashedPass, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
+	hashedPass, err := hashPassword(u.Password)
 	if err != nil {
 		return format.Error(op, err)
 	}
@@ -71,6 +73,12 @@ func (d *Driver) Create(ctx context.Context, u *views.User) error {
 	return nil
 }
 
+// hashPassword by bcrypt, duration is observed in metrics
+func hashPassword(password string) ([]byte, error) {
+	defer metrics.Since(metrics.Bcrypt, "hash", time.Now())
+	return bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+}
+
 func isDuplicateKeyError(err error) bool {
 	if err == nil {
 		return false
@@ -90,7 +98,7 @@ func (d *Driver) UpdatePassword(ctx context.Context, id, newPassword string) err
 	ctx, done := context.WithTimeout(ctx, waitTime)
 	defer done()
 
-	hashedPass, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	hashedPass, err := hashPassword(newPassword)
 	if err != nil {
 		return format.Error(op, err)
 	}
@@ -0,0 +1,125 @@
+package cards
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flicker/internal/llmjson"
+	"flicker/internal/views"
+	"fmt"
+	"strings"
+
+	"github.com/autumnterror/breezynotes/pkg/utils/format"
+)
+
+var (
+	ErrNoCards       = errors.New("no flashcards in output")
+	ErrInvalidOutput = errors.New("llm returned invalid flashcards")
+)
+
+// LLM run prompt webhook and return raw text answer, e.g. *n8n.Client
+type LLM interface {
+	Call(ctx context.Context, hook string, payload any) (string, error)
+}
+
+type request struct {
+	Content  string `json:"content"`
+	Count    int    `json:"count"`
+	Format   string `json:"format"`
+	Previous string `json:"previous,omitempty"`
+	Problems string `json:"problems,omitempty"`
+}
+
+const outputFormat = `[{"front": "question", "back": "short answer"}]`
+
+// Generate ask LLM for up to count flashcards from content. Unparsable output is retried
+// with explanation until attempts are over. Upstream errors are not retried
+func Generate(ctx context.Context, llm LLM, hook, content string, count, attempts int) ([]views.CardRequest, error) {
+	const op = "cards.Generate"
+
+	req := request{
+		Content: content,
+		Count:   count,
+		Format:  outputFormat,
+	}
+
+	var lastErr error
+	for i := 0; i < max(attempts, 1); i++ {
+		out, err := llm.Call(ctx, hook, req)
+		if err != nil {
+			return nil, err
+		}
+
+		cs, err := Parse(out)
+		if err == nil {
+			if count > 0 && len(cs) > count {
+				cs = cs[:count]
+			}
+			return cs, nil
+		}
+
+		lastErr = err
+		req.Previous = out
+		req.Problems = err.Error()
+	}
+
+	return nil, format.Error(op, fmt.Errorf("%w: %w", ErrInvalidOutput, lastErr))
+}
+
+type rawCard struct {
+	Front    string `json:"front"`
+	Back     string `json:"back"`
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+}
+
+// Parse extract flashcards from raw LLM output. Accept array or {"cards": [...]}, keys
+// front/back or question/answer. Empty and duplicate cards are dropped
+func Parse(raw string) ([]views.CardRequest, error) {
+	js, err := llmjson.Extract(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var rcs []rawCard
+	if strings.HasPrefix(js, "[") {
+		err = json.Unmarshal([]byte(js), &rcs)
+	} else {
+		var wrap struct {
+			Cards      []rawCard `json:"cards"`
+			Flashcards []rawCard `json:"flashcards"`
+		}
+		err = json.Unmarshal([]byte(js), &wrap)
+		rcs = append(wrap.Cards, wrap.Flashcards...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNoCards, err)
+	}
+
+	cs := make([]views.CardRequest, 0, len(rcs))
+	seen := make(map[string]bool, len(rcs))
+	for _, r := range rcs {
+		c := views.CardRequest{
+			Front: strings.TrimSpace(r.Front),
+			Back:  strings.TrimSpace(r.Back),
+		}
+		if c.Front == "" {
+			c.Front = strings.TrimSpace(r.Question)
+		}
+		if c.Back == "" {
+			c.Back = strings.TrimSpace(r.Answer)
+		}
+
+		key := strings.ToLower(c.Front)
+		if c.Front == "" || c.Back == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		cs = append(cs, c)
+	}
+
+	if len(cs) == 0 {
+		return nil, ErrNoCards
+	}
+	return cs, nil
+}
@@ -0,0 +1,76 @@
+package cards
+
+import (
+	"context"
+	"errors"
+	"flicker/internal/views"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	t.Run("array", func(t *testing.T) {
+		t.Parallel()
+		cs, err := Parse("```json\n" + `[
+			{"front": "Что такое энтропия?", "back": "Мера беспорядка"},
+			{"question": "Первый закон термодинамики", "answer": "Закон сохранения энергии"},
+			{"front": "что такое энтропия?", "back": "дубликат"},
+			{"front": "Без ответа", "back": " "},
+		]` + "\n```")
+		assert.NoError(t, err)
+		assert.Equal(t, []views.CardRequest{
+			{Front: "Что такое энтропия?", Back: "Мера беспорядка"},
+			{Front: "Первый закон термодинамики", Back: "Закон сохранения энергии"},
+		}, cs)
+	})
+
+	t.Run("wrapped", func(t *testing.T) {
+		t.Parallel()
+		cs, err := Parse(`Карточки: {"flashcards": [{"front": "a", "back": "b"}]}`)
+		assert.NoError(t, err)
+		assert.Len(t, cs, 1)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		t.Parallel()
+		_, err := Parse(`{"cards": []}`)
+		assert.ErrorIs(t, err, ErrNoCards)
+
+		_, err = Parse("нет карточек")
+		assert.Error(t, err)
+	})
+}
+
+type fakeLLM struct {
+	outputs []string
+	calls   int
+}
+
+func (f *fakeLLM) Call(context.Context, string, any) (string, error) {
+	if f.calls >= len(f.outputs) {
+		return "", errors.New("no more outputs")
+	}
+	f.calls++
+	return f.outputs[f.calls-1], nil
+}
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	llm := &fakeLLM{outputs: []string{
+		"извините",
+		`[{"front": "1", "back": "a"}, {"front": "2", "back": "b"}, {"front": "3", "back": "c"}]`,
+	}}
+	cs, err := Generate(context.Background(), llm, "flashcards", "text", 2, 3)
+	assert.NoError(t, err)
+	assert.Len(t, cs, 2)
+	assert.Equal(t, 2, llm.calls)
+
+	llm = &fakeLLM{outputs: []string{"нет", "нет"}}
+	_, err = Generate(context.Background(), llm, "flashcards", "text", 2, 2)
+	assert.ErrorIs(t, err, ErrInvalidOutput)
+	assert.Equal(t, 2, llm.calls)
+}
@@ -0,0 +1,150 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"flicker/internal/views"
+	"time"
+
+	"github.com/autumnterror/breezynotes/pkg/log"
+	"github.com/autumnterror/breezynotes/pkg/utils/format"
+)
+
+// Create new card. Id, owner and schedule must be set by caller
+func (d *Driver) Create(ctx context.Context, c *views.Card) error {
+	const op = "psql.cards.Create"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	query := `
+		INSERT INTO cards (id, owner, note_id, front, back, repetitions, interval_days, ease, lapses, due_at, prompt_version)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8, $9, $10, NULLIF($11, ''))
+		RETURNING created_at
+	`
+	if err := d.driver.QueryRowContext(ctx, query, c.Id, c.Owner, c.NoteId, c.Front, c.Back,
+		c.Repetitions, c.Interval, c.Ease, c.Lapses, c.DueAt, c.PromptVersion).Scan(&c.CreatedAt); err != nil {
+		return format.Error(op, err)
+	}
+
+	return nil
+}
+
+const cardColumns = `id, owner, COALESCE(note_id, ''), front, back, repetitions, interval_days, ease, lapses, due_at, reviewed_at, created_at, COALESCE(prompt_version, '')`
+
+func scanCard(row interface{ Scan(dest ...any) error }) (*views.Card, error) {
+	var (
+		c          views.Card
+		reviewedAt sql.NullTime
+	)
+	if err := row.Scan(&c.Id, &c.Owner, &c.NoteId, &c.Front, &c.Back, &c.Repetitions, &c.Interval,
+		&c.Ease, &c.Lapses, &c.DueAt, &reviewedAt, &c.CreatedAt, &c.PromptVersion); err != nil {
+		return nil, err
+	}
+	if reviewedAt.Valid {
+		c.ReviewedAt = &reviewedAt.Time
+	}
+	return &c, nil
+}
+
+// Get card of owner. May send sql.ErrNoRows
+func (d *Driver) Get(ctx context.Context, owner, id string) (*views.Card, error) {
+	const op = "psql.cards.Get"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	c, err := scanCard(d.driver.QueryRowContext(ctx, `SELECT `+cardColumns+` FROM cards WHERE id = $1 AND owner = $2`, id, owner))
+	if err != nil {
+		return nil, format.Error(op, err)
+	}
+
+	return c, nil
+}
+
+// List all cards of owner, newest first
+func (d *Driver) List(ctx context.Context, owner string) ([]*views.Card, error) {
+	const op = "psql.cards.List"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	return d.list(ctx, op, `SELECT `+cardColumns+` FROM cards WHERE owner = $1 ORDER BY created_at DESC`, owner)
+}
+
+// Due return cards of owner which should be reviewed at now, most overdue first
+func (d *Driver) Due(ctx context.Context, owner string, now time.Time, limit int) ([]*views.Card, error) {
+	const op = "psql.cards.Due"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	return d.list(ctx, op, `SELECT `+cardColumns+` FROM cards WHERE owner = $1 AND due_at <= $2 ORDER BY due_at LIMIT $3`, owner, now, limit)
+}
+
+func (d *Driver) list(ctx context.Context, op, query string, args ...any) ([]*views.Card, error) {
+	rows, err := d.driver.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, format.Error(op, err)
+	}
+	defer rows.Close()
+
+	ls := []*views.Card{}
+	for rows.Next() {
+		c, err := scanCard(rows)
+		if err != nil {
+			log.Error(op, "rows scan error", err)
+			continue
+		}
+		ls = append(ls, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, format.Error(op, err)
+	}
+
+	return ls, nil
+}
+
+// Review store new schedule of card. May send sql.ErrNoRows
+func (d *Driver) Review(ctx context.Context, owner string, c *views.Card) error {
+	const op = "psql.cards.Review"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	query := `
+		UPDATE cards
+		SET repetitions = $1, interval_days = $2, ease = $3, lapses = $4, due_at = $5, reviewed_at = now()
+		WHERE id = $6 AND owner = $7
+		RETURNING reviewed_at
+	`
+	var reviewedAt time.Time
+	if err := d.driver.QueryRowContext(ctx, query, c.Repetitions, c.Interval, c.Ease, c.Lapses, c.DueAt, c.Id, owner).
+		Scan(&reviewedAt); err != nil {
+		return format.Error(op, err)
+	}
+	c.ReviewedAt = &reviewedAt
+
+	return nil
+}
+
+// Delete card. May send sql.ErrNoRows
+func (d *Driver) Delete(ctx context.Context, owner, id string) error {
+	const op = "psql.cards.Delete"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	res, err := d.driver.ExecContext(ctx, `DELETE FROM cards WHERE id = $1 AND owner = $2`, id, owner)
+	if err != nil {
+		return format.Error(op, err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return format.Error(op, err)
+	}
+	if rows == 0 {
+		return format.Error(op, sql.ErrNoRows)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	authpsql "flicker/internal/auth/psql"
+	"flicker/internal/config"
+	"flicker/internal/views"
+	"testing"
+	"time"
+
+	"github.com/autumnterror/breezynotes/pkg/utils/id"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCardsOperations(t *testing.T) {
+	t.Parallel()
+
+	repo, owner, cleanup := setupTestTx(t)
+	defer cleanup()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	due := &views.Card{Id: id.New(), Owner: owner, Front: "due", Back: "a", Ease: 2.5, DueAt: now.Add(-time.Hour)}
+	later := &views.Card{Id: id.New(), Owner: owner, Front: "later", Back: "b", Ease: 2.5, DueAt: now.Add(24 * time.Hour)}
+
+	t.Run("create", func(t *testing.T) {
+		assert.NoError(t, repo.Create(context.TODO(), due))
+		assert.NoError(t, repo.Create(context.TODO(), later))
+		assert.False(t, due.CreatedAt.IsZero())
+	})
+
+	t.Run("get", func(t *testing.T) {
+		c, err := repo.Get(context.TODO(), owner, due.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, "due", c.Front)
+		assert.Empty(t, c.NoteId)
+		assert.Nil(t, c.ReviewedAt)
+
+		_, err = repo.Get(context.TODO(), id.New(), due.Id)
+		assert.True(t, errors.Is(err, sql.ErrNoRows))
+	})
+
+	t.Run("list and due", func(t *testing.T) {
+		ls, err := repo.List(context.TODO(), owner)
+		assert.NoError(t, err)
+		assert.Len(t, ls, 2)
+
+		ds, err := repo.Due(context.TODO(), owner, now, 10)
+		assert.NoError(t, err)
+		if assert.Len(t, ds, 1) {
+			assert.Equal(t, due.Id, ds[0].Id)
+		}
+	})
+
+	t.Run("review", func(t *testing.T) {
+		due.Repetitions, due.Interval, due.Ease, due.DueAt = 1, 1, 2.36, now.Add(24*time.Hour)
+		assert.NoError(t, repo.Review(context.TODO(), owner, due))
+		assert.NotNil(t, due.ReviewedAt)
+
+		c, err := repo.Get(context.TODO(), owner, due.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, 2.36, c.Ease)
+		assert.Equal(t, 1, c.Interval)
+
+		ds, err := repo.Due(context.TODO(), owner, now, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, ds)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.NoError(t, repo.Delete(context.TODO(), owner, due.Id))
+		assert.True(t, errors.Is(repo.Delete(context.TODO(), owner, due.Id), sql.ErrNoRows))
+	})
+}
+
+// setupTestTx return driver inside transaction with one created user
+func setupTestTx(t *testing.T) (*Driver, string, func()) {
+	pdb := authpsql.MustConnect(config.Test())
+
+	tx, err := pdb.Driver.Begin()
+	assert.NoError(t, err)
+
+	owner := id.New()
+	assert.NoError(t, authpsql.NewDriver(tx).Create(context.TODO(), &views.User{
+		Id:       owner,
+		Login:    owner[:10],
+		Email:    owner[:10] + "@example.com",
+		Password: "password",
+	}))
+
+	return NewDriver(tx), owner, func() {
+		assert.NoError(t, tx.Rollback())
+		assert.NoError(t, pdb.Disconnect())
+	}
+}
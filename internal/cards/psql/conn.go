@@ -0,0 +1,37 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"flicker/internal/views"
+	"time"
+)
+
+type SqlRepo interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+const (
+	waitTime = 3 * time.Second
+)
+
+type Driver struct {
+	driver SqlRepo
+}
+
+func NewDriver(driver SqlRepo) *Driver {
+	return &Driver{
+		driver: driver,
+	}
+}
+
+type CardsRepo interface {
+	Create(ctx context.Context, c *views.Card) error
+	Get(ctx context.Context, owner, id string) (*views.Card, error)
+	List(ctx context.Context, owner string) ([]*views.Card, error)
+	Due(ctx context.Context, owner string, now time.Time, limit int) ([]*views.Card, error)
+	Review(ctx context.Context, owner string, c *views.Card) error
+	Delete(ctx context.Context, owner, id string) error
+}
@@ -9,5 +9,69 @@ func Test() *Config {
 		AccessTokenLifeTime:  5 * time.Second,
 		RefreshTokenLifeTime: time.Minute,
 		Port:                 8008,
+		N8nURL:               "http://localhost:5678",
+		N8nSecret:            "test-n8n-secret",
+		TranscriberURL:       "http://localhost:8008",
+		QuizGrader:           "keyword",
+
+		VectorStore:  "memory",
+		Embedder:     "hash",
+		EmbeddingDim: 384,
+		ChunkSize:    1200,
+		ChunkOverlap: 200,
+
+		UploadDir:     "./data/uploads",
+		UploadMaxSize: 4 << 30,
+		UploadTTL:     24 * time.Hour,
+		BodyLimit:     "1G",
+
+		SummaryChunkTokens:   6000,
+		SummaryOverlapTokens: 200,
+		SummaryConcurrency:   4,
+		SummaryRetries:       2,
+		SummaryMaxFailed:     0.25,
+
+		AICache:        "memory",
+		AICacheSize:    1000,
+		AICacheTTL:     7 * 24 * time.Hour,
+		AIModel:        "n8n",
+		PromptVersions: map[string]string{"generatemd": "1", "reducemd": "1", "gentest": "1"},
+
+		UpstreamRetries:     2,
+		UpstreamBackoff:     200 * time.Millisecond,
+		UpstreamMaxBackoff:  5 * time.Second,
+		BreakerFailures:     5,
+		BreakerCooldown:     30 * time.Second,
+		UpstreamConcurrency: map[string]int{"n8n": 16, "transcriber": 2, "embeddings": 8},
+		UpstreamRetryPost:   map[string]bool{"embeddings": true},
+
+		UsageStore:       "memory",
+		UsageDefaultPlan: "free",
+		UsagePlans: map[string]Plan{
+			"anonymous": {Daily: Quota{Requests: 20}, Monthly: Quota{Requests: 200}},
+			"free": {
+				Daily:   Quota{Requests: 100, Tokens: 200_000, AudioSeconds: 2 * 3600},
+				Monthly: Quota{Requests: 1000, Tokens: 2_000_000, AudioSeconds: 20 * 3600},
+			},
+			"pro": {},
+		},
+
+		RateLimitStore: "memory",
+		RateLimits: map[string]RateLimit{
+			"global": {Limit: 300, Period: time.Minute},
+			"auth":   {Limit: 10, Period: time.Minute},
+			"ai":     {Limit: 30, Period: time.Minute},
+		},
+
+		PromptStore: "memory",
+
+		WebhookStore:        "memory",
+		WebhookRetries:      2,
+		WebhookBackoff:      10 * time.Millisecond,
+		WebhookTimeout:      5 * time.Second,
+		WebhookAllowPrivate: true,
+
+		LogLevel:    "info",
+		MetricsPort: 9090,
 	}
 }
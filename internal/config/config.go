@@ -1,9 +1,11 @@
 package config
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/autumnterror/breezynotes/pkg/utils/format"
@@ -16,8 +18,88 @@ type Config struct {
 	AccessTokenLifeTime  time.Duration
 	RefreshTokenLifeTime time.Duration
 	Port                 int
+	N8nURL               string
+	N8nSecret            string
+	TranscriberURL       string
+	QuizGrader           string
+
+	VectorStore    string
+	Embedder       string
+	EmbeddingURL   string
+	EmbeddingModel string
+	EmbeddingKey   string
+	EmbeddingDim   int
+	ChunkSize      int
+	ChunkOverlap   int
+
+	UploadDir     string
+	UploadMaxSize int64
+	UploadTTL     time.Duration
+	BodyLimit     string
+
+	SummaryChunkTokens   int
+	SummaryOverlapTokens int
+	SummaryConcurrency   int
+	SummaryRetries       int
+	SummaryMaxFailed     float64
+
+	AICache        string
+	AICacheSize    int
+	AICacheTTL     time.Duration
+	AIModel        string
+	PromptVersions map[string]string
+
+	UpstreamRetries     int
+	UpstreamBackoff     time.Duration
+	UpstreamMaxBackoff  time.Duration
+	BreakerFailures     int
+	BreakerCooldown     time.Duration
+	UpstreamConcurrency map[string]int
+	UpstreamRetryPost   map[string]bool
+
+	UsageStore       string
+	UsageDefaultPlan string
+	UsagePlans       map[string]Plan
+
+	RateLimitStore string
+	RateLimits     map[string]RateLimit
+
+	PromptStore string
+	Admins      []string
+
+	WebhookStore        string
+	WebhookRetries      int
+	WebhookBackoff      time.Duration
+	WebhookTimeout      time.Duration
+	WebhookAllowPrivate bool
+
+	LogLevel    string
+	MetricsPort int
 }
 
+// Quota limit AI usage for period, 0 is no limit
+type Quota struct {
+	Requests     int64   `mapstructure:"requests"`
+	Tokens       int64   `mapstructure:"tokens"`
+	AudioSeconds float64 `mapstructure:"audio_seconds"`
+}
+
+// Plan is daily and monthly quotas of users with this plan
+type Plan struct {
+	Daily   Quota `mapstructure:"daily"`
+	Monthly Quota `mapstructure:"monthly"`
+}
+
+// RateLimit allow Limit requests in a row, then Limit requests per Period
+type RateLimit struct {
+	Limit  int           `mapstructure:"limit"`
+	Period time.Duration `mapstructure:"period"`
+}
+
+// secrets are not kept in config files: they are taken from environment variables FLICKER_ and
+// upper key, e.g. FLICKER_EMBEDDING_KEY, which override value of file
+var secrets = []string{"embedding_key", "n8n_secret"}
+
 // MustSetup return config and panic if error
 func MustSetup() *Config {
 	cfg, err := setup()
@@ -45,17 +127,200 @@ func setup() (*Config, error) {
 		RefreshTokenLifeTime time.Duration `mapstructure:"refresh_token_life"`
 		Port                 int
 		Mode                 string
+		N8nURL               string `mapstructure:"n8n_url"`
+		N8nSecret            string `mapstructure:"n8n_secret"`
+		TranscriberURL       string `mapstructure:"transcriber_url"`
+		QuizGrader           string `mapstructure:"quiz_grader"`
+		VectorStore          string `mapstructure:"vector_store"`
+		Embedder             string
+		EmbeddingURL         string        `mapstructure:"embedding_url"`
+		EmbeddingModel       string        `mapstructure:"embedding_model"`
+		EmbeddingKey         string        `mapstructure:"embedding_key"`
+		EmbeddingDim         int           `mapstructure:"embedding_dim"`
+		ChunkSize            int           `mapstructure:"chunk_size"`
+		ChunkOverlap         int           `mapstructure:"chunk_overlap"`
+		UploadDir            string        `mapstructure:"upload_dir"`
+		UploadMaxSize        int64         `mapstructure:"upload_max_size"`
+		UploadTTL            time.Duration `mapstructure:"upload_ttl"`
+		BodyLimit            string        `mapstructure:"body_limit"`
+		SummaryChunkTokens   int           `mapstructure:"summary_chunk_tokens"`
+		SummaryOverlapTokens int           `mapstructure:"summary_overlap_tokens"`
+		SummaryConcurrency   int           `mapstructure:"summary_concurrency"`
+		SummaryRetries       int           `mapstructure:"summary_retries"`
+		SummaryMaxFailed     float64       `mapstructure:"summary_max_failed"`
+
+		AICache        string            `mapstructure:"ai_cache"`
+		AICacheSize    int               `mapstructure:"ai_cache_size"`
+		AICacheTTL     time.Duration     `mapstructure:"ai_cache_ttl"`
+		AIModel        string            `mapstructure:"ai_model"`
+		PromptVersions map[string]string `mapstructure:"prompt_versions"`
+
+		UpstreamRetries     int             `mapstructure:"upstream_retries"`
+		UpstreamBackoff     time.Duration   `mapstructure:"upstream_backoff"`
+		UpstreamMaxBackoff  time.Duration   `mapstructure:"upstream_max_backoff"`
+		BreakerFailures     int             `mapstructure:"breaker_failures"`
+		BreakerCooldown     time.Duration   `mapstructure:"breaker_cooldown"`
+		UpstreamConcurrency map[string]int  `mapstructure:"upstream_concurrency"`
+		UpstreamRetryPost   map[string]bool `mapstructure:"upstream_retry_post"`
+
+		UsageStore       string          `mapstructure:"usage_store"`
+		UsageDefaultPlan string          `mapstructure:"usage_default_plan"`
+		UsagePlans       map[string]Plan `mapstructure:"usage_plans"`
+
+		RateLimitStore string               `mapstructure:"rate_limit_store"`
+		RateLimits     map[string]RateLimit `mapstructure:"rate_limits"`
+
+		PromptStore string   `mapstructure:"prompt_store"`
+		Admins      []string `mapstructure:"admins"`
+
+		WebhookStore        string        `mapstructure:"webhook_store"`
+		WebhookRetries      int           `mapstructure:"webhook_retries"`
+		WebhookBackoff      time.Duration `mapstructure:"webhook_backoff"`
+		WebhookTimeout      time.Duration `mapstructure:"webhook_timeout"`
+		WebhookAllowPrivate bool          `mapstructure:"webhook_allow_private"`
+
+		LogLevel    string `mapstructure:"log_level"`
+		MetricsPort int    `mapstructure:"metrics_port"`
 	}
 
 	if err := viper.ReadInConfig(); err != nil {
 		return nil, format.Error(op, err)
 	}
+	for _, key := range secrets {
+		if err := viper.BindEnv(key, "FLICKER_"+strings.ToUpper(key)); err != nil {
+			return nil, format.Error(op, err)
+		}
+	}
 	if err := viper.Unmarshal(&cfg); err != nil {
 		return nil, format.Error(op, err)
 	}
 
+	if cfg.N8nURL == "" {
+		cfg.N8nURL = "http://n8n:5678"
+	}
+	if cfg.TranscriberURL == "" {
+		cfg.TranscriberURL = "http://whisper:8008"
+	}
+	if cfg.QuizGrader == "" {
+		cfg.QuizGrader = "llm"
+	}
+	if cfg.VectorStore == "" {
+		cfg.VectorStore = "pgvector"
+	}
+	if cfg.Embedder == "" {
+		cfg.Embedder = "hash"
+	}
+	if cfg.EmbeddingDim == 0 {
+		cfg.EmbeddingDim = 384
+	}
+	if cfg.ChunkSize == 0 {
+		cfg.ChunkSize = 1200
+	}
+	if !viper.IsSet("chunk_overlap") {
+		cfg.ChunkOverlap = 200
+	}
+	if cfg.UploadDir == "" {
+		cfg.UploadDir = "./data/uploads"
+	}
+	if cfg.UploadMaxSize == 0 {
+		cfg.UploadMaxSize = 4 << 30
+	}
+	if cfg.UploadTTL == 0 {
+		cfg.UploadTTL = 24 * time.Hour
+	}
+	if cfg.BodyLimit == "" {
+		cfg.BodyLimit = "1G"
+	}
+	if cfg.SummaryChunkTokens == 0 {
+		cfg.SummaryChunkTokens = 6000
+	}
+	if !viper.IsSet("summary_overlap_tokens") {
+		cfg.SummaryOverlapTokens = 200
+	}
+	if cfg.SummaryConcurrency == 0 {
+		cfg.SummaryConcurrency = 4
+	}
+	if !viper.IsSet("summary_retries") {
+		cfg.SummaryRetries = 2
+	}
+	if !viper.IsSet("summary_max_failed") {
+		cfg.SummaryMaxFailed = 0.25
+	}
+	if cfg.AICache == "" {
+		cfg.AICache = "memory"
+	}
+	if cfg.AICacheSize == 0 {
+		cfg.AICacheSize = 1000
+	}
+	if cfg.AICacheTTL == 0 {
+		cfg.AICacheTTL = 7 * 24 * time.Hour
+	}
+	if cfg.AIModel == "" {
+		cfg.AIModel = "n8n"
+	}
+	if !viper.IsSet("upstream_retries") {
+		cfg.UpstreamRetries = 2
+	}
+	if cfg.UpstreamBackoff == 0 {
+		cfg.UpstreamBackoff = 200 * time.Millisecond
+	}
+	if cfg.UpstreamMaxBackoff == 0 {
+		cfg.UpstreamMaxBackoff = 5 * time.Second
+	}
+	if cfg.BreakerFailures == 0 {
+		cfg.BreakerFailures = 5
+	}
+	if cfg.BreakerCooldown == 0 {
+		cfg.BreakerCooldown = 30 * time.Second
+	}
+	if cfg.UpstreamRetryPost == nil {
+		// n8n workflows and transcription are paid calls, embeddings have no side effects
+		cfg.UpstreamRetryPost = map[string]bool{"embeddings": true}
+	}
+	if cfg.UsageStore == "" {
+		cfg.UsageStore = "memory"
+	}
+	if cfg.UsageDefaultPlan == "" {
+		cfg.UsageDefaultPlan = "free"
+	}
+	if cfg.RateLimitStore == "" {
+		cfg.RateLimitStore = "memory"
+	}
+	if cfg.PromptStore == "" {
+		cfg.PromptStore = "memory"
+	}
+	if cfg.WebhookStore == "" {
+		cfg.WebhookStore = "memory"
+	}
+	if !viper.IsSet("webhook_retries") {
+		cfg.WebhookRetries = 5
+	}
+	if cfg.WebhookBackoff == 0 {
+		cfg.WebhookBackoff = 30 * time.Second
+	}
+	if cfg.WebhookTimeout == 0 {
+		cfg.WebhookTimeout = 10 * time.Second
+	}
+	if cfg.LogLevel == "" {
+		cfg.LogLevel = "info"
+	}
+	if cfg.MetricsPort == 0 {
+		cfg.MetricsPort = 9090
+	}
+
+	if cfg.Mode == "PROD" && cfg.Embedder == "openai" && cfg.EmbeddingKey == "" {
+		return nil, format.Error(op, errors.New("embedding_key is empty, set FLICKER_EMBEDDING_KEY"))
+	}
+	// unsigned requests would let anyone who reaches n8n run paid workflows
+	if cfg.Mode == "PROD" && cfg.N8nSecret == "" {
+		return nil, format.Error(op, errors.New("n8n_secret is empty, set FLICKER_N8N_SECRET"))
+	}
+
 	if cfg.Mode == "DEV" {
-		log.Println(format.Struct(cfg), fmt.Sprintf("URI: postgres://%s:%s@%s:%d/%s?sslmode=disable",
+		// secrets of environment are not logged
+		shown := cfg
+		shown.N8nSecret, shown.EmbeddingKey = redact(cfg.N8nSecret), redact(cfg.EmbeddingKey)
+		log.Println(format.Struct(shown), fmt.Sprintf("URI: postgres://%s:%s@%s:%d/%s?sslmode=disable",
 			cfg.User, cfg.Pw, cfg.DataSource, cfg.PortPostgres, cfg.Db))
 	}
 
@@ -66,5 +331,70 @@ func setup() (*Config, error) {
 		AccessTokenLifeTime:  cfg.AccessTokenLifeTime,
 		RefreshTokenLifeTime: cfg.RefreshTokenLifeTime,
 		Port:                 cfg.Port,
+		N8nURL:               cfg.N8nURL,
+		N8nSecret:            cfg.N8nSecret,
+		TranscriberURL:       cfg.TranscriberURL,
+		QuizGrader:           cfg.QuizGrader,
+
+		VectorStore:    cfg.VectorStore,
+		Embedder:       cfg.Embedder,
+		EmbeddingURL:   cfg.EmbeddingURL,
+		EmbeddingModel: cfg.EmbeddingModel,
+		EmbeddingKey:   cfg.EmbeddingKey,
+		EmbeddingDim:   cfg.EmbeddingDim,
+		ChunkSize:      cfg.ChunkSize,
+		ChunkOverlap:   cfg.ChunkOverlap,
+
+		UploadDir:     cfg.UploadDir,
+		UploadMaxSize: cfg.UploadMaxSize,
+		UploadTTL:     cfg.UploadTTL,
+		BodyLimit:     cfg.BodyLimit,
+
+		SummaryChunkTokens:   cfg.SummaryChunkTokens,
+		SummaryOverlapTokens: cfg.SummaryOverlapTokens,
+		SummaryConcurrency:   cfg.SummaryConcurrency,
+		SummaryRetries:       cfg.SummaryRetries,
+		SummaryMaxFailed:     cfg.SummaryMaxFailed,
+
+		AICache:        cfg.AICache,
+		AICacheSize:    cfg.AICacheSize,
+		AICacheTTL:     cfg.AICacheTTL,
+		AIModel:        cfg.AIModel,
+		PromptVersions: cfg.PromptVersions,
+
+		UpstreamRetries:     cfg.UpstreamRetries,
+		UpstreamBackoff:     cfg.UpstreamBackoff,
+		UpstreamMaxBackoff:  cfg.UpstreamMaxBackoff,
+		BreakerFailures:     cfg.BreakerFailures,
+		BreakerCooldown:     cfg.BreakerCooldown,
+		UpstreamConcurrency: cfg.UpstreamConcurrency,
+		UpstreamRetryPost:   cfg.UpstreamRetryPost,
+
+		UsageStore:       cfg.UsageStore,
+		UsageDefaultPlan: cfg.UsageDefaultPlan,
+		UsagePlans:       cfg.UsagePlans,
+
+		RateLimitStore: cfg.RateLimitStore,
+		RateLimits:     cfg.RateLimits,
+
+		PromptStore: cfg.PromptStore,
+		Admins:      cfg.Admins,
+
+		WebhookStore:        cfg.WebhookStore,
+		WebhookRetries:      cfg.WebhookRetries,
+		WebhookBackoff:      cfg.WebhookBackoff,
+		WebhookTimeout:      cfg.WebhookTimeout,
+		WebhookAllowPrivate: cfg.WebhookAllowPrivate,
+
+		LogLevel:    cfg.LogLevel,
+		MetricsPort: cfg.MetricsPort,
 	}, nil
 }
+
+// redact hide secret, telling only if it is set
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "***"
+}
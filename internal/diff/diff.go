@@ -0,0 +1,342 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultContext is number of unchanged lines around each change, same as diff -u
+const DefaultContext = 3
+
+// maxCost is how many edits paths of bisect may take before texts are split at the furthest
+// point reached. It bounds time of very different texts, edit script is not shortest then
+const maxCost = 1024
+
+type OpKind int
+
+const (
+	Equal OpKind = iota
+	Insert
+	Delete
+)
+
+// Op is one line of edit script
+type Op struct {
+	Kind OpKind
+	Line string
+}
+
+// Lines split text into lines keeping "\n" terminators, so last line without newline differs from one with it
+func Lines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	ls := strings.SplitAfter(s, "\n")
+	if ls[len(ls)-1] == "" {
+		ls = ls[:len(ls)-1]
+	}
+	return ls
+}
+
+// Compute return shortest edit script turning a into b. It is Myers O(ND) algorithm in linear
+// space: middle snake splits texts in halves, which are compared recursively. Lines which don't
+// occur in other text can't be equal to anything and are left out beforehand, so texts without
+// common lines are compared in linear time. Parts differing by more than 2*maxCost edits get
+// script which may be longer than shortest
+func Compute(a, b []string) []Op {
+	if len(a)+len(b) == 0 {
+		return nil
+	}
+
+	ids := make(map[string]int, len(a))
+	idA := make([]int, len(a))
+	for i, l := range a {
+		id, ok := ids[l]
+		if !ok {
+			id = len(ids)
+			ids[l] = id
+		}
+		idA[i] = id
+	}
+	inB := make([]bool, len(ids))
+	idB := make([]int, len(b))
+	for j, l := range b {
+		id, ok := ids[l]
+		if !ok {
+			id = -1
+		} else {
+			inB[id] = true
+		}
+		idB[j] = id
+	}
+
+	// indexes of lines which may be equal
+	var c lcs
+	for i, id := range idA {
+		if inB[id] {
+			c.a, c.ia = append(c.a, id), append(c.ia, i)
+		}
+	}
+	for j, id := range idB {
+		if id >= 0 {
+			c.b, c.ib = append(c.b, id), append(c.ib, j)
+		}
+	}
+	c.diff(0, len(c.a), 0, len(c.b))
+
+	ops := make([]Op, 0, len(a)+len(b)-len(c.matches))
+	i, j := 0, 0
+	for _, m := range append(c.matches, [2]int{len(a), len(b)}) {
+		for ; i < m[0]; i++ {
+			ops = append(ops, Op{Kind: Delete, Line: a[i]})
+		}
+		for ; j < m[1]; j++ {
+			ops = append(ops, Op{Kind: Insert, Line: b[j]})
+		}
+		if i < len(a) && j < len(b) {
+			ops = append(ops, Op{Kind: Equal, Line: a[i]})
+			i++
+			j++
+		}
+	}
+	return ops
+}
+
+// lcs find longest common subsequence of a and b, ia and ib are indexes of their lines in texts
+type lcs struct {
+	a, b    []int
+	ia, ib  []int
+	matches [][2]int
+}
+
+func (c *lcs) match(x, y int) {
+	c.matches = append(c.matches, [2]int{c.ia[x], c.ib[y]})
+}
+
+// diff add matches of a[a0:a1] and b[b0:b1] in order
+func (c *lcs) diff(a0, a1, b0, b1 int) {
+	for a0 < a1 && b0 < b1 && c.a[a0] == c.b[b0] {
+		c.match(a0, b0)
+		a0++
+		b0++
+	}
+	suffix := 0
+	for a1 > a0 && b1 > b0 && c.a[a1-1] == c.b[b1-1] {
+		a1--
+		b1--
+		suffix++
+	}
+
+	if a0 < a1 && b0 < b1 {
+		if x, y, ok := c.bisect(a0, a1, b0, b1); ok {
+			c.diff(a0, x, b0, y)
+			c.diff(x, a1, y, b1)
+		}
+	}
+
+	for i := range suffix {
+		c.match(a1+i, b1+i)
+	}
+}
+
+// bisect find middle snake of a[a0:a1] and b[b0:b1], which have no common prefix and suffix, by
+// running forward and reverse paths until they overlap. Point where forward path meets reverse
+// one splits texts. After maxCost edits texts are split at point of forward path closest to end.
+// Not ok means texts have no common lines
+func (c *lcs) bisect(a0, a1, b0, b1 int) (int, int, bool) {
+	n, m := a1-a0, b1-b0
+	maxD := min((n+m+1)/2, maxCost+1)
+	offset := maxD
+	vf := make([]int, 2*maxD+2)
+	vb := make([]int, 2*maxD+2)
+	for i := range vf {
+		vf[i], vb[i] = -1, -1
+	}
+	vf[offset+1], vb[offset+1] = 0, 0
+
+	delta := n - m
+	// with odd delta forward path meets reverse one, with even reverse meets forward
+	front := delta%2 != 0
+	// diagonals which left the grid are skipped
+	var kfStart, kfEnd, kbStart, kbEnd int
+
+	for d := 0; d < maxD; d++ {
+		for k := -d + kfStart; k <= d-kfEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && vf[offset+k-1] < vf[offset+k+1]) {
+				x = vf[offset+k+1]
+			} else {
+				x = vf[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && c.a[a0+x] == c.b[b0+y] {
+				x++
+				y++
+			}
+			vf[offset+k] = x
+			switch {
+			case x > n:
+				kfEnd += 2
+			case y > m:
+				kfStart += 2
+			case front:
+				if kb := offset + delta - k; kb >= 0 && kb < len(vb) && vb[kb] != -1 && x >= n-vb[kb] {
+					return a0 + x, b0 + y, true
+				}
+			}
+		}
+
+		for k := -d + kbStart; k <= d-kbEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && vb[offset+k-1] < vb[offset+k+1]) {
+				x = vb[offset+k+1]
+			} else {
+				x = vb[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && c.a[a1-x-1] == c.b[b1-y-1] {
+				x++
+				y++
+			}
+			vb[offset+k] = x
+			switch {
+			case x > n:
+				kbEnd += 2
+			case y > m:
+				kbStart += 2
+			case !front:
+				if kf := offset + delta - k; kf >= 0 && kf < len(vf) && vf[kf] != -1 {
+					fx := vf[kf]
+					if fx >= n-x {
+						return a0 + fx, b0 + fx - (kf - offset), true
+					}
+				}
+			}
+		}
+	}
+	if maxD <= maxCost {
+		return 0, 0, false
+	}
+
+	bestX, bestY := 0, 0
+	for k := -maxCost + kfStart; k <= maxCost-kfEnd; k += 2 {
+		x := vf[offset+k]
+		if y := x - k; x >= 0 && x <= n && y >= 0 && y <= m && x+y > bestX+bestY && (x < n || y < m) {
+			bestX, bestY = x, y
+		}
+	}
+	if bestX+bestY == 0 {
+		return 0, 0, false
+	}
+	return a0 + bestX, b0 + bestY, true
+}
+
+// Unified return diff of a and b in unified format. Empty string means texts are equal
+func Unified(fromName, toName, a, b string, context int) string {
+	ops := Compute(Lines(a), Lines(b))
+
+	changed := false
+	for _, op := range ops {
+		if op.Kind != Equal {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+
+	for _, h := range hunks(ops, context) {
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(h.aStart, h.aLen), hunkRange(h.bStart, h.bLen))
+		for _, op := range ops[h.from:h.to] {
+			switch op.Kind {
+			case Equal:
+				sb.WriteByte(' ')
+			case Insert:
+				sb.WriteByte('+')
+			case Delete:
+				sb.WriteByte('-')
+			}
+			sb.WriteString(op.Line)
+			if !strings.HasSuffix(op.Line, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+	}
+	return sb.String()
+}
+
+type hunk struct {
+	from, to     int
+	aStart, aLen int
+	bStart, bLen int
+}
+
+// hunks group changed ops with context lines around them, merging groups which context overlaps
+func hunks(ops []Op, context int) []hunk {
+	if context < 0 {
+		context = 0
+	}
+
+	var (
+		hs   []hunk
+		cur  *hunk
+		aPos int
+		bPos int
+		// positions in a and b before each op
+		aAt = make([]int, len(ops)+1)
+		bAt = make([]int, len(ops)+1)
+	)
+	for i, op := range ops {
+		aAt[i], bAt[i] = aPos, bPos
+		if op.Kind != Insert {
+			aPos++
+		}
+		if op.Kind != Delete {
+			bPos++
+		}
+	}
+	aAt[len(ops)], bAt[len(ops)] = aPos, bPos
+
+	for i, op := range ops {
+		if op.Kind == Equal {
+			continue
+		}
+		from := i - context
+		if from < 0 {
+			from = 0
+		}
+		to := i + 1 + context
+		if to > len(ops) {
+			to = len(ops)
+		}
+		if cur != nil && from <= cur.to {
+			cur.to = to
+			continue
+		}
+		hs = append(hs, hunk{from: from, to: to})
+		cur = &hs[len(hs)-1]
+	}
+
+	for i := range hs {
+		h := &hs[i]
+		h.aStart, h.bStart = aAt[h.from], bAt[h.from]
+		h.aLen, h.bLen = aAt[h.to]-h.aStart, bAt[h.to]-h.bStart
+	}
+	return hs
+}
+
+// hunkRange format range like GNU diff: empty range points to line before it, length 1 omitted
+func hunkRange(start, length int) string {
+	switch length {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, length)
+	}
+}
@@ -0,0 +1,216 @@
+package diff
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func apply(a []string, ops []Op) []string {
+	var res []string
+	i := 0
+	for _, op := range ops {
+		switch op.Kind {
+		case Equal:
+			res = append(res, a[i])
+			i++
+		case Delete:
+			i++
+		case Insert:
+			res = append(res, op.Line)
+		}
+	}
+	return res
+}
+
+func TestCompute(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		a, b string
+	}{
+		{"equal", "a\nb\nc\n", "a\nb\nc\n"},
+		{"insert", "a\nc\n", "a\nb\nc\n"},
+		{"delete", "a\nb\nc\n", "a\nc\n"},
+		{"replace", "a\nb\nc\n", "a\nx\nc\n"},
+		{"from empty", "", "a\nb\n"},
+		{"to empty", "a\nb\n", ""},
+		{"shuffled", "a\nb\nc\nd\ne\n", "e\nd\nc\nb\na\n"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			a, b := Lines(tc.a), Lines(tc.b)
+			ops := Compute(a, b)
+			assert.Equal(t, strings.Join(b, ""), strings.Join(apply(a, ops), ""))
+		})
+	}
+}
+
+func TestComputeMinimal(t *testing.T) {
+	t.Parallel()
+
+	ops := Compute(Lines("a\nb\nc\nd\n"), Lines("a\nc\nd\ne\n"))
+	edits := 0
+	for _, op := range ops {
+		if op.Kind != Equal {
+			edits++
+		}
+	}
+	assert.Equal(t, 2, edits)
+}
+
+// lcsLen is length of longest common subsequence by dynamic programming
+func lcsLen(a, b []string) int {
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(prev[j+1], cur[j])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func TestComputeRandom(t *testing.T) {
+	t.Parallel()
+
+	r := rand.New(rand.NewPCG(1, 2))
+	text := func() []string {
+		ls := make([]string, r.IntN(40))
+		for i := range ls {
+			ls[i] = fmt.Sprintf("%d\n", r.IntN(6))
+		}
+		return ls
+	}
+	for range 500 {
+		a, b := text(), text()
+		ops := Compute(a, b)
+		assert.Equal(t, strings.Join(b, ""), strings.Join(apply(a, ops), ""))
+
+		edits := 0
+		for _, op := range ops {
+			if op.Kind != Equal {
+				edits++
+			}
+		}
+		assert.Equal(t, len(a)+len(b)-2*lcsLen(a, b), edits)
+	}
+}
+
+func TestComputeLarge(t *testing.T) {
+	t.Parallel()
+
+	const n = 20_000
+	a, b, c := make([]string, n), make([]string, n), make([]string, n)
+	for i := range n {
+		a[i] = fmt.Sprintf("a %d\n", i)
+		b[i] = fmt.Sprintf("b %d\n", i)
+		c[i] = a[i]
+		if i%1000 == 0 {
+			c[i] = fmt.Sprintf("c %d\n", i)
+		}
+	}
+
+	// texts without common lines
+	ops := Compute(a, b)
+	assert.Len(t, ops, 2*n)
+	assert.Equal(t, strings.Join(b, ""), strings.Join(apply(a, ops), ""))
+
+	// same lines in other order
+	rev := make([]string, n)
+	for i := range n {
+		rev[i] = a[n-1-i]
+	}
+	ops = Compute(a, rev)
+	assert.Equal(t, strings.Join(rev, ""), strings.Join(apply(a, ops), ""))
+
+	ops = Compute(a, c)
+	assert.Len(t, ops, n+n/1000)
+	assert.Equal(t, strings.Join(c, ""), strings.Join(apply(a, ops), ""))
+}
+
+func TestUnifiedEqual(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "", Unified("a", "b", "x\ny\n", "x\ny\n", DefaultContext))
+}
+
+func TestUnified(t *testing.T) {
+	t.Parallel()
+
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	b := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n"
+
+	want := `--- rev 1
++++ rev 2
+@@ -1,6 +1,6 @@
+ 1
+ 2
+-3
++three
+ 4
+ 5
+ 6
+@@ -10,3 +10,4 @@
+ 10
+ 11
+ 12
++13
+`
+	assert.Equal(t, want, Unified("rev 1", "rev 2", a, b, DefaultContext))
+}
+
+func TestUnifiedMergeHunks(t *testing.T) {
+	t.Parallel()
+
+	a := "1\n2\n3\n4\n5\n"
+	b := "one\n2\n3\n4\nfive\n"
+
+	want := `--- a
++++ b
+@@ -1,5 +1,5 @@
+-1
++one
+ 2
+ 3
+ 4
+-5
++five
+`
+	assert.Equal(t, want, Unified("a", "b", a, b, DefaultContext))
+}
+
+func TestUnifiedNoNewline(t *testing.T) {
+	t.Parallel()
+
+	want := `--- a
++++ b
+@@ -1,2 +1,2 @@
+ x
+-y
+\ No newline at end of file
++y
+`
+	assert.Equal(t, want, Unified("a", "b", "x\ny", "x\ny\n", DefaultContext))
+}
+
+func TestUnifiedFromEmpty(t *testing.T) {
+	t.Parallel()
+
+	want := `--- a
++++ b
+@@ -0,0 +1,2 @@
++x
++y
+`
+	assert.Equal(t, want, Unified("a", "b", "", "x\ny\n", DefaultContext))
+}
@@ -0,0 +1,41 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"flicker/internal/views"
+	"time"
+)
+
+type SqlRepo interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+const (
+	waitTime = 3 * time.Second
+	// content of document goes with query
+	contentWaitTime = 30 * time.Second
+)
+
+type Driver struct {
+	driver SqlRepo
+}
+
+func NewDriver(driver SqlRepo) *Driver {
+	return &Driver{
+		driver: driver,
+	}
+}
+
+type DocumentsRepo interface {
+	Create(ctx context.Context, d *views.Document, content []byte) error
+	Get(ctx context.Context, owner, id string) (*views.Document, error)
+	Content(ctx context.Context, owner, id string) ([]byte, error)
+	List(ctx context.Context, owner string) ([]*views.Document, error)
+	Indexing(ctx context.Context, owner, id string) error
+	Indexed(ctx context.Context, owner, id string, r *views.IngestReport) error
+	Failed(ctx context.Context, owner, id, reason string) error
+	Delete(ctx context.Context, owner, id string) error
+}
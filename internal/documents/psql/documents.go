@@ -0,0 +1,188 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flicker/internal/views"
+
+	"github.com/autumnterror/breezynotes/pkg/log"
+	"github.com/autumnterror/breezynotes/pkg/utils/format"
+)
+
+// Create document with content in indexing status. Id and owner must be set by caller
+func (d *Driver) Create(ctx context.Context, doc *views.Document, content []byte) error {
+	const op = "psql.documents.Create"
+
+	ctx, done := context.WithTimeout(ctx, contentWaitTime)
+	defer done()
+
+	query := `
+		INSERT INTO documents (id, owner, filename, size, checksum, mime_type, content)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING status, created_at
+	`
+	if err := d.driver.QueryRowContext(ctx, query, doc.Id, doc.Owner, doc.Filename, doc.Size, doc.Checksum, doc.MimeType, content).
+		Scan(&doc.Status, &doc.CreatedAt); err != nil {
+		return format.Error(op, err)
+	}
+
+	return nil
+}
+
+const documentColumns = `id, owner, filename, size, checksum, mime_type, status, chunks, error, created_at, indexed_at`
+
+func scanDocument(row interface{ Scan(dest ...any) error }, extra ...any) (*views.Document, error) {
+	var (
+		doc       views.Document
+		indexedAt sql.NullTime
+	)
+	dest := append([]any{&doc.Id, &doc.Owner, &doc.Filename, &doc.Size, &doc.Checksum, &doc.MimeType,
+		&doc.Status, &doc.Chunks, &doc.Error, &doc.CreatedAt, &indexedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if indexedAt.Valid {
+		doc.IndexedAt = &indexedAt.Time
+	}
+	return &doc, nil
+}
+
+// Get document of owner with last ingestion report, without content. May send sql.ErrNoRows
+func (d *Driver) Get(ctx context.Context, owner, id string) (*views.Document, error) {
+	const op = "psql.documents.Get"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	var report []byte
+	doc, err := scanDocument(d.driver.QueryRowContext(ctx,
+		`SELECT `+documentColumns+`, report FROM documents WHERE id = $1 AND owner = $2`, id, owner), &report)
+	if err != nil {
+		return nil, format.Error(op, err)
+	}
+	if report != nil {
+		doc.Report = &views.IngestReport{}
+		if err := json.Unmarshal(report, doc.Report); err != nil {
+			return nil, format.Error(op, err)
+		}
+	}
+
+	return doc, nil
+}
+
+// Content return uploaded file. May send sql.ErrNoRows
+func (d *Driver) Content(ctx context.Context, owner, id string) ([]byte, error) {
+	const op = "psql.documents.Content"
+
+	ctx, done := context.WithTimeout(ctx, contentWaitTime)
+	defer done()
+
+	var content []byte
+	if err := d.driver.QueryRowContext(ctx, `SELECT content FROM documents WHERE id = $1 AND owner = $2`, id, owner).
+		Scan(&content); err != nil {
+		return nil, format.Error(op, err)
+	}
+
+	return content, nil
+}
+
+// List documents of owner without reports, newest first
+func (d *Driver) List(ctx context.Context, owner string) ([]*views.Document, error) {
+	const op = "psql.documents.List"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	rows, err := d.driver.QueryContext(ctx,
+		`SELECT `+documentColumns+` FROM documents WHERE owner = $1 ORDER BY created_at DESC, id`, owner)
+	if err != nil {
+		return nil, format.Error(op, err)
+	}
+	defer rows.Close()
+
+	docs := []*views.Document{}
+	for rows.Next() {
+		doc, err := scanDocument(rows)
+		if err != nil {
+			log.Error(op, "rows scan error", err)
+			continue
+		}
+		docs = append(docs, doc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, format.Error(op, err)
+	}
+
+	return docs, nil
+}
+
+// Indexing mark document as being indexed again. May send sql.ErrNoRows
+func (d *Driver) Indexing(ctx context.Context, owner, id string) error {
+	const op = "psql.documents.Indexing"
+
+	return d.setStatus(ctx, op, `UPDATE documents SET status = 'indexing', error = '' WHERE id = $1 AND owner = $2`, id, owner)
+}
+
+// Indexed save ingestion report. May send sql.ErrNoRows
+func (d *Driver) Indexed(ctx context.Context, owner, id string, r *views.IngestReport) error {
+	const op = "psql.documents.Indexed"
+
+	report, err := json.Marshal(r)
+	if err != nil {
+		return format.Error(op, err)
+	}
+	return d.setStatus(ctx, op, `
+		UPDATE documents SET status = 'indexed', chunks = $3, report = $4, error = '', indexed_at = now()
+		WHERE id = $1 AND owner = $2
+	`, id, owner, r.Chunks, report)
+}
+
+// Failed save reason of failed indexing. Previous report is kept, vectors of it may be still in store.
+// May send sql.ErrNoRows
+func (d *Driver) Failed(ctx context.Context, owner, id, reason string) error {
+	const op = "psql.documents.Failed"
+
+	return d.setStatus(ctx, op, `UPDATE documents SET status = 'failed', error = $3 WHERE id = $1 AND owner = $2`, id, owner, reason)
+}
+
+func (d *Driver) setStatus(ctx context.Context, op, query string, args ...any) error {
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	res, err := d.driver.ExecContext(ctx, query, args...)
+	if err != nil {
+		return format.Error(op, err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return format.Error(op, err)
+	}
+	if rows == 0 {
+		return format.Error(op, sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+// Delete document. Chunks must be deleted from store by caller. May send sql.ErrNoRows
+func (d *Driver) Delete(ctx context.Context, owner, id string) error {
+	const op = "psql.documents.Delete"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	res, err := d.driver.ExecContext(ctx, `DELETE FROM documents WHERE id = $1 AND owner = $2`, id, owner)
+	if err != nil {
+		return format.Error(op, err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return format.Error(op, err)
+	}
+	if rows == 0 {
+		return format.Error(op, sql.ErrNoRows)
+	}
+
+	return nil
+}
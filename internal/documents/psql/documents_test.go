@@ -0,0 +1,114 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	authpsql "flicker/internal/auth/psql"
+	"flicker/internal/config"
+	"flicker/internal/views"
+	"testing"
+
+	"github.com/autumnterror/breezynotes/pkg/utils/id"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocumentsOperations(t *testing.T) {
+	t.Parallel()
+
+	repo, owner, cleanup := setupTestTx(t)
+	defer cleanup()
+
+	doc := &views.Document{
+		Id:       id.New(),
+		Owner:    owner,
+		Filename: "lecture.txt",
+		Size:     5,
+		Checksum: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+		MimeType: "text/plain",
+	}
+
+	t.Run("create", func(t *testing.T) {
+		assert.NoError(t, repo.Create(context.TODO(), doc, []byte("hello")))
+		assert.Equal(t, views.DocumentIndexing, doc.Status)
+		assert.False(t, doc.CreatedAt.IsZero())
+	})
+
+	t.Run("content", func(t *testing.T) {
+		content, err := repo.Content(context.TODO(), owner, doc.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("hello"), content)
+
+		_, err = repo.Content(context.TODO(), id.New(), doc.Id)
+		assert.ErrorIs(t, err, sql.ErrNoRows, "other owner")
+	})
+
+	t.Run("indexed", func(t *testing.T) {
+		assert.NoError(t, repo.Indexed(context.TODO(), owner, doc.Id, &views.IngestReport{DocumentId: doc.Id, Chunks: 3, Format: "txt"}))
+
+		got, err := repo.Get(context.TODO(), owner, doc.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, views.DocumentIndexed, got.Status)
+		assert.Equal(t, 3, got.Chunks)
+		assert.NotNil(t, got.IndexedAt)
+		if assert.NotNil(t, got.Report) {
+			assert.Equal(t, "txt", got.Report.Format)
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		assert.NoError(t, repo.Indexing(context.TODO(), owner, doc.Id))
+		assert.NoError(t, repo.Failed(context.TODO(), owner, doc.Id, "embedding error"))
+
+		got, err := repo.Get(context.TODO(), owner, doc.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, views.DocumentFailed, got.Status)
+		assert.Equal(t, "embedding error", got.Error)
+		assert.NotNil(t, got.Report, "previous report is kept")
+
+		assert.ErrorIs(t, repo.Failed(context.TODO(), id.New(), doc.Id, ""), sql.ErrNoRows)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		other := &views.Document{Id: id.New(), Owner: owner, Filename: "b.md", Size: 1, Checksum: "x", MimeType: "text/markdown"}
+		assert.NoError(t, repo.Create(context.TODO(), other, []byte("b")))
+
+		docs, err := repo.List(context.TODO(), owner)
+		assert.NoError(t, err)
+		assert.Len(t, docs, 2)
+		for _, d := range docs {
+			assert.Nil(t, d.Report)
+		}
+
+		docs, err = repo.List(context.TODO(), id.New())
+		assert.NoError(t, err)
+		assert.Empty(t, docs)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.NoError(t, repo.Delete(context.TODO(), owner, doc.Id))
+		assert.ErrorIs(t, repo.Delete(context.TODO(), owner, doc.Id), sql.ErrNoRows)
+
+		_, err := repo.Get(context.TODO(), owner, doc.Id)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+}
+
+func setupTestTx(t *testing.T) (*Driver, string, func()) {
+	pdb := authpsql.MustConnect(config.Test())
+
+	tx, err := pdb.Driver.Begin()
+	assert.NoError(t, err)
+
+	owner := id.New()
+	assert.NoError(t, authpsql.NewDriver(tx).Create(context.TODO(), &views.User{
+		Id:       owner,
+		Login:    owner[:10],
+		Email:    owner[:10] + "@example.com",
+		Password: "password",
+	}))
+
+	return NewDriver(tx), owner, func() {
+		assert.NoError(t, tx.Rollback())
+		assert.NoError(t, pdb.Disconnect())
+	}
+}
@@ -0,0 +1,253 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/autumnterror/breezynotes/pkg/utils/format"
+	_ "modernc.org/sqlite"
+)
+
+// Card is Anki note with two HTML fields
+type Card struct {
+	Front string
+	Back  string
+}
+
+// Apkg build Anki package (collection schema 11, supported by Anki 2.1 and AnkiDroid)
+// with one deck of basic front/back notes
+func Apkg(deck string, cards []Card) ([]byte, error) {
+	const op = "export.Apkg"
+
+	f, err := os.CreateTemp("", "flicker-*.anki2")
+	if err != nil {
+		return nil, format.Error(op, err)
+	}
+	path := f.Name()
+	f.Close()
+	defer os.Remove(path)
+
+	if err := writeCollection(path, deck, cards); err != nil {
+		return nil, format.Error(op, err)
+	}
+	collection, err := os.ReadFile(path)
+	if err != nil {
+		return nil, format.Error(op, err)
+	}
+
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+	for _, file := range []struct {
+		name string
+		body []byte
+	}{
+		{"collection.anki2", collection},
+		{"media", []byte("{}")},
+	} {
+		w, err := z.Create(file.name)
+		if err != nil {
+			return nil, format.Error(op, err)
+		}
+		if _, err := w.Write(file.body); err != nil {
+			return nil, format.Error(op, err)
+		}
+	}
+	if err := z.Close(); err != nil {
+		return nil, format.Error(op, err)
+	}
+	return buf.Bytes(), nil
+}
+
+const ankiSchema = `
+	CREATE TABLE col (
+		id integer primary key, crt integer not null, mod integer not null, scm integer not null,
+		ver integer not null, dty integer not null, usn integer not null, ls integer not null,
+		conf text not null, models text not null, decks text not null, dconf text not null, tags text not null
+	);
+	CREATE TABLE notes (
+		id integer primary key, guid text not null, mid integer not null, mod integer not null,
+		usn integer not null, tags text not null, flds text not null, sfld integer not null,
+		csum integer not null, flags integer not null, data text not null
+	);
+	CREATE TABLE cards (
+		id integer primary key, nid integer not null, did integer not null, ord integer not null,
+		mod integer not null, usn integer not null, type integer not null, queue integer not null,
+		due integer not null, ivl integer not null, factor integer not null, reps integer not null,
+		lapses integer not null, left integer not null, odue integer not null, odid integer not null,
+		flags integer not null, data text not null
+	);
+	CREATE TABLE revlog (
+		id integer primary key, cid integer not null, usn integer not null, ease integer not null,
+		ivl integer not null, lastIvl integer not null, factor integer not null, time integer not null,
+		type integer not null
+	);
+	CREATE TABLE graves (usn integer not null, oid integer not null, type integer not null);
+	CREATE INDEX ix_notes_usn ON notes (usn);
+	CREATE INDEX ix_cards_usn ON cards (usn);
+	CREATE INDEX ix_revlog_usn ON revlog (usn);
+	CREATE INDEX ix_cards_nid ON cards (nid);
+	CREATE INDEX ix_cards_sched ON cards (did, queue, due);
+	CREATE INDEX ix_revlog_cid ON revlog (cid);
+	CREATE INDEX ix_notes_csum ON notes (csum);
+`
+
+const ankiCSS = `.card { font-family: arial; font-size: 20px; text-align: left; color: black; background-color: white; }
+table { border-collapse: collapse; } td, th { border: 1px solid #aaa; padding: 2px 6px; }
+pre { background: #f2f2f2; padding: 6px; }`
+
+func writeCollection(path, deck string, cards []Card) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(ankiSchema); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	ms := now.UnixMilli()
+	// ids of model and deck are derived from deck name, so reimport of same deck updates it
+	modelId := idFromName("flicker model " + deck)
+	deckId := idFromName("flicker deck " + deck)
+
+	models, decks, dconf, conf := ankiModels(modelId, deckId, now), ankiDecks(deck, deckId, now), ankiDeckConf(), ankiConf(deckId, modelId)
+	if _, err := tx.Exec(`INSERT INTO col VALUES (1, ?, ?, ?, 11, 0, 0, 0, ?, ?, ?, ?, '{}')`,
+		now.Unix(), ms, ms, conf, models, decks, dconf); err != nil {
+		return err
+	}
+
+	for i, c := range cards {
+		noteId := ms + int64(i)
+		sort := stripHTML(c.Front)
+		if _, err := tx.Exec(`INSERT INTO notes VALUES (?, ?, ?, ?, -1, '', ?, ?, ?, 0, '')`,
+			noteId, guid(deck, c.Front), modelId, now.Unix(), c.Front+"\x1f"+c.Back, sort, checksum(sort)); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO cards VALUES (?, ?, ?, 0, ?, -1, 0, 0, ?, 0, 0, 0, 0, 0, 0, 0, 0, '')`,
+			noteId, noteId, deckId, now.Unix(), i+1); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func idFromName(name string) int64 {
+	h := sha1.Sum([]byte(name))
+	// positive and below 2^53, anki stores ids as JSON numbers
+	return int64(binary.BigEndian.Uint64(h[:8]) >> 11)
+}
+
+// guid identify note between imports, so reexport of same card doesn't duplicate it
+func guid(deck, front string) string {
+	h := sha1.Sum([]byte(deck + "\x1f" + front))
+	return fmt.Sprintf("%x", h[:8])
+}
+
+// checksum is first 8 hex digits of sha1 of sort field, as anki computes it
+func checksum(s string) int64 {
+	h := sha1.Sum([]byte(s))
+	v, _ := strconv.ParseInt(fmt.Sprintf("%x", h[:4]), 16, 64)
+	return v
+}
+
+var tagRe = regexp.MustCompile(`<[^>]*>`)
+
+func stripHTML(s string) string {
+	return strings.TrimSpace(html.UnescapeString(tagRe.ReplaceAllString(s, " ")))
+}
+
+func mustJSON(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func ankiModels(modelId, deckId int64, now time.Time) string {
+	field := func(name string, ord int) map[string]any {
+		return map[string]any{"name": name, "ord": ord, "sticky": false, "rtl": false, "font": "Arial", "size": 20, "media": []any{}}
+	}
+	return mustJSON(map[string]any{
+		strconv.FormatInt(modelId, 10): map[string]any{
+			"id":        modelId,
+			"name":      "Flicker Basic",
+			"type":      0,
+			"mod":       now.Unix(),
+			"usn":       -1,
+			"sortf":     0,
+			"did":       deckId,
+			"tags":      []any{},
+			"vers":      []any{},
+			"flds":      []any{field("Front", 0), field("Back", 1)},
+			"css":       ankiCSS,
+			"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+			"latexPost": "\\end{document}",
+			"req":       []any{[]any{0, "all", []int{0}}},
+			"tmpls": []any{map[string]any{
+				"name":  "Card 1",
+				"ord":   0,
+				"qfmt":  "{{Front}}",
+				"afmt":  "{{FrontSide}}\n\n<hr id=answer>\n\n{{Back}}",
+				"bqfmt": "",
+				"bafmt": "",
+				"did":   nil,
+			}},
+		},
+	})
+}
+
+func ankiDecks(name string, deckId int64, now time.Time) string {
+	deck := func(id int64, name string) map[string]any {
+		return map[string]any{
+			"id": id, "name": name, "desc": "", "mod": now.Unix(), "usn": -1, "dyn": 0, "conf": 1, "collapsed": false,
+			"extendNew": 10, "extendRev": 50,
+			"newToday": []int{0, 0}, "revToday": []int{0, 0}, "lrnToday": []int{0, 0}, "timeToday": []int{0, 0},
+		}
+	}
+	return mustJSON(map[string]any{
+		"1":                           deck(1, "Default"),
+		strconv.FormatInt(deckId, 10): deck(deckId, name),
+	})
+}
+
+func ankiDeckConf() string {
+	return mustJSON(map[string]any{
+		"1": map[string]any{
+			"id": 1, "name": "Default", "mod": 0, "usn": 0, "maxTaken": 60, "autoplay": true, "timer": 0, "replayq": true, "dyn": false,
+			"new": map[string]any{
+				"bury": true, "delays": []float64{1, 10}, "initialFactor": 2500, "ints": []int{1, 4, 7}, "order": 1, "perDay": 20, "separate": true,
+			},
+			"rev": map[string]any{
+				"bury": true, "ease4": 1.3, "fuzz": 0.05, "ivlFct": 1, "maxIvl": 36500, "minSpace": 1, "perDay": 100,
+			},
+			"lapse": map[string]any{
+				"delays": []float64{10}, "leechAction": 0, "leechFails": 8, "minInt": 1, "mult": 0,
+			},
+		},
+	})
+}
+
+func ankiConf(deckId, modelId int64) string {
+	return mustJSON(map[string]any{
+		"activeDecks": []int64{deckId}, "curDeck": deckId, "curModel": modelId, "newSpread": 0, "collapseTime": 1200,
+		"timeLim": 0, "estTimes": true, "dueCounts": true, "sortType": "noteFld", "sortBackwards": false, "nextPos": 1,
+	})
+}
@@ -0,0 +1,167 @@
+package export
+
+import (
+	"flicker/internal/quiz"
+	"flicker/internal/views"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// FromQuiz return printable document with questions and answers key
+func FromQuiz(q *views.Quiz) *Document {
+	return Parse(q.Title, quiz.Markdown(q))
+}
+
+// CardsFromDocument make card from every section: heading path is front, section content is back.
+// Sections without content (heading followed by subheading) are skipped
+func CardsFromDocument(d *Document) []Card {
+	var (
+		cards   []Card
+		path    []string
+		content []Block
+	)
+	flush := func() {
+		if len(path) > 0 && len(content) > 0 {
+			cards = append(cards, Card{
+				Front: html.EscapeString(strings.Join(path, " › ")),
+				Back:  HTML(content),
+			})
+		}
+		content = nil
+	}
+
+	for _, b := range d.Blocks {
+		if b.Kind != Heading {
+			content = append(content, b)
+			continue
+		}
+		flush()
+		level := min(max(b.Level, 1), len(path)+1)
+		path = append(path[:level-1], PlainText(b.Text))
+	}
+	flush()
+
+	return cards
+}
+
+// CardsFromQuiz make card from every question: question with options is front,
+// correct answer with explanation is back
+func CardsFromQuiz(q *views.Quiz) []Card {
+	cards := make([]Card, 0, len(q.Questions))
+	for _, qs := range q.Questions {
+		var front strings.Builder
+		front.WriteString(html.EscapeString(qs.Text))
+		if len(qs.Options) > 0 {
+			front.WriteString(`<ol type="A">`)
+			for _, o := range qs.Options {
+				front.WriteString("<li>" + html.EscapeString(o) + "</li>")
+			}
+			front.WriteString("</ol>")
+		}
+
+		var back strings.Builder
+		if qs.Type == views.QuestionOpen {
+			back.WriteString(html.EscapeString(qs.Answer))
+		} else {
+			for i, c := range qs.Correct {
+				if i > 0 {
+					back.WriteString("<br>")
+				}
+				if c >= 0 && c < len(qs.Options) {
+					back.WriteString("<b>" + quiz.Label(c) + ")</b> " + html.EscapeString(qs.Options[c]))
+				}
+			}
+		}
+		if qs.Explanation != "" {
+			back.WriteString("<br><br><i>" + html.EscapeString(qs.Explanation) + "</i>")
+		}
+
+		cards = append(cards, Card{Front: front.String(), Back: back.String()})
+	}
+	return cards
+}
+
+// HTML render blocks as simple HTML for anki card fields
+func HTML(blocks []Block) string {
+	var sb strings.Builder
+	for _, b := range blocks {
+		switch b.Kind {
+		case Heading:
+			n := strconv.Itoa(min(max(b.Level, 1), 6))
+			sb.WriteString("<h" + n + ">" + inlineHTML(b.Text) + "</h" + n + ">")
+		case Paragraph:
+			sb.WriteString("<p>" + inlineHTML(b.Text) + "</p>")
+		case Quote:
+			sb.WriteString("<blockquote>" + inlineHTML(b.Text) + "</blockquote>")
+		case List:
+			listHTML(&sb, b)
+		case Code:
+			sb.WriteString("<pre><code>" + html.EscapeString(b.Code) + "</code></pre>")
+		case Table:
+			sb.WriteString("<table>")
+			for i, row := range b.Rows {
+				tag := "td"
+				if i == 0 {
+					tag = "th"
+				}
+				sb.WriteString("<tr>")
+				for _, cell := range row {
+					sb.WriteString("<" + tag + ">" + inlineHTML(cell) + "</" + tag + ">")
+				}
+				sb.WriteString("</tr>")
+			}
+			sb.WriteString("</table>")
+		case Rule:
+			sb.WriteString("<hr>")
+		}
+	}
+	return sb.String()
+}
+
+func listHTML(sb *strings.Builder, b Block) {
+	open, closeTag := "<ul>", "</ul>"
+	if b.Ordered {
+		open, closeTag = `<ol start="`+strconv.Itoa(max(b.Start, 1))+`">`, "</ol>"
+	}
+
+	level := -1
+	for _, it := range b.Items {
+		for level < it.Level {
+			if level < 0 {
+				sb.WriteString(open)
+			} else {
+				sb.WriteString("<ul>")
+			}
+			level++
+		}
+		for level > it.Level {
+			sb.WriteString("</ul>")
+			level--
+		}
+		sb.WriteString("<li>" + inlineHTML(it.Text) + "</li>")
+	}
+	for ; level > 0; level-- {
+		sb.WriteString("</ul>")
+	}
+	sb.WriteString(closeTag)
+}
+
+func inlineHTML(text string) string {
+	var sb strings.Builder
+	for _, s := range Spans(text) {
+		t := html.EscapeString(s.Text)
+		switch {
+		case s.Code:
+			t = "<code>" + t + "</code>"
+		case s.Bold && s.Italic:
+			t = "<b><i>" + t + "</i></b>"
+		case s.Bold:
+			t = "<b>" + t + "</b>"
+		case s.Italic:
+			t = "<i>" + t + "</i>"
+		}
+		sb.WriteString(t)
+	}
+	return sb.String()
+}
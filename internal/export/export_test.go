@@ -0,0 +1,478 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"encoding/binary"
+	"encoding/xml"
+	"flicker/internal/views"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sample = `# Механика
+
+Вводный абзац с **жирным**, *курсивом* и ` + "`кодом`" + `,
+который продолжается на второй строке.
+
+## Законы Ньютона
+
+1. Первый закон
+2. Второй закон
+   - F = ma
+   - a = F / m
+3. Третий закон
+
+| Закон | Формула |
+|-------|:-------:|
+| Второй | F = ma |
+| Третий | F1 = -F2 \| всегда |
+
+` + "```go\nfunc force(m, a float64) float64 {\n\treturn m * a\n}\n```" + `
+
+> Цитата из учебника
+
+---
+
+### Итог
+
+- один
+- два
+`
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	d := Parse("Механика", sample)
+	kinds := make([]Kind, 0, len(d.Blocks))
+	for _, b := range d.Blocks {
+		kinds = append(kinds, b.Kind)
+	}
+	assert.Equal(t, []Kind{Heading, Paragraph, Heading, List, Table, Code, Quote, Rule, Heading, List}, kinds)
+
+	assert.Equal(t, 1, d.Blocks[0].Level)
+	assert.Equal(t, "Механика", d.Blocks[0].Text)
+	assert.Equal(t, "Вводный абзац с **жирным**, *курсивом* и `кодом`, который продолжается на второй строке.", d.Blocks[1].Text)
+	assert.Equal(t, 2, d.Blocks[2].Level)
+
+	list := d.Blocks[3]
+	assert.True(t, list.Ordered)
+	assert.Equal(t, 1, list.Start)
+	assert.Equal(t, []Item{
+		{Level: 0, Text: "Первый закон"},
+		{Level: 0, Text: "Второй закон"},
+		{Level: 1, Text: "F = ma"},
+		{Level: 1, Text: "a = F / m"},
+		{Level: 0, Text: "Третий закон"},
+	}, list.Items)
+
+	assert.Equal(t, [][]string{
+		{"Закон", "Формула"},
+		{"Второй", "F = ma"},
+		{"Третий", "F1 = -F2 | всегда"},
+	}, d.Blocks[4].Rows)
+
+	assert.Equal(t, "go", d.Blocks[5].Lang)
+	assert.Equal(t, "func force(m, a float64) float64 {\n\treturn m * a\n}", d.Blocks[5].Code)
+	assert.Equal(t, "Цитата из учебника", d.Blocks[6].Text)
+	assert.False(t, d.Blocks[9].Ordered)
+	assert.Len(t, d.Blocks[9].Items, 2)
+}
+
+func TestParseRaggedTable(t *testing.T) {
+	t.Parallel()
+
+	d := Parse("", "| a | b | c |\n|---|---|---|\n| 1 |\n| 1 | 2 | 3 | 4 |\n")
+	require.Len(t, d.Blocks, 1)
+	assert.Equal(t, [][]string{{"a", "b", "c"}, {"1", "", ""}, {"1", "2", "3"}}, d.Blocks[0].Rows)
+}
+
+func TestSpans(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []Span{
+		{Text: "a "},
+		{Text: "b", Bold: true},
+		{Text: " "},
+		{Text: "c", Italic: true},
+		{Text: " "},
+		{Text: "x*y", Code: true},
+		{Text: " link snake_case 2*3"},
+	}, Spans("a **b** _c_ `x*y` [link](http://x) snake_case 2\\*3"))
+
+	assert.Equal(t, "bold and italic", PlainText("**bold** and *italic*"))
+}
+
+type docxParagraph struct {
+	Style string
+	NumId string
+	Level string
+	Text  string
+	Bold  bool
+}
+
+// readDocx return paragraphs outside of tables and cells text of every table
+func readDocx(t *testing.T, b []byte) ([]docxParagraph, [][][]string) {
+	z, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	require.NoError(t, err)
+
+	names := map[string]*zip.File{}
+	for _, f := range z.File {
+		names[f.Name] = f
+	}
+	for _, n := range []string{"[Content_Types].xml", "_rels/.rels", "word/document.xml", "word/styles.xml", "word/numbering.xml"} {
+		require.Contains(t, names, n)
+	}
+	// every part must be well formed
+	for n, f := range names {
+		r, err := f.Open()
+		require.NoError(t, err)
+		dec := xml.NewDecoder(r)
+		for {
+			if _, err := dec.Token(); err == io.EOF {
+				break
+			} else {
+				require.NoError(t, err, n)
+			}
+		}
+		r.Close()
+	}
+
+	r, err := names["word/document.xml"].Open()
+	require.NoError(t, err)
+	defer r.Close()
+
+	var (
+		paras  []docxParagraph
+		tables [][][]string
+		p      *docxParagraph
+		depth  int // table nesting
+		cell   strings.Builder
+	)
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		switch el := tok.(type) {
+		case xml.StartElement:
+			val := ""
+			for _, a := range el.Attr {
+				if a.Name.Local == "val" {
+					val = a.Value
+				}
+			}
+			switch el.Name.Local {
+			case "tbl":
+				depth++
+				tables = append(tables, nil)
+			case "tr":
+				tables[len(tables)-1] = append(tables[len(tables)-1], nil)
+			case "tc":
+				cell.Reset()
+			case "p":
+				p = &docxParagraph{}
+			case "pStyle":
+				p.Style = val
+			case "numId":
+				p.NumId = val
+			case "ilvl":
+				p.Level = val
+			case "b":
+				p.Bold = true
+			case "t":
+				var s string
+				require.NoError(t, dec.DecodeElement(&s, &el))
+				if depth > 0 {
+					cell.WriteString(s)
+				} else {
+					p.Text += s
+				}
+			}
+		case xml.EndElement:
+			switch el.Name.Local {
+			case "tbl":
+				depth--
+			case "tc":
+				rows := tables[len(tables)-1]
+				rows[len(rows)-1] = append(rows[len(rows)-1], cell.String())
+			case "p":
+				if depth == 0 {
+					paras = append(paras, *p)
+				}
+			}
+		}
+	}
+	return paras, tables
+}
+
+func TestDOCX(t *testing.T) {
+	t.Parallel()
+
+	b, err := DOCX(Parse("Механика", sample))
+	require.NoError(t, err)
+	paras, tables := readDocx(t, b)
+
+	type row struct{ Style, NumId, Level, Text string }
+	var got []row
+	for _, p := range paras {
+		got = append(got, row{p.Style, p.NumId, p.Level, p.Text})
+	}
+	assert.Equal(t, []row{
+		{"Heading1", "", "", "Механика"},
+		{"", "", "", "Вводный абзац с жирным, курсивом и кодом, который продолжается на второй строке."},
+		{"Heading2", "", "", "Законы Ньютона"},
+		{"ListParagraph", "2", "0", "Первый закон"},
+		{"ListParagraph", "2", "0", "Второй закон"},
+		{"ListParagraph", "2", "1", "F = ma"},
+		{"ListParagraph", "2", "1", "a = F / m"},
+		{"ListParagraph", "2", "0", "Третий закон"},
+		{"", "", "", ""}, // spacing after table
+		{"Code", "", "", "func force(m, a float64) float64 {"},
+		{"Code", "", "", "    return m * a"},
+		{"Code", "", "", "}"},
+		{"Quote", "", "", "Цитата из учебника"},
+		{"", "", "", ""}, // rule
+		{"Heading3", "", "", "Итог"},
+		{"ListParagraph", "1", "0", "один"},
+		{"ListParagraph", "1", "0", "два"},
+	}, got)
+	assert.True(t, paras[1].Bold)
+
+	assert.Equal(t, [][][]string{{
+		{"Закон", "Формула"},
+		{"Второй", "F = ma"},
+		{"Третий", "F1 = -F2 | всегда"},
+	}}, tables)
+}
+
+func TestDOCXOrderedListsRestart(t *testing.T) {
+	t.Parallel()
+
+	b, err := DOCX(Parse("", "1. a\n2. b\n\ntext\n\n5. c\n"))
+	require.NoError(t, err)
+	paras, _ := readDocx(t, b)
+	require.Len(t, paras, 4)
+	assert.Equal(t, "2", paras[0].NumId)
+	assert.Equal(t, "3", paras[3].NumId)
+
+	z, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	require.NoError(t, err)
+	for _, f := range z.File {
+		if f.Name == "word/numbering.xml" {
+			r, _ := f.Open()
+			body, _ := io.ReadAll(r)
+			assert.Contains(t, string(body), `<w:num w:numId="3"><w:abstractNumId w:val="1"/><w:lvlOverride w:ilvl="0"><w:startOverride w:val="5"/>`)
+		}
+	}
+}
+
+var (
+	pdfOutlineRe = regexp.MustCompile(`(?s)<</Title \((.*?)\)\n/Parent (\d+) 0 R`)
+	pdfPageRe    = regexp.MustCompile(`/Type /Page\b[^s]`)
+)
+
+// pdfString decode literal string written by fpdf: escapes and UTF-16BE with BOM
+func pdfString(s string) string {
+	var raw []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'r':
+				raw = append(raw, '\r')
+			case 'n':
+				raw = append(raw, '\n')
+			default:
+				raw = append(raw, s[i])
+			}
+			continue
+		}
+		raw = append(raw, s[i])
+	}
+	if !bytes.HasPrefix(raw, []byte{0xfe, 0xff}) {
+		return string(raw)
+	}
+	raw = raw[2:]
+	u := make([]uint16, len(raw)/2)
+	for i := range u {
+		u[i] = binary.BigEndian.Uint16(raw[2*i:])
+	}
+	return string(utf16.Decode(u))
+}
+
+// PDF tests are not parallel because they switch off compression
+func TestPDF(t *testing.T) {
+	compress = false
+	t.Cleanup(func() { compress = true })
+
+	b, err := PDF(Parse("Механика", sample))
+	require.NoError(t, err)
+	s := string(b)
+
+	assert.True(t, strings.HasPrefix(s, "%PDF-"))
+	assert.True(t, strings.HasSuffix(strings.TrimSpace(s), "%%EOF"))
+	assert.Len(t, pdfPageRe.FindAllString(s, -1), 1)
+
+	// headings are kept as outline with nesting
+	var titles []string
+	parents := map[string]bool{}
+	for _, m := range pdfOutlineRe.FindAllStringSubmatch(s, -1) {
+		titles = append(titles, pdfString(m[1]))
+		parents[m[2]] = true
+	}
+	assert.Equal(t, []string{"Механика", "Законы Ньютона", "Итог"}, titles)
+	assert.Len(t, parents, 3, "every level has own parent")
+	assert.Contains(t, s, "/PageMode /UseOutlines")
+
+	// regular, bold, italic and mono fonts are embedded
+	for _, f := range []string{"utf8go", "utf8goB", "utf8goI", "utf8gomono"} {
+		assert.Contains(t, s, "/BaseFont /"+f+"\n")
+	}
+	assert.Equal(t, 5, strings.Count(s, "/FontFile2"))
+
+	// table is 3 rows of 2 cells, header is filled; every code line has background
+	assert.Equal(t, 2, strings.Count(s, " re B"), "header cells")
+	assert.Equal(t, 4, strings.Count(s, " re S"), "body cells")
+	assert.Equal(t, 3, strings.Count(s, " re f"), "code lines")
+
+	assert.Contains(t, s, "/CreationDate (D:19700101000000")
+}
+
+func TestPDFPageBreak(t *testing.T) {
+	compress = false
+	t.Cleanup(func() { compress = true })
+
+	var md strings.Builder
+	md.WriteString("| n | square |\n|---|---|\n")
+	for i := range 120 {
+		md.WriteString("| " + strings.Repeat("x", i%7+1) + " | y |\n")
+	}
+	b, err := PDF(Parse("", md.String()))
+	require.NoError(t, err)
+	assert.Greater(t, len(pdfPageRe.FindAllString(string(b), -1)), 1)
+	assert.Equal(t, 2*120, strings.Count(string(b), " re S"), "no row is lost on break")
+}
+
+func readApkg(t *testing.T, b []byte) *sql.DB {
+	z, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	require.NoError(t, err)
+
+	var collection []byte
+	for _, f := range z.File {
+		r, err := f.Open()
+		require.NoError(t, err)
+		body, err := io.ReadAll(r)
+		require.NoError(t, err)
+		r.Close()
+		switch f.Name {
+		case "collection.anki2":
+			collection = body
+		case "media":
+			assert.Equal(t, "{}", string(body))
+		default:
+			t.Fatalf("unexpected file %s", f.Name)
+		}
+	}
+	require.NotNil(t, collection)
+
+	path := filepath.Join(t.TempDir(), "collection.anki2")
+	require.NoError(t, os.WriteFile(path, collection, 0o600))
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestApkg(t *testing.T) {
+	t.Parallel()
+
+	cards := []Card{
+		{Front: "Первый закон", Back: "<b>Инерция</b>"},
+		{Front: "Второй закон", Back: "F = ma"},
+	}
+	b, err := Apkg("Физика", cards)
+	require.NoError(t, err)
+	db := readApkg(t, b)
+
+	var ver int
+	var decks, models string
+	require.NoError(t, db.QueryRow(`SELECT ver, decks, models FROM col`).Scan(&ver, &decks, &models))
+	assert.Equal(t, 11, ver)
+	assert.Contains(t, decks, `"name":"Физика"`)
+	assert.Contains(t, models, `"name":"Front"`)
+
+	rows, err := db.Query(`SELECT n.flds, n.sfld, n.guid, c.did, c.ord FROM notes n JOIN cards c ON c.nid = n.id ORDER BY c.due`)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var (
+		got   []Card
+		guids = map[string]bool{}
+	)
+	for rows.Next() {
+		var (
+			flds, sfld, guid string
+			did              int64
+			ord              int
+		)
+		require.NoError(t, rows.Scan(&flds, &sfld, &guid, &did, &ord))
+		f := strings.Split(flds, "\x1f")
+		require.Len(t, f, 2)
+		got = append(got, Card{Front: f[0], Back: f[1]})
+		assert.Equal(t, f[0], sfld)
+		assert.Equal(t, idFromName("flicker deck Физика"), did)
+		assert.Zero(t, ord)
+		guids[guid] = true
+	}
+	assert.Equal(t, cards, got)
+	assert.Len(t, guids, 2)
+
+	// same card keeps guid in next export, so anki updates it instead of duplicating
+	again, err := Apkg("Физика", cards[:1])
+	require.NoError(t, err)
+	var g1, g2 string
+	require.NoError(t, db.QueryRow(`SELECT guid FROM notes ORDER BY id LIMIT 1`).Scan(&g1))
+	require.NoError(t, readApkg(t, again).QueryRow(`SELECT guid FROM notes`).Scan(&g2))
+	assert.Equal(t, g1, g2)
+}
+
+func TestCardsFromDocument(t *testing.T) {
+	t.Parallel()
+
+	cards := CardsFromDocument(Parse("", "# A\n\nintro\n\n## B\n\n- x\n- **y**\n\n## C\n### D\n\n`code` & <tag>\n"))
+	assert.Equal(t, []Card{
+		{Front: "A", Back: "<p>intro</p>"},
+		{Front: "A › B", Back: "<ul><li>x</li><li><b>y</b></li></ul>"},
+		{Front: "A › C › D", Back: "<p><code>code</code> &amp; &lt;tag&gt;</p>"},
+	}, cards)
+}
+
+func TestCardsFromQuiz(t *testing.T) {
+	t.Parallel()
+
+	q := &views.Quiz{Title: "T", Questions: []views.Question{
+		{Type: views.QuestionSingle, Text: "2+2?", Options: []string{"3", "4"}, Correct: []int{1}, Explanation: "арифметика"},
+		{Type: views.QuestionOpen, Text: "Что такое <сила>?", Answer: "F = ma"},
+	}}
+	assert.Equal(t, []Card{
+		{Front: `2+2?<ol type="A"><li>3</li><li>4</li></ol>`, Back: "<b>B)</b> 4<br><br><i>арифметика</i>"},
+		{Front: "Что такое &lt;сила&gt;?", Back: "F = ma"},
+	}, CardsFromQuiz(q))
+
+	d := FromQuiz(q)
+	assert.Equal(t, "T", d.Title)
+	assert.NotEmpty(t, d.Blocks)
+}
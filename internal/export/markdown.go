@@ -0,0 +1,296 @@
+// Package export render markdown notes, quizzes and flashcards to PDF, DOCX and Anki decks.
+// Everything is rendered in process without external tools
+package export
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type Kind int
+
+const (
+	Heading Kind = iota
+	Paragraph
+	List
+	Table
+	Code
+	Quote
+	Rule
+)
+
+// Block is top level element of markdown document. Text fields keep inline markup, see Spans
+type Block struct {
+	Kind  Kind
+	Level int // heading level 1-6
+	Text  string
+
+	Ordered bool
+	Start   int // number of first item of ordered list
+	Items   []Item
+
+	Rows [][]string // first row is header
+
+	Lang string
+	Code string
+}
+
+type Item struct {
+	Level int // nesting, 0 for top level
+	Text  string
+}
+
+// Document is parsed markdown with title used for file metadata and name
+type Document struct {
+	Title  string
+	Blocks []Block
+}
+
+var (
+	headingRe  = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	fenceRe    = regexp.MustCompile("^\\s*(```+|~~~+)\\s*([\\w+-]*)")
+	ruleRe     = regexp.MustCompile(`^\s*([-*_])(\s*([-*_])){2,}\s*$`)
+	itemRe     = regexp.MustCompile(`^(\s*)([-*+]|(\d{1,9})[.)])\s+(.*)$`)
+	tableSepRe = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+)
+
+// Parse split markdown into blocks. It supports subset of CommonMark and GFM used by
+// our LLM prompts: ATX headings, paragraphs, nested lists, pipe tables, fenced code, quotes and rules
+func Parse(title, md string) *Document {
+	lines := strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n")
+	d := &Document{Title: title}
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			i++
+
+		case fenceRe.MatchString(line):
+			m := fenceRe.FindStringSubmatch(line)
+			fence := m[1]
+			var code []string
+			i++
+			for i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence) {
+				code = append(code, lines[i])
+				i++
+			}
+			i++ // closing fence
+			d.Blocks = append(d.Blocks, Block{Kind: Code, Lang: m[2], Code: strings.Join(code, "\n")})
+
+		case headingRe.MatchString(trimmed):
+			m := headingRe.FindStringSubmatch(trimmed)
+			d.Blocks = append(d.Blocks, Block{Kind: Heading, Level: len(m[1]), Text: m[2]})
+			i++
+
+		case ruleRe.MatchString(line):
+			d.Blocks = append(d.Blocks, Block{Kind: Rule})
+			i++
+
+		case strings.Contains(line, "|") && i+1 < len(lines) && tableSepRe.MatchString(lines[i+1]) && strings.Contains(lines[i+1], "-"):
+			b := Block{Kind: Table, Rows: [][]string{splitRow(line)}}
+			i += 2
+			for i < len(lines) && strings.Contains(lines[i], "|") && strings.TrimSpace(lines[i]) != "" {
+				b.Rows = append(b.Rows, splitRow(lines[i]))
+				i++
+			}
+			d.Blocks = append(d.Blocks, normalizeTable(b))
+
+		case strings.HasPrefix(trimmed, ">"):
+			var quote []string
+			for i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">") {
+				quote = append(quote, strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")))
+				i++
+			}
+			d.Blocks = append(d.Blocks, Block{Kind: Quote, Text: joinLines(quote)})
+
+		case itemRe.MatchString(line):
+			var b Block
+			b, i = parseList(lines, i)
+			d.Blocks = append(d.Blocks, b)
+
+		default:
+			var para []string
+			for i < len(lines) && isParagraphLine(lines[i]) {
+				para = append(para, strings.TrimSpace(lines[i]))
+				i++
+			}
+			d.Blocks = append(d.Blocks, Block{Kind: Paragraph, Text: joinLines(para)})
+		}
+	}
+	return d
+}
+
+func isParagraphLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed != "" &&
+		!headingRe.MatchString(trimmed) &&
+		!fenceRe.MatchString(line) &&
+		!itemRe.MatchString(line) &&
+		!strings.HasPrefix(trimmed, ">") &&
+		!ruleRe.MatchString(line)
+}
+
+// joinLines join soft wrapped lines of one paragraph
+func joinLines(ls []string) string {
+	return strings.Join(ls, " ")
+}
+
+func parseList(lines []string, i int) (Block, int) {
+	first := itemRe.FindStringSubmatch(lines[i])
+	b := Block{Kind: List, Ordered: first[3] != ""}
+	if b.Ordered {
+		b.Start, _ = strconv.Atoi(first[3])
+	}
+
+	var indents []int
+	for i < len(lines) {
+		line := lines[i]
+		m := itemRe.FindStringSubmatch(line)
+		switch {
+		case m != nil:
+			indent := len(strings.ReplaceAll(m[1], "\t", "    "))
+			for len(indents) > 0 && indent < indents[len(indents)-1] {
+				indents = indents[:len(indents)-1]
+			}
+			if len(indents) == 0 || indent > indents[len(indents)-1] {
+				indents = append(indents, indent)
+			}
+			b.Items = append(b.Items, Item{Level: len(indents) - 1, Text: strings.TrimSpace(m[4])})
+			i++
+		case strings.TrimSpace(line) != "" && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(b.Items) > 0:
+			// continuation of previous item
+			last := &b.Items[len(b.Items)-1]
+			last.Text += " " + strings.TrimSpace(line)
+			i++
+		case strings.TrimSpace(line) == "" && i+1 < len(lines) && itemRe.MatchString(lines[i+1]):
+			// loose list
+			i++
+		default:
+			return b, i
+		}
+	}
+	return b, i
+}
+
+func splitRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+
+	var (
+		cells []string
+		sb    strings.Builder
+	)
+	for j := 0; j < len(line); j++ {
+		switch {
+		case line[j] == '\\' && j+1 < len(line) && line[j+1] == '|':
+			sb.WriteByte('|')
+			j++
+		case line[j] == '|':
+			cells = append(cells, strings.TrimSpace(sb.String()))
+			sb.Reset()
+		default:
+			sb.WriteByte(line[j])
+		}
+	}
+	return append(cells, strings.TrimSpace(sb.String()))
+}
+
+// normalizeTable make all rows as wide as header
+func normalizeTable(b Block) Block {
+	n := len(b.Rows[0])
+	for i, r := range b.Rows {
+		switch {
+		case len(r) > n:
+			b.Rows[i] = r[:n]
+		case len(r) < n:
+			b.Rows[i] = append(r, make([]string, n-len(r))...)
+		}
+	}
+	return b
+}
+
+// Span is piece of inline text with single style
+type Span struct {
+	Text   string
+	Bold   bool
+	Italic bool
+	Code   bool
+}
+
+var linkRe = regexp.MustCompile(`!?\[([^\]]*)\]\(([^)\s]*)(?:\s+"[^"]*")?\)`)
+
+// Spans split inline markdown into styled spans. Links are replaced by their text,
+// backslash escapes are resolved
+func Spans(text string) []Span {
+	text = linkRe.ReplaceAllString(text, "$1")
+
+	var (
+		res          []Span
+		sb           strings.Builder
+		bold, italic bool
+	)
+	flush := func() {
+		if sb.Len() > 0 {
+			res = append(res, Span{Text: sb.String(), Bold: bold, Italic: italic})
+			sb.Reset()
+		}
+	}
+
+	for i := 0; i < len(text); i++ {
+		ch := text[i]
+		switch {
+		case ch == '\\' && i+1 < len(text) && strings.IndexByte("\\`*_{}[]()#+-.!|>~", text[i+1]) >= 0:
+			sb.WriteByte(text[i+1])
+			i++
+		case ch == '`':
+			end := strings.IndexByte(text[i+1:], '`')
+			if end < 0 {
+				sb.WriteByte(ch)
+				continue
+			}
+			flush()
+			res = append(res, Span{Text: text[i+1 : i+1+end], Code: true})
+			i += end + 1
+		case (ch == '*' || ch == '_') && i+1 < len(text) && text[i+1] == ch:
+			if !bold && !strings.Contains(text[i+2:], string([]byte{ch, ch})) {
+				sb.WriteString(text[i : i+2])
+				i++
+				continue
+			}
+			flush()
+			bold = !bold
+			i++
+		case ch == '*' || (ch == '_' && (i == 0 || !isWordByte(text[i-1]) || italic)):
+			if !italic && !strings.ContainsRune(text[i+1:], rune(ch)) {
+				sb.WriteByte(ch)
+				continue
+			}
+			flush()
+			italic = !italic
+		default:
+			sb.WriteByte(ch)
+		}
+	}
+	flush()
+	return res
+}
+
+func isWordByte(b byte) bool {
+	return b >= 0x80 || b == '_' || (b >= '0' && b <= '9') || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+// PlainText return inline markdown without markup
+func PlainText(text string) string {
+	var sb strings.Builder
+	for _, s := range Spans(text) {
+		sb.WriteString(s.Text)
+	}
+	return sb.String()
+}
@@ -0,0 +1,240 @@
+package export
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/autumnterror/breezynotes/pkg/utils/format"
+	"github.com/go-pdf/fpdf"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gobolditalic"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// Go fonts cover latin and cyrillic, so they are embedded instead of standard PDF fonts
+const (
+	fontText = "go"
+	fontMono = "gomono"
+)
+
+const (
+	pageMargin = 20.0 // mm
+	textSize   = 11.0
+	codeSize   = 9.5
+	lineHeight = 5.5
+	listIndent = 6.0
+	cellPad    = 1.5
+)
+
+var headingSizes = [...]float64{20, 16, 14, 12.5, 11.5, 11}
+
+// compress page streams, tests turn it off to inspect drawing operators
+var compress = true
+
+// PDF render document to A4 pages. Headings also become PDF outline (bookmarks)
+func PDF(d *Document) ([]byte, error) {
+	const op = "export.PDF"
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetCompression(compress)
+	pdf.SetMargins(pageMargin, pageMargin, pageMargin)
+	pdf.SetAutoPageBreak(true, pageMargin)
+	pdf.SetTitle(d.Title, true)
+	pdf.SetCreator("flicker", true)
+	pdf.SetCreationDate(time.Unix(0, 0).UTC())
+	pdf.SetModificationDate(time.Unix(0, 0).UTC())
+
+	pdf.AddUTF8FontFromBytes(fontText, "", goregular.TTF)
+	pdf.AddUTF8FontFromBytes(fontText, "B", gobold.TTF)
+	pdf.AddUTF8FontFromBytes(fontText, "I", goitalic.TTF)
+	pdf.AddUTF8FontFromBytes(fontText, "BI", gobolditalic.TTF)
+	pdf.AddUTF8FontFromBytes(fontMono, "", gomono.TTF)
+
+	pdf.AddPage()
+	r := &pdfRenderer{pdf: pdf}
+	for i, b := range d.Blocks {
+		if i > 0 {
+			pdf.Ln(lineHeight / 2)
+		}
+		r.block(b)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, format.Error(op, err)
+	}
+	return buf.Bytes(), nil
+}
+
+type pdfRenderer struct {
+	pdf *fpdf.Fpdf
+}
+
+func (r *pdfRenderer) block(b Block) {
+	pdf := r.pdf
+	switch b.Kind {
+	case Heading:
+		size := headingSizes[min(max(b.Level, 1), len(headingSizes))-1]
+		pdf.Ln(size / 4)
+		// font goes first: fpdf encodes bookmark as UTF-16 only when current font is UTF-8
+		pdf.SetFont(fontText, "B", size)
+		pdf.Bookmark(PlainText(b.Text), b.Level-1, -1)
+		pdf.MultiCell(0, size*0.5, PlainText(b.Text), "", "L", false)
+
+	case Paragraph:
+		r.inline(b.Text, textSize, "")
+		pdf.Ln(lineHeight)
+
+	case Quote:
+		left, _, _, _ := pdf.GetMargins()
+		y := pdf.GetY()
+		pdf.SetLeftMargin(left + listIndent)
+		pdf.SetX(left + listIndent)
+		r.inline(b.Text, textSize, "I")
+		pdf.Ln(lineHeight)
+		pdf.SetLeftMargin(left)
+		pdf.SetDrawColor(180, 180, 180)
+		pdf.SetLineWidth(0.8)
+		pdf.Line(left+1, y, left+1, pdf.GetY())
+
+	case List:
+		left, _, _, _ := pdf.GetMargins()
+		n := b.Start
+		for _, it := range b.Items {
+			indent := left + float64(it.Level)*listIndent
+			marker := "•"
+			if b.Ordered && it.Level == 0 {
+				marker = strconv.Itoa(n) + "."
+				n++
+			} else if it.Level > 0 {
+				marker = "◦"
+			}
+			pdf.SetFont(fontText, "", textSize)
+			pdf.SetX(indent)
+			pdf.CellFormat(listIndent, lineHeight, marker, "", 0, "L", false, 0, "")
+			pdf.SetLeftMargin(indent + listIndent)
+			r.inline(it.Text, textSize, "")
+			pdf.Ln(lineHeight)
+			pdf.SetLeftMargin(left)
+		}
+
+	case Code:
+		pdf.SetFont(fontMono, "", codeSize)
+		pdf.SetFillColor(242, 242, 242)
+		pdf.MultiCell(0, codeSize*0.45, strings.ReplaceAll(b.Code, "\t", "    "), "", "L", true)
+
+	case Table:
+		r.table(b.Rows)
+
+	case Rule:
+		left, _, right, _ := pdf.GetMargins()
+		w, _ := pdf.GetPageSize()
+		y := pdf.GetY() + lineHeight/2
+		pdf.SetDrawColor(180, 180, 180)
+		pdf.SetLineWidth(0.3)
+		pdf.Line(left, y, w-right, y)
+		pdf.Ln(lineHeight)
+	}
+}
+
+// inline write text with bold, italic and code spans, wrapping at right margin
+func (r *pdfRenderer) inline(text string, size float64, style string) {
+	pdf := r.pdf
+	for _, s := range Spans(text) {
+		if s.Code {
+			pdf.SetFont(fontMono, "", size*0.9)
+		} else {
+			st := style
+			if s.Bold && !strings.Contains(st, "B") {
+				st = "B" + st
+			}
+			if s.Italic && !strings.Contains(st, "I") {
+				st += "I"
+			}
+			pdf.SetFont(fontText, st, size)
+		}
+		pdf.Write(lineHeight, s.Text)
+	}
+}
+
+func (r *pdfRenderer) table(rows [][]string) {
+	pdf := r.pdf
+	if len(rows) == 0 || len(rows[0]) == 0 {
+		return
+	}
+
+	left, _, right, bottom := pdf.GetMargins()
+	pageW, pageH := pdf.GetPageSize()
+	widths := r.columnWidths(rows, pageW-left-right)
+
+	pdf.SetDrawColor(160, 160, 160)
+	pdf.SetLineWidth(0.2)
+	pdf.SetFillColor(235, 235, 235)
+
+	for i, row := range rows {
+		style := ""
+		if i == 0 {
+			style = "B"
+		}
+		pdf.SetFont(fontText, style, textSize-1)
+
+		cells := make([][]string, len(row))
+		height := 0.0
+		for j, cell := range row {
+			cells[j] = pdf.SplitText(PlainText(cell), widths[j]-2*cellPad)
+			height = max(height, float64(max(len(cells[j]), 1))*lineHeight+2*cellPad)
+		}
+
+		if pdf.GetY()+height > pageH-bottom {
+			pdf.AddPage()
+		}
+
+		rect := "D"
+		if i == 0 {
+			rect = "FD"
+		}
+		x, y := left, pdf.GetY()
+		for j := range row {
+			pdf.Rect(x, y, widths[j], height, rect)
+			for k, line := range cells[j] {
+				pdf.SetXY(x+cellPad, y+cellPad+float64(k)*lineHeight)
+				pdf.CellFormat(widths[j]-2*cellPad, lineHeight, line, "", 0, "L", false, 0, "")
+			}
+			x += widths[j]
+		}
+		pdf.SetXY(left, y+height)
+	}
+}
+
+// columnWidths split available width between columns in proportion to their longest line,
+// but not less than minimum so narrow columns stay readable
+func (r *pdfRenderer) columnWidths(rows [][]string, total float64) []float64 {
+	n := len(rows[0])
+	minW := total / float64(n) / 2
+
+	want := make([]float64, n)
+	for i, row := range rows {
+		style := ""
+		if i == 0 {
+			style = "B"
+		}
+		r.pdf.SetFont(fontText, style, textSize-1)
+		for j, cell := range row {
+			want[j] = max(want[j], r.pdf.GetStringWidth(PlainText(cell))+2*cellPad, minW)
+		}
+	}
+
+	var sum float64
+	for _, w := range want {
+		sum += w
+	}
+	widths := make([]float64, n)
+	for j, w := range want {
+		widths[j] = w / sum * total
+	}
+	return widths
+}
@@ -0,0 +1,223 @@
+// Package filecheck validate uploaded files by content instead of trusting name and Content-Type:
+// type is sniffed from magic bytes, archives and executables are rejected, every endpoint
+// allows its own kinds of files and filename is made safe for storage and headers
+package filecheck
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"unicode/utf8"
+)
+
+type Kind string
+
+const (
+	Audio      Kind = "audio"
+	Video      Kind = "video"
+	Document   Kind = "document"
+	Text       Kind = "text"
+	Image      Kind = "image"
+	Archive    Kind = "archive"
+	Executable Kind = "executable"
+	Unknown    Kind = "unknown"
+)
+
+// Type is detected content type of file
+type Type struct {
+	MIME string
+	Kind Kind
+	// Extensions which file of this type may have, empty if extension is not checked
+	Extensions []string
+}
+
+// SniffLen is how many first bytes are enough for Detect
+const SniffLen = 1024
+
+var (
+	typePDF     = Type{MIME: "application/pdf", Kind: Document, Extensions: []string{".pdf"}}
+	typeDOCX    = Type{MIME: "application/vnd.openxmlformats-officedocument.wordprocessingml.document", Kind: Document, Extensions: []string{".docx"}}
+	typeText    = Type{MIME: "text/plain; charset=utf-8", Kind: Text, Extensions: []string{".txt", ".text", ".md", ".markdown"}}
+	typeZip     = Type{MIME: "application/zip", Kind: Archive}
+	typeUnknown = Type{MIME: "application/octet-stream", Kind: Unknown}
+)
+
+type magic struct {
+	offset int
+	prefix string
+	typ    Type
+}
+
+// signatures are checked in order, first match wins
+var signatures = []magic{
+	// executables
+	{0, "\x7fELF", Type{MIME: "application/x-elf", Kind: Executable}},
+	{0, "\xfe\xed\xfa\xce", Type{MIME: "application/x-mach-binary", Kind: Executable}},
+	{0, "\xfe\xed\xfa\xcf", Type{MIME: "application/x-mach-binary", Kind: Executable}},
+	{0, "\xce\xfa\xed\xfe", Type{MIME: "application/x-mach-binary", Kind: Executable}},
+	{0, "\xcf\xfa\xed\xfe", Type{MIME: "application/x-mach-binary", Kind: Executable}},
+	// universal Mach-O binary and java class
+	{0, "\xca\xfe\xba\xbe", Type{MIME: "application/x-mach-binary", Kind: Executable}},
+	{0, "\x00asm", Type{MIME: "application/wasm", Kind: Executable}},
+	// AMR audio also starts with #!
+	{0, "#!AMR", Type{MIME: "audio/amr", Kind: Audio}},
+	{0, "#!", Type{MIME: "text/x-shellscript", Kind: Executable}},
+	// compound file: .msi installers as well as old office documents
+	{0, "\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1", Type{MIME: "application/x-ole-storage", Kind: Executable}},
+
+	// archives, zip is checked separately because DOCX is zip too
+	{0, "\x1f\x8b", Type{MIME: "application/gzip", Kind: Archive}},
+	{0, "BZh", Type{MIME: "application/x-bzip2", Kind: Archive}},
+	{0, "\xfd7zXZ\x00", Type{MIME: "application/x-xz", Kind: Archive}},
+	{0, "7z\xbc\xaf\x27\x1c", Type{MIME: "application/x-7z-compressed", Kind: Archive}},
+	{0, "Rar!\x1a\x07", Type{MIME: "application/vnd.rar", Kind: Archive}},
+	{0, "\x28\xb5\x2f\xfd", Type{MIME: "application/zstd", Kind: Archive}},
+	{0, "MSCF", Type{MIME: "application/vnd.ms-cab-compressed", Kind: Archive}},
+	{0, "LZIP", Type{MIME: "application/x-lzip", Kind: Archive}},
+	{0, "!<arch>\n", Type{MIME: "application/x-archive", Kind: Archive}},
+	{257, "ustar", Type{MIME: "application/x-tar", Kind: Archive}},
+
+	// documents
+	{0, "%PDF-", typePDF},
+
+	// audio
+	{0, "ID3", Type{MIME: "audio/mpeg", Kind: Audio}},
+	{0, "fLaC", Type{MIME: "audio/flac", Kind: Audio}},
+	{0, "OggS", Type{MIME: "audio/ogg", Kind: Audio}},
+	{0, ".snd", Type{MIME: "audio/basic", Kind: Audio}},
+
+	// video
+	{0, "\x30\x26\xb2\x75\x8e\x66\xcf\x11", Type{MIME: "video/x-ms-asf", Kind: Video}},
+	{0, "\x00\x00\x01\xba", Type{MIME: "video/mpeg", Kind: Video}},
+	{0, "FLV\x01", Type{MIME: "video/x-flv", Kind: Video}},
+
+	// images are never allowed, but named in errors
+	{0, "\x89PNG\r\n\x1a\n", Type{MIME: "image/png", Kind: Image}},
+	{0, "\xff\xd8\xff", Type{MIME: "image/jpeg", Kind: Image}},
+	{0, "GIF8", Type{MIME: "image/gif", Kind: Image}},
+}
+
+// Detect return type of file by its first bytes, SniffLen of them are enough
+func Detect(head []byte) Type {
+	for _, m := range signatures {
+		if len(head) >= m.offset+len(m.prefix) && string(head[m.offset:m.offset+len(m.prefix)]) == m.prefix {
+			return m.typ
+		}
+	}
+
+	switch {
+	case isZip(head):
+		return typeZip
+	case isPE(head):
+		return Type{MIME: "application/vnd.microsoft.portable-executable", Kind: Executable}
+	case len(head) >= 12 && string(head[4:8]) == "ftyp":
+		return isoMedia(string(head[8:12]))
+	case len(head) >= 12 && string(head[:4]) == "RIFF":
+		return riff(string(head[8:12]))
+	case len(head) >= 12 && string(head[:4]) == "FORM" && (string(head[8:12]) == "AIFF" || string(head[8:12]) == "AIFC"):
+		return Type{MIME: "audio/aiff", Kind: Audio}
+	case bytes.HasPrefix(head, []byte("\x1a\x45\xdf\xa3")):
+		if bytes.Contains(head, []byte("webm")) {
+			return Type{MIME: "video/webm", Kind: Video}
+		}
+		return Type{MIME: "video/x-matroska", Kind: Video}
+	case len(head) >= 2 && head[0] == 0xff && head[1]&0xe0 == 0xe0:
+		// MPEG audio frame: layer bits 00 are reserved in MP3 and used by AAC ADTS
+		if head[1]&0x06 == 0 {
+			return Type{MIME: "audio/aac", Kind: Audio}
+		}
+		return Type{MIME: "audio/mpeg", Kind: Audio}
+	case isText(head):
+		return typeText
+	case bytes.Contains(head[:min(len(head), SniffLen)], []byte("%PDF-")):
+		// PDF may have garbage before header
+		return typePDF
+	}
+	return typeUnknown
+}
+
+func isZip(head []byte) bool {
+	return bytes.HasPrefix(head, []byte("PK\x03\x04")) || bytes.HasPrefix(head, []byte("PK\x05\x06"))
+}
+
+// isPE check DOS header and PE signature it points to. Plain MZ with binary header is DOS program
+func isPE(head []byte) bool {
+	if len(head) < 64 || string(head[:2]) != "MZ" {
+		return false
+	}
+	off := int(binary.LittleEndian.Uint32(head[0x3c:]))
+	if off+4 <= len(head) && string(head[off:off+4]) == "PE\x00\x00" {
+		return true
+	}
+	return bytes.IndexByte(head[:64], 0) >= 0
+}
+
+func isoMedia(brand string) Type {
+	switch {
+	case brand == "M4A " || brand == "M4B " || brand == "M4P " || brand == "F4A ":
+		return Type{MIME: "audio/mp4", Kind: Audio}
+	case brand == "qt  ":
+		return Type{MIME: "video/quicktime", Kind: Video}
+	case brand[:3] == "3gp" || brand[:3] == "3g2":
+		return Type{MIME: "video/3gpp", Kind: Video}
+	case brand == "heic" || brand == "heix" || brand == "mif1" || brand == "msf1" || brand == "avif":
+		return Type{MIME: "image/heif", Kind: Image}
+	}
+	return Type{MIME: "video/mp4", Kind: Video}
+}
+
+func riff(form string) Type {
+	switch form {
+	case "WAVE":
+		return Type{MIME: "audio/wav", Kind: Audio}
+	case "AVI ":
+		return Type{MIME: "video/x-msvideo", Kind: Video}
+	case "WEBP":
+		return Type{MIME: "image/webp", Kind: Image}
+	}
+	return typeUnknown
+}
+
+// isText report UTF-8 text without control characters except whitespace. Last rune may be cut
+func isText(head []byte) bool {
+	if len(head) == 0 {
+		return false
+	}
+	head = bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))
+	for i := 0; i < len(head); {
+		r, n := utf8.DecodeRune(head[i:])
+		if r == utf8.RuneError && n <= 1 {
+			return len(head)-i < utf8.UTFMax && !utf8.FullRune(head[i:])
+		}
+		if (r < 0x20 && r != '\n' && r != '\r' && r != '\t' && r != '\f') || r == 0x7f {
+			return false
+		}
+		i += n
+	}
+	return true
+}
+
+// DetectFile sniff file and look into zip: DOCX is document, other zip files are archives
+func DetectFile(f io.ReaderAt, size int64) (Type, error) {
+	head := make([]byte, min(size, SniffLen))
+	if _, err := f.ReadAt(head, 0); err != nil && err != io.EOF {
+		return typeUnknown, err
+	}
+
+	t := Detect(head)
+	if t.Kind != Archive || t.MIME != typeZip.MIME {
+		return t, nil
+	}
+	z, err := zip.NewReader(f, size)
+	if err != nil {
+		// broken zip is still archive
+		return t, nil
+	}
+	for _, zf := range z.File {
+		if zf.Name == "word/document.xml" {
+			return typeDOCX, nil
+		}
+	}
+	return t, nil
+}
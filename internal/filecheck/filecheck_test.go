@@ -0,0 +1,153 @@
+package filecheck
+
+import (
+	"archive/zip"
+	"bytes"
+	"flicker/internal/export"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func zipOf(t *testing.T, names ...string) []byte {
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+	for _, n := range names {
+		w, err := z.Create(n)
+		require.NoError(t, err)
+		_, _ = w.Write([]byte("x"))
+	}
+	require.NoError(t, z.Close())
+	return buf.Bytes()
+}
+
+func pe() []byte {
+	b := make([]byte, 256)
+	copy(b, "MZ")
+	b[0x3c] = 0x80
+	copy(b[0x80:], "PE\x00\x00")
+	return b
+}
+
+func TestDetect(t *testing.T) {
+	t.Parallel()
+
+	tar := make([]byte, 512)
+	copy(tar[257:], "ustar")
+
+	for name, c := range map[string]struct {
+		head []byte
+		mime string
+		kind Kind
+	}{
+		"mp3 id3":    {[]byte("ID3\x04\x00\x00\x00\x00"), "audio/mpeg", Audio},
+		"mp3 frame":  {[]byte{0xff, 0xfb, 0x90, 0x64}, "audio/mpeg", Audio},
+		"aac adts":   {[]byte{0xff, 0xf1, 0x50, 0x80}, "audio/aac", Audio},
+		"wav":        {[]byte("RIFF\x24\x00\x00\x00WAVEfmt "), "audio/wav", Audio},
+		"flac":       {[]byte("fLaC\x00\x00\x00\x22"), "audio/flac", Audio},
+		"ogg":        {[]byte("OggS\x00\x02"), "audio/ogg", Audio},
+		"m4a":        {[]byte("\x00\x00\x00\x20ftypM4A \x00\x00\x00\x00"), "audio/mp4", Audio},
+		"amr":        {[]byte("#!AMR\n"), "audio/amr", Audio},
+		"mp4":        {[]byte("\x00\x00\x00\x18ftypisom\x00\x00\x02\x00"), "video/mp4", Video},
+		"mov":        {[]byte("\x00\x00\x00\x14ftypqt  \x00\x00\x00\x00"), "video/quicktime", Video},
+		"webm":       {[]byte("\x1a\x45\xdf\xa3\x9f\x42\x86\x81\x01\x42\x82\x84webm"), "video/webm", Video},
+		"mkv":        {[]byte("\x1a\x45\xdf\xa3\x9f\x42\x86\x81\x01\x42\x82\x88matroska"), "video/x-matroska", Video},
+		"avi":        {[]byte("RIFF\x00\x00\x00\x00AVI LIST"), "video/x-msvideo", Video},
+		"pdf":        {[]byte("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n"), "application/pdf", Document},
+		"pdf junk":   {[]byte("\x00\x01garbage%PDF-1.4\n"), "application/pdf", Document},
+		"text":       {[]byte("# Лекция\n\nтекст\tс табом\r\n"), "text/plain; charset=utf-8", Text},
+		"text cut":   {[]byte("текст")[:9], "text/plain; charset=utf-8", Text},
+		"elf":        {[]byte("\x7fELF\x02\x01\x01"), "application/x-elf", Executable},
+		"pe":         {pe(), "application/vnd.microsoft.portable-executable", Executable},
+		"script":     {[]byte("#!/bin/sh\nrm -rf /\n"), "text/x-shellscript", Executable},
+		"mach-o":     {[]byte("\xcf\xfa\xed\xfe\x07\x00\x00\x01"), "application/x-mach-binary", Executable},
+		"msi":        {[]byte("\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1\x00"), "application/x-ole-storage", Executable},
+		"gzip":       {[]byte("\x1f\x8b\x08\x00"), "application/gzip", Archive},
+		"7z":         {[]byte("7z\xbc\xaf\x27\x1c\x00\x04"), "application/x-7z-compressed", Archive},
+		"rar":        {[]byte("Rar!\x1a\x07\x01\x00"), "application/vnd.rar", Archive},
+		"tar":        {tar, "application/x-tar", Archive},
+		"zip":        {[]byte("PK\x03\x04\x14\x00"), "application/zip", Archive},
+		"png":        {[]byte("\x89PNG\r\n\x1a\n\x00"), "image/png", Image},
+		"binary":     {[]byte{0x00, 0x01, 0x02, 0x03}, "application/octet-stream", Unknown},
+		"empty":      {nil, "application/octet-stream", Unknown},
+		"mz in text": {[]byte("MZ is not always a program, this is plain text of more than sixty four bytes long"), "text/plain; charset=utf-8", Text},
+	} {
+		got := Detect(c.head)
+		assert.Equal(t, c.mime, got.MIME, name)
+		assert.Equal(t, c.kind, got.Kind, name)
+	}
+}
+
+func TestDetectFile(t *testing.T) {
+	t.Parallel()
+
+	docx, err := export.DOCX(export.Parse("t", "# Заголовок\n\nтекст"))
+	require.NoError(t, err)
+	got, err := DetectFile(bytes.NewReader(docx), int64(len(docx)))
+	require.NoError(t, err)
+	assert.Equal(t, Document, got.Kind)
+	assert.Equal(t, []string{".docx"}, got.Extensions)
+
+	z := zipOf(t, "a.txt", "b/c.exe")
+	got, err = DetectFile(bytes.NewReader(z), int64(len(z)))
+	require.NoError(t, err)
+	assert.Equal(t, Archive, got.Kind)
+
+	got, err = DetectFile(bytes.NewReader([]byte("hi")), 2)
+	require.NoError(t, err)
+	assert.Equal(t, Text, got.Kind)
+}
+
+func TestPolicy(t *testing.T) {
+	t.Parallel()
+
+	mp3 := []byte("ID3\x04\x00\x00\x00\x00\x00\x00")
+	pdf := []byte("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	check := func(p Policy, name string, b []byte) error {
+		_, err := p.Check(name, bytes.NewReader(b), int64(len(b)))
+		return err
+	}
+
+	assert.NoError(t, check(Media, "lecture.mp3", mp3))
+	assert.NoError(t, check(Media, "lecture.bin", mp3), "extension of media is not checked")
+	assert.ErrorIs(t, check(Media, "a.pdf", pdf), ErrNotAllowed)
+	assert.ErrorIs(t, check(Media, "a.mp3", []byte("\x7fELF\x02\x01\x01")), ErrForbidden)
+	assert.ErrorIs(t, check(Media, "a.mp3", nil), ErrEmpty)
+
+	assert.NoError(t, check(Documents, "a.pdf", pdf))
+	assert.NoError(t, check(Documents, "notes.MD", []byte("# notes")))
+	assert.ErrorIs(t, check(Documents, "a.txt", pdf), ErrMismatch)
+	assert.ErrorIs(t, check(Documents, "a.pdf", []byte("plain")), ErrMismatch)
+	assert.ErrorIs(t, check(Documents, "a.mp3", mp3), ErrNotAllowed)
+	assert.ErrorIs(t, check(Documents, "a.docx", zipOf(t, "word/x.xml")), ErrForbidden)
+
+	small := Policy{Kinds: []Kind{Text}, MaxSize: 3}
+	assert.ErrorIs(t, check(small, "a.txt", []byte("abcd")), ErrTooLarge)
+	assert.Equal(t, "audio, video", Media.Allowed())
+}
+
+func TestSanitize(t *testing.T) {
+	t.Parallel()
+
+	for in, want := range map[string]string{
+		"lecture.mp3":              "lecture.mp3",
+		"../../etc/passwd":         "passwd",
+		`C:\Users\me\Лекция 1.pdf`: "Лекция 1.pdf",
+		"a<b>c:d\"e|f?g*h.txt":     "a_b_c_d_e_f_g_h.txt",
+		"new\nline\x00.txt":        "new line_.txt",
+		"  many    spaces\t.md  ":  "many spaces .md",
+		".hidden":                  "hidden",
+		"..":                       "file",
+		"":                         "file",
+		"bad\xffutf8.txt":          "badutf8.txt",
+		"rtl\u202etxt.exe":         "rtl_txt.exe",
+	} {
+		assert.Equal(t, want, Sanitize(in), in)
+	}
+
+	long := Sanitize(strings.Repeat("я", 300) + ".docx")
+	assert.LessOrEqual(t, len(long), maxNameBytes)
+	assert.True(t, strings.HasSuffix(long, ".docx"))
+}
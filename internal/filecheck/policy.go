@@ -0,0 +1,71 @@
+package filecheck
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrTooLarge   = errors.New("file too large")
+	ErrForbidden  = errors.New("archives and executables are not accepted")
+	ErrNotAllowed = errors.New("file type not allowed")
+	ErrMismatch   = errors.New("file content doesn't match extension")
+	ErrEmpty      = errors.New("file is empty")
+)
+
+// Policy is what endpoint accepts
+type Policy struct {
+	Kinds []Kind
+	// MaxSize in bytes, 0 is no limit beyond request body limit
+	MaxSize int64
+	// MatchExtension require extension to be one of detected type, for consumers which pick parser by it
+	MatchExtension bool
+}
+
+var (
+	// Media is audio and video for transcription
+	Media = Policy{Kinds: []Kind{Audio, Video}}
+	// Documents are files which text is extracted from, whole file is read into memory
+	Documents = Policy{Kinds: []Kind{Document, Text}, MaxSize: 100 << 20, MatchExtension: true}
+)
+
+// Check validate file of size bytes named name and return its type
+func (p Policy) Check(name string, f io.ReaderAt, size int64) (Type, error) {
+	if size == 0 {
+		return typeUnknown, ErrEmpty
+	}
+	if p.MaxSize > 0 && size > p.MaxSize {
+		return typeUnknown, fmt.Errorf("%w: %d bytes, max %d", ErrTooLarge, size, p.MaxSize)
+	}
+
+	t, err := DetectFile(f, size)
+	if err != nil {
+		return t, err
+	}
+	if t.Kind == Archive || t.Kind == Executable {
+		return t, fmt.Errorf("%w: %s", ErrForbidden, t.MIME)
+	}
+	if !slices.Contains(p.Kinds, t.Kind) {
+		return t, fmt.Errorf("%w: %s", ErrNotAllowed, t.MIME)
+	}
+	if p.MatchExtension && len(t.Extensions) > 0 {
+		ext := strings.ToLower(filepath.Ext(name))
+		if !slices.Contains(t.Extensions, ext) {
+			return t, fmt.Errorf("%w: %q is %s", ErrMismatch, ext, t.MIME)
+		}
+	}
+	return t, nil
+}
+
+// Allowed list kinds of policy for error messages
+func (p Policy) Allowed() string {
+	kinds := make([]string, len(p.Kinds))
+	for i, k := range p.Kinds {
+		kinds[i] = string(k)
+	}
+	return strings.Join(kinds, ", ")
+}
@@ -0,0 +1,54 @@
+package filecheck
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const maxNameBytes = 200
+
+// Sanitize return base name of uploaded file safe for disk, logs and Content-Disposition:
+// directories of both separators are dropped, control and reserved characters replaced,
+// leading dots removed and length limited with extension kept. Empty result becomes "file"
+func Sanitize(name string) string {
+	name = strings.ToValidUTF8(name, "")
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+
+	var sb strings.Builder
+	space := false
+	for _, r := range name {
+		switch {
+		case unicode.IsSpace(r):
+			// repeated and unusual spaces, tabs and newlines become one plain space
+			if space {
+				continue
+			}
+			r = ' '
+		case unicode.IsControl(r) || strings.ContainsRune(`<>:"|?*`, r) || unicode.Is(unicode.Cf, r):
+			r = '_'
+		}
+		space = r == ' '
+		sb.WriteRune(r)
+	}
+
+	name = strings.Trim(sb.String(), " .")
+	if len(name) > maxNameBytes {
+		ext := filepath.Ext(name)
+		if len(ext) > 16 || !utf8.ValidString(ext) {
+			ext = ""
+		}
+		base := name[:maxNameBytes-len(ext)]
+		for !utf8.ValidString(base) {
+			base = base[:len(base)-1]
+		}
+		name = strings.TrimRight(base, " .") + ext
+	}
+	if name == "" {
+		return "file"
+	}
+	return name
+}
@@ -0,0 +1,91 @@
+package ingest
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Chunk is piece of document text. Start and End are rune offsets in extracted text
+type Chunk struct {
+	Index int
+	Start int
+	End   int
+	Text  string
+}
+
+// Split cut text into chunks of at most size runes, neighbours share about overlap runes.
+// Chunk is ended at paragraph break, sentence end or space found in its last third,
+// so words are not cut unless there is no space at all
+func Split(text string, size, overlap int) []Chunk {
+	size = max(size, 1)
+	overlap = min(max(overlap, 0), size/2)
+
+	r := []rune(text)
+	var chunks []Chunk
+	for start := skipSpace(r, 0); start < len(r); {
+		end := min(start+size, len(r))
+		if end < len(r) {
+			end = boundary(r, start+size-size/3, end)
+		}
+
+		s, e := trim(r, start, end)
+		if s < e {
+			chunks = append(chunks, Chunk{Index: len(chunks), Start: s, End: e, Text: string(r[s:e])})
+		}
+		if end >= len(r) {
+			break
+		}
+
+		next := end - overlap
+		if overlap > 0 && next > 0 {
+			// start overlap from word beginning, if there is one
+			j := next
+			for j < end && !unicode.IsSpace(r[j-1]) {
+				j++
+			}
+			if j < end {
+				next = j
+			}
+		}
+		start = skipSpace(r, max(next, start+1))
+	}
+	return chunks
+}
+
+// boundary find best place to end chunk in r[from:to]: after paragraph, sentence or word
+func boundary(r []rune, from, to int) int {
+	from = max(from, 1)
+	best := [3]int{} // paragraph, sentence, space
+	for i := to; i >= from; i-- {
+		prev := r[i-1]
+		switch {
+		case best[0] == 0 && prev == '\n' && i >= 2 && r[i-2] == '\n':
+			best[0] = i
+		case best[1] == 0 && (prev == '\n' || (i < len(r) && unicode.IsSpace(r[i]) && strings.ContainsRune(".!?…;", prev))):
+			best[1] = i
+		case best[2] == 0 && unicode.IsSpace(prev):
+			best[2] = i
+		}
+	}
+	for _, b := range best {
+		if b > 0 {
+			return b
+		}
+	}
+	return to
+}
+
+func skipSpace(r []rune, i int) int {
+	for i < len(r) && unicode.IsSpace(r[i]) {
+		i++
+	}
+	return i
+}
+
+func trim(r []rune, s, e int) (int, int) {
+	s = skipSpace(r, s)
+	for e > s && unicode.IsSpace(r[e-1]) {
+		e--
+	}
+	return s, e
+}
@@ -0,0 +1,170 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flicker/internal/upstream"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+var ErrEmbedding = errors.New("embedding error")
+
+// Embedder turn texts into vectors of same dimension, similar texts get close vectors
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	Name() string
+}
+
+const (
+	EmbedderHash   = "hash"
+	EmbedderOpenAI = "openai"
+)
+
+// HashEmbedder is offline embedder without model: words and their stems are hashed into
+// buckets of vector (feature hashing). It finds lexical matches only, but needs no network,
+// so it is used in tests and as fallback when no embedding service is configured
+type HashEmbedder struct {
+	Dim int
+}
+
+func NewHashEmbedder(dim int) *HashEmbedder {
+	return &HashEmbedder{Dim: max(dim, 16)}
+}
+
+func (h *HashEmbedder) Name() string {
+	return fmt.Sprintf("%s-%d", EmbedderHash, h.Dim)
+}
+
+// stemLen is length of word prefix used as crude stem, enough for russian endings
+const stemLen = 5
+
+func (h *HashEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	res := make([][]float32, len(texts))
+	for i, t := range texts {
+		v := make([]float32, h.Dim)
+		for _, w := range strings.FieldsFunc(strings.ToLower(t), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			h.add(v, "w:"+w, 1)
+			if r := []rune(w); len(r) > stemLen {
+				h.add(v, "s:"+string(r[:stemLen]), 1)
+			}
+		}
+		res[i] = normalizeVector(v)
+	}
+	return res, nil
+}
+
+func (h *HashEmbedder) add(v []float32, feature string, weight float32) {
+	f := fnv.New64a()
+	f.Write([]byte(feature))
+	sum := f.Sum64()
+	// sign bit reduces bias of collisions
+	if sum>>63 == 1 {
+		weight = -weight
+	}
+	v[sum%uint64(len(v))] += weight
+}
+
+func normalizeVector(v []float32) []float32 {
+	var n float64
+	for _, x := range v {
+		n += float64(x) * float64(x)
+	}
+	if n == 0 {
+		return v
+	}
+	n = math.Sqrt(n)
+	for i := range v {
+		v[i] = float32(float64(v[i]) / n)
+	}
+	return v
+}
+
+// HTTPEmbedder call OpenAI compatible /embeddings endpoint (OpenAI, Ollama, vLLM, LocalAI...)
+type HTTPEmbedder struct {
+	URL   string
+	Model string
+	Key   string
+	http  *upstream.Client
+}
+
+func NewHTTPEmbedder(url, model, key string, client *upstream.Client) *HTTPEmbedder {
+	return &HTTPEmbedder{
+		URL:   url,
+		Model: model,
+		Key:   key,
+		http:  client,
+	}
+}
+
+func (h *HTTPEmbedder) Name() string {
+	return EmbedderOpenAI + ":" + h.Model
+}
+
+type embeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+func (h *HTTPEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(embeddingRequest{Model: h.Model, Input: texts})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.Key != "" {
+		req.Header.Set("Authorization", "Bearer "+h.Key)
+	}
+
+	resp, err := h.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrEmbedding, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrEmbedding, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: status: %s, body: %s", ErrEmbedding, resp.Status, string(respBody))
+	}
+
+	var out embeddingResponse
+	if err := json.Unmarshal(respBody, &out); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrEmbedding, err)
+	}
+	if len(out.Data) != len(texts) {
+		return nil, fmt.Errorf("%w: got %d vectors for %d texts", ErrEmbedding, len(out.Data), len(texts))
+	}
+
+	res := make([][]float32, len(texts))
+	for _, d := range out.Data {
+		if d.Index < 0 || d.Index >= len(res) || len(d.Embedding) == 0 {
+			return nil, fmt.Errorf("%w: bad vector %d", ErrEmbedding, d.Index)
+		}
+		res[d.Index] = d.Embedding
+	}
+	return res, nil
+}
@@ -0,0 +1,247 @@
+// Package ingest index user documents for retrieval: text is extracted from file, split into
+// overlapping chunks, embedded and stored in vector store, which later answers similarity search
+package ingest
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ledongthuc/pdf"
+)
+
+var (
+	ErrUnsupported = errors.New("unsupported file format")
+	ErrBadFile     = errors.New("cannot read file")
+	ErrNoText      = errors.New("no text in file")
+)
+
+const (
+	FormatPDF      = "pdf"
+	FormatDOCX     = "docx"
+	FormatText     = "txt"
+	FormatMarkdown = "md"
+)
+
+// maxDocumentXML is max unpacked size of word/document.xml. Compressed it may be much smaller
+// than size limit of upload
+const maxDocumentXML = 64 << 20
+
+var extensions = map[string]string{
+	".pdf":      FormatPDF,
+	".docx":     FormatDOCX,
+	".txt":      FormatText,
+	".text":     FormatText,
+	".md":       FormatMarkdown,
+	".markdown": FormatMarkdown,
+}
+
+// DetectFormat return format of file by its extension
+func DetectFormat(name string) (string, error) {
+	f, ok := extensions[strings.ToLower(filepath.Ext(name))]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnsupported, filepath.Ext(name))
+	}
+	return f, nil
+}
+
+var mimeTypes = map[string]string{
+	FormatPDF:      "application/pdf",
+	FormatDOCX:     "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	FormatText:     "text/plain; charset=utf-8",
+	FormatMarkdown: "text/markdown; charset=utf-8",
+}
+
+// MimeType of supported format, application/octet-stream for unknown
+func MimeType(format string) string {
+	if t, ok := mimeTypes[format]; ok {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// Extract return plain text of file. Problems which didn't stop extraction, e.g. unreadable
+// PDF page, are returned as warnings
+func Extract(format string, data []byte) (string, []string, error) {
+	var (
+		text     string
+		warnings []string
+		err      error
+	)
+	switch format {
+	case FormatPDF:
+		text, warnings, err = extractPDF(data)
+	case FormatDOCX:
+		text, err = extractDOCX(data)
+	case FormatText, FormatMarkdown:
+		text, warnings = extractText(data)
+	default:
+		return "", nil, fmt.Errorf("%w: %q", ErrUnsupported, format)
+	}
+	if err != nil {
+		return "", warnings, err
+	}
+
+	text = normalize(text)
+	if text == "" {
+		return "", warnings, ErrNoText
+	}
+	return text, warnings, nil
+}
+
+func extractText(data []byte) (string, []string) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if utf8.Valid(data) {
+		return string(data), nil
+	}
+	return strings.ToValidUTF8(string(data), "�"), []string{"file is not valid UTF-8, broken characters replaced"}
+}
+
+func extractPDF(data []byte) (text string, warnings []string, err error) {
+	// reader panics on some malformed files instead of returning error
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrBadFile, r)
+		}
+	}()
+
+	r, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %w", ErrBadFile, err)
+	}
+
+	var sb strings.Builder
+	for i := 1; i <= r.NumPage(); i++ {
+		p := r.Page(i)
+		if p.V.IsNull() {
+			continue
+		}
+		t, err := p.GetPlainText(nil)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("page %d skipped: %v", i, err))
+			continue
+		}
+		sb.WriteString(t)
+		sb.WriteString("\n\n")
+	}
+	return sb.String(), warnings, nil
+}
+
+// extractDOCX read text runs of word/document.xml. Paragraphs and table rows become lines,
+// cells are separated by tabs
+func extractDOCX(data []byte) (string, error) {
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrBadFile, err)
+	}
+
+	var doc *zip.File
+	for _, f := range z.File {
+		if f.Name == "word/document.xml" {
+			doc = f
+		}
+	}
+	if doc == nil {
+		return "", fmt.Errorf("%w: word/document.xml not found", ErrBadFile)
+	}
+	if doc.UncompressedSize64 > maxDocumentXML {
+		return "", fmt.Errorf("%w: word/document.xml is too large", ErrBadFile)
+	}
+	rc, err := doc.Open()
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrBadFile, err)
+	}
+	defer rc.Close()
+
+	var (
+		buf    bytes.Buffer
+		tables int // nesting of tables
+	)
+	// trimEnd drop separators written after last text of cell or row
+	trimEnd := func(seps string) {
+		for buf.Len() > 0 && strings.IndexByte(seps, buf.Bytes()[buf.Len()-1]) >= 0 {
+			buf.Truncate(buf.Len() - 1)
+		}
+	}
+
+	// declared size may lie, unpacked data is limited too
+	lr := &io.LimitedReader{R: rc, N: maxDocumentXML + 1}
+	dec := xml.NewDecoder(lr)
+	for {
+		tok, err := dec.Token()
+		if lr.N <= 0 {
+			return "", fmt.Errorf("%w: word/document.xml is too large", ErrBadFile)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("%w: %w", ErrBadFile, err)
+		}
+
+		switch el := tok.(type) {
+		case xml.StartElement:
+			switch el.Name.Local {
+			case "t":
+				var s string
+				if err := dec.DecodeElement(&s, &el); err != nil {
+					return "", fmt.Errorf("%w: %w", ErrBadFile, err)
+				}
+				buf.WriteString(s)
+			case "tab":
+				buf.WriteByte('\t')
+			case "br", "cr":
+				buf.WriteByte('\n')
+			case "tbl":
+				tables++
+			}
+		case xml.EndElement:
+			switch el.Name.Local {
+			case "p":
+				if tables > 0 {
+					buf.WriteByte(' ')
+				} else {
+					buf.WriteByte('\n')
+				}
+			case "tc":
+				trimEnd(" ")
+				buf.WriteByte('\t')
+			case "tr":
+				trimEnd("\t")
+				buf.WriteByte('\n')
+			case "tbl":
+				tables--
+			}
+		}
+	}
+	return buf.String(), nil
+}
+
+// normalize unify line breaks, drop trailing spaces and collapse runs of blank lines
+func normalize(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	lines := strings.Split(text, "\n")
+	res := lines[:0]
+	blank := false
+	for _, l := range lines {
+		l = strings.TrimRight(l, " \t ")
+		if l == "" {
+			if blank {
+				continue
+			}
+			blank = true
+		} else {
+			blank = false
+		}
+		res = append(res, l)
+	}
+	return strings.TrimSpace(strings.Join(res, "\n"))
+}
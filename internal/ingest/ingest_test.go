@@ -0,0 +1,342 @@
+package ingest
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/json"
+	"flicker/internal/export"
+	"flicker/internal/upstream"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectFormat(t *testing.T) {
+	t.Parallel()
+
+	for name, want := range map[string]string{
+		"a.PDF": FormatPDF, "b.docx": FormatDOCX, "c.txt": FormatText, "d.markdown": FormatMarkdown, "e.md": FormatMarkdown,
+	} {
+		f, err := DetectFormat(name)
+		assert.NoError(t, err)
+		assert.Equal(t, want, f, name)
+	}
+	for _, name := range []string{"a.doc", "b.zip", "noext"} {
+		_, err := DetectFormat(name)
+		assert.ErrorIs(t, err, ErrUnsupported, name)
+	}
+
+	assert.Equal(t, "application/pdf", MimeType(FormatPDF))
+	assert.Equal(t, "application/octet-stream", MimeType("zip"))
+}
+
+func TestExtractText(t *testing.T) {
+	t.Parallel()
+
+	text, warnings, err := Extract(FormatMarkdown, []byte("\xef\xbb\xbf# Заголовок\r\n\r\n\r\n\r\nтекст   \r\n"))
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+	assert.Equal(t, "# Заголовок\n\nтекст", text)
+
+	text, warnings, err = Extract(FormatText, []byte("ok \xff end"))
+	assert.NoError(t, err)
+	assert.Len(t, warnings, 1)
+	assert.True(t, utf8.ValidString(text))
+
+	_, _, err = Extract(FormatText, []byte(" \n\t "))
+	assert.ErrorIs(t, err, ErrNoText)
+}
+
+func TestExtractDOCX(t *testing.T) {
+	t.Parallel()
+
+	b, err := export.DOCX(export.Parse("t", "# Механика\n\nТекст **жирный**.\n\n- один\n- два\n\n| A | B |\n|---|---|\n| 1 | 2 |\n"))
+	require.NoError(t, err)
+
+	text, _, err := Extract(FormatDOCX, b)
+	assert.NoError(t, err)
+	assert.Equal(t, "Механика\nТекст жирный.\nодин\nдва\nA\tB\n1\t2", text)
+
+	_, _, err = Extract(FormatDOCX, []byte("not a zip"))
+	assert.ErrorIs(t, err, ErrBadFile)
+}
+
+func TestExtractDOCXBomb(t *testing.T) {
+	t.Parallel()
+
+	var xml bytes.Buffer
+	fw, err := flate.NewWriter(&xml, flate.BestCompression)
+	require.NoError(t, err)
+	crc := crc32.NewIEEE()
+	_, err = io.Copy(io.MultiWriter(fw, crc), io.MultiReader(
+		strings.NewReader(`<w:document><w:body><w:p><w:r><w:t>`),
+		io.LimitReader(zeros{}, maxDocumentXML+1),
+	))
+	require.NoError(t, err)
+	require.NoError(t, fw.Close())
+
+	// real size in header and size which lies, the latter is refused by archive/zip when
+	// unpacked data exceeds it
+	for _, size := range []uint64{maxDocumentXML + 36, 100} {
+		var buf bytes.Buffer
+		z := zip.NewWriter(&buf)
+		w, err := z.CreateRaw(&zip.FileHeader{
+			Name:               "word/document.xml",
+			Method:             zip.Deflate,
+			CRC32:              crc.Sum32(),
+			CompressedSize64:   uint64(xml.Len()),
+			UncompressedSize64: size,
+		})
+		require.NoError(t, err)
+		_, err = w.Write(xml.Bytes())
+		require.NoError(t, err)
+		require.NoError(t, z.Close())
+		assert.Less(t, buf.Len(), 1<<20)
+
+		_, _, err = Extract(FormatDOCX, buf.Bytes())
+		assert.ErrorIs(t, err, ErrBadFile)
+		if size > maxDocumentXML {
+			assert.ErrorContains(t, err, "too large")
+		}
+	}
+}
+
+// zeros is endless text of "0"
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = '0'
+	}
+	return len(p), nil
+}
+
+func TestExtractPDF(t *testing.T) {
+	t.Parallel()
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	pdf.Cell(0, 10, "First page about thermodynamics")
+	pdf.AddPage()
+	pdf.Cell(0, 10, "Second page about entropy")
+	var buf bytes.Buffer
+	require.NoError(t, pdf.Output(&buf))
+
+	text, warnings, err := Extract(FormatPDF, buf.Bytes())
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+	assert.Equal(t, "First page about thermodynamics\n\nSecond page about entropy", text)
+
+	_, _, err = Extract(FormatPDF, []byte("%PDF-1.4 garbage"))
+	assert.ErrorIs(t, err, ErrBadFile)
+}
+
+func TestSplit(t *testing.T) {
+	t.Parallel()
+
+	t.Run("short", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, []Chunk{{Index: 0, Start: 2, End: 7, Text: "hello"}}, Split("  hello  ", 100, 10))
+	})
+
+	t.Run("offsets and overlap", func(t *testing.T) {
+		t.Parallel()
+		var sb strings.Builder
+		for i := range 40 {
+			fmt.Fprintf(&sb, "Предложение номер %d о физике. ", i)
+			if i%10 == 9 {
+				sb.WriteString("\n\n")
+			}
+		}
+		text := sb.String()
+		r := []rune(text)
+
+		chunks := Split(text, 200, 50)
+		require.Greater(t, len(chunks), 5)
+		for i, c := range chunks {
+			assert.Equal(t, i, c.Index)
+			assert.LessOrEqual(t, c.End-c.Start, 200)
+			assert.Equal(t, string(r[c.Start:c.End]), c.Text, "offsets point to chunk text")
+			assert.Equal(t, strings.TrimSpace(c.Text), c.Text)
+			// chunks end at sentence or paragraph and start at word
+			assert.True(t, strings.HasSuffix(c.Text, ".") || i == len(chunks)-1, c.Text)
+			assert.True(t, c.Start == 0 || r[c.Start-1] == ' ' || r[c.Start-1] == '\n', c.Text)
+			if i > 0 {
+				prev := chunks[i-1]
+				assert.Greater(t, c.Start, prev.Start)
+				assert.Less(t, c.Start, prev.End, "neighbours overlap")
+			}
+		}
+		assert.Equal(t, len(r), chunks[len(chunks)-1].End+len(" \n\n"))
+	})
+
+	t.Run("no spaces", func(t *testing.T) {
+		t.Parallel()
+		chunks := Split(strings.Repeat("a", 25), 10, 3)
+		require.Len(t, chunks, 4)
+		assert.Equal(t, 0, chunks[0].Start)
+		assert.Equal(t, 10, chunks[0].End)
+		assert.Equal(t, 7, chunks[1].Start)
+		assert.Equal(t, 25, chunks[3].End)
+	})
+}
+
+func TestHashEmbedder(t *testing.T) {
+	t.Parallel()
+
+	e := NewHashEmbedder(256)
+	vs, err := e.Embed(context.Background(), []string{
+		"Энтропия характеризует беспорядок системы",
+		"энтропии и беспорядка",
+		"Рецепт яблочного пирога",
+		"",
+	})
+	require.NoError(t, err)
+	require.Len(t, vs, 4)
+	for _, v := range vs[:3] {
+		assert.Len(t, v, 256)
+		assert.InDelta(t, 1, dot(v, v), 1e-5)
+	}
+	assert.Greater(t, dot(vs[0], vs[1]), dot(vs[0], vs[2]))
+	assert.Zero(t, dot(vs[3], vs[3]))
+	assert.Equal(t, "hash-256", e.Name())
+}
+
+func TestHTTPEmbedder(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer key", r.Header.Get("Authorization"))
+		var req embeddingRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "model", req.Model)
+		if req.Input[0] == "fail" {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		// answer in reverse order, client must sort by index
+		var data []map[string]any
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			data = append(data, map[string]any{"index": i, "embedding": []float32{float32(len(req.Input[i])), 1}})
+		}
+		assert.NoError(t, json.NewEncoder(w).Encode(map[string]any{"data": data}))
+	}))
+	defer srv.Close()
+
+	e := NewHTTPEmbedder(srv.URL, "model", "key", upstream.New(upstream.Embeddings, upstream.Options{}))
+	vs, err := e.Embed(context.Background(), []string{"a", "bbb"})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]float32{{1, 1}, {3, 1}}, vs)
+
+	_, err = e.Embed(context.Background(), []string{"fail"})
+	assert.ErrorIs(t, err, ErrEmbedding)
+}
+
+func TestMemoryStore(t *testing.T) {
+	t.Parallel()
+
+	s := NewMemoryStore()
+	ctx := context.Background()
+	chunks := []Chunk{{Index: 0, Start: 0, End: 1, Text: "x"}, {Index: 1, Start: 1, End: 2, Text: "y"}}
+	require.NoError(t, s.Upsert(ctx, "u1", "d1", "a.txt", chunks, [][]float32{{1, 0}, {0, 2}}))
+	require.NoError(t, s.Upsert(ctx, "u2", "d2", "b.txt", chunks[:1], [][]float32{{1, 0}}))
+
+	res, err := s.Search(ctx, "u1", []float32{0, 1}, 5)
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	assert.Equal(t, "y", res[0].Text)
+	assert.InDelta(t, 1, res[0].Score, 1e-6)
+	assert.Equal(t, "a.txt", res[0].Source)
+	assert.Equal(t, "d1", res[0].DocumentId)
+	assert.InDelta(t, 0, res[1].Score, 1e-6)
+
+	res, err = s.Search(ctx, "u1", []float32{1, 0, 0}, 5)
+	require.NoError(t, err)
+	assert.Empty(t, res, "other dimension")
+
+	require.NoError(t, s.Upsert(ctx, "u1", "d1", "a.txt", chunks[:1], [][]float32{{1, 0}}))
+	res, _ = s.Search(ctx, "u1", []float32{0, 1}, 5)
+	assert.Len(t, res, 1, "upsert replaces document")
+
+	require.NoError(t, s.Delete(ctx, "u1", "d1"))
+	res, _ = s.Search(ctx, "u1", []float32{0, 1}, 5)
+	assert.Empty(t, res)
+	res, _ = s.Search(ctx, "u2", []float32{1, 0}, 5)
+	assert.Len(t, res, 1, "other owner untouched")
+}
+
+func TestPipeline(t *testing.T) {
+	t.Parallel()
+
+	var md strings.Builder
+	md.WriteString("# Термодинамика\n\n")
+	for range 20 {
+		md.WriteString("Первое начало термодинамики — закон сохранения энергии для тепловых процессов.\n\n")
+	}
+	md.WriteString("Энтропия изолированной системы не убывает, это второе начало.\n")
+
+	p := New(NewHashEmbedder(128), NewMemoryStore(), 300, 50)
+	ctx := context.Background()
+
+	report, err := p.Ingest(ctx, "u1", "d1", "thermo.md", []byte(md.String()))
+	require.NoError(t, err)
+	assert.Equal(t, "d1", report.DocumentId)
+	assert.Equal(t, FormatMarkdown, report.Format)
+	assert.Equal(t, int64(md.Len()), report.Size)
+	assert.Equal(t, utf8.RuneCountInString(strings.TrimSpace(md.String())), report.Characters)
+	assert.Greater(t, report.Chunks, 5)
+	assert.Equal(t, 300, report.ChunkSize)
+	assert.Equal(t, 50, report.ChunkOverlap)
+	assert.Equal(t, "hash-128", report.Embedder)
+	assert.Equal(t, 128, report.Dimensions)
+	assert.Equal(t, StoreMemory, report.Store)
+
+	res, err := p.Search(ctx, "u1", "Что говорит второе начало об энтропии?", 2)
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	assert.Contains(t, res[0].Text, "Энтропия изолированной системы")
+	assert.Equal(t, "thermo.md", res[0].Source)
+
+	res, err = p.Search(ctx, "u2", "энтропия", 2)
+	require.NoError(t, err)
+	assert.Empty(t, res)
+
+	require.NoError(t, p.Delete(ctx, "u1", "d1"))
+	res, _ = p.Search(ctx, "u1", "энтропия", 2)
+	assert.Empty(t, res)
+
+	_, err = p.Ingest(ctx, "u1", "d2", "a.exe", []byte("MZ"))
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
+
+type failingEmbedder struct{}
+
+func (failingEmbedder) Embed(context.Context, []string) ([][]float32, error) {
+	return nil, fmt.Errorf("down")
+}
+
+func (failingEmbedder) Name() string { return "failing" }
+
+func TestPipelineEmbeddingError(t *testing.T) {
+	t.Parallel()
+
+	store := NewMemoryStore()
+	p := New(failingEmbedder{}, store, 100, 10)
+	_, err := p.Ingest(context.Background(), "u1", "d1", "a.txt", []byte("text"))
+	assert.ErrorIs(t, err, ErrEmbedding)
+	_, err = p.Search(context.Background(), "u1", "text", 1)
+	assert.ErrorIs(t, err, ErrEmbedding)
+	assert.Empty(t, store.docs)
+}
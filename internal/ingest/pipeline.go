@@ -0,0 +1,142 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"flicker/internal/config"
+	"flicker/internal/rag"
+	"flicker/internal/upstream"
+	"flicker/internal/views"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/autumnterror/breezynotes/pkg/utils/format"
+)
+
+// batchSize is number of chunks embedded by one request
+const batchSize = 64
+
+// Pipeline extract, chunk, embed and store documents. It is also rag.Retriever over stored chunks
+type Pipeline struct {
+	Embedder     Embedder
+	Store        Store
+	ChunkSize    int
+	ChunkOverlap int
+}
+
+func New(embedder Embedder, store Store, chunkSize, chunkOverlap int) *Pipeline {
+	return &Pipeline{
+		Embedder:     embedder,
+		Store:        store,
+		ChunkSize:    chunkSize,
+		ChunkOverlap: chunkOverlap,
+	}
+}
+
+// NewEmbedder return embedder chosen in config, offline hash embedder by default
+func NewEmbedder(cfg *config.Config) Embedder {
+	if cfg.Embedder == EmbedderOpenAI {
+		client := upstream.New(upstream.Embeddings, upstream.FromConfig(cfg, upstream.Embeddings))
+		return NewHTTPEmbedder(cfg.EmbeddingURL, cfg.EmbeddingModel, cfg.EmbeddingKey, client)
+	}
+	return NewHashEmbedder(cfg.EmbeddingDim)
+}
+
+// Ingest index file as document of owner. Previous chunks of same document are replaced
+func (p *Pipeline) Ingest(ctx context.Context, owner, documentId, filename string, data []byte) (*views.IngestReport, error) {
+	const op = "ingest.Pipeline.Ingest"
+	started := time.Now()
+
+	f, err := DetectFormat(filename)
+	if err != nil {
+		return nil, format.Error(op, err)
+	}
+	text, warnings, err := Extract(f, data)
+	if err != nil {
+		return nil, format.Error(op, err)
+	}
+
+	chunks := Split(text, p.ChunkSize, p.ChunkOverlap)
+	vectors, err := p.embed(ctx, chunks)
+	if err != nil {
+		return nil, format.Error(op, err)
+	}
+	if err := p.Store.Upsert(ctx, owner, documentId, filename, chunks, vectors); err != nil {
+		return nil, format.Error(op, err)
+	}
+
+	return &views.IngestReport{
+		DocumentId:   documentId,
+		Filename:     filename,
+		Format:       f,
+		Size:         int64(len(data)),
+		Characters:   utf8.RuneCountInString(text),
+		Chunks:       len(chunks),
+		ChunkSize:    p.ChunkSize,
+		ChunkOverlap: p.ChunkOverlap,
+		Embedder:     p.Embedder.Name(),
+		Dimensions:   len(vectors[0]),
+		Store:        p.Store.Name(),
+		DurationMs:   time.Since(started).Milliseconds(),
+		Warnings:     warnings,
+	}, nil
+}
+
+func (p *Pipeline) embed(ctx context.Context, chunks []Chunk) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(chunks))
+	for i := 0; i < len(chunks); i += batchSize {
+		batch := chunks[i:min(i+batchSize, len(chunks))]
+		texts := make([]string, len(batch))
+		for j, c := range batch {
+			texts[j] = c.Text
+		}
+
+		vs, err := p.Embedder.Embed(ctx, texts)
+		if err != nil {
+			if !errors.Is(err, ErrEmbedding) {
+				err = fmt.Errorf("%w: %w", ErrEmbedding, err)
+			}
+			return nil, err
+		}
+		for _, v := range vs {
+			if len(v) != len(vs[0]) || (len(vectors) > 0 && len(v) != len(vectors[0])) {
+				return nil, fmt.Errorf("%w: vectors of different dimensions", ErrEmbedding)
+			}
+		}
+		vectors = append(vectors, vs...)
+	}
+	return vectors, nil
+}
+
+// Search embed query and return k closest chunks of owner documents
+func (p *Pipeline) Search(ctx context.Context, owner, query string, k int) ([]rag.Chunk, error) {
+	const op = "ingest.Pipeline.Search"
+
+	vs, err := p.Embedder.Embed(ctx, []string{query})
+	if err != nil {
+		if !errors.Is(err, ErrEmbedding) {
+			err = fmt.Errorf("%w: %w", ErrEmbedding, err)
+		}
+		return nil, format.Error(op, err)
+	}
+	if len(vs) != 1 {
+		return nil, format.Error(op, fmt.Errorf("%w: no query vector", ErrEmbedding))
+	}
+
+	res, err := p.Store.Search(ctx, owner, vs[0], k)
+	if err != nil {
+		return nil, format.Error(op, err)
+	}
+	return res, nil
+}
+
+// Delete remove all chunks of document
+func (p *Pipeline) Delete(ctx context.Context, owner, documentId string) error {
+	const op = "ingest.Pipeline.Delete"
+
+	if err := p.Store.Delete(ctx, owner, documentId); err != nil {
+		return format.Error(op, err)
+	}
+	return nil
+}
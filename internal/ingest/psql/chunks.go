@@ -0,0 +1,122 @@
+package psql
+
+import (
+	"context"
+	"flicker/internal/ingest"
+	"flicker/internal/rag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/autumnterror/breezynotes/pkg/log"
+	"github.com/autumnterror/breezynotes/pkg/utils/format"
+	"github.com/lib/pq"
+)
+
+func (d *Driver) Name() string {
+	return ingest.StorePgvector
+}
+
+// Upsert replace chunks of document in one statement, so search never sees half indexed document
+func (d *Driver) Upsert(ctx context.Context, owner, documentId, source string, chunks []ingest.Chunk, vectors [][]float32) error {
+	const op = "psql.chunks.Upsert"
+
+	if len(chunks) != len(vectors) {
+		return format.Error(op, fmt.Errorf("%d chunks but %d vectors", len(chunks), len(vectors)))
+	}
+
+	ctx, done := context.WithTimeout(ctx, upsertWaitTime)
+	defer done()
+
+	var (
+		index      = make([]int64, len(chunks))
+		start      = make([]int64, len(chunks))
+		end        = make([]int64, len(chunks))
+		texts      = make([]string, len(chunks))
+		embeddings = make([]string, len(chunks))
+	)
+	for i, c := range chunks {
+		index[i], start[i], end[i], texts[i] = int64(c.Index), int64(c.Start), int64(c.End), c.Text
+		embeddings[i] = vector(vectors[i])
+	}
+
+	query := `
+		WITH del AS (
+			DELETE FROM document_chunks WHERE owner = $1 AND document_id = $2
+		)
+		INSERT INTO document_chunks (owner, document_id, source, chunk, start_offset, end_offset, text, embedding)
+		SELECT $1, $2, $3, c.chunk, c.start_offset, c.end_offset, c.text, c.embedding::vector
+		FROM unnest($4::int[], $5::int[], $6::int[], $7::text[], $8::text[])
+			AS c (chunk, start_offset, end_offset, text, embedding)
+	`
+	if _, err := d.driver.ExecContext(ctx, query, owner, documentId, source,
+		pq.Array(index), pq.Array(start), pq.Array(end), pq.Array(texts), pq.Array(embeddings)); err != nil {
+		return format.Error(op, err)
+	}
+
+	return nil
+}
+
+// Search by cosine distance. Chunks embedded with vectors of other dimension are ignored
+func (d *Driver) Search(ctx context.Context, owner string, v []float32, k int) ([]rag.Chunk, error) {
+	const op = "psql.chunks.Search"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	query := `
+		SELECT document_id, source, chunk, start_offset, end_offset, text, 1 - (embedding <=> $2::vector)
+		FROM document_chunks
+		WHERE owner = $1 AND vector_dims(embedding) = $3
+		ORDER BY embedding <=> $2::vector
+		LIMIT $4
+	`
+	rows, err := d.driver.QueryContext(ctx, query, owner, vector(v), len(v), k)
+	if err != nil {
+		return nil, format.Error(op, err)
+	}
+	defer rows.Close()
+
+	res := []rag.Chunk{}
+	for rows.Next() {
+		var c rag.Chunk
+		if err := rows.Scan(&c.DocumentId, &c.Source, &c.Index, &c.Start, &c.End, &c.Text, &c.Score); err != nil {
+			log.Error(op, "rows scan error", err)
+			continue
+		}
+		res = append(res, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, format.Error(op, err)
+	}
+
+	return res, nil
+}
+
+// Delete all chunks of document
+func (d *Driver) Delete(ctx context.Context, owner, documentId string) error {
+	const op = "psql.chunks.Delete"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	if _, err := d.driver.ExecContext(ctx, `DELETE FROM document_chunks WHERE owner = $1 AND document_id = $2`, owner, documentId); err != nil {
+		return format.Error(op, err)
+	}
+
+	return nil
+}
+
+// vector format float slice as pgvector text literal
+func vector(v []float32) string {
+	var sb strings.Builder
+	sb.WriteByte('[')
+	for i, x := range v {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(strconv.FormatFloat(float64(x), 'g', -1, 32))
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
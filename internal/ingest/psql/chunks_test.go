@@ -0,0 +1,94 @@
+package psql
+
+import (
+	"context"
+	authpsql "flicker/internal/auth/psql"
+	"flicker/internal/config"
+	"flicker/internal/ingest"
+	"flicker/internal/views"
+	"testing"
+
+	"github.com/autumnterror/breezynotes/pkg/utils/id"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunksOperations(t *testing.T) {
+	t.Parallel()
+
+	repo, owner, cleanup := setupTestTx(t)
+	defer cleanup()
+
+	doc := id.New()
+	chunks := []ingest.Chunk{
+		{Index: 0, Start: 0, End: 10, Text: "энтропия"},
+		{Index: 1, Start: 8, End: 20, Text: "энергия"},
+	}
+
+	t.Run("upsert", func(t *testing.T) {
+		assert.NoError(t, repo.Upsert(context.TODO(), owner, doc, "a.pdf", chunks, [][]float32{{1, 0, 0}, {0, 1, 0}}))
+		assert.Error(t, repo.Upsert(context.TODO(), owner, doc, "a.pdf", chunks, [][]float32{{1, 0, 0}}))
+	})
+
+	t.Run("search", func(t *testing.T) {
+		res, err := repo.Search(context.TODO(), owner, []float32{0, 1, 0}, 5)
+		assert.NoError(t, err)
+		if assert.Len(t, res, 2) {
+			assert.Equal(t, "энергия", res[0].Text)
+			assert.Equal(t, doc, res[0].DocumentId)
+			assert.Equal(t, "a.pdf", res[0].Source)
+			assert.Equal(t, 1, res[0].Index)
+			assert.Equal(t, 8, res[0].Start)
+			assert.Equal(t, 20, res[0].End)
+			assert.InDelta(t, 1, res[0].Score, 1e-6)
+		}
+
+		res, err = repo.Search(context.TODO(), owner, []float32{0, 1}, 5)
+		assert.NoError(t, err)
+		assert.Empty(t, res, "other dimension")
+
+		res, err = repo.Search(context.TODO(), id.New(), []float32{0, 1, 0}, 5)
+		assert.NoError(t, err)
+		assert.Empty(t, res, "other owner")
+	})
+
+	t.Run("reupsert", func(t *testing.T) {
+		assert.NoError(t, repo.Upsert(context.TODO(), owner, doc, "a.pdf", chunks[:1], [][]float32{{1, 0, 0}}))
+		res, err := repo.Search(context.TODO(), owner, []float32{0, 1, 0}, 5)
+		assert.NoError(t, err)
+		assert.Len(t, res, 1)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.NoError(t, repo.Delete(context.TODO(), owner, doc))
+		res, err := repo.Search(context.TODO(), owner, []float32{1, 0, 0}, 5)
+		assert.NoError(t, err)
+		assert.Empty(t, res)
+	})
+}
+
+func TestVector(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "[]", vector(nil))
+	assert.Equal(t, "[1,-0.5,0.1]", vector([]float32{1, -0.5, 0.1}))
+}
+
+func setupTestTx(t *testing.T) (*Driver, string, func()) {
+	pdb := authpsql.MustConnect(config.Test())
+
+	tx, err := pdb.Driver.Begin()
+	assert.NoError(t, err)
+
+	owner := id.New()
+	assert.NoError(t, authpsql.NewDriver(tx).Create(context.TODO(), &views.User{
+		Id:       owner,
+		Login:    owner[:10],
+		Email:    owner[:10] + "@example.com",
+		Password: "password",
+	}))
+
+	return NewDriver(tx), owner, func() {
+		assert.NoError(t, tx.Rollback())
+		assert.NoError(t, pdb.Disconnect())
+	}
+}
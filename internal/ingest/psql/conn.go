@@ -0,0 +1,30 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type SqlRepo interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+const (
+	waitTime = 3 * time.Second
+	// big documents have hundreds of chunks with vectors
+	upsertWaitTime = 30 * time.Second
+)
+
+// Driver is ingest.Store on top of pgvector
+type Driver struct {
+	driver SqlRepo
+}
+
+func NewDriver(driver SqlRepo) *Driver {
+	return &Driver{
+		driver: driver,
+	}
+}
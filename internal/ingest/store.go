@@ -0,0 +1,117 @@
+package ingest
+
+import (
+	"context"
+	"flicker/internal/rag"
+	"sort"
+	"sync"
+)
+
+const (
+	StorePgvector = "pgvector"
+	StoreMemory   = "memory"
+)
+
+// Store keep chunk vectors of documents. Vectors are expected to be L2 normalized
+// by embedder or store itself, score of search is cosine similarity
+type Store interface {
+	// Upsert replace all chunks of document
+	Upsert(ctx context.Context, owner, documentId, source string, chunks []Chunk, vectors [][]float32) error
+	// Search return k chunks of owner closest to vector, best first
+	Search(ctx context.Context, owner string, vector []float32, k int) ([]rag.Chunk, error)
+	Delete(ctx context.Context, owner, documentId string) error
+	Name() string
+}
+
+// MemoryStore is pure Go exact index kept in process memory. It is lost on restart, so fits
+// tests, local runs and installations without pgvector
+type MemoryStore struct {
+	mu   sync.RWMutex
+	docs map[string]map[string][]memoryEntry // owner -> document -> chunks
+}
+
+type memoryEntry struct {
+	chunk  rag.Chunk
+	vector []float32
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{docs: map[string]map[string][]memoryEntry{}}
+}
+
+func (m *MemoryStore) Name() string {
+	return StoreMemory
+}
+
+func (m *MemoryStore) Upsert(_ context.Context, owner, documentId, source string, chunks []Chunk, vectors [][]float32) error {
+	entries := make([]memoryEntry, len(chunks))
+	for i, c := range chunks {
+		entries[i] = memoryEntry{
+			chunk: rag.Chunk{
+				DocumentId: documentId,
+				Source:     source,
+				Index:      c.Index,
+				Start:      c.Start,
+				End:        c.End,
+				Text:       c.Text,
+			},
+			vector: normalizeVector(append([]float32(nil), vectors[i]...)),
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.docs[owner] == nil {
+		m.docs[owner] = map[string][]memoryEntry{}
+	}
+	m.docs[owner][documentId] = entries
+	return nil
+}
+
+func (m *MemoryStore) Search(_ context.Context, owner string, vector []float32, k int) ([]rag.Chunk, error) {
+	q := normalizeVector(append([]float32(nil), vector...))
+
+	m.mu.RLock()
+	var res []rag.Chunk
+	for _, entries := range m.docs[owner] {
+		for _, e := range entries {
+			if len(e.vector) != len(q) {
+				// indexed by another embedder
+				continue
+			}
+			c := e.chunk
+			c.Score = dot(e.vector, q)
+			res = append(res, c)
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Score != res[j].Score {
+			return res[i].Score > res[j].Score
+		}
+		if res[i].DocumentId != res[j].DocumentId {
+			return res[i].DocumentId < res[j].DocumentId
+		}
+		return res[i].Index < res[j].Index
+	})
+	if k > 0 && len(res) > k {
+		res = res[:k]
+	}
+	return res, nil
+}
+
+func (m *MemoryStore) Delete(_ context.Context, owner, documentId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.docs[owner], documentId)
+	return nil
+}
+
+func dot(a, b []float32) float64 {
+	var s float64
+	for i := range a {
+		s += float64(a[i]) * float64(b[i])
+	}
+	return s
+}
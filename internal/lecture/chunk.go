@@ -0,0 +1,106 @@
+package lecture
+
+import (
+	"flicker/internal/summary"
+	"flicker/internal/transcript"
+	"flicker/internal/views"
+	"fmt"
+	"strings"
+)
+
+// Chunk is part of transcript from Start to End seconds
+type Chunk struct {
+	Start, End float64
+	Text       string
+}
+
+// Chunks group segments into chunks of at most maxTokens estimated tokens. Segments longer than
+// chunk are split at sentence or word boundaries, so time of chunk borders inside them is estimated
+func Chunks(segments []views.TranscriptSegment, maxTokens int) []Chunk {
+	var (
+		chunks []Chunk
+		cur    Chunk
+		n      int
+	)
+	// rune costs at most half of token
+	for _, c := range transcript.Cues(segments, maxTokens*2) {
+		l := summary.Tokens(c.Text)
+		if n > 0 && n+1+l > maxTokens {
+			chunks = append(chunks, cur)
+			n = 0
+		}
+		if n == 0 {
+			cur = Chunk{Start: c.Start, End: c.End, Text: c.Text}
+			n = l
+			continue
+		}
+		cur.End = c.End
+		cur.Text += " " + c.Text
+		n += 1 + l
+	}
+	if n > 0 {
+		chunks = append(chunks, cur)
+	}
+	return chunks
+}
+
+// defaultTitle is heading of merged note when title is not given
+const defaultTitle = "Конспект лекции"
+
+// failedNote stand in place of summary of failed chunk
+const failedNote = "> Эту часть лекции не удалось законспектировать, её текст есть в расшифровке."
+
+// Merge join summaries of chunks into one note: every summary becomes section with time range
+// it covers, its headings are moved two levels down. Summary of single chunk is returned as is
+func Merge(title string, chunks []views.LectureChunk) string {
+	if len(chunks) == 1 {
+		return chunks[0].Summary
+	}
+
+	title = strings.TrimSpace(title)
+	if title == "" {
+		title = defaultTitle
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# " + title + "\n")
+	for i, c := range chunks {
+		fmt.Fprintf(&sb, "\n## Часть %d · %s–%s\n\n", i+1, transcript.Clock(c.StartSec), transcript.Clock(c.EndSec))
+		if c.Failed {
+			sb.WriteString(failedNote)
+		} else {
+			sb.WriteString(demote(c.Summary, 2))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// demote add by levels to ATX headings outside of code fences, up to level 6
+func demote(md string, by int) string {
+	lines := strings.Split(strings.TrimSpace(md), "\n")
+	fence := ""
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			continue
+		}
+
+		level := 0
+		for level < len(line) && line[level] == '#' {
+			level++
+		}
+		if level == 0 || level > 6 || (level < len(line) && line[level] != ' ') {
+			continue
+		}
+		lines[i] = strings.Repeat("#", min(level+by, 6)) + line[level:]
+	}
+	return strings.Join(lines, "\n")
+}
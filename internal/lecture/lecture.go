@@ -0,0 +1,192 @@
+// Package lecture turn recording of lecture into markdown note: transcription is split into chunks
+// which fit LLM context, every chunk is summarized and summaries are merged into one note,
+// optionally with quiz generated from it
+package lecture
+
+import (
+	"context"
+	"errors"
+	"flicker/internal/quiz"
+	"flicker/internal/summary"
+	"flicker/internal/transcript"
+	"flicker/internal/views"
+	"fmt"
+	"io"
+	"time"
+	"unicode/utf8"
+
+	"github.com/autumnterror/breezynotes/pkg/utils/format"
+)
+
+var (
+	ErrTranscription = errors.New("transcription failed")
+	ErrEmpty         = errors.New("nothing recognized in recording")
+	ErrSummary       = errors.New("summarization failed")
+	ErrQuiz          = errors.New("quiz generation failed")
+)
+
+const (
+	quizHook     = "gentest"
+	quizAttempts = 3
+)
+
+// Transcriber turn audio into text with segments, e.g. *transcript.Client
+type Transcriber interface {
+	Transcribe(ctx context.Context, filename string, r io.Reader) (*views.TranscriberServiceResponse, error)
+}
+
+type Pipeline struct {
+	Transcriber Transcriber
+	// Summarizer make notes of transcript parts of its chunk size
+	Summarizer   *summary.Summarizer
+	LLM          quiz.LLM
+	QuizAttempts int
+}
+
+func New(transcriber Transcriber, summarizer *summary.Summarizer, llm quiz.LLM) *Pipeline {
+	return &Pipeline{
+		Transcriber:  transcriber,
+		Summarizer:   summarizer,
+		LLM:          llm,
+		QuizAttempts: quizAttempts,
+	}
+}
+
+type Request struct {
+	Filename string
+	Audio    io.Reader
+	// Title is heading of merged note, default is used when it is empty
+	Title string
+	Quiz  bool
+}
+
+// Run process recording stage by stage. progress is called when stage starts, advances and ends;
+// it may be nil
+func (p *Pipeline) Run(ctx context.Context, r Request, progress func(views.LectureStage)) (*views.LectureResponse, error) {
+	const op = "lecture.Pipeline.Run"
+
+	t := &tracker{progress: progress}
+	res := &views.LectureResponse{}
+
+	s := t.begin(views.StageTranscribe, 0)
+	tr, err := p.Transcriber.Transcribe(ctx, r.Filename, r.Audio)
+	if err != nil {
+		return nil, format.Error(op, s.fail(ErrTranscription, err))
+	}
+	segments := transcript.Segments(tr.Segments, tr.Text, tr.DurationSeconds)
+	if len(segments) == 0 {
+		return nil, format.Error(op, s.fail(ErrEmpty, nil))
+	}
+	res.Transcript = views.TranscribeResponse{
+		Text:            tr.Text,
+		Filename:        tr.Filename,
+		DurationSeconds: tr.DurationSeconds,
+		Language:        tr.Language,
+		Model:           tr.Model,
+		Segments:        segments,
+	}
+	s.end()
+
+	s = t.begin(views.StageChunk, 0)
+	chunks := Chunks(segments, p.Summarizer.ChunkTokens)
+	s.Done, s.Total = len(chunks), len(chunks)
+	s.end()
+
+	s = t.begin(views.StageSummarize, len(chunks))
+	texts := make([]string, len(chunks))
+	for i, c := range chunks {
+		texts[i] = c.Text
+	}
+	notes, failed, err := p.Summarizer.Map(ctx, texts, views.SummaryOptions{}, s.step)
+	if err != nil {
+		return nil, format.Error(op, s.fail(ErrSummary, err))
+	}
+	for i, c := range chunks {
+		res.Chunks = append(res.Chunks, views.LectureChunk{
+			Index:      i,
+			StartSec:   c.Start,
+			EndSec:     c.End,
+			Characters: utf8.RuneCountInString(c.Text),
+			Summary:    notes[i],
+		})
+	}
+	for _, i := range failed {
+		res.Chunks[i].Failed = true
+	}
+	if len(failed) > 0 {
+		res.Warnings = append(res.Warnings, fmt.Sprintf("%d of %d parts are not summarized", len(failed), len(chunks)))
+	}
+	s.end()
+
+	s = t.begin(views.StageMerge, 0)
+	res.Markdown = Merge(r.Title, res.Chunks)
+	s.end()
+
+	if r.Quiz {
+		s = t.begin(views.StageQuiz, 0)
+		q, err := quiz.Generate(ctx, p.LLM, quizHook, res.Markdown, views.QuizOptions{}, p.QuizAttempts)
+		if err != nil {
+			return nil, format.Error(op, s.fail(ErrQuiz, err))
+		}
+		res.Quiz = q
+		s.end()
+	}
+
+	res.Stages = t.stages
+	return res, nil
+}
+
+// tracker collect finished stages and report every change of them
+type tracker struct {
+	stages   []views.LectureStage
+	progress func(views.LectureStage)
+}
+
+type stage struct {
+	views.LectureStage
+	t       *tracker
+	started time.Time
+}
+
+// begin start stage of total steps, 0 if it is done at once
+func (t *tracker) begin(name string, total int) *stage {
+	s := &stage{
+		LectureStage: views.LectureStage{Name: name, Status: views.StageRunning, Total: total},
+		t:            t,
+		started:      time.Now(),
+	}
+	t.report(s.LectureStage)
+	return s
+}
+
+func (t *tracker) report(s views.LectureStage) {
+	if t.progress != nil {
+		t.progress(s)
+	}
+}
+
+func (s *stage) step(done, total int) {
+	s.Done, s.Total = done, total
+	s.t.report(s.LectureStage)
+}
+
+func (s *stage) end() {
+	s.Status = views.StageDone
+	s.DurationMs = time.Since(s.started).Milliseconds()
+	s.t.stages = append(s.t.stages, s.LectureStage)
+	s.t.report(s.LectureStage)
+}
+
+// fail finish stage with error of kind and return err wrapped in kind.
+// Only kind is reported, err may contain details of upstream services
+func (s *stage) fail(kind, err error) error {
+	s.Status = views.StageFailed
+	s.DurationMs = time.Since(s.started).Milliseconds()
+	s.Error = kind.Error()
+	s.t.stages = append(s.t.stages, s.LectureStage)
+	s.t.report(s.LectureStage)
+	if err == nil {
+		return kind
+	}
+	return fmt.Errorf("%w: %w", kind, err)
+}
@@ -0,0 +1,198 @@
+package lecture
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flicker/internal/summary"
+	"flicker/internal/views"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeTranscriber struct {
+	resp *views.TranscriberServiceResponse
+	err  error
+}
+
+func (f fakeTranscriber) Transcribe(_ context.Context, _ string, r io.Reader) (*views.TranscriberServiceResponse, error) {
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return nil, err
+	}
+	return f.resp, f.err
+}
+
+// fakeLLM answer summary hook with heading and length of content, quiz hook with fixed quiz
+type fakeLLM struct {
+	mu    sync.Mutex
+	hooks []string
+	fail  bool
+}
+
+func (f *fakeLLM) Call(_ context.Context, hook string, payload any) (string, error) {
+	f.mu.Lock()
+	f.hooks = append(f.hooks, hook)
+	f.mu.Unlock()
+
+	if hook == quizHook {
+		return `{"title": "Тест", "questions": [{"type": "single", "text": "2+2?", "options": ["3", "4"], "correct": [1], "difficulty": "easy"}]}`, nil
+	}
+	if f.fail {
+		return "", errors.New("boom")
+	}
+	b, _ := json.Marshal(payload)
+	var req struct{ Content string }
+	_ = json.Unmarshal(b, &req)
+	return fmt.Sprintf("# Тема\n\n%d символов", utf8.RuneCountInString(req.Content)), nil
+}
+
+func newPipeline(tr Transcriber, llm *fakeLLM) *Pipeline {
+	return New(tr, &summary.Summarizer{LLM: llm, ChunkTokens: 150, Concurrency: 2}, llm)
+}
+
+func segments(n int, text string) []views.TranscriptSegment {
+	segs := make([]views.TranscriptSegment, n)
+	for i := range segs {
+		segs[i] = views.TranscriptSegment{Index: i, StartSec: float64(i * 60), EndSec: float64(i*60 + 60), Text: text}
+	}
+	return segs
+}
+
+func TestChunks(t *testing.T) {
+	t.Parallel()
+
+	sentence := strings.Repeat("слово ", 15) + "конец." // 96 runes
+	chunks := Chunks(segments(10, sentence), 150)
+
+	assert.Len(t, chunks, 4)
+	total := 0
+	for i, c := range chunks {
+		n := utf8.RuneCountInString(c.Text)
+		assert.LessOrEqual(t, summary.Tokens(c.Text), 150)
+		total += n
+		if i > 0 {
+			assert.Equal(t, chunks[i-1].End, c.Start)
+		}
+	}
+	assert.Equal(t, 0.0, chunks[0].Start)
+	assert.Equal(t, 180.0, chunks[0].End)
+	assert.Equal(t, 600.0, chunks[3].End)
+	// segments are joined by single space
+	assert.Equal(t, 10*96+9-3, total)
+}
+
+func TestChunksLongSegment(t *testing.T) {
+	t.Parallel()
+
+	long := strings.Repeat("Одно предложение. ", 50)
+	chunks := Chunks([]views.TranscriptSegment{{StartSec: 0, EndSec: 100, Text: long}}, 100)
+
+	assert.Greater(t, len(chunks), 4)
+	for _, c := range chunks {
+		assert.LessOrEqual(t, summary.Tokens(c.Text), 100)
+		assert.True(t, strings.HasSuffix(c.Text, "."))
+	}
+	assert.Equal(t, 100.0, chunks[len(chunks)-1].End)
+}
+
+func TestMerge(t *testing.T) {
+	t.Parallel()
+
+	chunks := []views.LectureChunk{
+		{StartSec: 0, EndSec: 1260, Summary: "# Энтропия\n\n## Определение\n\n```\n# не заголовок\n```\n#хештег"},
+		{StartSec: 1260, EndSec: 3725, Summary: "##### Мелкий\n###### Самый мелкий"},
+		{StartSec: 3725, EndSec: 4000, Failed: true},
+	}
+
+	md := Merge("", chunks)
+	assert.Equal(t, "# Конспект лекции\n"+
+		"\n## Часть 1 · 00:00:00–00:21:00\n\n"+
+		"### Энтропия\n\n#### Определение\n\n```\n# не заголовок\n```\n#хештег\n"+
+		"\n## Часть 2 · 00:21:00–01:02:05\n\n"+
+		"###### Мелкий\n###### Самый мелкий\n"+
+		"\n## Часть 3 · 01:02:05–01:06:40\n\n"+failedNote+"\n", md)
+
+	single := []views.LectureChunk{{Summary: "# Один"}}
+	assert.Equal(t, "# Один", Merge("Лекция", single))
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	llm := &fakeLLM{}
+	p := newPipeline(fakeTranscriber{resp: &views.TranscriberServiceResponse{
+		Filename:        "lecture.mp3",
+		DurationSeconds: 600,
+		Text:            "весь текст",
+		Segments:        segments(10, strings.Repeat("слово ", 15)+"конец."),
+	}}, llm)
+
+	var events []views.LectureStage
+	res, err := p.Run(context.Background(), Request{
+		Filename: "lecture.mp3",
+		Audio:    strings.NewReader("audio"),
+		Title:    "Термодинамика",
+		Quiz:     true,
+	}, func(s views.LectureStage) { events = append(events, s) })
+	assert.NoError(t, err)
+
+	assert.Equal(t, "весь текст", res.Transcript.Text)
+	assert.Len(t, res.Transcript.Segments, 10)
+	assert.Len(t, res.Chunks, 4)
+	assert.Equal(t, "# Тема\n\n290 символов", res.Chunks[0].Summary)
+	assert.True(t, strings.HasPrefix(res.Markdown, "# Термодинамика\n\n## Часть 1 · 00:00:00–00:03:00\n\n### Тема\n"))
+	assert.Equal(t, "Тест", res.Quiz.Title)
+	assert.Equal(t, []string{summary.MapHook, summary.MapHook, summary.MapHook, summary.MapHook, quizHook}, llm.hooks)
+
+	var names []string
+	for _, s := range res.Stages {
+		assert.Equal(t, views.StageDone, s.Status)
+		names = append(names, s.Name)
+	}
+	assert.Equal(t, []string{views.StageTranscribe, views.StageChunk, views.StageSummarize, views.StageMerge, views.StageQuiz}, names)
+
+	var steps []int
+	for _, s := range events {
+		if s.Name == views.StageSummarize && s.Status == views.StageRunning && s.Total > 0 {
+			assert.Equal(t, 4, s.Total)
+			steps = append(steps, s.Done)
+		}
+	}
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, steps)
+	assert.Equal(t, res.Stages[len(res.Stages)-1], events[len(events)-1])
+}
+
+func TestRunErrors(t *testing.T) {
+	t.Parallel()
+
+	ok := &views.TranscriberServiceResponse{Text: "текст", Segments: segments(3, strings.Repeat("слово ", 40))}
+	tests := []struct {
+		name        string
+		transcriber fakeTranscriber
+		fail        bool
+		err         error
+		stage       string
+	}{
+		{"transcriber down", fakeTranscriber{err: errors.New("down")}, false, ErrTranscription, views.StageTranscribe},
+		{"silence", fakeTranscriber{resp: &views.TranscriberServiceResponse{Text: "  "}}, false, ErrEmpty, views.StageTranscribe},
+		{"summary", fakeTranscriber{resp: ok}, true, ErrSummary, views.StageSummarize},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var last views.LectureStage
+			p := newPipeline(tt.transcriber, &fakeLLM{fail: tt.fail})
+
+			_, err := p.Run(context.Background(), Request{Audio: strings.NewReader("audio")}, func(s views.LectureStage) { last = s })
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.stage, last.Name)
+			assert.Equal(t, views.StageFailed, last.Status)
+			assert.Equal(t, tt.err.Error(), last.Error)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+// Package llmjson pull JSON out of LLM answers, which like to wrap it in markdown and chatter
+package llmjson
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrNoJSON = errors.New("no JSON found in output")
+
+// Extract find first JSON object or array in text, ignoring markdown fences and chatter around it
+func Extract(raw string) (string, error) {
+	start := strings.IndexAny(raw, "{[")
+	if start < 0 {
+		return "", ErrNoJSON
+	}
+
+	depth, inString, escaped := 0, false, false
+	for i := start; i < len(raw); i++ {
+		ch := raw[i]
+		switch {
+		case escaped:
+			escaped = false
+		case inString && ch == '\\':
+			escaped = true
+		case ch == '"':
+			inString = !inString
+		case inString:
+		case ch == '{' || ch == '[':
+			depth++
+		case ch == '}' || ch == ']':
+			depth--
+			if depth == 0 {
+				return dropTrailingCommas(raw[start : i+1]), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%w: unbalanced brackets", ErrNoJSON)
+}
+
+// dropTrailingCommas remove commas before closing brackets, LLM likes to leave them
+func dropTrailingCommas(js string) string {
+	var sb strings.Builder
+	inString, escaped := false, false
+	for i := 0; i < len(js); i++ {
+		ch := js[i]
+		switch {
+		case escaped:
+			escaped = false
+		case inString && ch == '\\':
+			escaped = true
+		case ch == '"':
+			inString = !inString
+		case !inString && ch == ',':
+			j := i + 1
+			for j < len(js) && strings.IndexByte(" \t\r\n", js[j]) >= 0 {
+				j++
+			}
+			if j < len(js) && (js[j] == '}' || js[j] == ']') {
+				continue
+			}
+		}
+		sb.WriteByte(ch)
+	}
+	return sb.String()
+}
@@ -0,0 +1,120 @@
+// Package metrics is Prometheus metrics of flicker: HTTP requests, calls to upstreams, bcrypt,
+// issued tokens and pool of postgres connections. They are served by Handler on /metrics
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "flicker"
+
+// reasons of upstream errors
+const (
+	ErrorConnection = "connection"
+	ErrorStatus     = "status"
+	ErrorOpen       = "breaker_open"
+)
+
+// Registry has metrics of flicker, Go runtime and process. Own registry is used instead of
+// default one, so libraries can't add metrics unnoticed
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPDuration of answered requests by route pattern, e.g. /api/notes/:id, so ids don't
+	// make new series. Requests without route are counted with route "unmatched"
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of HTTP requests by method, route and status.",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 300},
+	}, []string{"method", "route", "status"})
+
+	// UpstreamDuration of every attempt of request to upstream. Status is "error" when answer
+	// wasn't received
+	UpstreamDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_request_duration_seconds",
+		Help:      "Duration of attempts of requests to upstreams by upstream and status.",
+		Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"upstream", "status"})
+
+	// UpstreamErrors are failed attempts: connection errors, 5xx and 429 answers and requests
+	// refused by open breaker
+	UpstreamErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_errors_total",
+		Help:      "Failed attempts of requests to upstreams by upstream and reason.",
+	}, []string{"upstream", "reason"})
+
+	// Bcrypt is duration of hashing of new passwords and comparing on authentication
+	Bcrypt = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "bcrypt_duration_seconds",
+		Help:      "Duration of bcrypt operations: hash or compare.",
+		Buckets:   []float64{.01, .025, .05, .075, .1, .15, .2, .3, .5, 1},
+	}, []string{"op"})
+
+	// TokensIssued by type, ACCESS or REFRESH. Access tokens given by refresh are counted too
+	TokensIssued = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tokens_issued_total",
+		Help:      "Issued JWT by type.",
+	}, []string{"type"})
+
+	// TokenRefreshes by result: ok, expired or invalid
+	TokenRefreshes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "token_refreshes_total",
+		Help:      "Refreshes of access token by result.",
+	}, []string{"result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPDuration,
+		UpstreamDuration,
+		UpstreamErrors,
+		Bcrypt,
+		TokensIssued,
+		TokenRefreshes,
+	)
+}
+
+// RegisterDB add stats of connection pool of db, sql.DB.Stats, with label db_name
+func RegisterDB(db *sql.DB, name string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler serve metrics of Registry in Prometheus format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveHTTP record answered request. Methods other than standard ones are counted as "other",
+// so clients can't make new series
+func ObserveHTTP(method, route string, status int, d time.Duration) {
+	if route == "" {
+		route = "unmatched"
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions, http.MethodConnect, http.MethodTrace:
+	default:
+		method = "other"
+	}
+	HTTPDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(d.Seconds())
+}
+
+// Since observe time passed from start in histogram of label, e.g. Since(Bcrypt, "hash", start)
+func Since(h *prometheus.HistogramVec, label string, start time.Time) {
+	h.WithLabelValues(label).Observe(time.Since(start).Seconds())
+}
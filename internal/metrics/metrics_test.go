@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func TestHandler(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	RegisterDB(db, "test")
+
+	ObserveHTTP(http.MethodGet, "/api/notes/:id", http.StatusOK, 30*time.Millisecond)
+	ObserveHTTP(http.MethodGet, "", http.StatusNotFound, time.Millisecond)
+	ObserveHTTP("XYZ1", "", http.StatusMethodNotAllowed, time.Millisecond)
+	ObserveHTTP("XYZ2", "", http.StatusMethodNotAllowed, time.Millisecond)
+	TokensIssued.WithLabelValues("ACCESS").Inc()
+	Since(Bcrypt, "compare", time.Now())
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	body, _ := io.ReadAll(w.Body)
+	for _, s := range []string{
+		`flicker_http_request_duration_seconds_count{method="GET",route="/api/notes/:id",status="200"} 1`,
+		`flicker_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`,
+		`flicker_http_request_duration_seconds_count{method="other",route="unmatched",status="405"} 2`,
+		`flicker_tokens_issued_total{type="ACCESS"} 1`,
+		`flicker_bcrypt_duration_seconds_count{op="compare"} 1`,
+		`go_sql_max_open_connections{db_name="test"} 0`,
+		`go_goroutines`,
+	} {
+		assert.Contains(t, string(body), s)
+	}
+}
@@ -0,0 +1,88 @@
+package n8n
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flicker/internal/config"
+	"flicker/internal/prompt"
+	"flicker/internal/upstream"
+	"flicker/internal/views"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/autumnterror/breezynotes/pkg/utils/format"
+)
+
+var (
+	ErrRequest  = errors.New("n8n request error")
+	ErrStatus   = errors.New("n8n returned non-200")
+	ErrResponse = errors.New("bad n8n response")
+)
+
+// Client call n8n webhooks which answer with {"output": "..."}
+type Client struct {
+	url  string
+	http *upstream.Client
+	// secret sign requests, empty one sends them unsigned
+	secret string
+}
+
+func New(cfg *config.Config) *Client {
+	return &Client{
+		url:    strings.TrimRight(cfg.N8nURL, "/"),
+		http:   upstream.New(upstream.N8n, upstream.FromConfig(cfg, upstream.N8n)),
+		secret: cfg.N8nSecret,
+	}
+}
+
+// Call send payload as JSON to webhook/<hook> and return output field of answer. If ctx has
+// template of hook, rendered prompt is sent with payload. Request is signed if client has secret
+func (c *Client) Call(ctx context.Context, hook string, payload any) (string, error) {
+	const op = "n8n.Client.Call"
+
+	payload, err := prompt.Apply(ctx, hook, payload)
+	if err != nil {
+		return "", format.Error(op, err)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", format.Error(op, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url+"/webhook/"+hook, bytes.NewReader(body))
+	if err != nil {
+		return "", format.Error(op, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.secret != "" {
+		Sign(req, ContentHash(body), c.secret, time.Now())
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", format.Error(op, fmt.Errorf("%w: %w", ErrRequest, err))
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", format.Error(op, fmt.Errorf("%w: %w", ErrRequest, err))
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", format.Error(op, fmt.Errorf("%w: status: %s, body: %s", ErrStatus, resp.Status, string(respBody)))
+	}
+
+	var out views.N8nResponse
+	if err := json.Unmarshal(respBody, &out); err != nil {
+		return "", format.Error(op, fmt.Errorf("%w: %w", ErrResponse, err))
+	}
+
+	return out.Output, nil
+}
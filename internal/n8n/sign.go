@@ -0,0 +1,83 @@
+package n8n
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Requests between flicker and n8n are signed by shared secret. Signature is "sha256=" and hex
+// HMAC-SHA256 of timestamp, method, path and hex SHA-256 of body joined by "\n"
+const (
+	HeaderTimestamp   = "X-Flicker-Timestamp"
+	HeaderContentHash = "X-Flicker-Content-Sha256"
+	HeaderSignature   = "X-Flicker-Signature"
+)
+
+// UnsignedPayload is content hash of streamed body, which is not hashed before sending
+const UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+// DefaultTolerance is max difference between timestamp of signed request and clock of receiver
+const DefaultTolerance = 5 * time.Minute
+
+var ErrSignature = errors.New("bad n8n signature")
+
+// ContentHash return value of HeaderContentHash for body
+func ContentHash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// Sign set signature headers of request. contentHash is ContentHash of body or UnsignedPayload
+func Sign(r *http.Request, contentHash, secret string, now time.Time) {
+	ts := strconv.FormatInt(now.Unix(), 10)
+	r.Header.Set(HeaderTimestamp, ts)
+	r.Header.Set(HeaderContentHash, contentHash)
+	r.Header.Set(HeaderSignature, signature(secret, ts, r.Method, r.URL.Path, contentHash))
+}
+
+func signature(secret, ts, method, path, contentHash string) string {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write([]byte(strings.Join([]string{ts, method, path, contentHash}, "\n")))
+	return "sha256=" + hex.EncodeToString(m.Sum(nil))
+}
+
+// Verify check signature of inbound request, e.g. callback of n8n workflow, and that it is not
+// older than tolerance. Body is read and put back for handler, unsigned payload is not accepted.
+// Sends ErrSignature
+func Verify(r *http.Request, secret string, now time.Time, tolerance time.Duration) error {
+	ts := r.Header.Get(HeaderTimestamp)
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: bad timestamp", ErrSignature)
+	}
+	if d := now.Sub(time.Unix(sec, 0)); d > tolerance || d < -tolerance {
+		return fmt.Errorf("%w: timestamp is out of tolerance", ErrSignature)
+	}
+
+	hash := r.Header.Get(HeaderContentHash)
+	if !hmac.Equal([]byte(r.Header.Get(HeaderSignature)), []byte(signature(secret, ts, r.Method, r.URL.Path, hash))) {
+		return fmt.Errorf("%w: signature mismatch", ErrSignature)
+	}
+
+	var body []byte
+	if r.Body != nil {
+		if body, err = io.ReadAll(r.Body); err != nil {
+			return err
+		}
+		r.Body.Close()
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if !hmac.Equal([]byte(hash), []byte(ContentHash(body))) {
+		return fmt.Errorf("%w: content hash mismatch", ErrSignature)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package n8n
+
+import (
+	"context"
+	"encoding/json"
+	"flicker/internal/config"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCallSigned(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Test()
+	secret := cfg.N8nSecret
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := Verify(r, secret, time.Now(), DefaultTolerance); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		_ = json.NewEncoder(w).Encode(map[string]string{"output": string(b)})
+	}))
+	defer srv.Close()
+	cfg.N8nURL = srv.URL
+
+	out, err := New(cfg).Call(context.Background(), "generatemd", map[string]string{"content": "text"})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"content":"text"}`, out)
+
+	cfg.N8nSecret = "other"
+	_, err = New(cfg).Call(context.Background(), "generatemd", map[string]string{"content": "text"})
+	assert.ErrorIs(t, err, ErrStatus)
+}
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	const secret = "secret"
+	now := time.Unix(1_700_000_000, 0)
+	body := `{"job":"1"}`
+	signed := func() *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/api/n8n/callback", strings.NewReader(body))
+		Sign(r, ContentHash([]byte(body)), secret, now)
+		return r
+	}
+
+	r := signed()
+	assert.NoError(t, Verify(r, secret, now.Add(time.Minute), DefaultTolerance))
+	b, _ := io.ReadAll(r.Body)
+	assert.Equal(t, body, string(b))
+
+	assert.ErrorIs(t, Verify(signed(), "other", now, DefaultTolerance), ErrSignature)
+	assert.ErrorIs(t, Verify(signed(), secret, now.Add(10*time.Minute), DefaultTolerance), ErrSignature)
+
+	r = signed()
+	r.Body = io.NopCloser(strings.NewReader(`{"job":"2"}`))
+	assert.ErrorIs(t, Verify(r, secret, now, DefaultTolerance), ErrSignature)
+
+	r = signed()
+	r.URL.Path = "/api/other"
+	assert.ErrorIs(t, Verify(r, secret, now, DefaultTolerance), ErrSignature)
+
+	r = httptest.NewRequest(http.MethodPost, "/api/n8n/callback", strings.NewReader(body))
+	Sign(r, UnsignedPayload, secret, now)
+	assert.ErrorIs(t, Verify(r, secret, now, DefaultTolerance), ErrSignature)
+
+	r = httptest.NewRequest(http.MethodPost, "/api/n8n/callback", strings.NewReader(body))
+	assert.ErrorIs(t, Verify(r, secret, now, DefaultTolerance), ErrSignature)
+}
@@ -3,28 +3,72 @@ package net
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"flicker/internal/filecheck"
+	"flicker/internal/ingest"
+	"flicker/internal/n8n"
+	"flicker/internal/prompt"
+	"flicker/internal/quiz"
+	"flicker/internal/rag"
+	"flicker/internal/stream"
+	"flicker/internal/summary"
+	"flicker/internal/transcript"
 	"flicker/internal/views"
 	"fmt"
 	"io"
-	"mime/multipart"
+	"mime"
 	"net/http"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/autumnterror/breezynotes/pkg/log"
+	"github.com/autumnterror/breezynotes/pkg/utils/id"
 	"github.com/labstack/echo/v4"
 )
 
+// maxQuizAttempts сколько раз просим LLM сгенерировать тест, прежде чем сдаться
+const maxQuizAttempts = 3
+
+const (
+	defaultAskTopK = 5
+	maxAskTopK     = 20
+)
+
+// summaryTimeout covers map and reduce passes over long text
+const summaryTimeout = 10 * time.Minute
+
+// summaryError is message for client on summarization failure
+func summaryError(err error) string {
+	switch {
+	case errors.Is(err, n8n.ErrStatus), errors.Is(err, summary.ErrSummary), errors.Is(err, summary.ErrEmptyOutput):
+		return "markdown generation error"
+	case errors.Is(err, n8n.ErrResponse):
+		return "bad n8n response"
+	}
+	return "n8n request error"
+}
+
 // GenerateMarkdown godoc
 // @Summary Generate Markdown summary
-// @Description Принимает текст и отправляет его в n8n webhook, который генерирует Markdown-конспект через LLM
+// @Description Принимает текст и отправляет его в n8n webhook, который генерирует Markdown-конспект через LLM. Текст длиннее контекста LLM режется на части по summary_chunk_tokens токенов с перекрытием, части конспектируются параллельно, неудачные вызовы повторяются, затем конспекты частей сводятся в один (webhook reducemd) с единой структурой заголовков: # title, ## раздел, ### подраздел. Поле options задаёт язык конспекта (тег BCP 47), подробность (brief, standard, detailed) и стиль (outline — план, cornell — конспект Корнелла, mindmap — карта памяти); неизвестные значения отклоняются с 400, выбранные параметры передаются в n8n вместе с инструкциями для LLM. Если часть так и не удалось законспектировать, конспект собирается из остальных, а их номера возвращаются в failed_chunks; такой конспект не кешируется. При save=true конспект сохраняется как заметка текущего пользователя. Результат кешируется по хешу нормализованного текста, options, версии промпта и модели: повторный запрос отдаётся из кеша (заголовок X-Cache: HIT), Cache-Control: no-cache запрашивает новую генерацию, no-store ещё и не сохраняет её
 // @Tags ai
 // @Accept json
 // @Produce json
 // @Param Content body views.GenerateMDRequest true "Text content to summarize"
+// @Param Cache-Control header string false "no-cache to generate again, no-store to also not cache result"
 // @Success 200 {object} views.MarkdownResponse
+// @Header 200 {string} X-Cache "HIT, MISS or BYPASS"
 // @Failure 400 {object} views.SWGError
+// @Failure 401 {object} views.SWGError
+// @Failure 429 {object} views.SWGError
+// @Header 429 {string} Retry-After "Seconds until quota is reset"
 // @Failure 502 {object} views.SWGError
+// @Failure 503 {object} views.SWGError
+// @Header 503 {string} Retry-After "Seconds until upstream is called again"
 // @Router /api/ai/generatemd [post]
 func (e *Echo) GenerateMarkdown(c echo.Context) error {
 	const op = "net.GenerateMarkdown"
@@ -40,64 +84,67 @@ func (e *Echo) GenerateMarkdown(c echo.Context) error {
 		log.Warn(op, "empty content", nil)
 		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "content is required"})
 	}
-
-	ctx, done := context.WithTimeout(c.Request().Context(), 30*time.Second)
-	defer done()
-
-	n8nURL := "http://n8n:5678/webhook/generatemd"
-
-	payload := struct {
-		Content string `json:"content"`
-	}{
-		Content: r.Content,
-	}
-
-	bodyBytes, err := json.Marshal(payload)
-	if err != nil {
-		log.Error(op, "marshal request body", err)
-		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "bad argument"})
-	}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n8nURL, bytes.NewReader(bodyBytes))
-	if err != nil {
-		log.Error(op, "create request to n8n", err)
-		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot create request to n8n"})
+	if err := r.Options.Validate(); err != nil {
+		log.Warn(op, "bad options", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: err.Error()})
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	client := http.DefaultClient
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Error(op, "request to n8n", err)
-		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "n8n request error"})
+	var owner string
+	if r.Save {
+		id, err := e.noteOwner(c, r.SaveAsNote)
+		if err != nil {
+			log.Warn(op, "cannot save note", err)
+			if errors.Is(err, errBadNoteSource) {
+				return c.JSON(http.StatusBadRequest, views.SWGError{Error: "unknown source_type"})
+			}
+			return unauthorized(c, err)
+		}
+		owner = id
 	}
-	defer resp.Body.Close()
 
-	respBody, err := io.ReadAll(resp.Body)
+	set, err := e.promptSet(c.Request().Context(), cachedPrompts[promptMarkdown], withOptions(r.Prompt, r.Options.Language, r.Options.Detail, r.Options.Style))
 	if err != nil {
-		log.Error(op, "read n8n response", err)
-		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot read n8n response"})
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		log.Warn(op, "n8n returned non-200", fmt.Errorf("status: %s, body: %s", resp.Status, string(respBody)))
-		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "markdown generation error"})
-	}
-
-	var n8nResp views.N8nResponse
-	if err := json.Unmarshal(respBody, &n8nResp); err != nil {
-		log.Error(op, "unmarshal n8n response", err)
-		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "bad n8n response"})
+		return promptError(c, op, err)
+	}
+
+	// заголовок попадает в сводный конспект, поэтому он тоже часть ключа
+	key := e.cacheKey(promptMarkdown, set, append([]string{r.Title, r.Content}, optionsInput(r.Options)...)...)
+	var res views.MarkdownResponse
+	if !e.cacheGet(c, key, &res) {
+		// длинный текст конспектируется по частям, каждая часть — отдельный вызов LLM
+		ctx, done := context.WithTimeout(prompt.With(c.Request().Context(), set), summaryTimeout)
+		defer done()
+
+		sum, err := e.summarizer.Summarize(ctx, r.Title, r.Content, r.Options)
+		if err != nil {
+			log.Error(op, "", err)
+			return upstreamError(c, err, http.StatusBadGateway, summaryError(err))
+		}
+		res = views.MarkdownResponse{
+			Markdown:     sum.Markdown,
+			Chunks:       sum.Chunks,
+			FailedChunks: sum.Failed,
+			Warnings:     sum.Warnings,
+		}
+		usageOf(c).Tokens = llmTokens(r.Content, sum.Markdown)
+		// конспект с пропусками не кешируем, следующий запрос может получить полный
+		if len(sum.Warnings) == 0 {
+			e.cachePut(c, promptMarkdown, set, key, res)
+		}
+	}
+	res.PromptVersion = set.Version()
+	if r.Save {
+		nid, err := e.saveNote(c.Request().Context(), owner, r.SaveAsNote, views.NoteSourceText, res.Markdown, res.PromptVersion)
+		if err != nil {
+			log.Error(op, "save note", err)
+			return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot save note"})
+		}
+		res.NoteId = nid
 	}
 
-	// markdown лежит здесь
-	md := n8nResp.Output
-
 	log.Success(op, "")
 
-	return c.JSON(http.StatusOK, views.MarkdownResponse{
-		Markdown: md,
-	})
+	return c.JSON(http.StatusOK, res)
 }
 
 // GenerateMarkdown godoc
@@ -109,7 +156,11 @@ func (e *Echo) GenerateMarkdown(c echo.Context) error {
 // @Param Content body views.GenerateMDRequest true "Text content to summarize"
 // @Success 200 {object} views.MarkdownResponse
 // @Failure 400 {object} views.SWGError
+// @Failure 429 {object} views.SWGError
+// @Header 429 {string} Retry-After "Seconds until quota is reset"
 // @Failure 502 {object} views.SWGError
+// @Failure 503 {object} views.SWGError
+// @Header 503 {string} Retry-After "Seconds until upstream is called again"
 // @Router /api/ai/generatemd-test [post]
 func (e *Echo) GenerateMarkdownTest(c echo.Context) error {
 	const op = "net.GenerateMarkdown"
@@ -149,12 +200,14 @@ func (e *Echo) GenerateMarkdownTest(c echo.Context) error {
 		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot create request to n8n"})
 	}
 	req.Header.Set("Content-Type", "application/json")
+	if e.cfg.N8nSecret != "" {
+		n8n.Sign(req, n8n.ContentHash(bodyBytes), e.cfg.N8nSecret, time.Now())
+	}
 
-	client := http.DefaultClient
-	resp, err := client.Do(req)
+	resp, err := e.n8nTest.Do(req)
 	if err != nil {
 		log.Error(op, "request to n8n", err)
-		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "n8n request error"})
+		return upstreamError(c, err, http.StatusBadGateway, "n8n request error")
 	}
 	defer resp.Body.Close()
 
@@ -178,199 +231,226 @@ func (e *Echo) GenerateMarkdownTest(c echo.Context) error {
 
 // TranscribeAudio godoc
 // @Summary Transcribe audio file
-// @Description Принимает аудио-файл, отправляет его в сервис транскрипции и возвращает текст
+// @Description Принимает аудио- или видеофайл или id завершённой загрузки /api/uploads, отправляет его в сервис транскрипции и возвращает текст с сегментами по времени, субтитры SRT или WebVTT либо текст с метками времени. Тип файла определяется по содержимому, архивы и исполняемые файлы отклоняются. Если запрос с токеном, по завершении зарегистрированным webhook пользователя отправляется событие transcription.completed или transcription.failed
 // @Tags ai
 // @Accept mpfd
-// @Produce json
-// @Param file formData file true "Audio file"
+// @Produce json,application/x-subrip,text/vtt,text/plain
+// @Param file formData file false "Audio file"
+// @Param upload_id formData string false "Id of completed upload, used instead of file"
+// @Param format query string false "Response format: json with segments, srt or vtt subtitles, text with timestamps" Enums(json, srt, vtt, text)
+// @Param save formData bool false "Save transcript as note"
+// @Param title formData string false "Title of saved note"
 // @Success 200 {object} views.TranscribeResponse
 // @Failure 400 {object} views.SWGError
+// @Failure 401 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 409 {object} views.SWGError
+// @Failure 413 {object} views.SWGError
+// @Failure 415 {object} views.SWGError
+// @Failure 429 {object} views.SWGError
+// @Header 429 {string} Retry-After "Seconds until quota is reset"
 // @Failure 502 {object} views.SWGError
+// @Failure 503 {object} views.SWGError
+// @Header 503 {string} Retry-After "Seconds until upstream is called again"
 // @Router /api/ai/transcribe [post]
 func (e *Echo) TranscribeAudio(c echo.Context) error {
 	const op = "net.TranscribeAudio"
 	log.Info(op, "")
 
-	// Получаем файл из запроса
-	fileHeader, err := c.FormFile("file")
-	if err != nil {
-		log.Error(op, "form file", err)
-		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "file is required"})
-	}
-
-	file, err := fileHeader.Open()
+	f := c.QueryParam("format")
+	if f == "" {
+		f = views.TranscriptJSON
+	}
+	if _, ok := transcriptTypes[f]; !ok && f != views.TranscriptJSON {
+		log.Warn(op, "bad format", nil)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "format must be one of json, srt, vtt, text"})
+	}
+
+	save := views.SaveAsNote{
+		Save:       c.FormValue("save") == "true",
+		Title:      c.FormValue("title"),
+		SourceType: views.NoteSourceTranscript,
+	}
+	// загрузки принадлежат пользователю, поэтому для upload_id нужен токен
+	var owner string
+	if save.Save {
+		id, err := e.noteOwner(c, save)
+		if err != nil {
+			log.Warn(op, "cannot save note", err)
+			return unauthorized(c, err)
+		}
+		owner = id
+	} else if c.FormValue("upload_id") != "" {
+		id, err := e.userFromToken(c)
+		if err != nil {
+			log.Warn(op, "upload of anonymous user", err)
+			return unauthorized(c, err)
+		}
+		owner = id
+	}
+
+	// Получаем файл из запроса или из завершённой загрузки
+	file, filename, err := e.sourceFile(c, owner, filecheck.Media)
 	if err != nil {
-		log.Error(op, "open uploaded file", err)
-		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "cannot open uploaded file"})
+		return sourceError(c, op, err)
 	}
 	defer file.Close()
+	save.SourceRef = filename
 
 	// Таймаут побольше, чем для LLM — аудио может быть длинным
 	ctx, done := context.WithTimeout(c.Request().Context(), 2*time.Minute)
 	defer done()
 
-	// URL Python-сервиса транскрипции.
-	// Можешь вынести в конфиг: e.cfg.TranscriberURL
-	transcriberURL := "http://whisper:8008/transcribe"
-
-	// Готовим multipart/form-data тело для запроса к сервису
-	var buf bytes.Buffer
-	writer := multipart.NewWriter(&buf)
-
-	part, err := writer.CreateFormFile("file", fileHeader.Filename)
-	if err != nil {
-		log.Error(op, "create form file", err)
-		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot create form file for transcriber"})
+	// уведомления о задаче получают только пользователи с токеном
+	caller := owner
+	if caller == "" && e.webhooksAPI != nil {
+		caller, _ = e.userFromToken(c)
 	}
+	failed := views.WebhookEvent{Event: views.EventTranscriptionFailed, Subject: filename}
 
-	if _, err := io.Copy(part, file); err != nil {
-		log.Error(op, "copy file to form", err)
-		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot copy file to transcriber request"})
+	svcResp, err := e.transcriber.Transcribe(ctx, filename, file)
+	if err != nil {
+		log.Error(op, "", err)
+		failed.Error = transcriberError(err)
+		e.notify(ctx, caller, failed)
+		return upstreamError(c, err, http.StatusBadGateway, transcriberError(err))
 	}
+	usageOf(c).AudioSeconds = svcResp.DurationSeconds
 
-	if err := writer.Close(); err != nil {
-		log.Error(op, "close multipart writer", err)
-		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot finalize transcriber request"})
+	res := views.TranscribeResponse{
+		Text:            svcResp.Text,
+		Filename:        svcResp.Filename,
+		DurationSeconds: svcResp.DurationSeconds,
+		Language:        svcResp.Language,
+		Model:           svcResp.Model,
+		Segments:        transcript.Segments(svcResp.Segments, svcResp.Text, svcResp.DurationSeconds),
 	}
-
-	// Создаём запрос к Python-сервису
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, transcriberURL, &buf)
-	if err != nil {
-		log.Error(op, "create request to transcriber", err)
-		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot create request to transcriber"})
+	if save.Save {
+		nid, err := e.saveNote(c.Request().Context(), owner, save, views.NoteSourceTranscript, svcResp.Text, "")
+		if err != nil {
+			log.Error(op, "save note", err)
+			failed.Error = "cannot save note"
+			e.notify(ctx, caller, failed)
+			return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot save note"})
+		}
+		res.NoteId = nid
 	}
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		log.Error(op, "request to transcriber", err)
-		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "transcriber request error"})
+	completed := views.WebhookEvent{Event: views.EventTranscriptionCompleted, Subject: filename, Data: res}
+	if res.NoteId != "" {
+		completed.Subject = res.NoteId
 	}
-	defer resp.Body.Close()
+	e.notify(ctx, caller, completed)
 
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Error(op, "read transcriber response", err)
-		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot read transcriber response"})
-	}
+	log.Success(op, "")
 
-	if resp.StatusCode != http.StatusOK {
-		log.Warn(op, "transcriber returned non-200", fmt.Errorf("status: %s, body: %s", resp.Status, string(respBody)))
-		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "transcription error"})
+	if t, ok := transcriptTypes[f]; ok {
+		if res.NoteId != "" {
+			c.Response().Header().Set(headerNoteId, res.NoteId)
+		}
+		c.Response().Header().Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{
+			"filename": fileName(strings.TrimSuffix(filename, filepath.Ext(filename))) + t.ext,
+		}))
+		return c.Blob(http.StatusOK, t.mime, []byte(t.render(res.Segments)))
 	}
+	return c.JSON(http.StatusOK, res)
+}
 
-	var svcResp views.TranscriberServiceResponse
-	if err := json.Unmarshal(respBody, &svcResp); err != nil {
-		log.Error(op, "unmarshal transcriber response", err)
-		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "bad transcriber response"})
+// transcriberError is message for client on transcription failure
+func transcriberError(err error) string {
+	switch {
+	case errors.Is(err, transcript.ErrStatus):
+		return "transcription error"
+	case errors.Is(err, transcript.ErrResponse):
+		return "bad transcriber response"
 	}
+	return "transcriber request error"
+}
 
-	log.Success(op, "")
+// headerNoteId carry id of saved note when response body is not JSON
+const headerNoteId = "X-Note-Id"
 
-	return c.JSON(http.StatusOK, views.TranscribeResponse{
-		Text:            svcResp.Text,
-		Filename:        svcResp.Filename,
-		DurationSeconds: svcResp.DurationSeconds,
-		Language:        svcResp.Language,
-		Model:           svcResp.Model,
-	})
+var transcriptTypes = map[string]struct {
+	mime, ext string
+	render    func([]views.TranscriptSegment) string
+}{
+	views.TranscriptSRT:  {"application/x-subrip; charset=utf-8", ".srt", transcript.SRT},
+	views.TranscriptVTT:  {"text/vtt; charset=utf-8", ".vtt", transcript.VTT},
+	views.TranscriptText: {"text/plain; charset=utf-8", ".txt", transcript.Text},
 }
 
 // FileToVectorDB godoc
-// @Summary Upload file to vector DB
-// @Description Принимает файл, отправляет его в n8n webhook file2db, который сохраняет данные во векторную БД
+// @Summary Index file for search
+// @Description Сохраняет файл в библиотеку документов (/api/documents), извлекает текст из PDF, DOCX, TXT или MD, режет его на перекрывающиеся фрагменты, считает эмбеддинги и сохраняет их в векторное хранилище текущего пользователя. По этим фрагментам отвечает /api/ai/ask. Вместо файла можно передать id завершённой загрузки /api/uploads. Содержимое файла должно соответствовать расширению. По завершении webhook пользователя получают событие document.indexed или document.failed
 // @Tags ai
 // @Accept mpfd
 // @Produce json
-// @Param file formData file true "File to index"
-// @Success 200 {object} views.File2DBResponse
+// @Param file formData file false "File to index"
+// @Param upload_id formData string false "Id of completed upload, used instead of file"
+// @Success 200 {object} views.IngestReport
 // @Failure 400 {object} views.SWGError
+// @Failure 401 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 409 {object} views.SWGError
+// @Failure 413 {object} views.SWGError
+// @Failure 415 {object} views.SWGError
+// @Failure 429 {object} views.SWGError
+// @Header 429 {string} Retry-After "Seconds until quota is reset"
 // @Failure 502 {object} views.SWGError
+// @Failure 503 {object} views.SWGError
+// @Header 503 {string} Retry-After "Seconds until upstream is called again"
 // @Router /api/ai/file2db [post]
 func (e *Echo) FileToVectorDB(c echo.Context) error {
 	const op = "net.FileToVectorDB"
 	log.Info(op, "")
 
-	// Получаем файл из запроса
-	fileHeader, err := c.FormFile("file")
-	if err != nil {
-		log.Error(op, "form file", err)
-		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "file is required"})
-	}
-
-	file, err := fileHeader.Open()
+	// Получаем файл из запроса или из завершённой загрузки
+	file, filename, err := e.sourceFile(c, userId(c), filecheck.Documents)
 	if err != nil {
-		log.Error(op, "open uploaded file", err)
-		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "cannot open uploaded file"})
+		return sourceError(c, op, err)
 	}
 	defer file.Close()
 
-	// Таймаут: индексирование может занять время (парсинг + эмбеддинги)
-	ctx, done := context.WithTimeout(c.Request().Context(), 2*time.Minute)
-	defer done()
-
-	// URL webhook-а n8n, который занимается записью во векторную БД
-	// лучше вынести в конфиг, например e.cfg.N8nFile2DBURL
-	n8nURL := "http://n8n:5678/webhook/file2db"
-
-	// Готовим multipart/form-data тело для запроса к n8n
-	var buf bytes.Buffer
-	writer := multipart.NewWriter(&buf)
-
-	part, err := writer.CreateFormFile("file", fileHeader.Filename)
+	f, err := ingest.DetectFormat(filename)
 	if err != nil {
-		log.Error(op, "create form file", err)
-		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot create form file for n8n"})
-	}
-
-	if _, err := io.Copy(part, file); err != nil {
-		log.Error(op, "copy file to form", err)
-		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot copy file to n8n request"})
+		log.Warn(op, "", err)
+		status, msg := ingestError(err)
+		return upstreamError(c, err, status, msg)
 	}
 
-	if err := writer.Close(); err != nil {
-		log.Error(op, "close multipart writer", err)
-		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot finalize n8n request"})
-	}
-
-	// Создаём запрос к n8n
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n8nURL, &buf)
+	data, err := io.ReadAll(file)
 	if err != nil {
-		log.Error(op, "create request to n8n", err)
-		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot create request to n8n"})
+		log.Error(op, "read uploaded file", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "cannot read uploaded file"})
 	}
-	req.Header.Set("Content-Type", writer.FormDataContentType())
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		log.Error(op, "request to n8n", err)
-		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "n8n request error"})
-	}
-	defer resp.Body.Close()
+	// Таймаут: индексирование может занять время (парсинг + эмбеддинги)
+	ctx, done := context.WithTimeout(c.Request().Context(), 2*time.Minute)
+	defer done()
 
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Error(op, "read n8n response", err)
-		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot read n8n response"})
+	sum := sha256.Sum256(data)
+	doc := &views.Document{
+		Id:       id.New(),
+		Owner:    userId(c),
+		Filename: filename,
+		Size:     int64(len(data)),
+		Checksum: hex.EncodeToString(sum[:]),
+		MimeType: ingest.MimeType(f),
 	}
-
-	if resp.StatusCode != http.StatusOK {
-		log.Warn(op, "n8n returned non-200", fmt.Errorf("status: %s, body: %s", resp.Status, string(respBody)))
-		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "file2db error"})
+	if err := e.documentsAPI.Create(ctx, doc, data); err != nil {
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot save document"})
 	}
 
-	// Пробуем распарсить JSON-ответ от n8n
-	var n8nResp views.File2DBResponse
-	if err := json.Unmarshal(respBody, &n8nResp); err != nil {
-		log.Error(op, "unmarshal n8n response", err)
-		// если вдруг n8n вернул не-JSON, можно просто вернуть «сырое» тело как message
-		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "bad n8n response"})
+	report, err := e.index(ctx, doc, data)
+	if err != nil {
+		log.Error(op, "", err)
+		status, msg := ingestError(err)
+		return upstreamError(c, err, status, msg)
 	}
 
 	log.Success(op, "")
 
-	// Проксируем JSON от n8n клиенту
-	return c.JSON(http.StatusOK, n8nResp)
+	return c.JSON(http.StatusOK, report)
 }
 
 // FileToVectorDB godoc
@@ -382,23 +462,22 @@ func (e *Echo) FileToVectorDB(c echo.Context) error {
 // @Param file formData file true "File to index"
 // @Success 200 {object} views.File2DBResponse
 // @Failure 400 {object} views.SWGError
+// @Failure 413 {object} views.SWGError
+// @Failure 415 {object} views.SWGError
+// @Failure 429 {object} views.SWGError
+// @Header 429 {string} Retry-After "Seconds until quota is reset"
 // @Failure 502 {object} views.SWGError
+// @Failure 503 {object} views.SWGError
+// @Header 503 {string} Retry-After "Seconds until upstream is called again"
 // @Router /api/ai/file2dbtest [post]
 func (e *Echo) FileToVectorDBTest(c echo.Context) error {
 	const op = "net.FileToVectorDBTest"
 	log.Info(op, "")
 
 	// Получаем файл из запроса
-	fileHeader, err := c.FormFile("file")
+	file, filename, err := e.sourceFile(c, "", filecheck.Documents)
 	if err != nil {
-		log.Error(op, "form file", err)
-		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "file is required"})
-	}
-
-	file, err := fileHeader.Open()
-	if err != nil {
-		log.Error(op, "open uploaded file", err)
-		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "cannot open uploaded file"})
+		return sourceError(c, op, err)
 	}
 	defer file.Close()
 
@@ -410,38 +489,26 @@ func (e *Echo) FileToVectorDBTest(c echo.Context) error {
 	// лучше вынести в конфиг, например e.cfg.N8nFile2DBURL
 	n8nURL := "http://n8n:5678/webhook-test/file2db"
 
-	// Готовим multipart/form-data тело для запроса к n8n
-	var buf bytes.Buffer
-	writer := multipart.NewWriter(&buf)
-
-	part, err := writer.CreateFormFile("file", fileHeader.Filename)
-	if err != nil {
-		log.Error(op, "create form file", err)
-		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot create form file for n8n"})
-	}
-
-	if _, err := io.Copy(part, file); err != nil {
-		log.Error(op, "copy file to form", err)
-		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot copy file to n8n request"})
-	}
-
-	if err := writer.Close(); err != nil {
-		log.Error(op, "close multipart writer", err)
-		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot finalize n8n request"})
-	}
+	// Тело multipart/form-data пишется в запрос по мере отправки, файл не копируется в память
+	body, contentType := stream.Multipart("file", filename, file, nil)
 
 	// Создаём запрос к n8n
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n8nURL, &buf)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n8nURL, body)
 	if err != nil {
+		body.Close()
 		log.Error(op, "create request to n8n", err)
 		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot create request to n8n"})
 	}
-	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Content-Type", contentType)
+	if e.cfg.N8nSecret != "" {
+		// файл не хешируется заранее, поэтому подписываются только заголовки
+		n8n.Sign(req, n8n.UnsignedPayload, e.cfg.N8nSecret, time.Now())
+	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := e.n8nTest.Do(req)
 	if err != nil {
 		log.Error(op, "request to n8n", err)
-		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "n8n request error"})
+		return upstreamError(c, err, http.StatusBadGateway, "n8n request error")
 	}
 	defer resp.Body.Close()
 
@@ -470,87 +537,166 @@ func (e *Echo) FileToVectorDBTest(c echo.Context) error {
 	return c.JSON(http.StatusOK, n8nResp)
 }
 
-// GenerateTest godoc
-// @Summary Generate tasks in Markdown
-// @Description Принимает контекст/промт и отправляет его в n8n webhook, который генерирует задания (тесты, вопросы) в формате Markdown на основе этого контекста
+// Ask godoc
+// @Summary Ask question about documents
+// @Description Ищет top_k фрагментов, наиболее близких к вопросу, среди документов пользователя, проиндексированных через file2db, и отправляет их вместе с вопросом в n8n webhook ask. Ответ ссылается на фрагменты номерами [N], для каждого номера возвращается файл и смещения фрагмента в нём
 // @Tags ai
 // @Accept json
 // @Produce json
-// @Param Content body views.GenerateTasksRequest true "Context and/or prompt for tasks generation"
-// @Success 200 {object} views.TasksMarkdownResponse
+// @Param Request body views.AskRequest true "Question"
+// @Success 200 {object} views.AskResponse
 // @Failure 400 {object} views.SWGError
+// @Failure 401 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 429 {object} views.SWGError
+// @Header 429 {string} Retry-After "Seconds until quota is reset"
 // @Failure 502 {object} views.SWGError
-// @Router /api/ai/gentest [post]
-func (e *Echo) GenerateTest(c echo.Context) error {
-	const op = "net.GenerateMarkdownTest"
+// @Failure 503 {object} views.SWGError
+// @Header 503 {string} Retry-After "Seconds until upstream is called again"
+// @Router /api/ai/ask [post]
+func (e *Echo) Ask(c echo.Context) error {
+	const op = "net.Ask"
 	log.Info(op, "")
 
-	var r views.GenerateTasksRequest
+	var r views.AskRequest
 	if err := c.Bind(&r); err != nil {
 		log.Error(op, "bind json", err)
 		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "bad JSON"})
 	}
-
-	if r.Content == "" {
-		log.Warn(op, "empty content", nil)
-		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "content is required"})
+	if strings.TrimSpace(r.Question) == "" {
+		log.Warn(op, "empty question", nil)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "question is required"})
 	}
-
-	ctx, done := context.WithTimeout(c.Request().Context(), 30*time.Second)
-	defer done()
-
-	// Тестовый webhook в n8n, который генерирует задания по контексту/промту
-	n8nURL := "http://n8n:5678/webhook/gentest"
-
-	payload := struct {
-		Content string `json:"content"`
-	}{
-		Content: r.Content,
+	if r.TopK == 0 {
+		r.TopK = defaultAskTopK
 	}
-
-	bodyBytes, err := json.Marshal(payload)
-	if err != nil {
-		log.Error(op, "marshal request body", err)
-		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "bad argument"})
+	if r.TopK < 1 || r.TopK > maxAskTopK {
+		log.Warn(op, "bad top_k", nil)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "top_k must be from 1 to 20"})
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n8nURL, bytes.NewReader(bodyBytes))
+	set, err := e.promptSet(c.Request().Context(), []string{promptAsk}, r.Prompt)
 	if err != nil {
-		log.Error(op, "create request to n8n", err)
-		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot create request to n8n"})
+		return promptError(c, op, err)
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	client := http.DefaultClient
-	resp, err := client.Do(req)
+	ctx, done := context.WithTimeout(prompt.With(c.Request().Context(), set), 60*time.Second)
+	defer done()
+
+	res, err := rag.Ask(ctx, e.n8nAPI, promptAsk, e.ingestAPI, userId(c), r.Question, r.TopK)
 	if err != nil {
-		log.Error(op, "request to n8n", err)
-		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "n8n request error"})
+		if errors.Is(err, rag.ErrNoContext) {
+			log.Warn(op, "", err)
+			return c.JSON(http.StatusNotFound, views.SWGError{Error: "no indexed documents match question"})
+		}
+		log.Error(op, "ask", err)
+		return upstreamError(c, err, http.StatusBadGateway, "n8n request error")
 	}
-	defer resp.Body.Close()
+	usageOf(c).Tokens = llmTokens(r.Question, res.Answer)
+	res.PromptVersion = set.Version()
 
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Error(op, "read n8n response", err)
-		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot read n8n response"})
+	log.Success(op, "")
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// GenerateTest godoc
+// @Summary Generate quiz
+// @Description Принимает контекст/промт и отправляет его в n8n webhook, который генерирует тест по JSON-схеме. Ответ LLM проверяется и чинится, при невалидном ответе запрос повторяется со списком ошибок. Поле options задаёт язык вопросов (тег BCP 47), число вопросов question_count (до 30), сложность (easy, medium, hard, mixed) и допустимые типы question_types; неизвестные значения отклоняются с 400, тест, не соответствующий options, запрашивается заново, лишние вопросы отбрасываются. При save=true тест сохраняется как заметка в формате Markdown, при save_quiz=true — как тест для прохождения в /api/quizzes. Результат кешируется по хешу нормализованного текста, options, версии промпта и модели: повторный запрос отдаётся из кеша (заголовок X-Cache: HIT), Cache-Control: no-cache запрашивает новую генерацию, no-store ещё и не сохраняет её
+// @Tags ai
+// @Accept json
+// @Produce json
+// @Param Content body views.GenerateTasksRequest true "Context and/or prompt for tasks generation"
+// @Param Cache-Control header string false "no-cache to generate again, no-store to also not cache result"
+// @Success 200 {object} views.QuizResponse
+// @Header 200 {string} X-Cache "HIT, MISS or BYPASS"
+// @Failure 400 {object} views.SWGError
+// @Failure 401 {object} views.SWGError
+// @Failure 429 {object} views.SWGError
+// @Header 429 {string} Retry-After "Seconds until quota is reset"
+// @Failure 502 {object} views.SWGError
+// @Failure 503 {object} views.SWGError
+// @Header 503 {string} Retry-After "Seconds until upstream is called again"
+// @Router /api/ai/gentest [post]
+func (e *Echo) GenerateTest(c echo.Context) error {
+	const op = "net.GenerateTest"
+	log.Info(op, "")
+
+	var r views.GenerateTasksRequest
+	if err := c.Bind(&r); err != nil {
+		log.Error(op, "bind json", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "bad JSON"})
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		log.Warn(op, "n8n returned non-200", fmt.Errorf("status: %s, body: %s", resp.Status, string(respBody)))
-		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "tasks generation error"})
+	if r.Content == "" {
+		log.Warn(op, "empty content", nil)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "content is required"})
+	}
+	if err := r.Options.Validate(); err != nil {
+		log.Warn(op, "bad options", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: err.Error()})
 	}
 
-	var n8nResp views.N8nResponse
-	if err := json.Unmarshal(respBody, &n8nResp); err != nil {
-		log.Error(op, "unmarshal n8n response", err)
-		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "bad n8n response"})
+	var owner string
+	if r.Save || r.SaveQuiz {
+		id, err := e.noteOwner(c, r.SaveAsNote)
+		if err != nil {
+			log.Warn(op, "cannot save result", err)
+			if errors.Is(err, errBadNoteSource) {
+				return c.JSON(http.StatusBadRequest, views.SWGError{Error: "unknown source_type"})
+			}
+			return unauthorized(c, err)
+		}
+		owner = id
 	}
 
-	tasksMD := n8nResp.Output
+	set, err := e.promptSet(c.Request().Context(), cachedPrompts[promptQuiz], withOptions(r.Prompt, r.Options.Language, r.Options.Difficulty, ""))
+	if err != nil {
+		return promptError(c, op, err)
+	}
+
+	key := e.cacheKey(promptQuiz, set, append([]string{r.Content}, optionsInput(r.Options)...)...)
+	q := &views.Quiz{}
+	if !e.cacheGet(c, key, q) {
+		// на каждую попытку свой ответ LLM, поэтому таймаут с запасом
+		ctx, done := context.WithTimeout(prompt.With(c.Request().Context(), set), maxQuizAttempts*30*time.Second)
+		defer done()
+
+		var err error
+		q, err = quiz.Generate(ctx, e.n8nAPI, promptQuiz, r.Content, r.Options, maxQuizAttempts)
+		if err != nil {
+			log.Error(op, "generate quiz", err)
+			if errors.Is(err, quiz.ErrInvalidOutput) {
+				return c.JSON(http.StatusBadGateway, views.SWGError{Error: "LLM returned invalid quiz"})
+			}
+			return upstreamError(c, err, http.StatusBadGateway, "n8n request error")
+		}
+		usageOf(c).Tokens = llmTokens(r.Content, quiz.Markdown(q))
+		e.cachePut(c, promptQuiz, set, key, q)
+	}
+
+	res := views.QuizResponse{
+		Quiz:          *q,
+		PromptVersion: set.Version(),
+	}
+	if r.Save {
+		nid, err := e.saveNote(c.Request().Context(), owner, r.SaveAsNote, views.NoteSourceText, quiz.Markdown(q), res.PromptVersion)
+		if err != nil {
+			log.Error(op, "save note", err)
+			return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot save note"})
+		}
+		res.NoteId = nid
+	}
+	if r.SaveQuiz {
+		qid, err := e.saveQuiz(c.Request().Context(), owner, q, res.PromptVersion)
+		if err != nil {
+			log.Error(op, "save quiz", err)
+			return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot save quiz"})
+		}
+		res.QuizId = qid
+	}
 
 	log.Success(op, "")
 
-	return c.JSON(http.StatusOK, views.TasksMarkdownResponse{
-		Markdown: tasksMD,
-	})
+	return c.JSON(http.StatusOK, res)
 }
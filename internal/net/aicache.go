@@ -0,0 +1,150 @@
+package net
+
+import (
+	"context"
+	"encoding/json"
+	"flicker/internal/aicache"
+	"flicker/internal/prompt"
+	"flicker/internal/summary"
+	"flicker/internal/views"
+	"fmt"
+	"strings"
+
+	"github.com/autumnterror/breezynotes/pkg/log"
+	"github.com/labstack/echo/v4"
+)
+
+// headerCache report if response is taken from AI cache
+const headerCache = "X-Cache"
+
+const (
+	cacheHit    = "HIT"
+	cacheMiss   = "MISS"
+	cacheBypass = "BYPASS"
+)
+
+const (
+	promptMarkdown = "generatemd"
+	promptQuiz     = "gentest"
+)
+
+// cachedPrompts are n8n hooks which cached results of endpoint depend on
+var cachedPrompts = map[string][]string{
+	promptMarkdown: {summary.MapHook, summary.ReduceHook},
+	promptQuiz:     {promptQuiz},
+}
+
+// cacheKey identify result of prompt for input in cache. Variables of request are part of input
+// only when templates use them, so keys of requests without them are not changed
+func (e *Echo) cacheKey(prompt string, set *prompt.Set, input ...string) string {
+	if vars := set.Vars(); vars != "" {
+		input = append(input, vars)
+	}
+	return aicache.Key(prompt, set.Version(), e.cfg.AIModel, input...)
+}
+
+// optionsInput is typed options of request as part of cache input, nil for default options so keys
+// of requests without them are not changed
+func optionsInput(o any) []string {
+	b, err := json.Marshal(o)
+	if err != nil || string(b) == "{}" {
+		return nil
+	}
+	return []string{string(b)}
+}
+
+// cacheControl return if client allows to read and to store cached results.
+// no-cache ask for fresh result which still replace cached one, no-store disable cache at all
+func cacheControl(c echo.Context) (read, write bool) {
+	read, write = true, true
+	h := c.Request().Header
+	for _, d := range strings.Split(h.Get("Cache-Control"), ",") {
+		switch strings.ToLower(strings.TrimSpace(d)) {
+		case "no-cache":
+			read = false
+		case "no-store":
+			read, write = false, false
+		}
+	}
+	if strings.EqualFold(h.Get("Pragma"), "no-cache") {
+		read = false
+	}
+	return read, write
+}
+
+// cacheGet read cached result of key into v and set X-Cache header. Errors of cache are logged
+// and treated as miss
+func (e *Echo) cacheGet(c echo.Context, key string, v any) bool {
+	const op = "net.cacheGet"
+
+	if e.cacheAPI == nil {
+		return false
+	}
+	if read, _ := cacheControl(c); !read {
+		c.Response().Header().Set(headerCache, cacheBypass)
+		return false
+	}
+
+	c.Response().Header().Set(headerCache, cacheMiss)
+	b, ok, err := e.cacheAPI.Get(c.Request().Context(), key)
+	if err != nil {
+		log.Warn(op, "", err)
+		return false
+	}
+	if !ok {
+		return false
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		log.Warn(op, "bad cached value", err)
+		return false
+	}
+	c.Response().Header().Set(headerCache, cacheHit)
+	return true
+}
+
+// cachePut store result of prompt made by templates of set unless client forbid it
+func (e *Echo) cachePut(c echo.Context, prompt string, set *prompt.Set, key string, v any) {
+	const op = "net.cachePut"
+
+	if e.cacheAPI == nil {
+		return
+	}
+	if _, write := cacheControl(c); !write {
+		return
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		log.Warn(op, "", err)
+		return
+	}
+	// result is stored even if client is gone
+	if err := e.cacheAPI.Set(context.WithoutCancel(c.Request().Context()), key, prompt, set.Version(), b, e.cfg.AICacheTTL); err != nil {
+		log.Warn(op, "", err)
+	}
+}
+
+// InvalidateCache drop cached results made by versions of prompts other than active ones: active
+// templates or, for hooks without template, configured versions of n8n workflows
+func (e *Echo) InvalidateCache(ctx context.Context) {
+	const op = "net.InvalidateCache"
+
+	if e.cacheAPI == nil {
+		return
+	}
+	for prompt, hooks := range cachedPrompts {
+		set, err := e.promptSet(ctx, hooks, views.PromptOptions{})
+		if err != nil {
+			log.Error(op, prompt, err)
+			continue
+		}
+		n, err := e.cacheAPI.Invalidate(ctx, prompt, set.Version())
+		if err != nil {
+			log.Error(op, prompt, err)
+			continue
+		}
+		if n > 0 {
+			log.Info(op, fmt.Sprintf("%d results of %s dropped", n, prompt))
+		}
+	}
+}
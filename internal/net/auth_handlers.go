@@ -25,6 +25,8 @@ import (
 // @Success 200 {object} views.Tokens
 // @Failure 400 {object} views.SWGError
 // @Failure 401 {object} views.SWGError
+// @Failure 429 {object} views.SWGError
+// @Header 429 {string} Retry-After "Seconds until next request is allowed"
 // @Failure 502 {object} views.SWGError
 // @Router /api/auth [post]
 func (e *Echo) Auth(c echo.Context) error {
@@ -105,6 +107,8 @@ func (e *Echo) Auth(c echo.Context) error {
 // @Success 200 {object} views.SWGError
 // @Failure 400 {object} views.SWGError
 // @Failure 302 {object} views.SWGError
+// @Failure 429 {object} views.SWGError
+// @Header 429 {string} Retry-After "Seconds until next request is allowed"
 // @Failure 502 {object} views.SWGError
 // @Router /api/auth/reg [post]
 func (e *Echo) Reg(c echo.Context) error {
@@ -0,0 +1,359 @@
+package net
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flicker/internal/cards"
+	"flicker/internal/prompt"
+	"flicker/internal/srs"
+	"flicker/internal/views"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/autumnterror/breezynotes/pkg/log"
+	"github.com/autumnterror/breezynotes/pkg/utils/id"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultCardsCount = 10
+	maxCardsCount     = 50
+	maxCardsAttempts  = 2
+
+	defaultDueLimit = 20
+	maxDueLimit     = 100
+)
+
+// GenerateCards godoc
+// @Summary Generate flashcards
+// @Description Отправляет заметку пользователя (note_id) или текст (content) в n8n webhook flashcards, который генерирует карточки вопрос/ответ через LLM. Карточки сохраняются и сразу доступны для повторения
+// @Tags cards
+// @Accept json
+// @Produce json
+// @Param Request body views.GenerateCardsRequest true "Source of cards"
+// @Success 201 {array} views.Card
+// @Failure 400 {object} views.SWGError
+// @Failure 401 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 429 {object} views.SWGError
+// @Header 429 {string} Retry-After "Seconds until quota is reset"
+// @Failure 502 {object} views.SWGError
+// @Failure 503 {object} views.SWGError
+// @Header 503 {string} Retry-After "Seconds until upstream is called again"
+// @Router /api/cards/generate [post]
+func (e *Echo) GenerateCards(c echo.Context) error {
+	const op = "net.GenerateCards"
+	log.Info(op, "")
+
+	var r views.GenerateCardsRequest
+	if err := c.Bind(&r); err != nil {
+		log.Warn(op, "bad JSON", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "bad JSON"})
+	}
+	if (r.NoteId == "") == (strings.TrimSpace(r.Content) == "") {
+		log.Warn(op, "bad source", nil)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "exactly one of note_id and content is required"})
+	}
+	if r.Count == 0 {
+		r.Count = defaultCardsCount
+	}
+	if r.Count < 1 || r.Count > maxCardsCount {
+		log.Warn(op, "bad count", nil)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "count must be from 1 to 50"})
+	}
+
+	if r.NoteId != "" {
+		ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+		defer done()
+
+		n, err := e.notesAPI.Get(ctx, userId(c), r.NoteId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				log.Warn(op, "", err)
+				return c.JSON(http.StatusNotFound, views.SWGError{Error: "note not found"})
+			}
+			log.Error(op, "", err)
+			return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot get note"})
+		}
+		r.Content = n.Body
+	}
+
+	set, err := e.promptSet(c.Request().Context(), []string{promptCards}, r.Prompt)
+	if err != nil {
+		return promptError(c, op, err)
+	}
+
+	ctx, done := context.WithTimeout(prompt.With(c.Request().Context(), set), maxCardsAttempts*30*time.Second)
+	defer done()
+
+	generated, err := cards.Generate(ctx, e.n8nAPI, promptCards, r.Content, r.Count, maxCardsAttempts)
+	if err != nil {
+		log.Error(op, "generate cards", err)
+		if errors.Is(err, cards.ErrInvalidOutput) {
+			return c.JSON(http.StatusBadGateway, views.SWGError{Error: "LLM returned invalid flashcards"})
+		}
+		return upstreamError(c, err, http.StatusBadGateway, "n8n request error")
+	}
+	texts := []string{r.Content}
+	for _, g := range generated {
+		texts = append(texts, g.Front, g.Back)
+	}
+	usageOf(c).Tokens = llmTokens(texts...)
+
+	ls := make([]*views.Card, 0, len(generated))
+	for _, g := range generated {
+		card := newCard(userId(c), r.NoteId, g)
+		card.PromptVersion = set.Version()
+		if err := e.cardsAPI.Create(c.Request().Context(), card); err != nil {
+			log.Error(op, "save card", err)
+			return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot save cards"})
+		}
+		ls = append(ls, card)
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusCreated, ls)
+}
+
+// CreateCard godoc
+// @Summary Create flashcard
+// @Description Creates flashcard of current user, due immediately
+// @Tags cards
+// @Accept json
+// @Produce json
+// @Param Card body views.CardRequest true "Card"
+// @Success 201 {object} views.Card
+// @Failure 400 {object} views.SWGError
+// @Failure 401 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/cards [post]
+func (e *Echo) CreateCard(c echo.Context) error {
+	const op = "net.CreateCard"
+	log.Info(op, "")
+
+	var r views.CardRequest
+	if err := c.Bind(&r); err != nil {
+		log.Warn(op, "bad JSON", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "bad JSON"})
+	}
+	r.Front, r.Back = strings.TrimSpace(r.Front), strings.TrimSpace(r.Back)
+	if r.Front == "" || r.Back == "" {
+		log.Warn(op, "empty card", nil)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "front and back are required"})
+	}
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	card := newCard(userId(c), "", r)
+	if err := e.cardsAPI.Create(ctx, card); err != nil {
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "card creation failed"})
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusCreated, card)
+}
+
+// ListCards godoc
+// @Summary List flashcards
+// @Description Returns all flashcards of current user, newest first
+// @Tags cards
+// @Produce json
+// @Success 200 {array} views.Card
+// @Failure 401 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/cards [get]
+func (e *Echo) ListCards(c echo.Context) error {
+	const op = "net.ListCards"
+	log.Info(op, "")
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	ls, err := e.cardsAPI.List(ctx, userId(c))
+	if err != nil {
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot list cards"})
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusOK, ls)
+}
+
+// DueCards godoc
+// @Summary Due flashcards
+// @Description Returns flashcards which should be reviewed now, most overdue first
+// @Tags cards
+// @Produce json
+// @Param limit query int false "Max number of cards, 20 by default, up to 100"
+// @Success 200 {array} views.Card
+// @Failure 400 {object} views.SWGError
+// @Failure 401 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/cards/due [get]
+func (e *Echo) DueCards(c echo.Context) error {
+	const op = "net.DueCards"
+	log.Info(op, "")
+
+	limit, err := queryInt(c, "limit")
+	if err != nil {
+		log.Warn(op, "bad limit", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "bad limit"})
+	}
+	if limit == 0 {
+		limit = defaultDueLimit
+	}
+	limit = min(limit, maxDueLimit)
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	ls, err := e.cardsAPI.Due(ctx, userId(c), time.Now(), limit)
+	if err != nil {
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot list due cards"})
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusOK, ls)
+}
+
+// ReviewCard godoc
+// @Summary Review flashcard
+// @Description Applies recall grade from 0 to 5 and schedules next review by SM-2 algorithm. Grade below 3 means card is forgotten and will be shown again tomorrow. Cards which are not due yet can't be reviewed
+// @Tags cards
+// @Accept json
+// @Produce json
+// @Param id path string true "Card id"
+// @Param Review body views.CardReview true "Recall grade"
+// @Success 200 {object} views.Card
+// @Failure 400 {object} views.SWGError
+// @Failure 401 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 409 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/cards/{id}/review [post]
+func (e *Echo) ReviewCard(c echo.Context) error {
+	const op = "net.ReviewCard"
+	log.Info(op, "")
+
+	var r views.CardReview
+	if err := c.Bind(&r); err != nil {
+		log.Warn(op, "bad JSON", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "bad JSON"})
+	}
+	if r.Grade == nil {
+		log.Warn(op, "no grade", nil)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "grade is required"})
+	}
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	card, err := e.cardsAPI.Get(ctx, userId(c), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn(op, "", err)
+			return c.JSON(http.StatusNotFound, views.SWGError{Error: "card not found"})
+		}
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot get card"})
+	}
+
+	// early reviews would grow interval for cards user just saw
+	now := time.Now()
+	if !cardState(card).IsDue(now) {
+		log.Warn(op, "card is not due", nil)
+		return c.JSON(http.StatusConflict, views.SWGError{Error: "card is not due until " + card.DueAt.UTC().Format(time.RFC3339)})
+	}
+
+	s, err := srs.Review(cardState(card), srs.Grade(*r.Grade), now)
+	if err != nil {
+		log.Warn(op, "", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: err.Error()})
+	}
+	setCardState(card, s)
+
+	if err := e.cardsAPI.Review(ctx, userId(c), card); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn(op, "", err)
+			return c.JSON(http.StatusNotFound, views.SWGError{Error: "card not found"})
+		}
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot save review"})
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusOK, card)
+}
+
+// DeleteCard godoc
+// @Summary Delete flashcard
+// @Description Deletes flashcard of current user
+// @Tags cards
+// @Produce json
+// @Param id path string true "Card id"
+// @Success 200 {object} views.SWGMessage
+// @Failure 401 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/cards/{id} [delete]
+func (e *Echo) DeleteCard(c echo.Context) error {
+	const op = "net.DeleteCard"
+	log.Info(op, "")
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	if err := e.cardsAPI.Delete(ctx, userId(c), c.Param("id")); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn(op, "", err)
+			return c.JSON(http.StatusNotFound, views.SWGError{Error: "card not found"})
+		}
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "card deletion failed"})
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusOK, views.SWGMessage{Message: "card deleted"})
+}
+
+// newCard return never reviewed card, due immediately
+func newCard(owner, noteId string, r views.CardRequest) *views.Card {
+	card := &views.Card{
+		Id:     id.New(),
+		Owner:  owner,
+		NoteId: noteId,
+		Front:  r.Front,
+		Back:   r.Back,
+	}
+	setCardState(card, srs.New(time.Now()))
+	return card
+}
+
+func cardState(c *views.Card) srs.State {
+	return srs.State{
+		Repetitions: c.Repetitions,
+		Interval:    c.Interval,
+		Ease:        c.Ease,
+		Lapses:      c.Lapses,
+		Due:         c.DueAt,
+	}
+}
+
+func setCardState(c *views.Card, s srs.State) {
+	c.Repetitions = s.Repetitions
+	c.Interval = s.Interval
+	c.Ease = s.Ease
+	c.Lapses = s.Lapses
+	c.DueAt = s.Due
+}
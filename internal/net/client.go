@@ -2,10 +2,30 @@ package net
 
 import (
 	"errors"
+	"flicker/internal/aicache"
 	"flicker/internal/auth/jwt"
 	"flicker/internal/auth/psql"
+	cardspsql "flicker/internal/cards/psql"
 	"flicker/internal/config"
+	documentspsql "flicker/internal/documents/psql"
+	"flicker/internal/ingest"
+	"flicker/internal/lecture"
+	"flicker/internal/metrics"
+	"flicker/internal/n8n"
+	notespsql "flicker/internal/notes/psql"
+	"flicker/internal/prompt"
+	"flicker/internal/quiz"
+	quizpsql "flicker/internal/quiz/psql"
+	"flicker/internal/ratelimit"
+	"flicker/internal/reqid"
+	"flicker/internal/summary"
+	"flicker/internal/transcript"
+	"flicker/internal/upload"
+	"flicker/internal/upstream"
+	"flicker/internal/usage"
+	"flicker/internal/webhook"
 	"fmt"
+	"log/slog"
 
 	"net/http"
 
@@ -16,54 +36,218 @@ import (
 )
 
 type Echo struct {
-	echo    *echo.Echo
-	cfg     *config.Config
-	authAPI psql.AuthRepo
-	jwtAPI  *jwt.WithConfig
+	echo     *echo.Echo
+	cfg      *config.Config
+	logger   *slog.Logger
+	authAPI  psql.AuthRepo
+	jwtAPI   *jwt.WithConfig
+	notesAPI notespsql.NotesRepo
+	n8nAPI   *n8n.Client
+
+	quizzesAPI   quizpsql.QuizzesRepo
+	grader       quiz.OpenGrader
+	cardsAPI     cardspsql.CardsRepo
+	ingestAPI    *ingest.Pipeline
+	documentsAPI documentspsql.DocumentsRepo
+	uploadsAPI   *upload.Store
+	transcriber  *transcript.Client
+	summarizer   *summary.Summarizer
+	lectureAPI   *lecture.Pipeline
+	cacheAPI     aicache.Cache
+	usageAPI     *usage.Meter
+	limitsAPI    ratelimit.Store
+	promptsAPI   *prompt.Library
+	webhooksAPI  *webhook.Dispatcher
+
+	// n8nTest call test webhooks, which work only while workflow is open in editor,
+	// so their failures have own breaker and don't open breaker of n8n
+	n8nTest *upstream.Client
+	// metrics serve /metrics on metrics_port
+	metrics *http.Server
 }
 
 func New(
 	cfg *config.Config,
 	authAPI psql.AuthRepo,
 	jwtAPI *jwt.WithConfig,
+	notesAPI notespsql.NotesRepo,
+	n8nAPI *n8n.Client,
+	quizzesAPI quizpsql.QuizzesRepo,
+	grader quiz.OpenGrader,
+	cardsAPI cardspsql.CardsRepo,
+	ingestAPI *ingest.Pipeline,
+	documentsAPI documentspsql.DocumentsRepo,
+	uploadsAPI *upload.Store,
+	transcriber *transcript.Client,
+	summarizer *summary.Summarizer,
+	lectureAPI *lecture.Pipeline,
+	cacheAPI aicache.Cache,
+	usageAPI *usage.Meter,
+	limitsAPI ratelimit.Store,
+	promptsAPI *prompt.Library,
+	webhooksAPI *webhook.Dispatcher,
 ) *Echo {
 	e := &Echo{
-		echo:    echo.New(),
-		cfg:     cfg,
-		authAPI: authAPI,
-		jwtAPI:  jwtAPI,
+		echo:     echo.New(),
+		cfg:      cfg,
+		logger:   stdoutLogger(cfg),
+		authAPI:  authAPI,
+		jwtAPI:   jwtAPI,
+		notesAPI: notesAPI,
+		n8nAPI:   n8nAPI,
+
+		quizzesAPI:   quizzesAPI,
+		grader:       grader,
+		cardsAPI:     cardsAPI,
+		ingestAPI:    ingestAPI,
+		documentsAPI: documentsAPI,
+		uploadsAPI:   uploadsAPI,
+		transcriber:  transcriber,
+		summarizer:   summarizer,
+		lectureAPI:   lectureAPI,
+		cacheAPI:     cacheAPI,
+		usageAPI:     usageAPI,
+		limitsAPI:    limitsAPI,
+		promptsAPI:   promptsAPI,
+		webhooksAPI:  webhooksAPI,
+
+		n8nTest: upstream.New("n8n_test", upstream.FromConfig(cfg, upstream.N8n)),
 	}
 
+	e.echo.HTTPErrorHandler = httpError
+	// X-Forwarded-For is trusted only from proxies in private networks, so clients can't pick IP
+	// which rate limits and anonymous quotas are counted by
+	e.echo.IPExtractor = echo.ExtractIPFromXFFHeader()
 	e.echo.GET("/swagger/*", echoSwagger.WrapHandler)
+	// metrics aren't served on port of API, so clients can't read them
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	e.metrics = &http.Server{Addr: fmt.Sprintf(":%d", cfg.MetricsPort), Handler: mux}
+	e.echo.Use(requestId, e.identify, e.accessLog, middleware.RecoverWithConfig(middleware.RecoverConfig{LogErrorFunc: e.logPanic}))
 	e.echo.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{echo.GET, echo.POST, echo.PUT, echo.DELETE, echo.PATCH, echo.OPTIONS},
-		AllowHeaders:     []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization},
+		// OPTIONS without Access-Control-Request-Method is not preflight but tus discovery request
+		Skipper: func(c echo.Context) bool {
+			r := c.Request()
+			return r.Method == http.MethodOptions && r.Header.Get(echo.HeaderAccessControlRequestMethod) == ""
+		},
+		AllowOrigins: []string{"*"},
+		AllowMethods: []string{echo.GET, echo.HEAD, echo.POST, echo.PUT, echo.DELETE, echo.PATCH, echo.OPTIONS},
+		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization, echo.HeaderCacheControl, "Pragma", reqid.Header,
+			upload.HeaderResumable, upload.HeaderLength, upload.HeaderOffset, upload.HeaderMetadata, upload.HeaderChecksum},
+		ExposeHeaders: []string{echo.HeaderLocation, echo.HeaderContentDisposition, echo.HeaderRetryAfter, headerNoteId, headerCache, reqid.Header,
+			headerRateLimit, headerRateRemaining, headerRateReset, headerRatePolicy, upload.HeaderResumable, upload.HeaderVersion, upload.HeaderExtension,
+			upload.HeaderMaxSize, upload.HeaderAlgorithm, upload.HeaderLength, upload.HeaderOffset, upload.HeaderMetadata, upload.HeaderExpires},
 		AllowCredentials: true,
 	}))
-	//e.echo.Use(middleware.Logger(), middleware.Recover())
 
-	api := e.echo.Group("/api")
+	// multipart uploads are limited, bigger files are uploaded by parts to /api/uploads
+	bodyLimit := middleware.BodyLimit(cfg.BodyLimit)
+
+	authLimit, aiLimit := e.limited(ratelimit.GroupAuth), e.limited(ratelimit.GroupAI)
+
+	// health checks of orchestrator are not throttled
+	e.echo.GET("/api/health", e.Healthz)
+	api := e.echo.Group("/api", e.limited(ratelimit.GroupGlobal))
 	{
-		api.GET("/health", e.Healthz)
 		auth := api.Group("/auth")
 		{
 			auth.GET("/token", e.ValidateToken)
 
-			auth.POST("", e.Auth)
-			auth.POST("/reg", e.Reg)
+			auth.POST("", e.Auth, authLimit)
+			auth.POST("/reg", e.Reg, authLimit)
 		}
-		ai := api.Group("/ai")
+		ai := api.Group("/ai", aiLimit)
 		{
-			ai.POST("/generatemd", e.GenerateMarkdown)
-			ai.POST("/generatemd-test", e.GenerateMarkdownTest)
-			ai.POST("/gentest", e.GenerateTest)
+			ai.POST("/generatemd", e.GenerateMarkdown, e.metered)
+			ai.POST("/generatemd-test", e.GenerateMarkdownTest, e.metered)
+			ai.POST("/gentest", e.GenerateTest, e.metered)
+			ai.POST("/ask", e.Ask, e.authorized, e.metered)
 
-			ai.POST("/transcribe", e.TranscribeAudio)
-			ai.POST("/file2db", e.FileToVectorDB)
-			ai.POST("/file2dbtest", e.FileToVectorDBTest)
+			ai.POST("/transcribe", e.TranscribeAudio, bodyLimit, e.metered)
+			ai.POST("/lecture", e.Lecture, bodyLimit, e.authorized, e.metered)
+			ai.POST("/file2db", e.FileToVectorDB, bodyLimit, e.authorized, e.metered)
+			ai.POST("/file2dbtest", e.FileToVectorDBTest, bodyLimit, e.metered)
 
 		}
+		api.POST("/export", e.Export, e.authorized, middleware.BodyLimit(exportBodyLimit))
+		notes := api.Group("/notes", e.authorized)
+		{
+			notes.GET("", e.ListNotes)
+			notes.POST("", e.CreateNote)
+			notes.GET("/:id", e.GetNote)
+			notes.PUT("/:id", e.UpdateNote)
+			notes.DELETE("/:id", e.DeleteNote)
+
+			notes.GET("/:id/revisions", e.ListNoteRevisions)
+			notes.GET("/:id/revisions/:rev", e.GetNoteRevision)
+			notes.POST("/:id/revisions/:rev/restore", e.RestoreNoteRevision)
+			notes.GET("/:id/diff", e.DiffNoteRevisions)
+			notes.GET("/:id/export", e.ExportNote)
+		}
+		uploads := api.Group("/uploads", e.tus)
+		{
+			uploads.OPTIONS("", e.UploadOptions)
+			uploads.POST("", e.CreateUpload, e.authorized)
+			uploads.HEAD("/:id", e.UploadStatus, e.authorized)
+			uploads.PATCH("/:id", e.WriteUpload, e.authorized)
+			uploads.DELETE("/:id", e.DeleteUpload, e.authorized)
+		}
+		documents := api.Group("/documents", e.authorized)
+		{
+			documents.GET("", e.ListDocuments)
+			documents.GET("/:id", e.GetDocument)
+			documents.DELETE("/:id", e.DeleteDocument)
+			documents.POST("/:id/reindex", e.ReindexDocument, aiLimit, e.metered)
+		}
+		quizzes := api.Group("/quizzes", e.authorized)
+		{
+			quizzes.GET("", e.ListQuizzes)
+			quizzes.POST("", e.CreateQuiz)
+			quizzes.GET("/:id", e.GetQuiz)
+			quizzes.DELETE("/:id", e.DeleteQuiz)
+			quizzes.GET("/:id/stats", e.QuizStats)
+			quizzes.GET("/:id/export", e.ExportQuiz)
+
+			quizzes.GET("/:id/attempts", e.ListAttempts)
+			quizzes.POST("/:id/attempts", e.StartAttempt)
+			quizzes.GET("/:id/attempts/:attempt", e.GetAttempt)
+			quizzes.POST("/:id/attempts/:attempt/submit", e.SubmitAttempt, aiLimit, e.metered)
+		}
+		user := api.Group("/user", e.authorized)
+		{
+			user.GET("/me/usage", e.MyUsage)
+		}
+		if promptsAPI != nil {
+			prompts := api.Group("/admin/prompts", e.authorized, e.admin)
+			{
+				prompts.GET("", e.ListPrompts)
+				prompts.GET("/:name", e.ListPromptVersions)
+				prompts.POST("/:name", e.CreatePromptVersion)
+				prompts.GET("/:name/versions/:version", e.GetPromptVersion)
+				prompts.PUT("/:name/active", e.ActivatePrompt)
+				prompts.POST("/:name/preview", e.PreviewPrompt)
+			}
+		}
+		if webhooksAPI != nil {
+			webhooks := api.Group("/webhooks", e.authorized)
+			{
+				webhooks.GET("", e.ListWebhooks)
+				webhooks.POST("", e.CreateWebhook)
+				webhooks.DELETE("/:id", e.DeleteWebhook)
+				webhooks.GET("/:id/deliveries", e.ListWebhookDeliveries)
+				webhooks.POST("/:id/ping", e.PingWebhook)
+			}
+		}
+		cards := api.Group("/cards", e.authorized)
+		{
+			cards.GET("", e.ListCards)
+			cards.POST("", e.CreateCard)
+			cards.POST("/generate", e.GenerateCards, aiLimit, e.metered)
+			cards.GET("/due", e.DueCards)
+			cards.GET("/export", e.ExportCards)
+			cards.POST("/:id/review", e.ReviewCard)
+			cards.DELETE("/:id", e.DeleteCard)
+		}
 	}
 
 	return e
@@ -72,6 +256,11 @@ func New(
 func (e *Echo) MustRun() {
 	const op = "net.Run"
 
+	go func() {
+		if err := e.metrics.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			e.echo.Logger.Fatal(format.Error(op, err))
+		}
+	}()
 	if err := e.echo.Start(fmt.Sprintf(":%d", e.cfg.Port)); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		e.echo.Logger.Fatal(format.Error(op, err))
 	}
@@ -80,6 +269,9 @@ func (e *Echo) MustRun() {
 func (e *Echo) Stop() error {
 	const op = "net.Stop"
 
+	if err := e.metrics.Close(); err != nil {
+		return format.Error(op, err)
+	}
 	if err := e.echo.Close(); err != nil {
 		return format.Error(op, err)
 	}
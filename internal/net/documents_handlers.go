@@ -0,0 +1,204 @@
+package net
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flicker/internal/ingest"
+	"flicker/internal/views"
+	"net/http"
+	"time"
+
+	"github.com/autumnterror/breezynotes/pkg/log"
+	"github.com/labstack/echo/v4"
+)
+
+// ListDocuments godoc
+// @Summary List documents
+// @Description Returns documents uploaded by current user, newest first. Ingestion reports are omitted
+// @Tags documents
+// @Produce json
+// @Success 200 {array} views.Document
+// @Failure 401 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/documents [get]
+func (e *Echo) ListDocuments(c echo.Context) error {
+	const op = "net.ListDocuments"
+	log.Info(op, "")
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	docs, err := e.documentsAPI.List(ctx, userId(c))
+	if err != nil {
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot get documents"})
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusOK, docs)
+}
+
+// GetDocument godoc
+// @Summary Get document
+// @Description Returns document of current user with its last ingestion report
+// @Tags documents
+// @Produce json
+// @Param id path string true "Document id"
+// @Success 200 {object} views.Document
+// @Failure 401 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/documents/{id} [get]
+func (e *Echo) GetDocument(c echo.Context) error {
+	const op = "net.GetDocument"
+	log.Info(op, "")
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	doc, err := e.documentsAPI.Get(ctx, userId(c), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn(op, "", err)
+			return c.JSON(http.StatusNotFound, views.SWGError{Error: "document not found"})
+		}
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot get document"})
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusOK, doc)
+}
+
+// DeleteDocument godoc
+// @Summary Delete document
+// @Description Deletes document of current user and its chunks from vector store, so /api/ai/ask no longer cites it
+// @Tags documents
+// @Produce json
+// @Param id path string true "Document id"
+// @Success 200 {object} views.SWGMessage
+// @Failure 401 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/documents/{id} [delete]
+func (e *Echo) DeleteDocument(c echo.Context) error {
+	const op = "net.DeleteDocument"
+	log.Info(op, "")
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer done()
+
+	owner, docId := userId(c), c.Param("id")
+
+	// vectors go first: if purge fails, document stays and deletion can be repeated
+	if err := e.ingestAPI.Delete(ctx, owner, docId); err != nil {
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot delete document chunks"})
+	}
+	if err := e.documentsAPI.Delete(ctx, owner, docId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn(op, "", err)
+			return c.JSON(http.StatusNotFound, views.SWGError{Error: "document not found"})
+		}
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "document deletion failed"})
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusOK, views.SWGMessage{Message: "document deleted"})
+}
+
+// ReindexDocument godoc
+// @Summary Reindex document
+// @Description Indexes stored file again with current embedder and chunking settings, replacing its chunks in vector store. If indexing fails, previous chunks are kept and document gets failed status. Webhooks of owner get document.indexed or document.failed event
+// @Tags documents
+// @Produce json
+// @Param id path string true "Document id"
+// @Success 200 {object} views.IngestReport
+// @Failure 400 {object} views.SWGError
+// @Failure 401 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 415 {object} views.SWGError
+// @Failure 429 {object} views.SWGError
+// @Header 429 {string} Retry-After "Seconds until quota is reset"
+// @Failure 502 {object} views.SWGError
+// @Failure 503 {object} views.SWGError
+// @Header 503 {string} Retry-After "Seconds until upstream is called again"
+// @Router /api/documents/{id}/reindex [post]
+func (e *Echo) ReindexDocument(c echo.Context) error {
+	const op = "net.ReindexDocument"
+	log.Info(op, "")
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 2*time.Minute)
+	defer done()
+
+	doc, err := e.documentsAPI.Get(ctx, userId(c), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn(op, "", err)
+			return c.JSON(http.StatusNotFound, views.SWGError{Error: "document not found"})
+		}
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot get document"})
+	}
+	data, err := e.documentsAPI.Content(ctx, doc.Owner, doc.Id)
+	if err != nil {
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot get document"})
+	}
+	if err := e.documentsAPI.Indexing(ctx, doc.Owner, doc.Id); err != nil {
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot update document"})
+	}
+
+	report, err := e.index(ctx, doc, data)
+	if err != nil {
+		log.Error(op, "", err)
+		status, msg := ingestError(err)
+		return upstreamError(c, err, status, msg)
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusOK, report)
+}
+
+// index run ingestion of document, record result in it and notify webhooks of owner. Failure
+// reason is saved even if request context is already done
+func (e *Echo) index(ctx context.Context, doc *views.Document, data []byte) (*views.IngestReport, error) {
+	const op = "net.index"
+
+	report, err := e.ingestAPI.Ingest(ctx, doc.Owner, doc.Id, doc.Filename, data)
+	if err != nil {
+		_, reason := ingestError(err)
+		if err := e.documentsAPI.Failed(context.WithoutCancel(ctx), doc.Owner, doc.Id, reason); err != nil {
+			log.Error(op, "save failed status", err)
+		}
+		e.notify(ctx, doc.Owner, views.WebhookEvent{Event: views.EventDocumentFailed, Subject: doc.Id, Error: reason})
+		return nil, err
+	}
+	if err := e.documentsAPI.Indexed(ctx, doc.Owner, doc.Id, report); err != nil {
+		return nil, err
+	}
+	e.notify(ctx, doc.Owner, views.WebhookEvent{Event: views.EventDocumentIndexed, Subject: doc.Id, Data: report})
+	return report, nil
+}
+
+// ingestError map ingestion error to response status and message
+func ingestError(err error) (int, string) {
+	switch {
+	case errors.Is(err, ingest.ErrUnsupported):
+		return http.StatusUnsupportedMediaType, "only pdf, docx, txt and md files are supported"
+	case errors.Is(err, ingest.ErrBadFile):
+		return http.StatusBadRequest, "cannot read file"
+	case errors.Is(err, ingest.ErrNoText):
+		return http.StatusBadRequest, "no text found in file"
+	case errors.Is(err, ingest.ErrEmbedding):
+		return http.StatusBadGateway, "embedding error"
+	}
+	return http.StatusBadGateway, "indexing failed"
+}
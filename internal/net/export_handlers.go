@@ -0,0 +1,233 @@
+package net
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flicker/internal/export"
+	"flicker/internal/views"
+	"html"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/autumnterror/breezynotes/pkg/log"
+	"github.com/labstack/echo/v4"
+)
+
+// exportBodyLimit of markdown or quiz to render, they are much smaller than files
+const exportBodyLimit = "10M"
+
+var exportTypes = map[string]struct{ mime, ext string }{
+	views.ExportPDF:  {"application/pdf", ".pdf"},
+	views.ExportDOCX: {"application/vnd.openxmlformats-officedocument.wordprocessingml.document", ".docx"},
+	views.ExportApkg: {"application/apkg", ".apkg"},
+}
+
+// Export godoc
+// @Summary Export document
+// @Description Рендерит markdown конспект или тест (например, результат generatemd или gentest) в PDF, DOCX или колоду Anki. В PDF и DOCX сохраняются заголовки, списки, таблицы и блоки кода. Колода Anki строится по разделам конспекта (заголовок — вопрос, содержимое — ответ) или по вопросам теста
+// @Tags export
+// @Accept json
+// @Produce application/pdf,application/vnd.openxmlformats-officedocument.wordprocessingml.document,application/apkg
+// @Param format query string true "Output format" Enums(pdf, docx, apkg)
+// @Param Request body views.ExportRequest true "Markdown or quiz"
+// @Success 200 {file} file
+// @Failure 400 {object} views.SWGError
+// @Failure 401 {object} views.SWGError
+// @Failure 413 {object} views.SWGError
+// @Failure 500 {object} views.SWGError
+// @Router /api/export [post]
+func (e *Echo) Export(c echo.Context) error {
+	const op = "net.Export"
+	log.Info(op, "")
+
+	var r views.ExportRequest
+	if err := c.Bind(&r); err != nil {
+		log.Warn(op, "bad JSON", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "bad JSON"})
+	}
+	if (strings.TrimSpace(r.Markdown) == "") == (r.Quiz == nil) {
+		log.Warn(op, "bad source", nil)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "exactly one of markdown and quiz is required"})
+	}
+
+	if r.Quiz != nil {
+		if r.Title == "" {
+			r.Title = r.Quiz.Title
+		}
+		return e.sendQuiz(c, op, r.Title, r.Quiz)
+	}
+	return e.sendMarkdown(c, op, noteTitle(r.Title, r.Markdown), r.Markdown)
+}
+
+// ExportNote godoc
+// @Summary Export note
+// @Description Returns note of current user as PDF, DOCX or Anki deck with one card per section
+// @Tags notes
+// @Produce application/pdf,application/vnd.openxmlformats-officedocument.wordprocessingml.document,application/apkg
+// @Param id path string true "Note id"
+// @Param format query string true "Output format" Enums(pdf, docx, apkg)
+// @Success 200 {file} file
+// @Failure 400 {object} views.SWGError
+// @Failure 401 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 500 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/notes/{id}/export [get]
+func (e *Echo) ExportNote(c echo.Context) error {
+	const op = "net.ExportNote"
+	log.Info(op, "")
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	n, err := e.notesAPI.Get(ctx, userId(c), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn(op, "", err)
+			return c.JSON(http.StatusNotFound, views.SWGError{Error: "note not found"})
+		}
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot get note"})
+	}
+
+	return e.sendMarkdown(c, op, n.Title, n.Body)
+}
+
+// ExportQuiz godoc
+// @Summary Export quiz
+// @Description Returns quiz of current user as printable PDF or DOCX with answer key, or as Anki deck with one card per question
+// @Tags quizzes
+// @Produce application/pdf,application/vnd.openxmlformats-officedocument.wordprocessingml.document,application/apkg
+// @Param id path string true "Quiz id"
+// @Param format query string true "Output format" Enums(pdf, docx, apkg)
+// @Success 200 {file} file
+// @Failure 400 {object} views.SWGError
+// @Failure 401 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 500 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/quizzes/{id}/export [get]
+func (e *Echo) ExportQuiz(c echo.Context) error {
+	const op = "net.ExportQuiz"
+	log.Info(op, "")
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	q, err := e.quizzesAPI.Get(ctx, userId(c), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn(op, "", err)
+			return c.JSON(http.StatusNotFound, views.SWGError{Error: "quiz not found"})
+		}
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot get quiz"})
+	}
+
+	return e.sendQuiz(c, op, q.Title, &views.Quiz{Title: q.Title, Questions: q.Questions})
+}
+
+// ExportCards godoc
+// @Summary Export flashcards
+// @Description Returns all flashcards of current user as Anki deck. Cards keep their ids between exports, so reimport updates deck instead of duplicating
+// @Tags cards
+// @Produce application/apkg
+// @Success 200 {file} file
+// @Failure 401 {object} views.SWGError
+// @Failure 500 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/cards/export [get]
+func (e *Echo) ExportCards(c echo.Context) error {
+	const op = "net.ExportCards"
+	log.Info(op, "")
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	ls, err := e.cardsAPI.List(ctx, userId(c))
+	if err != nil {
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot list cards"})
+	}
+
+	cs := make([]export.Card, 0, len(ls))
+	for _, cd := range ls {
+		cs = append(cs, export.Card{Front: plainHTML(cd.Front), Back: plainHTML(cd.Back)})
+	}
+	return e.send(c, op, views.ExportApkg, "Flicker", func() ([]byte, error) {
+		return export.Apkg("Flicker", cs)
+	})
+}
+
+func (e *Echo) sendMarkdown(c echo.Context, op, title, md string) error {
+	d := export.Parse(title, md)
+	return e.sendDocument(c, op, d, func() []export.Card { return export.CardsFromDocument(d) })
+}
+
+func (e *Echo) sendQuiz(c echo.Context, op, title string, q *views.Quiz) error {
+	d := export.FromQuiz(q)
+	d.Title = title
+	return e.sendDocument(c, op, d, func() []export.Card { return export.CardsFromQuiz(q) })
+}
+
+// sendDocument render document in format from query. Cards are built only for anki deck
+func (e *Echo) sendDocument(c echo.Context, op string, d *export.Document, cards func() []export.Card) error {
+	f := c.QueryParam("format")
+	return e.send(c, op, f, d.Title, func() ([]byte, error) {
+		switch f {
+		case views.ExportPDF:
+			return export.PDF(d)
+		case views.ExportDOCX:
+			return export.DOCX(d)
+		default:
+			return export.Apkg(d.Title, cards())
+		}
+	})
+}
+
+func (e *Echo) send(c echo.Context, op, f, title string, render func() ([]byte, error)) error {
+	t, ok := exportTypes[f]
+	if !ok {
+		log.Warn(op, "bad format", nil)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "format must be one of pdf, docx, apkg"})
+	}
+
+	b, err := render()
+	if err != nil {
+		log.Error(op, "", err)
+		return c.JSON(http.StatusInternalServerError, views.SWGError{Error: "export failed"})
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{
+		"filename": fileName(title) + t.ext,
+	}))
+
+	log.Success(op, "")
+
+	return c.Blob(http.StatusOK, t.mime, b)
+}
+
+// fileName make file name from title: path separators and control characters are dropped
+func fileName(title string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r < 0x20 || r == 0x7f:
+			return -1
+		case strings.ContainsRune(`/\:*?"<>|`, r):
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(title))
+	if name == "" || strings.Trim(name, ".") == "" {
+		return "export"
+	}
+	return name
+}
+
+// plainHTML make anki field from plain text of card
+func plainHTML(s string) string {
+	return strings.ReplaceAll(html.EscapeString(s), "\n", "<br>")
+}
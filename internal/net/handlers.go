@@ -1,23 +1,31 @@
 package net
 
 import (
+	"flicker/internal/upstream"
+	"flicker/internal/views"
 	"net/http"
 
 	"github.com/autumnterror/breezynotes/pkg/log"
-	"github.com/autumnterror/breezynotes/views"
 	"github.com/labstack/echo/v4"
 )
 
 // Healthz godoc
 // @Summary check health of gateway
-// @Description
+// @Description Возвращает состояние circuit breaker каждого внешнего сервиса. Пока breaker открыт, запросы к сервису не отправляются и AI-эндпоинты отвечают 503 с заголовком Retry-After, статус при этом degraded
 // @Tags healthz
 // @Produce json
-// @Success 200 {object} views.SWGMessage
+// @Success 200 {object} views.Health
 // @Router /api/health [get]
 func (e *Echo) Healthz(c echo.Context) error {
 	const op = "net.Healthz"
 	log.Info(op, "")
 
-	return c.JSON(http.StatusOK, views.SWGMessage{Message: "HEALTHZ"})
+	res := views.Health{Message: "HEALTHZ", Status: views.HealthOK, Upstreams: upstream.States()}
+	for _, u := range res.Upstreams {
+		if u.State != upstream.StateClosed {
+			res.Status = views.HealthDegraded
+		}
+	}
+
+	return c.JSON(http.StatusOK, res)
 }
@@ -0,0 +1,212 @@
+package net
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flicker/internal/filecheck"
+	"flicker/internal/lecture"
+	"flicker/internal/prompt"
+	"flicker/internal/quiz"
+	"flicker/internal/summary"
+	"flicker/internal/views"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/autumnterror/breezynotes/pkg/log"
+	"github.com/labstack/echo/v4"
+)
+
+// lectureTimeout covers transcription of long recording and LLM call for every its part
+const lectureTimeout = 15 * time.Minute
+
+// Lecture godoc
+// @Summary Lecture recording to note
+// @Description Принимает запись лекции (файл или id завершённой загрузки /api/uploads), расшифровывает её, делит длинную расшифровку на части по summary_chunk_tokens токенов, параллельно конспектирует части через LLM (неудачные вызовы повторяются, часть, которую так и не удалось законспектировать, помечается failed) и собирает из них один Markdown-конспект с разделами по времени. Если quiz=true, по конспекту генерируется тест. С заголовком Accept: text/event-stream ход обработки приходит событиями progress (views.LectureStage), итог — событием result (views.LectureResponse) или error (views.SWGError); иначе возвращается JSON после завершения всех этапов. По завершении webhook пользователя получают событие lecture.completed (data — views.LectureResponse) или lecture.failed
+// @Tags ai
+// @Accept mpfd
+// @Produce json,text/event-stream
+// @Param file formData file false "Audio or video recording"
+// @Param upload_id formData string false "Id of completed upload, used instead of file"
+// @Param title formData string false "Title of note"
+// @Param quiz formData bool false "Generate quiz from note"
+// @Param save formData bool false "Save note"
+// @Param save_quiz formData bool false "Save generated quiz"
+// @Param prompt formData string false "Prompt options as JSON object, see views.PromptOptions"
+// @Success 200 {object} views.LectureResponse
+// @Failure 400 {object} views.SWGError
+// @Failure 401 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 409 {object} views.SWGError
+// @Failure 413 {object} views.SWGError
+// @Failure 415 {object} views.SWGError
+// @Failure 422 {object} views.SWGError
+// @Failure 429 {object} views.SWGError
+// @Header 429 {string} Retry-After "Seconds until quota is reset"
+// @Failure 502 {object} views.SWGError
+// @Failure 503 {object} views.SWGError
+// @Header 503 {string} Retry-After "Seconds until upstream is called again"
+// @Router /api/ai/lecture [post]
+func (e *Echo) Lecture(c echo.Context) error {
+	const op = "net.Lecture"
+	log.Info(op, "")
+
+	save := views.SaveAsNote{
+		Save:       c.FormValue("save") == "true",
+		Title:      c.FormValue("title"),
+		SourceType: views.NoteSourceTranscript,
+	}
+	saveQuiz := c.FormValue("save_quiz") == "true"
+	withQuiz := c.FormValue("quiz") == "true" || saveQuiz
+
+	var options views.PromptOptions
+	if raw := c.FormValue("prompt"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &options); err != nil {
+			log.Warn(op, "bad prompt options", err)
+			return c.JSON(http.StatusBadRequest, views.SWGError{Error: "bad prompt options"})
+		}
+	}
+	hooks := []string{summary.MapHook}
+	if withQuiz {
+		hooks = append(hooks, promptQuiz)
+	}
+	set, err := e.promptSet(c.Request().Context(), hooks, options)
+	if err != nil {
+		return promptError(c, op, err)
+	}
+
+	file, filename, err := e.sourceFile(c, userId(c), filecheck.Media)
+	if err != nil {
+		return sourceError(c, op, err)
+	}
+	defer file.Close()
+	save.SourceRef = filename
+
+	ctx, done := context.WithTimeout(prompt.With(c.Request().Context(), set), lectureTimeout)
+	defer done()
+
+	var events *eventWriter
+	progress := func(views.LectureStage) {}
+	if strings.Contains(c.Request().Header.Get(echo.HeaderAccept), mimeEventStream) {
+		events = newEventWriter(c.Response())
+		progress = func(s views.LectureStage) { events.send("progress", s) }
+	}
+	fail := func(status int, msg string) error {
+		e.notify(ctx, userId(c), views.WebhookEvent{Event: views.EventLectureFailed, Error: msg})
+		if events != nil {
+			// stream is already answered with 200, so outcome is set by status of error
+			if rec := usageOf(c); rec.Outcome == "" {
+				rec.Outcome = usageOutcome(status, "")
+			}
+			events.send("error", views.SWGError{Error: msg})
+			return nil
+		}
+		return c.JSON(status, views.SWGError{Error: msg})
+	}
+
+	res, err := e.lectureAPI.Run(ctx, lecture.Request{
+		Filename: filename,
+		Audio:    file,
+		Title:    save.Title,
+		Quiz:     withQuiz,
+	}, progress)
+	if err != nil {
+		log.Error(op, "", err)
+		status, msg := lectureError(err)
+		return fail(upstreamStatus(c, err, status, msg))
+	}
+	usageOf(c).AudioSeconds = res.Transcript.DurationSeconds
+	usageOf(c).Tokens = llmTokens(res.Transcript.Text, res.Markdown)
+	res.PromptVersion = set.Version()
+
+	if save.Save || saveQuiz {
+		started := time.Now()
+		stage := views.LectureStage{Name: views.StageSave, Status: views.StageRunning}
+		progress(stage)
+
+		if save.Save {
+			nid, err := e.saveNote(context.WithoutCancel(ctx), userId(c), save, views.NoteSourceTranscript, res.Markdown, res.PromptVersion)
+			if err != nil {
+				log.Error(op, "save note", err)
+				return fail(http.StatusBadGateway, "cannot save note")
+			}
+			res.NoteId = nid
+		}
+		if saveQuiz {
+			qid, err := e.saveQuiz(context.WithoutCancel(ctx), userId(c), res.Quiz, res.PromptVersion)
+			if err != nil {
+				log.Error(op, "save quiz", err)
+				return fail(http.StatusBadGateway, "cannot save quiz")
+			}
+			res.QuizId = qid
+		}
+
+		stage.Status, stage.DurationMs = views.StageDone, time.Since(started).Milliseconds()
+		progress(stage)
+		res.Stages = append(res.Stages, stage)
+	}
+
+	e.notify(ctx, userId(c), views.WebhookEvent{Event: views.EventLectureCompleted, Subject: res.NoteId, Data: res})
+
+	log.Success(op, "")
+
+	if events != nil {
+		events.send("result", res)
+		return nil
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// lectureError return status and message for error of lecture pipeline
+func lectureError(err error) (int, string) {
+	switch {
+	case errors.Is(err, lecture.ErrEmpty):
+		return http.StatusUnprocessableEntity, "nothing recognized in recording"
+	case errors.Is(err, lecture.ErrTranscription):
+		return http.StatusBadGateway, transcriberError(err)
+	case errors.Is(err, lecture.ErrSummary):
+		return http.StatusBadGateway, "markdown generation error"
+	case errors.Is(err, quiz.ErrInvalidOutput):
+		return http.StatusBadGateway, "LLM returned invalid quiz"
+	}
+	return http.StatusBadGateway, "n8n request error"
+}
+
+const mimeEventStream = "text/event-stream"
+
+// eventWriter send server-sent events, each is flushed at once. It is safe for concurrent use
+type eventWriter struct {
+	mu sync.Mutex
+	w  *echo.Response
+}
+
+func newEventWriter(w *echo.Response) *eventWriter {
+	w.Header().Set(echo.HeaderContentType, mimeEventStream)
+	w.Header().Set("Cache-Control", "no-cache")
+	// nginx buffers responses by default, events must go to client as they happen
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	w.Flush()
+	return &eventWriter{w: w}
+}
+
+func (ew *eventWriter) send(event string, data any) {
+	const op = "net.eventWriter.send"
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		log.Error(op, "marshal event", err)
+		return
+	}
+
+	ew.mu.Lock()
+	defer ew.mu.Unlock()
+	if _, err := fmt.Fprintf(ew.w, "event: %s\ndata: %s\n\n", event, b); err != nil {
+		log.Warn(op, "client gone", err)
+		return
+	}
+	ew.w.Flush()
+}
@@ -0,0 +1,102 @@
+package net
+
+import (
+	"flicker/internal/config"
+	"flicker/internal/metrics"
+	"flicker/internal/reqid"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/autumnterror/breezynotes/pkg/log"
+	"github.com/labstack/echo/v4"
+)
+
+// newLogger return JSON logger of requests with level of config
+func newLogger(cfg *config.Config, w io.Writer) *slog.Logger {
+	const op = "net.newLogger"
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+		log.Warn(op, "bad log_level, info is used", err)
+		level = slog.LevelInfo
+	}
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level}))
+}
+
+func stdoutLogger(cfg *config.Config) *slog.Logger {
+	return newLogger(cfg, os.Stdout)
+}
+
+// requestId take id of request from X-Request-ID or generate it, when header is missing or has
+// characters not allowed by reqid.Valid. Id is put into context of request, so stores and
+// upstream clients get it, and sent back in answer
+func requestId(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		r := c.Request()
+		id := r.Header.Get(reqid.Header)
+		if !reqid.Valid(id) {
+			id = reqid.New()
+		}
+		c.SetRequest(r.WithContext(reqid.With(r.Context(), id)))
+		c.Response().Header().Set(reqid.Header, id)
+		return next(c)
+	}
+}
+
+// accessLog write one JSON line per request after it is answered and observe its duration in
+// metrics. Must follow requestId and identify
+func (e *Echo) accessLog(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+		err := next(c)
+		if err != nil {
+			// answer error here, so its status is logged
+			c.Error(err)
+		}
+
+		r, status, latency := c.Request(), c.Response().Status, time.Since(start)
+		metrics.ObserveHTTP(r.Method, c.Path(), status, latency)
+
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		// routes with optional token don't pass authorized, token is taken as checked by identify
+		user, _ := e.userFromToken(c)
+		attrs := []slog.Attr{
+			slog.String("request_id", reqid.From(r.Context())),
+			slog.String("method", r.Method),
+			slog.String("route", c.Path()),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(latency.Microseconds())/1000),
+			slog.Int64("bytes", c.Response().Size),
+			slog.String("ip", c.RealIP()),
+		}
+		if user != "" {
+			attrs = append(attrs, slog.String("user_id", user))
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+		e.logger.LogAttrs(r.Context(), level, "request", attrs...)
+		return nil
+	}
+}
+
+// logPanic log panic recovered in handler with its stack, request is answered with 500
+func (e *Echo) logPanic(c echo.Context, err error, stack []byte) error {
+	e.logger.LogAttrs(c.Request().Context(), slog.LevelError, "panic",
+		slog.String("request_id", reqid.From(c.Request().Context())),
+		slog.String("error", err.Error()),
+		slog.String("stack", string(stack)),
+	)
+	return err
+}
@@ -0,0 +1,144 @@
+package net
+
+import (
+	"errors"
+	"flicker/internal/auth/jwt"
+	"flicker/internal/views"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/autumnterror/breezynotes/pkg/log"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	ctxUserId = "user_id"
+	ctxToken  = "token"
+)
+
+var errNoToken = errors.New("access token missing")
+
+// tokenResult is access token of request checked by identify
+type tokenResult struct {
+	id  string
+	err error
+}
+
+// identify check access token of request once and put result into context, so authorized,
+// rate limiter, usage meter, handlers and access log don't verify it again
+func (e *Echo) identify(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		id, err := e.verifyAccess(c)
+		c.Set(ctxToken, tokenResult{id: id, err: err})
+		return next(c)
+	}
+}
+
+// userFromToken return user id from access token checked by identify
+func (e *Echo) userFromToken(c echo.Context) (string, error) {
+	if t, ok := c.Get(ctxToken).(tokenResult); ok {
+		return t.id, t.err
+	}
+	return e.verifyAccess(c)
+}
+
+// verifyAccess return user id from access token. Token taken from cookie or Authorization header
+func (e *Echo) verifyAccess(c echo.Context) (string, error) {
+	var raw string
+	if at, err := c.Cookie("access_token"); err == nil && at.Value != "" {
+		raw = at.Value
+	} else if h := c.Request().Header.Get(echo.HeaderAuthorization); strings.HasPrefix(h, "Bearer ") {
+		raw = strings.TrimPrefix(h, "Bearer ")
+	}
+	if raw == "" {
+		return "", errNoToken
+	}
+
+	token, err := e.jwtAPI.VerifyToken(raw)
+	if err != nil {
+		return "", err
+	}
+	tp, err := e.jwtAPI.GetTypeFromToken(token)
+	if err != nil {
+		return "", err
+	}
+	if tp != jwt.TokenTypeAccess {
+		return "", jwt.ErrWrongType
+	}
+	return e.jwtAPI.GetIdFromToken(token)
+}
+
+// authorized reject requests without valid access token and put user id into context
+func (e *Echo) authorized(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		const op = "net.authorized"
+
+		id, err := e.userFromToken(c)
+		if err != nil {
+			log.Warn(op, "", err)
+			return unauthorized(c, err)
+		}
+
+		c.Set(ctxUserId, id)
+		return next(c)
+	}
+}
+
+// admin reject users which are not admins of config, must follow authorized
+func (e *Echo) admin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		const op = "net.admin"
+
+		if !slices.Contains(e.cfg.Admins, userId(c)) {
+			log.Warn(op, "not admin", nil)
+			return c.JSON(http.StatusForbidden, views.SWGError{Error: "forbidden"})
+		}
+		return next(c)
+	}
+}
+
+func unauthorized(c echo.Context, err error) error {
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return c.JSON(http.StatusUnauthorized, views.SWGError{Error: "access token expired"})
+	}
+	return c.JSON(http.StatusUnauthorized, views.SWGError{Error: "unauthorized"})
+}
+
+// userId return id set by authorized middleware
+func userId(c echo.Context) string {
+	id, _ := c.Get(ctxUserId).(string)
+	return id
+}
+
+// httpError answer errors of echo and its middleware, e.g. body limit, in the same format as handlers
+func httpError(err error, c echo.Context) {
+	const op = "net.httpError"
+
+	if c.Response().Committed {
+		return
+	}
+
+	code, msg := http.StatusInternalServerError, "internal error"
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		code = he.Code
+		msg = strings.ToLower(fmt.Sprint(he.Message))
+	}
+	if code == http.StatusRequestEntityTooLarge {
+		msg = "request body too large, send big files to /api/uploads"
+	}
+	if code >= http.StatusInternalServerError {
+		log.Error(op, "", err)
+	}
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(code)
+	} else {
+		err = c.JSON(code, views.SWGError{Error: msg})
+	}
+	if err != nil {
+		log.Error(op, "send error", err)
+	}
+}
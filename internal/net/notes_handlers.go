@@ -0,0 +1,257 @@
+package net
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flicker/internal/views"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/autumnterror/breezynotes/pkg/log"
+	"github.com/autumnterror/breezynotes/pkg/utils/id"
+	"github.com/labstack/echo/v4"
+)
+
+const maxTitleLen = 255
+
+// CreateNote godoc
+// @Summary Create note
+// @Description Saves markdown note of current user
+// @Tags notes
+// @Accept json
+// @Produce json
+// @Param Note body views.NoteRequest true "Note"
+// @Success 201 {object} views.NoteId
+// @Failure 400 {object} views.SWGError
+// @Failure 401 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/notes [post]
+func (e *Echo) CreateNote(c echo.Context) error {
+	const op = "net.CreateNote"
+	log.Info(op, "")
+
+	var r views.NoteRequest
+	if err := c.Bind(&r); err != nil {
+		log.Warn(op, "bad JSON", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "bad JSON"})
+	}
+	if r.Body == "" {
+		log.Warn(op, "empty body", nil)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "body is required"})
+	}
+	if !views.ValidNoteSource(r.SourceType) {
+		log.Warn(op, "bad source type", nil)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "unknown source_type"})
+	}
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	nid, err := e.saveNote(ctx, userId(c), views.SaveAsNote{
+		Title:      r.Title,
+		SourceType: r.SourceType,
+		SourceRef:  r.SourceRef,
+	}, views.NoteSourceText, r.Body, "")
+	if err != nil {
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "note creation failed"})
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusCreated, views.NoteId{Id: nid})
+}
+
+// ListNotes godoc
+// @Summary List notes
+// @Description Returns all notes of current user, most recently updated first
+// @Tags notes
+// @Produce json
+// @Success 200 {array} views.Note
+// @Failure 401 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/notes [get]
+func (e *Echo) ListNotes(c echo.Context) error {
+	const op = "net.ListNotes"
+	log.Info(op, "")
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	ls, err := e.notesAPI.List(ctx, userId(c))
+	if err != nil {
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot list notes"})
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusOK, ls)
+}
+
+// GetNote godoc
+// @Summary Get note
+// @Description Returns note of current user by id
+// @Tags notes
+// @Produce json
+// @Param id path string true "Note id"
+// @Success 200 {object} views.Note
+// @Failure 401 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/notes/{id} [get]
+func (e *Echo) GetNote(c echo.Context) error {
+	const op = "net.GetNote"
+	log.Info(op, "")
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	n, err := e.notesAPI.Get(ctx, userId(c), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn(op, "", err)
+			return c.JSON(http.StatusNotFound, views.SWGError{Error: "note not found"})
+		}
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot get note"})
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusOK, n)
+}
+
+// UpdateNote godoc
+// @Summary Update note
+// @Description Replaces title and body of note and stores them as new revision. Source of note can't be changed
+// @Tags notes
+// @Accept json
+// @Produce json
+// @Param id path string true "Note id"
+// @Param Note body views.NoteRequest true "New title and body"
+// @Success 200 {object} views.SWGMessage
+// @Failure 400 {object} views.SWGError
+// @Failure 401 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/notes/{id} [put]
+func (e *Echo) UpdateNote(c echo.Context) error {
+	const op = "net.UpdateNote"
+	log.Info(op, "")
+
+	var r views.NoteRequest
+	if err := c.Bind(&r); err != nil {
+		log.Warn(op, "bad JSON", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "bad JSON"})
+	}
+	if r.Body == "" {
+		log.Warn(op, "empty body", nil)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "body is required"})
+	}
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	if _, err := e.notesAPI.Update(ctx, userId(c), c.Param("id"), noteTitle(r.Title, r.Body), r.Body); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn(op, "", err)
+			return c.JSON(http.StatusNotFound, views.SWGError{Error: "note not found"})
+		}
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "note update failed"})
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusOK, views.SWGMessage{Message: "note updated"})
+}
+
+// DeleteNote godoc
+// @Summary Delete note
+// @Description Deletes note of current user
+// @Tags notes
+// @Produce json
+// @Param id path string true "Note id"
+// @Success 200 {object} views.SWGMessage
+// @Failure 401 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/notes/{id} [delete]
+func (e *Echo) DeleteNote(c echo.Context) error {
+	const op = "net.DeleteNote"
+	log.Info(op, "")
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	if err := e.notesAPI.Delete(ctx, userId(c), c.Param("id")); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn(op, "", err)
+			return c.JSON(http.StatusNotFound, views.SWGError{Error: "note not found"})
+		}
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "note deletion failed"})
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusOK, views.SWGMessage{Message: "note deleted"})
+}
+
+// saveNote create note of owner and return its id. Empty source type replaced by defaultSource,
+// promptVersion identify prompts which generated body and is empty for notes written by user
+func (e *Echo) saveNote(ctx context.Context, owner string, s views.SaveAsNote, defaultSource, body, promptVersion string) (string, error) {
+	if s.SourceType == "" {
+		s.SourceType = defaultSource
+	}
+
+	n := &views.Note{
+		Id:         id.New(),
+		Owner:      owner,
+		Title:      noteTitle(s.Title, body),
+		Body:       body,
+		SourceType: s.SourceType,
+		SourceRef:  s.SourceRef,
+
+		PromptVersion: promptVersion,
+	}
+	if err := e.notesAPI.Create(ctx, n); err != nil {
+		return "", err
+	}
+	return n.Id, nil
+}
+
+// noteTitle return title or, if it empty, first line of markdown without heading marks
+func noteTitle(title, body string) string {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		for _, line := range strings.Split(body, "\n") {
+			line = strings.TrimSpace(strings.TrimLeft(line, "#"))
+			if line != "" {
+				title = line
+				break
+			}
+		}
+	}
+	if title == "" {
+		title = "Без названия"
+	}
+	if utf8.RuneCountInString(title) > maxTitleLen {
+		title = string([]rune(title)[:maxTitleLen])
+	}
+	return title
+}
+
+var errBadNoteSource = errors.New("unknown source_type")
+
+// noteOwner check save options and return id of user who will own saved note
+func (e *Echo) noteOwner(c echo.Context, s views.SaveAsNote) (string, error) {
+	if !views.ValidNoteSource(s.SourceType) {
+		return "", errBadNoteSource
+	}
+	return e.userFromToken(c)
+}
@@ -0,0 +1,342 @@
+package net
+
+import (
+	"context"
+	"errors"
+	"flicker/internal/prompt"
+	"flicker/internal/summary"
+	"flicker/internal/views"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/autumnterror/breezynotes/pkg/log"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	promptCards = "flashcards"
+	promptAsk   = "ask"
+)
+
+// promptNames are n8n hooks which prompts can be set by templates
+var promptNames = []string{summary.MapHook, summary.ReduceHook, promptQuiz, promptCards, promptAsk}
+
+// promptSet choose templates of hooks for request. Without template store every hook keeps
+// prompt of its n8n workflow
+func (e *Echo) promptSet(ctx context.Context, hooks []string, o views.PromptOptions) (*prompt.Set, error) {
+	lib := e.promptsAPI
+	if lib == nil {
+		lib = &prompt.Library{Versions: e.cfg.PromptVersions}
+	}
+	return lib.Resolve(ctx, hooks, o)
+}
+
+// withOptions fill variables of templates not set by request from its typed options
+func withOptions(o views.PromptOptions, language, detail, format string) views.PromptOptions {
+	if o.Language == "" {
+		o.Language = language
+	}
+	if o.Detail == "" {
+		o.Detail = detail
+	}
+	if o.Format == "" {
+		o.Format = format
+	}
+	return o
+}
+
+// promptError answer error of promptSet
+func promptError(c echo.Context, op string, err error) error {
+	switch {
+	case errors.Is(err, prompt.ErrOptions):
+		log.Warn(op, "", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "bad prompt options"})
+	case errors.Is(err, prompt.ErrNotFound):
+		log.Warn(op, "", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "prompt template not found"})
+	}
+	log.Error(op, "prompt templates", err)
+	return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot get prompt templates"})
+}
+
+// promptName return name of template from path, unknown names are answered with 404
+func promptName(c echo.Context) (string, bool) {
+	name := c.Param("name")
+	return name, slices.Contains(promptNames, name)
+}
+
+// ListPrompts godoc
+// @Summary List active prompt templates
+// @Description Возвращает активные версии шаблонов промптов. Шаблоны есть у webhook generatemd, reducemd, gentest, flashcards и ask; webhook без активного шаблона использует промпт своего n8n workflow. Доступно администраторам
+// @Tags admin
+// @Produce json
+// @Success 200 {array} views.PromptTemplate
+// @Failure 401 {object} views.SWGError
+// @Failure 403 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/admin/prompts [get]
+func (e *Echo) ListPrompts(c echo.Context) error {
+	const op = "net.ListPrompts"
+	log.Info(op, "")
+
+	ls, err := e.promptsAPI.Store.Active(c.Request().Context())
+	if err != nil {
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot get prompt templates"})
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusOK, ls)
+}
+
+// ListPromptVersions godoc
+// @Summary List versions of prompt template
+// @Description Возвращает все версии шаблона, новые первыми. Доступно администраторам
+// @Tags admin
+// @Produce json
+// @Param name path string true "Template name" Enums(generatemd, reducemd, gentest, flashcards, ask)
+// @Success 200 {array} views.PromptTemplate
+// @Failure 401 {object} views.SWGError
+// @Failure 403 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/admin/prompts/{name} [get]
+func (e *Echo) ListPromptVersions(c echo.Context) error {
+	const op = "net.ListPromptVersions"
+	log.Info(op, "")
+
+	name, ok := promptName(c)
+	if !ok {
+		log.Warn(op, "unknown prompt", nil)
+		return c.JSON(http.StatusNotFound, views.SWGError{Error: "unknown prompt"})
+	}
+
+	ls, err := e.promptsAPI.Store.List(c.Request().Context(), name)
+	if err != nil {
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot get prompt templates"})
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusOK, ls)
+}
+
+// GetPromptVersion godoc
+// @Summary Get version of prompt template
+// @Description Возвращает версию шаблона, версия 0 — активная. Доступно администраторам
+// @Tags admin
+// @Produce json
+// @Param name path string true "Template name" Enums(generatemd, reducemd, gentest, flashcards, ask)
+// @Param version path int true "Version, 0 is active one"
+// @Success 200 {object} views.PromptTemplate
+// @Failure 400 {object} views.SWGError
+// @Failure 401 {object} views.SWGError
+// @Failure 403 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/admin/prompts/{name}/versions/{version} [get]
+func (e *Echo) GetPromptVersion(c echo.Context) error {
+	const op = "net.GetPromptVersion"
+	log.Info(op, "")
+
+	name, ok := promptName(c)
+	if !ok {
+		log.Warn(op, "unknown prompt", nil)
+		return c.JSON(http.StatusNotFound, views.SWGError{Error: "unknown prompt"})
+	}
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 0 {
+		log.Warn(op, "bad version", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "bad version"})
+	}
+
+	t, err := e.promptsAPI.Store.Get(c.Request().Context(), name, version)
+	if err != nil {
+		if errors.Is(err, prompt.ErrNotFound) {
+			log.Warn(op, "", err)
+			return c.JSON(http.StatusNotFound, views.SWGError{Error: "prompt template not found"})
+		}
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot get prompt template"})
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusOK, t)
+}
+
+// CreatePromptVersion godoc
+// @Summary Create version of prompt template
+// @Description Сохраняет новую версию шаблона Go text/template. В шаблоне доступны переменные запроса {{.Language}}, {{.Detail}}, {{.Audience}}, {{.Format}} и поля запроса к webhook {{.Input.<поле>}}, например {{.Input.content}}. Отрисованный шаблон отправляется в webhook полем prompt, номер версии — полем prompt_version. При activate=true версия сразу становится активной, закешированные результаты других версий удаляются. Доступно администраторам
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param name path string true "Template name" Enums(generatemd, reducemd, gentest, flashcards, ask)
+// @Param Template body views.PromptTemplateRequest true "Template"
+// @Success 201 {object} views.PromptTemplate
+// @Failure 400 {object} views.SWGError
+// @Failure 401 {object} views.SWGError
+// @Failure 403 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/admin/prompts/{name} [post]
+func (e *Echo) CreatePromptVersion(c echo.Context) error {
+	const op = "net.CreatePromptVersion"
+	log.Info(op, "")
+
+	name, ok := promptName(c)
+	if !ok {
+		log.Warn(op, "unknown prompt", nil)
+		return c.JSON(http.StatusNotFound, views.SWGError{Error: "unknown prompt"})
+	}
+	var r views.PromptTemplateRequest
+	if err := c.Bind(&r); err != nil {
+		log.Warn(op, "bad JSON", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "bad JSON"})
+	}
+	if _, err := prompt.Parse(name, r.Body); err != nil {
+		log.Warn(op, "", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: err.Error()})
+	}
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	t := &views.PromptTemplate{
+		Name:    name,
+		Body:    r.Body,
+		Comment: r.Comment,
+		Author:  userId(c),
+	}
+	if err := e.promptsAPI.Store.Create(ctx, t); err != nil {
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot save prompt template"})
+	}
+	if r.Activate {
+		if err := e.promptsAPI.Store.Activate(ctx, name, t.Version); err != nil {
+			log.Error(op, "activate", err)
+			return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot activate prompt template"})
+		}
+		t.Active = true
+		e.InvalidateCache(context.WithoutCancel(ctx))
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusCreated, t)
+}
+
+// ActivatePrompt godoc
+// @Summary Activate version of prompt template
+// @Description Делает версию шаблона активной, версия 0 отключает шаблон, и webhook использует промпт своего n8n workflow. Закешированные результаты других версий удаляются. Доступно администраторам
+// @Tags admin
+// @Accept json
+// @Param name path string true "Template name" Enums(generatemd, reducemd, gentest, flashcards, ask)
+// @Param Version body views.PromptActivateRequest true "Version"
+// @Success 204
+// @Failure 400 {object} views.SWGError
+// @Failure 401 {object} views.SWGError
+// @Failure 403 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/admin/prompts/{name}/active [put]
+func (e *Echo) ActivatePrompt(c echo.Context) error {
+	const op = "net.ActivatePrompt"
+	log.Info(op, "")
+
+	name, ok := promptName(c)
+	if !ok {
+		log.Warn(op, "unknown prompt", nil)
+		return c.JSON(http.StatusNotFound, views.SWGError{Error: "unknown prompt"})
+	}
+	var r views.PromptActivateRequest
+	if err := c.Bind(&r); err != nil {
+		log.Warn(op, "bad JSON", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "bad JSON"})
+	}
+	if r.Version < 0 {
+		log.Warn(op, "bad version", nil)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "bad version"})
+	}
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	if err := e.promptsAPI.Store.Activate(ctx, name, r.Version); err != nil {
+		if errors.Is(err, prompt.ErrNotFound) {
+			log.Warn(op, "", err)
+			return c.JSON(http.StatusNotFound, views.SWGError{Error: "prompt template not found"})
+		}
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot activate prompt template"})
+	}
+	e.InvalidateCache(context.WithoutCancel(ctx))
+
+	log.Success(op, "")
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// PreviewPrompt godoc
+// @Summary Preview prompt template
+// @Description Отрисовывает шаблон без вызова LLM: переданный body, чтобы проверить ещё не сохранённый шаблон, иначе версию version или активную версию. Переменные берутся из prompt, поля запроса к webhook — из input. Доступно администраторам
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param name path string true "Template name" Enums(generatemd, reducemd, gentest, flashcards, ask)
+// @Param Preview body views.PromptPreviewRequest true "Template and its data"
+// @Success 200 {object} views.PromptPreview
+// @Failure 400 {object} views.SWGError
+// @Failure 401 {object} views.SWGError
+// @Failure 403 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/admin/prompts/{name}/preview [post]
+func (e *Echo) PreviewPrompt(c echo.Context) error {
+	const op = "net.PreviewPrompt"
+	log.Info(op, "")
+
+	name, ok := promptName(c)
+	if !ok {
+		log.Warn(op, "unknown prompt", nil)
+		return c.JSON(http.StatusNotFound, views.SWGError{Error: "unknown prompt"})
+	}
+	var r views.PromptPreviewRequest
+	if err := c.Bind(&r); err != nil {
+		log.Warn(op, "bad JSON", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "bad JSON"})
+	}
+
+	body := r.Body
+	if body == "" {
+		t, err := e.promptsAPI.Store.Get(c.Request().Context(), name, r.Version)
+		if err != nil {
+			if errors.Is(err, prompt.ErrNotFound) {
+				log.Warn(op, "", err)
+				return c.JSON(http.StatusNotFound, views.SWGError{Error: "prompt template not found"})
+			}
+			log.Error(op, "", err)
+			return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot get prompt template"})
+		}
+		body = t.Body
+	}
+
+	tpl, err := prompt.Parse(name, body)
+	if err != nil {
+		log.Warn(op, "", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: err.Error()})
+	}
+	out, err := prompt.Render(tpl, r.Prompt, r.Input)
+	if err != nil {
+		log.Warn(op, "", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: err.Error()})
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusOK, views.PromptPreview{Prompt: out})
+}
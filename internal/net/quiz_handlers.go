@@ -0,0 +1,403 @@
+package net
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flicker/internal/quiz"
+	"flicker/internal/views"
+	"net/http"
+	"time"
+
+	"github.com/autumnterror/breezynotes/pkg/log"
+	"github.com/autumnterror/breezynotes/pkg/utils/id"
+	"github.com/labstack/echo/v4"
+)
+
+// CreateQuiz godoc
+// @Summary Save quiz
+// @Description Validates quiz (e.g. returned by /api/ai/gentest) and saves it for current user
+// @Tags quizzes
+// @Accept json
+// @Produce json
+// @Param Quiz body views.Quiz true "Quiz"
+// @Success 201 {object} views.QuizId
+// @Failure 400 {object} views.SWGError
+// @Failure 401 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/quizzes [post]
+func (e *Echo) CreateQuiz(c echo.Context) error {
+	const op = "net.CreateQuiz"
+	log.Info(op, "")
+
+	var q views.Quiz
+	if err := c.Bind(&q); err != nil {
+		log.Warn(op, "bad JSON", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "bad JSON"})
+	}
+	if err := quiz.Validate(&q); err != nil {
+		log.Warn(op, "invalid quiz", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: err.Error()})
+	}
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	qid, err := e.saveQuiz(ctx, userId(c), &q, "")
+	if err != nil {
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "quiz creation failed"})
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusCreated, views.QuizId{Id: qid})
+}
+
+// ListQuizzes godoc
+// @Summary List quizzes
+// @Description Returns quizzes of current user without questions, newest first
+// @Tags quizzes
+// @Produce json
+// @Success 200 {array} views.SavedQuiz
+// @Failure 401 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/quizzes [get]
+func (e *Echo) ListQuizzes(c echo.Context) error {
+	const op = "net.ListQuizzes"
+	log.Info(op, "")
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	ls, err := e.quizzesAPI.List(ctx, userId(c))
+	if err != nil {
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot list quizzes"})
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusOK, ls)
+}
+
+// GetQuiz godoc
+// @Summary Get quiz
+// @Description Returns quiz of current user with questions and correct answers
+// @Tags quizzes
+// @Produce json
+// @Param id path string true "Quiz id"
+// @Success 200 {object} views.SavedQuiz
+// @Failure 401 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/quizzes/{id} [get]
+func (e *Echo) GetQuiz(c echo.Context) error {
+	const op = "net.GetQuiz"
+	log.Info(op, "")
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	q, err := e.quizzesAPI.Get(ctx, userId(c), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn(op, "", err)
+			return c.JSON(http.StatusNotFound, views.SWGError{Error: "quiz not found"})
+		}
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot get quiz"})
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusOK, q)
+}
+
+// DeleteQuiz godoc
+// @Summary Delete quiz
+// @Description Deletes quiz of current user with all attempts
+// @Tags quizzes
+// @Produce json
+// @Param id path string true "Quiz id"
+// @Success 200 {object} views.SWGMessage
+// @Failure 401 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/quizzes/{id} [delete]
+func (e *Echo) DeleteQuiz(c echo.Context) error {
+	const op = "net.DeleteQuiz"
+	log.Info(op, "")
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	if err := e.quizzesAPI.Delete(ctx, userId(c), c.Param("id")); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn(op, "", err)
+			return c.JSON(http.StatusNotFound, views.SWGError{Error: "quiz not found"})
+		}
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "quiz deletion failed"})
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusOK, views.SWGMessage{Message: "quiz deleted"})
+}
+
+// StartAttempt godoc
+// @Summary Start quiz attempt
+// @Description Starts new attempt and returns questions without correct answers
+// @Tags quizzes
+// @Produce json
+// @Param id path string true "Quiz id"
+// @Success 201 {object} views.QuizAttempt
+// @Failure 401 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/quizzes/{id}/attempts [post]
+func (e *Echo) StartAttempt(c echo.Context) error {
+	const op = "net.StartAttempt"
+	log.Info(op, "")
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	q, err := e.quizzesAPI.Get(ctx, userId(c), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn(op, "", err)
+			return c.JSON(http.StatusNotFound, views.SWGError{Error: "quiz not found"})
+		}
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot get quiz"})
+	}
+
+	a := &views.QuizAttempt{
+		Id:       id.New(),
+		QuizId:   q.Id,
+		MaxScore: float64(len(q.Questions)),
+	}
+	if err := e.quizzesAPI.StartAttempt(ctx, userId(c), a); err != nil {
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot start attempt"})
+	}
+	a.Questions = quiz.Strip(q.Questions)
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusCreated, a)
+}
+
+// ListAttempts godoc
+// @Summary List quiz attempts
+// @Description Returns history of attempts at quiz, newest first
+// @Tags quizzes
+// @Produce json
+// @Param id path string true "Quiz id"
+// @Success 200 {array} views.QuizAttempt
+// @Failure 401 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/quizzes/{id}/attempts [get]
+func (e *Echo) ListAttempts(c echo.Context) error {
+	const op = "net.ListAttempts"
+	log.Info(op, "")
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	ls, err := e.quizzesAPI.Attempts(ctx, userId(c), c.Param("id"))
+	if err != nil {
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot list attempts"})
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusOK, ls)
+}
+
+// GetAttempt godoc
+// @Summary Get quiz attempt
+// @Description Returns attempt. Started attempt contains questions, submitted one contains results
+// @Tags quizzes
+// @Produce json
+// @Param id path string true "Quiz id"
+// @Param attempt path string true "Attempt id"
+// @Success 200 {object} views.QuizAttempt
+// @Failure 401 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/quizzes/{id}/attempts/{attempt} [get]
+func (e *Echo) GetAttempt(c echo.Context) error {
+	const op = "net.GetAttempt"
+	log.Info(op, "")
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	q, a, err := e.attempt(ctx, c)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn(op, "", err)
+			return c.JSON(http.StatusNotFound, views.SWGError{Error: "attempt not found"})
+		}
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot get attempt"})
+	}
+	if a.Status == views.AttemptStarted {
+		a.Questions = quiz.Strip(q.Questions)
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusOK, a)
+}
+
+// SubmitAttempt godoc
+// @Summary Submit quiz attempt
+// @Description Grades answers and finishes attempt. Closed questions are scored exactly, open answers are scored by configured grader (LLM or keywords). Each question costs 1 point. Every open answer graded by LLM counts as one request of AI quota
+// @Tags quizzes
+// @Accept json
+// @Produce json
+// @Param id path string true "Quiz id"
+// @Param attempt path string true "Attempt id"
+// @Param Answers body views.AttemptSubmit true "Answers"
+// @Success 200 {object} views.QuizAttempt
+// @Failure 400 {object} views.SWGError
+// @Failure 401 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 409 {object} views.SWGError
+// @Failure 429 {object} views.SWGError
+// @Header 429 {string} Retry-After "Seconds until quota is reset"
+// @Failure 502 {object} views.SWGError
+// @Failure 503 {object} views.SWGError
+// @Header 503 {string} Retry-After "Seconds until upstream is called again"
+// @Router /api/quizzes/{id}/attempts/{attempt}/submit [post]
+func (e *Echo) SubmitAttempt(c echo.Context) error {
+	const op = "net.SubmitAttempt"
+	log.Info(op, "")
+
+	var r views.AttemptSubmit
+	if err := c.Bind(&r); err != nil {
+		log.Warn(op, "bad JSON", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "bad JSON"})
+	}
+
+	// открытые ответы может проверять LLM, поэтому таймаут как у генерации
+	ctx, done := context.WithTimeout(c.Request().Context(), 30*time.Second)
+	defer done()
+
+	q, a, err := e.attempt(ctx, c)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn(op, "", err)
+			return c.JSON(http.StatusNotFound, views.SWGError{Error: "attempt not found"})
+		}
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot get attempt"})
+	}
+	if a.Status != views.AttemptStarted {
+		log.Warn(op, "attempt already submitted", nil)
+		return c.JSON(http.StatusConflict, views.SWGError{Error: "attempt already submitted"})
+	}
+	if qe := e.reserveUnits(c, int64(quiz.LLMCalls(q.Questions, r.Answers, e.grader))); qe != nil {
+		log.Warn(op, "", qe)
+		return quotaExceeded(c, qe)
+	}
+
+	results, score, err := quiz.Grade(ctx, q.Questions, r.Answers, e.grader)
+	if err != nil {
+		if errors.Is(err, quiz.ErrBadAnswer) {
+			log.Warn(op, "", err)
+			return c.JSON(http.StatusBadRequest, views.SWGError{Error: err.Error()})
+		}
+		log.Error(op, "grade", err)
+		return upstreamError(c, err, http.StatusBadGateway, "grading failed")
+	}
+	a.Answers = r.Answers
+	a.Results = results
+	a.Score = score
+
+	if err := e.quizzesAPI.SubmitAttempt(ctx, userId(c), a); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn(op, "attempt submitted concurrently", err)
+			return c.JSON(http.StatusConflict, views.SWGError{Error: "attempt already submitted"})
+		}
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot submit attempt"})
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusOK, a)
+}
+
+// QuizStats godoc
+// @Summary Quiz statistics
+// @Description Returns best, worst, average and last score of submitted attempts and average score of every question, all in percents
+// @Tags quizzes
+// @Produce json
+// @Param id path string true "Quiz id"
+// @Success 200 {object} views.QuizStats
+// @Failure 401 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/quizzes/{id}/stats [get]
+func (e *Echo) QuizStats(c echo.Context) error {
+	const op = "net.QuizStats"
+	log.Info(op, "")
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	q, err := e.quizzesAPI.Get(ctx, userId(c), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn(op, "", err)
+			return c.JSON(http.StatusNotFound, views.SWGError{Error: "quiz not found"})
+		}
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot get quiz"})
+	}
+
+	ls, err := e.quizzesAPI.Attempts(ctx, userId(c), q.Id)
+	if err != nil {
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot list attempts"})
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusOK, quiz.Stats(ls, len(q.Questions)))
+}
+
+// attempt load quiz and attempt from path params. May send sql.ErrNoRows
+func (e *Echo) attempt(ctx context.Context, c echo.Context) (*views.SavedQuiz, *views.QuizAttempt, error) {
+	q, err := e.quizzesAPI.Get(ctx, userId(c), c.Param("id"))
+	if err != nil {
+		return nil, nil, err
+	}
+	a, err := e.quizzesAPI.Attempt(ctx, userId(c), q.Id, c.Param("attempt"))
+	if err != nil {
+		return nil, nil, err
+	}
+	return q, a, nil
+}
+
+// saveQuiz create quiz of owner and return its id
+func (e *Echo) saveQuiz(ctx context.Context, owner string, q *views.Quiz, promptVersion string) (string, error) {
+	sq := &views.SavedQuiz{
+		Id:        id.New(),
+		Owner:     owner,
+		Title:     noteTitle(q.Title, ""),
+		Questions: q.Questions,
+
+		PromptVersion: promptVersion,
+	}
+	if err := e.quizzesAPI.Create(ctx, sq); err != nil {
+		return "", err
+	}
+	return sq.Id, nil
+}
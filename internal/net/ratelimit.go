@@ -0,0 +1,77 @@
+package net
+
+import (
+	"flicker/internal/ratelimit"
+	"flicker/internal/views"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/autumnterror/breezynotes/pkg/log"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	headerRateLimit     = "RateLimit-Limit"
+	headerRateRemaining = "RateLimit-Remaining"
+	headerRateReset     = "RateLimit-Reset"
+	headerRatePolicy    = "RateLimit-Policy"
+)
+
+// limited throttle requests of route group with token bucket of caller. Callers with access token
+// are keyed by user id, others by IP. Auth routes are keyed by IP, their callers have no token yet
+func (e *Echo) limited(group string) echo.MiddlewareFunc {
+	limit, ok := e.cfg.RateLimits[group]
+	if e.limitsAPI == nil || !ok || limit.Limit <= 0 || limit.Period <= 0 {
+		return func(next echo.HandlerFunc) echo.HandlerFunc { return next }
+	}
+	rate := ratelimit.FromConfig(limit)
+	policy := fmt.Sprintf("%d;w=%d", limit.Limit, seconds(limit.Period))
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			const op = "net.limited"
+
+			key := group + ":" + e.caller(c, group == ratelimit.GroupAuth)
+			res, err := e.limitsAPI.Take(c.Request().Context(), key, rate)
+			if err != nil {
+				// store is down: serve request rather than fail every one
+				log.Error(op, group, err)
+				return next(c)
+			}
+
+			// route can be in several groups, client sees the limit closest to exhaustion
+			h := c.Response().Header()
+			if cur, err := strconv.Atoi(h.Get(headerRateRemaining)); err != nil || res.Remaining < cur || !res.Allowed {
+				h.Set(headerRateLimit, strconv.Itoa(res.Limit))
+				h.Set(headerRateRemaining, strconv.Itoa(res.Remaining))
+				h.Set(headerRateReset, strconv.Itoa(seconds(res.Reset)))
+				h.Set(headerRatePolicy, policy)
+			}
+
+			if !res.Allowed {
+				log.Warn(op, key, nil)
+				h.Set(echo.HeaderRetryAfter, strconv.Itoa(max(seconds(res.RetryAfter), 1)))
+				return c.JSON(http.StatusTooManyRequests, views.SWGError{Error: "too many requests"})
+			}
+			return next(c)
+		}
+	}
+}
+
+// caller return key of user from access token, or of IP if there is no valid token or ipOnly is set
+func (e *Echo) caller(c echo.Context, ipOnly bool) string {
+	if !ipOnly {
+		if id, err := e.userFromToken(c); err == nil {
+			return "user:" + id
+		}
+	}
+	return "ip:" + c.RealIP()
+}
+
+// seconds round duration up to whole seconds for headers
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,240 @@
+package net
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flicker/internal/diff"
+	"flicker/internal/views"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/autumnterror/breezynotes/pkg/log"
+	"github.com/labstack/echo/v4"
+)
+
+// ListNoteRevisions godoc
+// @Summary List note revisions
+// @Description Returns revisions of note without bodies, newest first
+// @Tags notes
+// @Produce json
+// @Param id path string true "Note id"
+// @Success 200 {array} views.NoteRevision
+// @Failure 401 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/notes/{id}/revisions [get]
+func (e *Echo) ListNoteRevisions(c echo.Context) error {
+	const op = "net.ListNoteRevisions"
+	log.Info(op, "")
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	ls, err := e.notesAPI.Revisions(ctx, userId(c), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn(op, "", err)
+			return c.JSON(http.StatusNotFound, views.SWGError{Error: "note not found"})
+		}
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot list revisions"})
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusOK, ls)
+}
+
+// GetNoteRevision godoc
+// @Summary Get note revision
+// @Description Returns one revision of note with body
+// @Tags notes
+// @Produce json
+// @Param id path string true "Note id"
+// @Param rev path int true "Revision number"
+// @Success 200 {object} views.NoteRevision
+// @Failure 400 {object} views.SWGError
+// @Failure 401 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/notes/{id}/revisions/{rev} [get]
+func (e *Echo) GetNoteRevision(c echo.Context) error {
+	const op = "net.GetNoteRevision"
+	log.Info(op, "")
+
+	rev, err := strconv.Atoi(c.Param("rev"))
+	if err != nil {
+		log.Warn(op, "bad revision", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "bad revision number"})
+	}
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	r, err := e.notesAPI.Revision(ctx, userId(c), c.Param("id"), rev)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn(op, "", err)
+			return c.JSON(http.StatusNotFound, views.SWGError{Error: "revision not found"})
+		}
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot get revision"})
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusOK, r)
+}
+
+// DiffNoteRevisions godoc
+// @Summary Diff two note revisions
+// @Description Returns unified diff between two revisions. By default to is head revision and from is revision before it. With format=text diff returned as plain text
+// @Tags notes
+// @Produce json
+// @Produce plain
+// @Param id path string true "Note id"
+// @Param from query int false "Old revision number"
+// @Param to query int false "New revision number"
+// @Param format query string false "Response format" Enums(json, text)
+// @Success 200 {object} views.NoteDiff
+// @Failure 400 {object} views.SWGError
+// @Failure 401 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/notes/{id}/diff [get]
+func (e *Echo) DiffNoteRevisions(c echo.Context) error {
+	const op = "net.DiffNoteRevisions"
+	log.Info(op, "")
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	owner, nid := userId(c), c.Param("id")
+
+	to, err := queryInt(c, "to")
+	if err != nil {
+		log.Warn(op, "bad to", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "bad revision number"})
+	}
+	from, err := queryInt(c, "from")
+	if err != nil {
+		log.Warn(op, "bad from", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "bad revision number"})
+	}
+	if to == 0 {
+		n, err := e.notesAPI.Get(ctx, owner, nid)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				log.Warn(op, "", err)
+				return c.JSON(http.StatusNotFound, views.SWGError{Error: "note not found"})
+			}
+			log.Error(op, "", err)
+			return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot get note"})
+		}
+		to = n.Revision
+	}
+	if from == 0 {
+		from = max(to-1, 1)
+	}
+
+	revs := make([]*views.NoteRevision, 0, 2)
+	for _, number := range []int{from, to} {
+		r, err := e.notesAPI.Revision(ctx, owner, nid, number)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				log.Warn(op, "", err)
+				return c.JSON(http.StatusNotFound, views.SWGError{Error: fmt.Sprintf("revision %d not found", number)})
+			}
+			log.Error(op, "", err)
+			return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot get revision"})
+		}
+		revs = append(revs, r)
+	}
+
+	d := diff.Unified(
+		fmt.Sprintf("revision %d", from),
+		fmt.Sprintf("revision %d", to),
+		revs[0].Body,
+		revs[1].Body,
+		diff.DefaultContext,
+	)
+
+	log.Success(op, "")
+
+	if c.QueryParam("format") == "text" {
+		return c.String(http.StatusOK, d)
+	}
+	return c.JSON(http.StatusOK, views.NoteDiff{
+		From: from,
+		To:   to,
+		Diff: d,
+	})
+}
+
+// RestoreNoteRevision godoc
+// @Summary Restore note revision
+// @Description Copies old revision into note as new head revision. History is not rewritten
+// @Tags notes
+// @Produce json
+// @Param id path string true "Note id"
+// @Param rev path int true "Revision number to restore"
+// @Success 200 {object} views.NoteRevision
+// @Failure 400 {object} views.SWGError
+// @Failure 401 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/notes/{id}/revisions/{rev}/restore [post]
+func (e *Echo) RestoreNoteRevision(c echo.Context) error {
+	const op = "net.RestoreNoteRevision"
+	log.Info(op, "")
+
+	rev, err := strconv.Atoi(c.Param("rev"))
+	if err != nil {
+		log.Warn(op, "bad revision", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "bad revision number"})
+	}
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	owner, nid := userId(c), c.Param("id")
+
+	head, err := e.notesAPI.Restore(ctx, owner, nid, rev)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn(op, "", err)
+			return c.JSON(http.StatusNotFound, views.SWGError{Error: "revision not found"})
+		}
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "revision restore failed"})
+	}
+
+	r, err := e.notesAPI.Revision(ctx, owner, nid, head)
+	if err != nil {
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot get revision"})
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusOK, r)
+}
+
+// queryInt parse optional positive int query param. Missing param is 0
+func queryInt(c echo.Context, name string) (int, error) {
+	raw := c.QueryParam(name)
+	if raw == "" {
+		return 0, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, err
+	}
+	if v < 1 {
+		return 0, fmt.Errorf("%s must be positive", name)
+	}
+	return v, nil
+}
@@ -0,0 +1,324 @@
+package net
+
+import (
+	"errors"
+	"flicker/internal/filecheck"
+	"flicker/internal/upload"
+	"flicker/internal/views"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+
+	"github.com/autumnterror/breezynotes/pkg/log"
+	"github.com/labstack/echo/v4"
+)
+
+// tus check protocol version of request and mark every response with it
+func (e *Echo) tus(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		const op = "net.tus"
+
+		c.Response().Header().Set(upload.HeaderResumable, upload.Version)
+		if c.Request().Method != http.MethodOptions && c.Request().Header.Get(upload.HeaderResumable) != upload.Version {
+			log.Warn(op, "", errors.New("unsupported tus version "+c.Request().Header.Get(upload.HeaderResumable)))
+			c.Response().Header().Set(upload.HeaderVersion, upload.Version)
+			return c.JSON(http.StatusPreconditionFailed, views.SWGError{Error: "unsupported tus version"})
+		}
+		return next(c)
+	}
+}
+
+// UploadOptions godoc
+// @Summary Tus server capabilities
+// @Description Returns supported tus version, extensions, checksum algorithms and maximum upload size in headers
+// @Tags uploads
+// @Success 204
+// @Router /api/uploads [options]
+func (e *Echo) UploadOptions(c echo.Context) error {
+	h := c.Response().Header()
+	h.Set(upload.HeaderVersion, upload.Version)
+	h.Set(upload.HeaderExtension, upload.Extensions)
+	h.Set(upload.HeaderAlgorithm, upload.ChecksumAlgorithms)
+	if e.uploadsAPI.MaxSize > 0 {
+		h.Set(upload.HeaderMaxSize, strconv.FormatInt(e.uploadsAPI.MaxSize, 10))
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// CreateUpload godoc
+// @Summary Create resumable upload
+// @Description Creates tus upload of Upload-Length bytes for current user and returns its URL in Location. Body with Content-Type application/offset+octet-stream is written as first chunk. Completed upload is passed to /api/ai/transcribe or /api/ai/file2db as upload_id
+// @Tags uploads
+// @Accept application/offset+octet-stream
+// @Param Tus-Resumable header string true "Protocol version" default(1.0.0)
+// @Param Upload-Length header int true "Size of file in bytes"
+// @Param Upload-Metadata header string false "Comma separated keys with base64 values, e.g. filename"
+// @Param Upload-Checksum header string false "Checksum of first chunk: algorithm and base64 digest"
+// @Success 201
+// @Failure 400 {object} views.SWGError
+// @Failure 401 {object} views.SWGError
+// @Failure 412 {object} views.SWGError
+// @Failure 413 {object} views.SWGError
+// @Failure 460 {object} views.SWGError
+// @Router /api/uploads [post]
+func (e *Echo) CreateUpload(c echo.Context) error {
+	const op = "net.CreateUpload"
+	log.Info(op, "")
+
+	req := c.Request()
+	length, err := strconv.ParseInt(req.Header.Get(upload.HeaderLength), 10, 64)
+	if err != nil || length < 0 {
+		log.Warn(op, "bad Upload-Length", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "Upload-Length is required"})
+	}
+	meta, err := upload.ParseMetadata(req.Header.Get(upload.HeaderMetadata))
+	if err != nil {
+		log.Warn(op, "", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "bad Upload-Metadata"})
+	}
+	withData := req.Header.Get(echo.HeaderContentType) == upload.ContentType
+	checksum, err := upload.ParseChecksum(req.Header.Get(upload.HeaderChecksum))
+	if err != nil {
+		log.Warn(op, "", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "bad Upload-Checksum"})
+	}
+
+	info, err := e.uploadsAPI.Create(userId(c), length, meta)
+	if err != nil {
+		if errors.Is(err, upload.ErrTooLarge) {
+			log.Warn(op, "", err)
+			return c.JSON(http.StatusRequestEntityTooLarge, views.SWGError{Error: "upload too large"})
+		}
+		log.Error(op, "", err)
+		return c.JSON(http.StatusInternalServerError, views.SWGError{Error: "cannot create upload"})
+	}
+
+	h := c.Response().Header()
+	h.Set(echo.HeaderLocation, "/api/uploads/"+info.Id)
+	h.Set(upload.HeaderExpires, info.ExpiresAt.Format(http.TimeFormat))
+	if withData {
+		written, err := e.uploadsAPI.Write(info.Owner, info.Id, 0, req.Body, checksum)
+		if written != nil {
+			h.Set(upload.HeaderOffset, strconv.FormatInt(written.Offset, 10))
+		}
+		if err != nil {
+			// upload is created anyway, client resumes it from Upload-Offset
+			return uploadWriteError(c, op, err)
+		}
+	}
+
+	log.Success(op, "")
+
+	return c.NoContent(http.StatusCreated)
+}
+
+// UploadStatus godoc
+// @Summary Get upload offset
+// @Description Returns in headers how many bytes of upload are received, so client resumes from Upload-Offset
+// @Tags uploads
+// @Param id path string true "Upload id"
+// @Param Tus-Resumable header string true "Protocol version" default(1.0.0)
+// @Success 200
+// @Failure 401 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 412 {object} views.SWGError
+// @Router /api/uploads/{id} [head]
+func (e *Echo) UploadStatus(c echo.Context) error {
+	const op = "net.UploadStatus"
+	log.Info(op, "")
+
+	h := c.Response().Header()
+	h.Set(echo.HeaderCacheControl, "no-store")
+
+	info, err := e.uploadsAPI.Get(userId(c), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, upload.ErrNotFound) {
+			log.Warn(op, "", err)
+			return c.NoContent(http.StatusNotFound)
+		}
+		log.Error(op, "", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+	setUploadHeaders(c, info)
+
+	log.Success(op, "")
+
+	return c.NoContent(http.StatusOK)
+}
+
+// WriteUpload godoc
+// @Summary Append chunk to upload
+// @Description Writes request body at Upload-Offset, which must be equal to current offset of upload. Chunk with Upload-Checksum is verified and discarded on mismatch with status 460
+// @Tags uploads
+// @Accept application/offset+octet-stream
+// @Param id path string true "Upload id"
+// @Param Tus-Resumable header string true "Protocol version" default(1.0.0)
+// @Param Upload-Offset header int true "Offset of chunk"
+// @Param Upload-Checksum header string false "Algorithm and base64 digest of chunk"
+// @Success 204
+// @Failure 400 {object} views.SWGError
+// @Failure 401 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 409 {object} views.SWGError
+// @Failure 412 {object} views.SWGError
+// @Failure 413 {object} views.SWGError
+// @Failure 415 {object} views.SWGError
+// @Failure 423 {object} views.SWGError
+// @Failure 460 {object} views.SWGError
+// @Router /api/uploads/{id} [patch]
+func (e *Echo) WriteUpload(c echo.Context) error {
+	const op = "net.WriteUpload"
+	log.Info(op, "")
+
+	req := c.Request()
+	if req.Header.Get(echo.HeaderContentType) != upload.ContentType {
+		log.Warn(op, "", errors.New("content type "+req.Header.Get(echo.HeaderContentType)))
+		return c.JSON(http.StatusUnsupportedMediaType, views.SWGError{Error: "Content-Type must be " + upload.ContentType})
+	}
+	offset, err := strconv.ParseInt(req.Header.Get(upload.HeaderOffset), 10, 64)
+	if err != nil || offset < 0 {
+		log.Warn(op, "bad Upload-Offset", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "Upload-Offset is required"})
+	}
+	checksum, err := upload.ParseChecksum(req.Header.Get(upload.HeaderChecksum))
+	if err != nil {
+		log.Warn(op, "", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "bad Upload-Checksum"})
+	}
+
+	info, err := e.uploadsAPI.Write(userId(c), c.Param("id"), offset, req.Body, checksum)
+	if info != nil {
+		setUploadHeaders(c, info)
+	}
+	if err != nil {
+		return uploadWriteError(c, op, err)
+	}
+
+	log.Success(op, "")
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// DeleteUpload godoc
+// @Summary Delete upload
+// @Description Terminates upload of current user and removes received data
+// @Tags uploads
+// @Param id path string true "Upload id"
+// @Param Tus-Resumable header string true "Protocol version" default(1.0.0)
+// @Success 204
+// @Failure 401 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 412 {object} views.SWGError
+// @Failure 423 {object} views.SWGError
+// @Router /api/uploads/{id} [delete]
+func (e *Echo) DeleteUpload(c echo.Context) error {
+	const op = "net.DeleteUpload"
+	log.Info(op, "")
+
+	if err := e.uploadsAPI.Delete(userId(c), c.Param("id")); err != nil {
+		switch {
+		case errors.Is(err, upload.ErrNotFound):
+			log.Warn(op, "", err)
+			return c.JSON(http.StatusNotFound, views.SWGError{Error: "upload not found"})
+		case errors.Is(err, upload.ErrLocked):
+			log.Warn(op, "", err)
+			return c.JSON(http.StatusLocked, views.SWGError{Error: "upload is being written"})
+		}
+		log.Error(op, "", err)
+		return c.JSON(http.StatusInternalServerError, views.SWGError{Error: "cannot delete upload"})
+	}
+
+	log.Success(op, "")
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func setUploadHeaders(c echo.Context, info *upload.Info) {
+	h := c.Response().Header()
+	h.Set(upload.HeaderOffset, strconv.FormatInt(info.Offset, 10))
+	h.Set(upload.HeaderLength, strconv.FormatInt(info.Length, 10))
+	h.Set(upload.HeaderExpires, info.ExpiresAt.Format(http.TimeFormat))
+	if len(info.Metadata) > 0 {
+		h.Set(upload.HeaderMetadata, upload.EncodeMetadata(info.Metadata))
+	}
+}
+
+func uploadWriteError(c echo.Context, op string, err error) error {
+	switch {
+	case errors.Is(err, upload.ErrNotFound):
+		log.Warn(op, "", err)
+		return c.JSON(http.StatusNotFound, views.SWGError{Error: "upload not found"})
+	case errors.Is(err, upload.ErrOffset):
+		log.Warn(op, "", err)
+		return c.JSON(http.StatusConflict, views.SWGError{Error: "Upload-Offset doesn't match upload"})
+	case errors.Is(err, upload.ErrTooLarge):
+		log.Warn(op, "", err)
+		return c.JSON(http.StatusRequestEntityTooLarge, views.SWGError{Error: "chunk exceeds Upload-Length"})
+	case errors.Is(err, upload.ErrChecksum):
+		log.Warn(op, "", err)
+		return c.JSON(upload.StatusChecksumMismatch, views.SWGError{Error: "checksum mismatch"})
+	case errors.Is(err, upload.ErrLocked):
+		log.Warn(op, "", err)
+		return c.JSON(http.StatusLocked, views.SWGError{Error: "upload is being written"})
+	}
+	// usually client disconnected, received bytes are kept
+	log.Error(op, "", err)
+	return c.JSON(http.StatusInternalServerError, views.SWGError{Error: "cannot write upload"})
+}
+
+var errNoFile = errors.New("file or upload_id is required")
+
+// sourceFile open multipart field "file" or, when upload_id is set, completed upload of owner.
+// File is checked by policy and returned with sanitized name
+func (e *Echo) sourceFile(c echo.Context, owner string, policy filecheck.Policy) (multipart.File, string, error) {
+	var (
+		f    multipart.File
+		name string
+		size int64
+	)
+	if uploadId := c.FormValue("upload_id"); uploadId != "" {
+		file, info, err := e.uploadsAPI.Open(owner, uploadId)
+		if err != nil {
+			return nil, "", err
+		}
+		f, name, size = file, info.Filename(), info.Length
+	} else {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return nil, "", errNoFile
+		}
+		if f, err = fileHeader.Open(); err != nil {
+			return nil, "", err
+		}
+		name, size = fileHeader.Filename, fileHeader.Size
+	}
+
+	name = filecheck.Sanitize(name)
+	if _, err := policy.Check(name, f, size); err != nil {
+		f.Close()
+		return nil, "", err
+	}
+	// file of upload is not in request body, so its size is counted as input here
+	usageOf(c).InputBytes = size
+	return f, name, nil
+}
+
+// sourceError answer on error of sourceFile
+func sourceError(c echo.Context, op string, err error) error {
+	log.Warn(op, "", err)
+	switch {
+	case errors.Is(err, errNoFile):
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "file is required"})
+	case errors.Is(err, upload.ErrNotFound):
+		return c.JSON(http.StatusNotFound, views.SWGError{Error: "upload not found"})
+	case errors.Is(err, upload.ErrIncomplete):
+		return c.JSON(http.StatusConflict, views.SWGError{Error: "upload is not completed"})
+	case errors.Is(err, filecheck.ErrEmpty):
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: err.Error()})
+	case errors.Is(err, filecheck.ErrTooLarge):
+		return c.JSON(http.StatusRequestEntityTooLarge, views.SWGError{Error: err.Error()})
+	case errors.Is(err, filecheck.ErrForbidden), errors.Is(err, filecheck.ErrNotAllowed), errors.Is(err, filecheck.ErrMismatch):
+		return c.JSON(http.StatusUnsupportedMediaType, views.SWGError{Error: err.Error()})
+	}
+	return c.JSON(http.StatusBadRequest, views.SWGError{Error: "cannot open uploaded file"})
+}
@@ -0,0 +1,30 @@
+package net
+
+import (
+	"errors"
+	"flicker/internal/upstream"
+	"flicker/internal/views"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// upstreamError send status with msg, or 503 with Retry-After if upstream was not called
+// because its circuit breaker is open
+func upstreamError(c echo.Context, err error, status int, msg string) error {
+	status, msg = upstreamStatus(c, err, status, msg)
+	return c.JSON(status, views.SWGError{Error: msg})
+}
+
+// upstreamStatus replace status and msg for error of open circuit breaker and set Retry-After.
+// Such request is not counted in usage quota
+func upstreamStatus(c echo.Context, err error, status int, msg string) (int, string) {
+	var open *upstream.OpenError
+	if !errors.As(err, &open) {
+		return status, msg
+	}
+	usageOf(c).Outcome = views.UsageUnavailable
+	c.Response().Header().Set(echo.HeaderRetryAfter, strconv.Itoa(max(seconds(open.RetryAfter), 1)))
+	return http.StatusServiceUnavailable, open.Upstream + " is unavailable, retry later"
+}
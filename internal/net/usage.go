@@ -0,0 +1,171 @@
+package net
+
+import (
+	"context"
+	"errors"
+	"flicker/internal/summary"
+	"flicker/internal/usage"
+	"flicker/internal/views"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/autumnterror/breezynotes/pkg/log"
+	"github.com/labstack/echo/v4"
+)
+
+const ctxUsage = "usage"
+
+// metered reject callers over AI quota with 429 and record every request in usage ledger.
+// Request is reserved in ledger before handler runs, so concurrent requests can't pass quota
+// together, and its record is completed after answer. Callers without token are counted by IP.
+// Handlers add audio seconds and tokens with usageOf
+func (e *Echo) metered(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		const op = "net.metered"
+
+		if e.usageAPI == nil {
+			return next(c)
+		}
+
+		user, err := e.userFromToken(c)
+		if err != nil {
+			user = usage.Anonymous(c.RealIP())
+		}
+
+		ctx := c.Request().Context()
+		rec, err := e.usageAPI.Reserve(ctx, user, c.Path())
+		if err != nil {
+			var qe *usage.QuotaError
+			if errors.As(err, &qe) {
+				log.Warn(op, user, err)
+				return quotaExceeded(c, qe)
+			}
+			// ledger is down: serve request rather than block every AI call
+			log.Error(op, "reserve quota", err)
+			rec = &views.UsageRecord{UserId: user, Endpoint: c.Path(), Units: 1}
+		}
+
+		body := &countingBody{ReadCloser: c.Request().Body}
+		c.Request().Body = body
+		// outcome of pending record is set by handler or by status of answer
+		rec.Outcome = ""
+		c.Set(ctxUsage, rec)
+
+		started := time.Now()
+		err = next(c)
+		if err != nil {
+			// answer error now to know its status
+			c.Error(err)
+		}
+
+		rec.LatencyMs = time.Since(started).Milliseconds()
+		rec.Status = c.Response().Status
+		rec.InputBytes = max(rec.InputBytes, body.n)
+		if rec.Outcome == "" {
+			rec.Outcome = usageOutcome(rec.Status, c.Response().Header().Get(headerCache))
+		}
+		if err := e.usageAPI.Finish(context.WithoutCancel(ctx), rec); err != nil {
+			log.Error(op, "record usage", err)
+		}
+		return nil
+	}
+}
+
+// reserveUnits count metered request as units requests of quota, e.g. one per LLM call made by it.
+// Return *usage.QuotaError if they don't fit, other errors of ledger are logged and request is served
+func (e *Echo) reserveUnits(c echo.Context, units int64) *usage.QuotaError {
+	const op = "net.reserveUnits"
+
+	rec, ok := c.Get(ctxUsage).(*views.UsageRecord)
+	if e.usageAPI == nil || !ok {
+		return nil
+	}
+	err := e.usageAPI.Resize(c.Request().Context(), rec, units)
+	var qe *usage.QuotaError
+	if errors.As(err, &qe) {
+		return qe
+	}
+	if err != nil {
+		log.Error(op, "", err)
+	}
+	return nil
+}
+
+// quotaExceeded answer 429 with Retry-After until quota is reset
+func quotaExceeded(c echo.Context, qe *usage.QuotaError) error {
+	c.Response().Header().Set(echo.HeaderRetryAfter, strconv.Itoa(max(seconds(time.Until(qe.ResetAt)), 1)))
+	return c.JSON(http.StatusTooManyRequests, views.SWGError{Error: qe.Error()})
+}
+
+func usageOutcome(status int, cache string) string {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return views.UsageError
+	case status >= http.StatusBadRequest:
+		return views.UsageInvalid
+	case cache == cacheHit:
+		return views.UsageCached
+	}
+	return views.UsageOK
+}
+
+// llmTokens estimate tokens of LLM input and output
+func llmTokens(texts ...string) int64 {
+	var n int64
+	for _, s := range texts {
+		n += int64(summary.Tokens(s))
+	}
+	return n
+}
+
+// usageOf return usage record of metered request, or throwaway one if request is not metered
+func usageOf(c echo.Context) *views.UsageRecord {
+	if rec, ok := c.Get(ctxUsage).(*views.UsageRecord); ok {
+		return rec
+	}
+	return &views.UsageRecord{}
+}
+
+// countingBody count bytes of request body read by handler
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+// MyUsage godoc
+// @Summary AI usage of current user
+// @Description Возвращает использование AI текущим пользователем за сутки и месяц (UTC): число запросов, объём входных данных, секунды аудио и оценку токенов, всего и по эндпоинтам, а также квоты его тарифа (0 — без ограничения). Ответы из кеша, запросы, отклонённые как некорректные, и запросы, не отправленные недоступному внешнему сервису (503), в квоту не засчитываются; выполняющиеся запросы засчитываются сразу. Запросы сверх квоты получают 429 с заголовком Retry-After до сброса квоты
+// @Tags user
+// @Produce json
+// @Success 200 {object} views.UsageSummary
+// @Failure 401 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/user/me/usage [get]
+func (e *Echo) MyUsage(c echo.Context) error {
+	const op = "net.MyUsage"
+	log.Info(op, "")
+
+	if e.usageAPI == nil {
+		log.Warn(op, "usage ledger is off", nil)
+		return c.JSON(http.StatusNotFound, views.SWGError{Error: "usage metering is off"})
+	}
+
+	res, err := e.usageAPI.Summary(c.Request().Context(), userId(c))
+	if err != nil {
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot get usage"})
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusOK, res)
+}
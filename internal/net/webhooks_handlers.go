@@ -0,0 +1,243 @@
+package net
+
+import (
+	"context"
+	"errors"
+	"flicker/internal/views"
+	"flicker/internal/webhook"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/autumnterror/breezynotes/pkg/log"
+	"github.com/autumnterror/breezynotes/pkg/utils/id"
+	"github.com/labstack/echo/v4"
+)
+
+// deliveriesLimit is how many last deliveries of webhook are returned
+const deliveriesLimit = 50
+
+// notify send event about finished job to webhooks of owner. Jobs of anonymous users are not
+// notified, and failure of notification doesn't change outcome of job
+func (e *Echo) notify(ctx context.Context, owner string, ev views.WebhookEvent) {
+	const op = "net.notify"
+
+	if e.webhooksAPI == nil || owner == "" {
+		return
+	}
+	ctx, done := context.WithTimeout(context.WithoutCancel(ctx), 3*time.Second)
+	defer done()
+	if err := e.webhooksAPI.Notify(ctx, owner, ev); err != nil {
+		log.Error(op, ev.Event, err)
+	}
+}
+
+// webhookError answer error of webhook store
+func webhookError(c echo.Context, op string, err error) error {
+	if errors.Is(err, webhook.ErrNotFound) {
+		log.Warn(op, "", err)
+		return c.JSON(http.StatusNotFound, views.SWGError{Error: "webhook not found"})
+	}
+	log.Error(op, "", err)
+	return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot get webhook"})
+}
+
+// CreateWebhook godoc
+// @Summary Register webhook
+// @Description Registers callback URL of current user. When subscribed job (transcription, document indexing, lecture) finishes or fails, POST with views.WebhookEvent is sent to URL. Request carries headers X-Flicker-Event, X-Flicker-Delivery, X-Flicker-Timestamp (unix seconds) and X-Flicker-Signature: "sha256=" and hex HMAC-SHA256 of timestamp, "." and body by secret of webhook. Secret is returned only in this response. Delivery without 2xx answer is retried with exponential backoff. Empty events subscribe to all events
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param Webhook body views.WebhookRequest true "Callback URL and events"
+// @Success 201 {object} views.Webhook
+// @Failure 400 {object} views.SWGError
+// @Failure 401 {object} views.SWGError
+// @Failure 409 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/webhooks [post]
+func (e *Echo) CreateWebhook(c echo.Context) error {
+	const op = "net.CreateWebhook"
+	log.Info(op, "")
+
+	var r views.WebhookRequest
+	if err := c.Bind(&r); err != nil {
+		log.Error(op, "bind json", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: "bad JSON"})
+	}
+	if err := webhook.ValidateURL(r.URL, e.cfg.WebhookAllowPrivate); err != nil {
+		log.Warn(op, "", err)
+		return c.JSON(http.StatusBadRequest, views.SWGError{Error: err.Error()})
+	}
+	events := slices.Clone(views.WebhookEvents)
+	if len(r.Events) > 0 {
+		events = nil
+		for _, ev := range r.Events {
+			if !slices.Contains(views.WebhookEvents, ev) {
+				log.Warn(op, "unknown event", nil)
+				return c.JSON(http.StatusBadRequest, views.SWGError{Error: fmt.Sprintf("unknown event %q", ev)})
+			}
+			if !slices.Contains(events, ev) {
+				events = append(events, ev)
+			}
+		}
+	}
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	hooks, err := e.webhooksAPI.Store.List(ctx, userId(c))
+	if err != nil {
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot get webhooks"})
+	}
+	if len(hooks) >= webhook.MaxWebhooks {
+		log.Warn(op, "too many webhooks", nil)
+		return c.JSON(http.StatusConflict, views.SWGError{Error: fmt.Sprintf("user may have at most %d webhooks", webhook.MaxWebhooks)})
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		log.Error(op, "", err)
+		return c.JSON(http.StatusInternalServerError, views.SWGError{Error: "cannot create webhook"})
+	}
+	h := &views.Webhook{
+		Id:     id.New(),
+		Owner:  userId(c),
+		URL:    r.URL,
+		Events: events,
+		Secret: secret,
+	}
+	if err := e.webhooksAPI.Store.Create(ctx, h); err != nil {
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot create webhook"})
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusCreated, h)
+}
+
+// ListWebhooks godoc
+// @Summary List webhooks
+// @Description Returns webhooks of current user without secrets, oldest first
+// @Tags webhooks
+// @Produce json
+// @Success 200 {array} views.Webhook
+// @Failure 401 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/webhooks [get]
+func (e *Echo) ListWebhooks(c echo.Context) error {
+	const op = "net.ListWebhooks"
+	log.Info(op, "")
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	hooks, err := e.webhooksAPI.Store.List(ctx, userId(c))
+	if err != nil {
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot get webhooks"})
+	}
+	for _, h := range hooks {
+		h.Secret = ""
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusOK, hooks)
+}
+
+// DeleteWebhook godoc
+// @Summary Delete webhook
+// @Description Deletes webhook of current user with its delivery log. Deliveries already being retried are still sent
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Webhook id"
+// @Success 200 {object} views.SWGMessage
+// @Failure 401 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/webhooks/{id} [delete]
+func (e *Echo) DeleteWebhook(c echo.Context) error {
+	const op = "net.DeleteWebhook"
+	log.Info(op, "")
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	if err := e.webhooksAPI.Store.Delete(ctx, userId(c), c.Param("id")); err != nil {
+		return webhookError(c, op, err)
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusOK, views.SWGMessage{Message: "webhook deleted"})
+}
+
+// ListWebhookDeliveries godoc
+// @Summary List webhook deliveries
+// @Description Returns last 50 deliveries of webhook of current user, newest first: payload, status (pending while retries go on), number of attempts, status code and error of last attempt
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Webhook id"
+// @Success 200 {array} views.WebhookDelivery
+// @Failure 401 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/webhooks/{id}/deliveries [get]
+func (e *Echo) ListWebhookDeliveries(c echo.Context) error {
+	const op = "net.ListWebhookDeliveries"
+	log.Info(op, "")
+
+	ctx, done := context.WithTimeout(c.Request().Context(), 3*time.Second)
+	defer done()
+
+	h, err := e.webhooksAPI.Store.Get(ctx, userId(c), c.Param("id"))
+	if err != nil {
+		return webhookError(c, op, err)
+	}
+	ds, err := e.webhooksAPI.Store.Deliveries(ctx, h.Id, deliveriesLimit)
+	if err != nil {
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot get deliveries"})
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusOK, ds)
+}
+
+// PingWebhook godoc
+// @Summary Test webhook
+// @Description Sends signed ping event to webhook of current user once, without retries, and returns delivery with answer of receiver. Delivery is added to log
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Webhook id"
+// @Success 200 {object} views.WebhookDelivery
+// @Failure 401 {object} views.SWGError
+// @Failure 404 {object} views.SWGError
+// @Failure 502 {object} views.SWGError
+// @Router /api/webhooks/{id}/ping [post]
+func (e *Echo) PingWebhook(c echo.Context) error {
+	const op = "net.PingWebhook"
+	log.Info(op, "")
+
+	h, err := e.webhooksAPI.Store.Get(c.Request().Context(), userId(c), c.Param("id"))
+	if err != nil {
+		return webhookError(c, op, err)
+	}
+
+	ctx, done := context.WithTimeout(c.Request().Context(), e.cfg.WebhookTimeout+3*time.Second)
+	defer done()
+
+	dl, err := e.webhooksAPI.Ping(ctx, h)
+	if err != nil {
+		log.Error(op, "", err)
+		return c.JSON(http.StatusBadGateway, views.SWGError{Error: "cannot save delivery"})
+	}
+
+	log.Success(op, "")
+
+	return c.JSON(http.StatusOK, dl)
+}
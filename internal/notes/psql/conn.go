@@ -0,0 +1,40 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"flicker/internal/views"
+	"time"
+)
+
+type SqlRepo interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+const (
+	waitTime = 3 * time.Second
+)
+
+type Driver struct {
+	driver SqlRepo
+}
+
+func NewDriver(driver SqlRepo) *Driver {
+	return &Driver{
+		driver: driver,
+	}
+}
+
+type NotesRepo interface {
+	Create(ctx context.Context, n *views.Note) error
+	Get(ctx context.Context, owner, id string) (*views.Note, error)
+	List(ctx context.Context, owner string) ([]*views.Note, error)
+	Update(ctx context.Context, owner, id, title, body string) (int, error)
+	Delete(ctx context.Context, owner, id string) error
+
+	Revisions(ctx context.Context, owner, id string) ([]*views.NoteRevision, error)
+	Revision(ctx context.Context, owner, id string, number int) (*views.NoteRevision, error)
+	Restore(ctx context.Context, owner, id string, number int) (int, error)
+}
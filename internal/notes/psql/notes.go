@@ -0,0 +1,234 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"flicker/internal/views"
+
+	"github.com/autumnterror/breezynotes/pkg/log"
+	"github.com/autumnterror/breezynotes/pkg/utils/format"
+)
+
+// Create new note with first revision. Id and owner must be set by caller
+func (d *Driver) Create(ctx context.Context, n *views.Note) error {
+	const op = "psql.notes.Create"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	query := `
+				WITH n AS (
+					INSERT INTO notes (id, owner, title, body, source_type, source_ref, prompt_version)
+					VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''))
+					RETURNING id, revision, title, body, created_at
+				)
+				INSERT INTO note_revisions (note_id, number, title, body, created_at)
+				SELECT id, revision, title, body, created_at FROM n
+				RETURNING number, created_at
+			`
+
+	if err := d.driver.QueryRowContext(ctx, query, n.Id, n.Owner, n.Title, n.Body, n.SourceType, n.SourceRef, n.PromptVersion).
+		Scan(&n.Revision, &n.CreatedAt); err != nil {
+		return format.Error(op, err)
+	}
+	n.UpdatedAt = n.CreatedAt
+
+	return nil
+}
+
+// Get note of owner by id. May send sql.ErrNoRows
+func (d *Driver) Get(ctx context.Context, owner, id string) (*views.Note, error) {
+	const op = "psql.notes.Get"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	query := `
+		SELECT id, owner, title, body, source_type, COALESCE(source_ref, ''), COALESCE(prompt_version, ''), revision, created_at, updated_at
+		FROM notes
+		WHERE id = $1 AND owner = $2
+	`
+	var n views.Note
+	if err := d.driver.QueryRowContext(ctx, query, id, owner).
+		Scan(&n.Id, &n.Owner, &n.Title, &n.Body, &n.SourceType, &n.SourceRef, &n.PromptVersion, &n.Revision, &n.CreatedAt, &n.UpdatedAt); err != nil {
+		return nil, format.Error(op, err)
+	}
+
+	return &n, nil
+}
+
+// List all notes of owner, most recently updated first
+func (d *Driver) List(ctx context.Context, owner string) ([]*views.Note, error) {
+	const op = "psql.notes.List"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	query := `
+		SELECT id, owner, title, body, source_type, COALESCE(source_ref, ''), COALESCE(prompt_version, ''), revision, created_at, updated_at
+		FROM notes
+		WHERE owner = $1
+		ORDER BY updated_at DESC
+	`
+	rows, err := d.driver.QueryContext(ctx, query, owner)
+	if err != nil {
+		return nil, format.Error(op, err)
+	}
+	defer rows.Close()
+
+	ls := []*views.Note{}
+	for rows.Next() {
+		var n views.Note
+		if err := rows.Scan(&n.Id, &n.Owner, &n.Title, &n.Body, &n.SourceType, &n.SourceRef, &n.PromptVersion, &n.Revision, &n.CreatedAt, &n.UpdatedAt); err != nil {
+			log.Error(op, "rows scan error", err)
+			continue
+		}
+		ls = append(ls, &n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, format.Error(op, err)
+	}
+
+	return ls, nil
+}
+
+// Update title and body of note and store them as new revision. Return number of new revision.
+// Returns sql.ErrNoRows if note not found.
+func (d *Driver) Update(ctx context.Context, owner, id, title, body string) (int, error) {
+	const op = "psql.notes.Update"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	query := `
+		WITH upd AS (
+			UPDATE notes SET title = $1, body = $2, revision = revision + 1, updated_at = now()
+			WHERE id = $3 AND owner = $4
+			RETURNING id, revision, title, body, updated_at
+		)
+		INSERT INTO note_revisions (note_id, number, title, body, created_at)
+		SELECT id, revision, title, body, updated_at FROM upd
+		RETURNING number
+	`
+
+	var number int
+	if err := d.driver.QueryRowContext(ctx, query, title, body, id, owner).Scan(&number); err != nil {
+		return 0, format.Error(op, err)
+	}
+
+	return number, nil
+}
+
+// Delete note. May send sql.ErrNoRows
+func (d *Driver) Delete(ctx context.Context, owner, id string) error {
+	const op = "psql.notes.Delete"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	res, err := d.driver.ExecContext(ctx, `DELETE FROM notes WHERE id = $1 AND owner = $2`, id, owner)
+	if err != nil {
+		return format.Error(op, err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return format.Error(op, err)
+	}
+	if rows == 0 {
+		return format.Error(op, sql.ErrNoRows)
+	}
+	return nil
+}
+
+// Revisions return revisions of note without bodies, newest first. May send sql.ErrNoRows
+func (d *Driver) Revisions(ctx context.Context, owner, id string) ([]*views.NoteRevision, error) {
+	const op = "psql.notes.Revisions"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	query := `
+		SELECT r.number, r.title, COALESCE(r.restored_from, 0), r.created_at
+		FROM note_revisions r
+		JOIN notes n ON n.id = r.note_id
+		WHERE r.note_id = $1 AND n.owner = $2
+		ORDER BY r.number DESC
+	`
+	rows, err := d.driver.QueryContext(ctx, query, id, owner)
+	if err != nil {
+		return nil, format.Error(op, err)
+	}
+	defer rows.Close()
+
+	var ls []*views.NoteRevision
+	for rows.Next() {
+		var r views.NoteRevision
+		if err := rows.Scan(&r.Number, &r.Title, &r.RestoredFrom, &r.CreatedAt); err != nil {
+			log.Error(op, "rows scan error", err)
+			continue
+		}
+		ls = append(ls, &r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, format.Error(op, err)
+	}
+	// every existing note has at least one revision
+	if len(ls) == 0 {
+		return nil, format.Error(op, sql.ErrNoRows)
+	}
+
+	return ls, nil
+}
+
+// Revision return one revision of note with body. May send sql.ErrNoRows
+func (d *Driver) Revision(ctx context.Context, owner, id string, number int) (*views.NoteRevision, error) {
+	const op = "psql.notes.Revision"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	query := `
+		SELECT r.number, r.title, r.body, COALESCE(r.restored_from, 0), r.created_at
+		FROM note_revisions r
+		JOIN notes n ON n.id = r.note_id
+		WHERE r.note_id = $1 AND n.owner = $2 AND r.number = $3
+	`
+	var r views.NoteRevision
+	if err := d.driver.QueryRowContext(ctx, query, id, owner, number).
+		Scan(&r.Number, &r.Title, &r.Body, &r.RestoredFrom, &r.CreatedAt); err != nil {
+		return nil, format.Error(op, err)
+	}
+
+	return &r, nil
+}
+
+// Restore copy old revision into note as new head revision. Return number of new revision.
+// Returns sql.ErrNoRows if note or revision not found.
+func (d *Driver) Restore(ctx context.Context, owner, id string, number int) (int, error) {
+	const op = "psql.notes.Restore"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	query := `
+		WITH old AS (
+			SELECT title, body FROM note_revisions
+			WHERE note_id = $1 AND number = $3
+		), upd AS (
+			UPDATE notes SET title = old.title, body = old.body, revision = revision + 1, updated_at = now()
+			FROM old
+			WHERE notes.id = $1 AND notes.owner = $2
+			RETURNING notes.id, notes.revision, notes.title, notes.body, notes.updated_at
+		)
+		INSERT INTO note_revisions (note_id, number, title, body, restored_from, created_at)
+		SELECT id, revision, title, body, $3, updated_at FROM upd
+		RETURNING number
+	`
+
+	var head int
+	if err := d.driver.QueryRowContext(ctx, query, id, owner, number).Scan(&head); err != nil {
+		return 0, format.Error(op, err)
+	}
+
+	return head, nil
+}
@@ -0,0 +1,152 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	authpsql "flicker/internal/auth/psql"
+	"flicker/internal/config"
+	"flicker/internal/views"
+	"fmt"
+	"testing"
+
+	"github.com/autumnterror/breezynotes/pkg/utils/format"
+	"github.com/autumnterror/breezynotes/pkg/utils/id"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotesOperations(t *testing.T) {
+	t.Parallel()
+
+	repo, owner, cleanup := setupTestTx(t)
+	defer cleanup()
+
+	note := &views.Note{
+		Id:         id.New(),
+		Owner:      owner,
+		Title:      "test note",
+		Body:       "# Конспект",
+		SourceType: views.NoteSourceTranscript,
+		SourceRef:  "lecture.mp3",
+
+		PromptVersion: "generatemd=t1;reducemd=1",
+	}
+
+	t.Run("create", func(t *testing.T) {
+		assert.NoError(t, repo.Create(context.TODO(), note))
+		assert.False(t, note.CreatedAt.IsZero())
+	})
+
+	t.Run("get", func(t *testing.T) {
+		n, err := repo.Get(context.TODO(), owner, note.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, note.Body, n.Body)
+		assert.Equal(t, note.SourceRef, n.SourceRef)
+		assert.Equal(t, note.PromptVersion, n.PromptVersion)
+		fmt.Printf("📝 Get: %s", format.Struct(n))
+	})
+
+	t.Run("update", func(t *testing.T) {
+		rev, err := repo.Update(context.TODO(), owner, note.Id, "new title", "new body")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, rev)
+		n, err := repo.Get(context.TODO(), owner, note.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, "new title", n.Title)
+		assert.Equal(t, "new body", n.Body)
+		assert.Equal(t, 2, n.Revision)
+	})
+
+	t.Run("revisions", func(t *testing.T) {
+		ls, err := repo.Revisions(context.TODO(), owner, note.Id)
+		assert.NoError(t, err)
+		assert.Len(t, ls, 2)
+		assert.Equal(t, 2, ls[0].Number)
+
+		r, err := repo.Revision(context.TODO(), owner, note.Id, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "# Конспект", r.Body)
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		head, err := repo.Restore(context.TODO(), owner, note.Id, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, head)
+
+		n, err := repo.Get(context.TODO(), owner, note.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, "# Конспект", n.Body)
+
+		r, err := repo.Revision(context.TODO(), owner, note.Id, 3)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, r.RestoredFrom)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		ls, err := repo.List(context.TODO(), owner)
+		assert.NoError(t, err)
+		assert.Len(t, ls, 1)
+	})
+
+	t.Run("foreign owner", func(t *testing.T) {
+		_, err := repo.Get(context.TODO(), id.New(), note.Id)
+		assert.True(t, errors.Is(err, sql.ErrNoRows))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.NoError(t, repo.Delete(context.TODO(), owner, note.Id))
+		_, err := repo.Get(context.TODO(), owner, note.Id)
+		assert.True(t, errors.Is(err, sql.ErrNoRows))
+	})
+}
+
+func TestDeleteNonExistentNote(t *testing.T) {
+	t.Parallel()
+	repo, owner, cleanup := setupTestTx(t)
+	defer cleanup()
+
+	err := repo.Delete(context.TODO(), owner, id.New())
+	assert.True(t, errors.Is(err, sql.ErrNoRows))
+}
+
+func TestUpdateNonExistentNote(t *testing.T) {
+	t.Parallel()
+	repo, owner, cleanup := setupTestTx(t)
+	defer cleanup()
+
+	_, err := repo.Update(context.TODO(), owner, id.New(), "title", "body")
+	assert.True(t, errors.Is(err, sql.ErrNoRows))
+}
+
+func TestRevisionsNonExistentNote(t *testing.T) {
+	t.Parallel()
+	repo, owner, cleanup := setupTestTx(t)
+	defer cleanup()
+
+	_, err := repo.Revisions(context.TODO(), owner, id.New())
+	assert.True(t, errors.Is(err, sql.ErrNoRows))
+
+	_, err = repo.Restore(context.TODO(), owner, id.New(), 1)
+	assert.True(t, errors.Is(err, sql.ErrNoRows))
+}
+
+// setupTestTx return driver inside transaction with one created user
+func setupTestTx(t *testing.T) (*Driver, string, func()) {
+	pdb := authpsql.MustConnect(config.Test())
+
+	tx, err := pdb.Driver.Begin()
+	assert.NoError(t, err)
+
+	owner := id.New()
+	assert.NoError(t, authpsql.NewDriver(tx).Create(context.TODO(), &views.User{
+		Id:       owner,
+		Login:    owner[:10],
+		Email:    owner[:10] + "@example.com",
+		Password: "password",
+	}))
+
+	return NewDriver(tx), owner, func() {
+		assert.NoError(t, tx.Rollback())
+		assert.NoError(t, pdb.Disconnect())
+	}
+}
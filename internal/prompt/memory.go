@@ -0,0 +1,85 @@
+package prompt
+
+import (
+	"context"
+	"flicker/internal/views"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Memory keep templates of one instance, for development and tests
+type Memory struct {
+	mu        sync.Mutex
+	templates map[string][]views.PromptTemplate
+}
+
+func NewMemory() *Memory {
+	return &Memory{templates: map[string][]views.PromptTemplate{}}
+}
+
+func (m *Memory) Create(_ context.Context, t *views.PromptTemplate) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t.Version = len(m.templates[t.Name]) + 1
+	t.Active = false
+	t.CreatedAt = time.Now()
+	m.templates[t.Name] = append(m.templates[t.Name], *t)
+	return nil
+}
+
+func (m *Memory) Get(_ context.Context, name string, version int) (*views.PromptTemplate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, t := range m.templates[name] {
+		if t.Version == version || version == 0 && t.Active {
+			return &t, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *Memory) List(_ context.Context, name string) ([]*views.PromptTemplate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	versions := m.templates[name]
+	ls := make([]*views.PromptTemplate, 0, len(versions))
+	for i := len(versions) - 1; i >= 0; i-- {
+		t := versions[i]
+		ls = append(ls, &t)
+	}
+	return ls, nil
+}
+
+func (m *Memory) Active(_ context.Context) ([]*views.PromptTemplate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ls := []*views.PromptTemplate{}
+	for _, versions := range m.templates {
+		for _, t := range versions {
+			if t.Active {
+				ls = append(ls, &t)
+			}
+		}
+	}
+	sort.Slice(ls, func(i, j int) bool { return ls[i].Name < ls[j].Name })
+	return ls, nil
+}
+
+func (m *Memory) Activate(_ context.Context, name string, version int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	versions := m.templates[name]
+	if version < 0 || version > len(versions) {
+		return ErrNotFound
+	}
+	for i := range versions {
+		versions[i].Active = versions[i].Version == version
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+// Package prompt keep templates of LLM prompts in flicker instead of n8n workflows. Templates are
+// versioned, request picks versions and variables, and n8n client sends rendered prompt with payload.
+package prompt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flicker/internal/config"
+	"flicker/internal/views"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/autumnterror/breezynotes/pkg/utils/format"
+)
+
+const (
+	StoreMemory   = "memory"
+	StorePostgres = "postgres"
+)
+
+const (
+	// MaxBody limit size of template in bytes
+	MaxBody = 64 << 10
+	// MaxVar limit length of variable of request in characters
+	MaxVar = 100
+)
+
+var (
+	ErrNotFound = errors.New("prompt template not found")
+	ErrTemplate = errors.New("bad prompt template")
+	ErrOptions  = errors.New("bad prompt options")
+)
+
+// Store keep versions of templates
+type Store interface {
+	// Create save t as next version of template t.Name, set its version and creation time
+	Create(ctx context.Context, t *views.PromptTemplate) error
+	// Get version of template, version 0 is active one. Sends ErrNotFound
+	Get(ctx context.Context, name string, version int) (*views.PromptTemplate, error)
+	// List versions of template, newest first
+	List(ctx context.Context, name string) ([]*views.PromptTemplate, error)
+	// Active return active versions of all templates by name
+	Active(ctx context.Context) ([]*views.PromptTemplate, error)
+	// Activate make version of template active, version 0 deactivate template. Sends ErrNotFound
+	Activate(ctx context.Context, name string, version int) error
+}
+
+// Data is what template is executed with: variables of request and fields of request to hook,
+// e.g. {{.Language}} or {{.Input.content}}
+type Data struct {
+	Language string
+	Detail   string
+	Audience string
+	Format   string
+	Input    map[string]any
+}
+
+// Parse check body of template. Error is meant for author of template, so it has no op
+func Parse(name, body string) (*template.Template, error) {
+	if strings.TrimSpace(body) == "" {
+		return nil, fmt.Errorf("%w: empty body", ErrTemplate)
+	}
+	if len(body) > MaxBody {
+		return nil, fmt.Errorf("%w: body is longer than %d bytes", ErrTemplate, MaxBody)
+	}
+	t, err := template.New(name).Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTemplate, err)
+	}
+	return t, nil
+}
+
+// Render execute template with variables of o and input. Error is meant for author of template
+func Render(t *template.Template, o views.PromptOptions, input map[string]any) (string, error) {
+	var b strings.Builder
+	if err := t.Execute(&b, Data{
+		Language: o.Language,
+		Detail:   o.Detail,
+		Audience: o.Audience,
+		Format:   o.Format,
+		Input:    input,
+	}); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrTemplate, err)
+	}
+	return b.String(), nil
+}
+
+// Library choose templates for requests
+type Library struct {
+	Store Store
+	// Versions are versions of prompts of n8n workflows, they identify hooks without template
+	Versions map[string]string
+}
+
+func New(store Store, cfg *config.Config) *Library {
+	return &Library{
+		Store:    store,
+		Versions: cfg.PromptVersions,
+	}
+}
+
+// Resolve choose templates of hooks for one request: versions picked by o or active ones.
+// Hook without active template keeps prompt of its n8n workflow
+func (l *Library) Resolve(ctx context.Context, hooks []string, o views.PromptOptions) (*Set, error) {
+	const op = "prompt.Library.Resolve"
+
+	for _, v := range []string{o.Language, o.Detail, o.Audience, o.Format} {
+		if utf8.RuneCountInString(v) > MaxVar {
+			return nil, format.Error(op, fmt.Errorf("%w: variables must be shorter than %d characters", ErrOptions, MaxVar))
+		}
+	}
+	for name, v := range o.Templates {
+		if !slices.Contains(hooks, name) {
+			return nil, format.Error(op, fmt.Errorf("%w: template %s is not used by request", ErrOptions, name))
+		}
+		if v < 0 {
+			return nil, format.Error(op, fmt.Errorf("%w: bad version of %s", ErrOptions, name))
+		}
+	}
+
+	s := &Set{
+		hooks:     hooks,
+		options:   o,
+		fallback:  l.Versions,
+		templates: map[string]entry{},
+	}
+	if l.Store == nil {
+		return s, nil
+	}
+	for _, h := range hooks {
+		v := o.Templates[h]
+		t, err := l.Store.Get(ctx, h, v)
+		if errors.Is(err, ErrNotFound) && v == 0 {
+			continue
+		}
+		if err != nil {
+			return nil, format.Error(op, err)
+		}
+		parsed, err := Parse(t.Name, t.Body)
+		if err != nil {
+			return nil, format.Error(op, err)
+		}
+		s.templates[h] = entry{version: t.Version, template: parsed}
+	}
+	return s, nil
+}
+
+type entry struct {
+	version  int
+	template *template.Template
+}
+
+// Set is templates chosen for one request and variables of request
+type Set struct {
+	hooks     []string
+	options   views.PromptOptions
+	fallback  map[string]string
+	templates map[string]entry
+}
+
+// Version identify prompts of all hooks of set, e.g. "generatemd=t3;reducemd=1": tN is version of
+// template, plain version is the one of n8n workflow from config
+func (s *Set) Version() string {
+	parts := make([]string, len(s.hooks))
+	for i, h := range s.hooks {
+		v := s.fallback[h]
+		if e, ok := s.templates[h]; ok {
+			v = "t" + strconv.Itoa(e.version)
+		}
+		if v == "" {
+			v = "0"
+		}
+		parts[i] = h + "=" + v
+	}
+	return strings.Join(parts, ";")
+}
+
+// Vars identify variables of request, empty if there are none or no template uses them
+func (s *Set) Vars() string {
+	o := s.options
+	if len(s.templates) == 0 || o.Language+o.Detail+o.Audience+o.Format == "" {
+		return ""
+	}
+	return fmt.Sprintf("language=%s;detail=%s;audience=%s;format=%s", o.Language, o.Detail, o.Audience, o.Format)
+}
+
+type ctxKey struct{}
+
+// With return ctx which LLM calls use templates of s
+func With(ctx context.Context, s *Set) context.Context {
+	return context.WithValue(ctx, ctxKey{}, s)
+}
+
+func from(ctx context.Context) *Set {
+	s, _ := ctx.Value(ctxKey{}).(*Set)
+	return s
+}
+
+// Apply add prompt rendered by template of hook to payload as prompt and prompt_version fields.
+// Payload is returned as is if ctx has no template for hook
+func Apply(ctx context.Context, hook string, payload any) (any, error) {
+	const op = "prompt.Apply"
+
+	s := from(ctx)
+	if s == nil {
+		return payload, nil
+	}
+	e, ok := s.templates[hook]
+	if !ok {
+		return payload, nil
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, format.Error(op, err)
+	}
+	var input map[string]any
+	if err := json.Unmarshal(b, &input); err != nil {
+		return nil, format.Error(op, err)
+	}
+	text, err := Render(e.template, s.options, input)
+	if err != nil {
+		return nil, format.Error(op, err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, format.Error(op, err)
+	}
+	if fields["prompt"], err = json.Marshal(text); err != nil {
+		return nil, format.Error(op, err)
+	}
+	fields["prompt_version"] = json.RawMessage(strconv.Itoa(e.version))
+	return fields, nil
+}
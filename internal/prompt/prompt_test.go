@@ -0,0 +1,148 @@
+package prompt
+
+import (
+	"context"
+	"encoding/json"
+	"flicker/internal/views"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mapRequest struct {
+	Content string `json:"content"`
+	Title   string `json:"title,omitempty"`
+}
+
+func newLibrary(t *testing.T, templates map[string][]string) *Library {
+	m := NewMemory()
+	for name, bodies := range templates {
+		for _, b := range bodies {
+			assert.NoError(t, m.Create(context.TODO(), &views.PromptTemplate{Name: name, Body: b}))
+		}
+		assert.NoError(t, m.Activate(context.TODO(), name, len(bodies)))
+	}
+	return &Library{Store: m, Versions: map[string]string{"generatemd": "2", "reducemd": "1"}}
+}
+
+func TestParse(t *testing.T) {
+	_, err := Parse("generatemd", "Конспект на языке {{.Language}}")
+	assert.NoError(t, err)
+
+	for _, body := range []string{"", "  ", "{{.Language", "{{end}}", strings.Repeat("a", MaxBody+1)} {
+		_, err := Parse("generatemd", body)
+		assert.ErrorIs(t, err, ErrTemplate, body)
+	}
+}
+
+func TestRender(t *testing.T) {
+	tpl, err := Parse("flashcards", `{{.Input.count}} cards for {{.Audience}}{{if .Input.title}} about {{.Input.title}}{{end}}`)
+	assert.NoError(t, err)
+
+	out, err := Render(tpl, views.PromptOptions{Audience: "students"}, map[string]any{"count": 5})
+	assert.NoError(t, err)
+	assert.Equal(t, "5 cards for students", out)
+
+	tpl, err = Parse("flashcards", `{{.Input.count.n}}`)
+	assert.NoError(t, err)
+	_, err = Render(tpl, views.PromptOptions{}, map[string]any{"count": 5})
+	assert.ErrorIs(t, err, ErrTemplate)
+}
+
+func TestResolve(t *testing.T) {
+	l := newLibrary(t, map[string][]string{"generatemd": {"v1 {{.Language}}", "v2 {{.Language}}"}})
+	hooks := []string{"generatemd", "reducemd"}
+
+	t.Run("active", func(t *testing.T) {
+		s, err := l.Resolve(context.TODO(), hooks, views.PromptOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, "generatemd=t2;reducemd=1", s.Version())
+		assert.Equal(t, "", s.Vars())
+	})
+
+	t.Run("selected", func(t *testing.T) {
+		s, err := l.Resolve(context.TODO(), hooks, views.PromptOptions{Templates: map[string]int{"generatemd": 1}, Language: "en"})
+		assert.NoError(t, err)
+		assert.Equal(t, "generatemd=t1;reducemd=1", s.Version())
+		assert.Equal(t, "language=en;detail=;audience=;format=", s.Vars())
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := l.Resolve(context.TODO(), hooks, views.PromptOptions{Templates: map[string]int{"generatemd": 3}})
+		assert.ErrorIs(t, err, ErrNotFound)
+		_, err = l.Resolve(context.TODO(), hooks, views.PromptOptions{Templates: map[string]int{"reducemd": 1}})
+		assert.ErrorIs(t, err, ErrNotFound)
+		_, err = l.Resolve(context.TODO(), hooks, views.PromptOptions{Templates: map[string]int{"gentest": 1}})
+		assert.ErrorIs(t, err, ErrOptions)
+		_, err = l.Resolve(context.TODO(), hooks, views.PromptOptions{Templates: map[string]int{"generatemd": -1}})
+		assert.ErrorIs(t, err, ErrOptions)
+		_, err = l.Resolve(context.TODO(), hooks, views.PromptOptions{Audience: strings.Repeat("я", MaxVar+1)})
+		assert.ErrorIs(t, err, ErrOptions)
+	})
+
+	t.Run("without store", func(t *testing.T) {
+		s, err := (&Library{Versions: l.Versions}).Resolve(context.TODO(), []string{"generatemd", "gentest"}, views.PromptOptions{Language: "en"})
+		assert.NoError(t, err)
+		assert.Equal(t, "generatemd=2;gentest=0", s.Version())
+		assert.Equal(t, "", s.Vars())
+	})
+}
+
+func TestApply(t *testing.T) {
+	l := newLibrary(t, map[string][]string{"generatemd": {"Summarize {{.Input.title}} in {{.Language}}"}})
+	payload := mapRequest{Content: "text", Title: "Lecture"}
+
+	out, err := Apply(context.TODO(), "generatemd", payload)
+	assert.NoError(t, err)
+	assert.Equal(t, payload, out)
+
+	s, err := l.Resolve(context.TODO(), []string{"generatemd", "reducemd"}, views.PromptOptions{Language: "en"})
+	assert.NoError(t, err)
+	ctx := With(context.TODO(), s)
+
+	out, err = Apply(ctx, "reducemd", payload)
+	assert.NoError(t, err)
+	assert.Equal(t, payload, out)
+
+	out, err = Apply(ctx, "generatemd", payload)
+	assert.NoError(t, err)
+	b, err := json.Marshal(out)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"content":"text","title":"Lecture","prompt":"Summarize Lecture in en","prompt_version":1}`, string(b))
+}
+
+func TestMemory(t *testing.T) {
+	m := NewMemory()
+	ctx := context.TODO()
+
+	assert.ErrorIs(t, m.Activate(ctx, "ask", 1), ErrNotFound)
+	for range 2 {
+		assert.NoError(t, m.Create(ctx, &views.PromptTemplate{Name: "ask", Body: "b"}))
+	}
+	assert.NoError(t, m.Create(ctx, &views.PromptTemplate{Name: "gentest", Body: "b"}))
+	assert.NoError(t, m.Activate(ctx, "ask", 1))
+	assert.NoError(t, m.Activate(ctx, "gentest", 1))
+
+	a, err := m.Get(ctx, "ask", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, a.Version)
+
+	ls, err := m.List(ctx, "ask")
+	assert.NoError(t, err)
+	if assert.Len(t, ls, 2) {
+		assert.Equal(t, 2, ls[0].Version)
+		assert.False(t, ls[0].Active)
+	}
+
+	active, err := m.Active(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, active, 2) {
+		assert.Equal(t, "ask", active[0].Name)
+		assert.Equal(t, "gentest", active[1].Name)
+	}
+
+	assert.NoError(t, m.Activate(ctx, "ask", 0))
+	_, err = m.Get(ctx, "ask", 0)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
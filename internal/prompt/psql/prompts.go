@@ -0,0 +1,119 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flicker/internal/prompt"
+	"flicker/internal/views"
+
+	"github.com/autumnterror/breezynotes/pkg/utils/format"
+)
+
+const templateColumns = `name, version, body, comment, active, COALESCE(author, ''), created_at`
+
+func scanTemplate(row interface{ Scan(dest ...any) error }) (*views.PromptTemplate, error) {
+	var t views.PromptTemplate
+	if err := row.Scan(&t.Name, &t.Version, &t.Body, &t.Comment, &t.Active, &t.Author, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (d *Driver) Create(ctx context.Context, t *views.PromptTemplate) error {
+	const op = "psql.prompt.Create"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	// concurrent create of the same version fails on primary key
+	query := `
+		INSERT INTO prompt_templates (name, version, body, comment, author)
+		SELECT $1, COALESCE(max(version), 0) + 1, $2, $3, NULLIF($4, '')
+		FROM prompt_templates
+		WHERE name = $1
+		RETURNING version, active, created_at
+	`
+	if err := d.driver.QueryRowContext(ctx, query, t.Name, t.Body, t.Comment, t.Author).
+		Scan(&t.Version, &t.Active, &t.CreatedAt); err != nil {
+		return format.Error(op, err)
+	}
+	return nil
+}
+
+func (d *Driver) Get(ctx context.Context, name string, version int) (*views.PromptTemplate, error) {
+	const op = "psql.prompt.Get"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	query := `SELECT ` + templateColumns + ` FROM prompt_templates WHERE name = $1 AND (version = $2 OR $2 = 0 AND active)`
+	t, err := scanTemplate(d.driver.QueryRowContext(ctx, query, name, version))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, format.Error(op, prompt.ErrNotFound)
+	}
+	if err != nil {
+		return nil, format.Error(op, err)
+	}
+	return t, nil
+}
+
+func (d *Driver) List(ctx context.Context, name string) ([]*views.PromptTemplate, error) {
+	const op = "psql.prompt.List"
+
+	return d.list(ctx, op, `SELECT `+templateColumns+` FROM prompt_templates WHERE name = $1 ORDER BY version DESC`, name)
+}
+
+func (d *Driver) Active(ctx context.Context) ([]*views.PromptTemplate, error) {
+	const op = "psql.prompt.Active"
+
+	return d.list(ctx, op, `SELECT `+templateColumns+` FROM prompt_templates WHERE active ORDER BY name`)
+}
+
+func (d *Driver) list(ctx context.Context, op, query string, args ...any) ([]*views.PromptTemplate, error) {
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	rows, err := d.driver.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, format.Error(op, err)
+	}
+	defer rows.Close()
+
+	ls := []*views.PromptTemplate{}
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, format.Error(op, err)
+		}
+		ls = append(ls, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, format.Error(op, err)
+	}
+	return ls, nil
+}
+
+func (d *Driver) Activate(ctx context.Context, name string, version int) error {
+	const op = "psql.prompt.Activate"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	if version != 0 {
+		var exists bool
+		query := `SELECT EXISTS (SELECT 1 FROM prompt_templates WHERE name = $1 AND version = $2)`
+		if err := d.driver.QueryRowContext(ctx, query, name, version).Scan(&exists); err != nil {
+			return format.Error(op, err)
+		}
+		if !exists {
+			return format.Error(op, prompt.ErrNotFound)
+		}
+	}
+
+	query := `UPDATE prompt_templates SET active = (version = $2) WHERE name = $1 AND (active OR version = $2)`
+	if _, err := d.driver.ExecContext(ctx, query, name, version); err != nil {
+		return format.Error(op, err)
+	}
+	return nil
+}
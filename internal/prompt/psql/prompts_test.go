@@ -0,0 +1,96 @@
+package psql
+
+import (
+	"context"
+	authpsql "flicker/internal/auth/psql"
+	"flicker/internal/config"
+	"flicker/internal/prompt"
+	"flicker/internal/views"
+	"testing"
+
+	"github.com/autumnterror/breezynotes/pkg/utils/id"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPromptOperations(t *testing.T) {
+	t.Parallel()
+
+	repo, cleanup := setupTestTx(t)
+	defer cleanup()
+
+	name := id.New()
+
+	t.Run("empty", func(t *testing.T) {
+		_, err := repo.Get(context.TODO(), name, 0)
+		assert.ErrorIs(t, err, prompt.ErrNotFound)
+
+		ls, err := repo.List(context.TODO(), name)
+		assert.NoError(t, err)
+		assert.Empty(t, ls)
+
+		assert.ErrorIs(t, repo.Activate(context.TODO(), name, 1), prompt.ErrNotFound)
+	})
+
+	t.Run("versions", func(t *testing.T) {
+		for i, body := range []string{"first {{.Language}}", "second {{.Language}}"} {
+			tpl := &views.PromptTemplate{Name: name, Body: body, Comment: "c"}
+			assert.NoError(t, repo.Create(context.TODO(), tpl))
+			assert.Equal(t, i+1, tpl.Version)
+			assert.False(t, tpl.Active)
+			assert.False(t, tpl.CreatedAt.IsZero())
+		}
+
+		ls, err := repo.List(context.TODO(), name)
+		assert.NoError(t, err)
+		if assert.Len(t, ls, 2) {
+			assert.Equal(t, 2, ls[0].Version)
+			assert.Equal(t, "second {{.Language}}", ls[0].Body)
+		}
+
+		_, err = repo.Get(context.TODO(), name, 0)
+		assert.ErrorIs(t, err, prompt.ErrNotFound)
+		tpl, err := repo.Get(context.TODO(), name, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "first {{.Language}}", tpl.Body)
+	})
+
+	t.Run("activate", func(t *testing.T) {
+		assert.NoError(t, repo.Activate(context.TODO(), name, 1))
+		tpl, err := repo.Get(context.TODO(), name, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, tpl.Version)
+
+		assert.NoError(t, repo.Activate(context.TODO(), name, 2))
+		tpl, err = repo.Get(context.TODO(), name, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, tpl.Version)
+
+		active, err := repo.Active(context.TODO())
+		assert.NoError(t, err)
+		n := 0
+		for _, a := range active {
+			if a.Name == name {
+				n++
+			}
+		}
+		assert.Equal(t, 1, n)
+
+		assert.ErrorIs(t, repo.Activate(context.TODO(), name, 3), prompt.ErrNotFound)
+
+		assert.NoError(t, repo.Activate(context.TODO(), name, 0))
+		_, err = repo.Get(context.TODO(), name, 0)
+		assert.ErrorIs(t, err, prompt.ErrNotFound)
+	})
+}
+
+func setupTestTx(t *testing.T) (*Driver, func()) {
+	pdb := authpsql.MustConnect(config.Test())
+
+	tx, err := pdb.Driver.Begin()
+	assert.NoError(t, err)
+
+	return NewDriver(tx), func() {
+		assert.NoError(t, tx.Rollback())
+		assert.NoError(t, pdb.Disconnect())
+	}
+}
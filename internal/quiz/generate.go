@@ -0,0 +1,155 @@
+package quiz
+
+import (
+	"context"
+	"errors"
+	"flicker/internal/views"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/autumnterror/breezynotes/pkg/utils/format"
+)
+
+var ErrInvalidOutput = errors.New("llm returned invalid quiz")
+
+// LLM run prompt webhook and return raw text answer, e.g. *n8n.Client
+type LLM interface {
+	Call(ctx context.Context, hook string, payload any) (string, error)
+}
+
+// Schema is JSON schema of quiz which LLM must follow
+const Schema = `{
+  "type": "object",
+  "required": ["title", "questions"],
+  "properties": {
+    "title": {"type": "string"},
+    "questions": {
+      "type": "array",
+      "minItems": 1,
+      "items": {
+        "type": "object",
+        "required": ["type", "text", "difficulty"],
+        "properties": {
+          "type": {"enum": ["single", "multi", "open", "true_false"]},
+          "text": {"type": "string"},
+          "options": {"type": "array", "items": {"type": "string"}, "description": "without A) B) labels; omit for open questions"},
+          "correct": {"type": "array", "items": {"type": "integer"}, "description": "zero based indexes of correct options; true_false options are [\"Верно\", \"Неверно\"]"},
+          "answer": {"type": "string", "description": "reference answer, required for open questions"},
+          "keywords": {"type": "array", "items": {"type": "string"}, "description": "key terms which correct open answer must mention"},
+          "explanation": {"type": "string"},
+          "difficulty": {"enum": ["easy", "medium", "hard"]}
+        }
+      }
+    }
+  }
+}`
+
+type request struct {
+	Content      string             `json:"content"`
+	Format       string             `json:"format"`
+	Schema       string             `json:"schema"`
+	Instructions string             `json:"instructions,omitempty"`
+	Options      *views.QuizOptions `json:"options,omitempty"`
+	Previous     string             `json:"previous,omitempty"`
+	Problems     string             `json:"problems,omitempty"`
+}
+
+// typeInstructions name question types for LLM
+var typeInstructions = map[string]string{
+	views.QuestionSingle:    "single choice",
+	views.QuestionMulti:     "multiple choice",
+	views.QuestionOpen:      "open",
+	views.QuestionTrueFalse: "true/false",
+}
+
+// Instructions tell LLM how to follow options, empty for default ones
+func Instructions(o views.QuizOptions) string {
+	var ls []string
+	if o.Questions > 0 {
+		ls = append(ls, fmt.Sprintf("Write exactly %d questions.", o.Questions))
+	}
+	if len(o.Types) > 0 {
+		names := make([]string, len(o.Types))
+		for i, t := range o.Types {
+			names[i] = fmt.Sprintf("%s (%q)", typeInstructions[t], t)
+		}
+		ls = append(ls, "Use only questions of types: "+strings.Join(names, ", ")+".")
+	}
+	switch o.Difficulty {
+	case "":
+	case views.DifficultyMixed:
+		ls = append(ls, "Mix easy, medium and hard questions.")
+	default:
+		ls = append(ls, fmt.Sprintf("All questions are %q.", o.Difficulty))
+	}
+	if o.Language != "" {
+		ls = append(ls, fmt.Sprintf(`Write questions, options and answers in the language with BCP 47 tag %q, translate if the text is in other language; `+
+			`true_false options stay ["Верно", "Неверно"].`, o.Language))
+	}
+	return strings.Join(ls, " ")
+}
+
+// Check quiz against options. Questions over requested count are dropped. Return *ValidationError
+// if quiz does not follow options
+func Check(q *views.Quiz, o views.QuizOptions) error {
+	var ps []string
+	if o.Questions > 0 {
+		if len(q.Questions) > o.Questions {
+			q.Questions = q.Questions[:o.Questions]
+		}
+		if len(q.Questions) < o.Questions {
+			ps = append(ps, fmt.Sprintf("quiz has %d questions, want %d", len(q.Questions), o.Questions))
+		}
+	}
+	for i, qs := range q.Questions {
+		if len(o.Types) > 0 && !slices.Contains(o.Types, qs.Type) {
+			ps = append(ps, fmt.Sprintf("question %d: type %q is not allowed, want one of %s", i+1, qs.Type, strings.Join(o.Types, ", ")))
+		}
+		if o.Difficulty != "" && o.Difficulty != views.DifficultyMixed && qs.Difficulty != o.Difficulty {
+			ps = append(ps, fmt.Sprintf("question %d: difficulty %q, want %q", i+1, qs.Difficulty, o.Difficulty))
+		}
+	}
+	if len(ps) > 0 {
+		return &ValidationError{Problems: ps}
+	}
+	return nil
+}
+
+// Generate ask LLM for quiz in JSON format following options. Invalid output sent back with list
+// of problems until valid quiz received or attempts are over. Upstream errors are not retried
+func Generate(ctx context.Context, llm LLM, hook, content string, o views.QuizOptions, attempts int) (*views.Quiz, error) {
+	const op = "quiz.Generate"
+
+	req := request{
+		Content:      content,
+		Format:       "json",
+		Schema:       Schema,
+		Instructions: Instructions(o),
+	}
+	if !o.IsZero() {
+		req.Options = &o
+	}
+
+	var lastErr error
+	for i := 0; i < max(attempts, 1); i++ {
+		out, err := llm.Call(ctx, hook, req)
+		if err != nil {
+			return nil, err
+		}
+
+		q, err := Parse(out)
+		if err == nil {
+			err = Check(q, o)
+		}
+		if err == nil {
+			return q, nil
+		}
+
+		lastErr = err
+		req.Previous = out
+		req.Problems = err.Error()
+	}
+
+	return nil, format.Error(op, fmt.Errorf("%w: %w", ErrInvalidOutput, lastErr))
+}
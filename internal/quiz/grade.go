@@ -0,0 +1,296 @@
+package quiz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flicker/internal/llmjson"
+	"flicker/internal/views"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/autumnterror/breezynotes/pkg/utils/format"
+)
+
+var ErrBadAnswer = errors.New("bad answer")
+
+// PassScore is minimal score of open answer which counts as correct
+const PassScore = 0.5
+
+// OpenGrader score answer to open question from 0 to 1
+type OpenGrader interface {
+	Grade(ctx context.Context, q views.Question, answer string) (score float64, feedback string, err error)
+}
+
+// NewGrader return grader by name from config: "keyword" or "llm". LLM grader falls back
+// to keywords when n8n is unavailable
+func NewGrader(name string, llm LLM) OpenGrader {
+	if name == "keyword" {
+		return KeywordGrader{}
+	}
+	return LLMGrader{
+		LLM:      llm,
+		Hook:     "gradeanswer",
+		Fallback: KeywordGrader{},
+	}
+}
+
+// Strip return questions without correct answers, as user see them during attempt
+func Strip(qs []views.Question) []views.AttemptQuestion {
+	res := make([]views.AttemptQuestion, 0, len(qs))
+	for _, q := range qs {
+		res = append(res, views.AttemptQuestion{
+			Type:       q.Type,
+			Text:       q.Text,
+			Options:    q.Options,
+			Difficulty: q.Difficulty,
+		})
+	}
+	return res
+}
+
+// Grade score every question of quiz, each question costs 1 point. Closed questions are scored
+// exactly: selected options must match correct ones, otherwise 0. Open answers go to grader.
+// Questions without answer get 0. Return ErrBadAnswer if answers don't fit quiz
+func Grade(ctx context.Context, qs []views.Question, answers []views.Answer, open OpenGrader) ([]views.QuestionResult, float64, error) {
+	const op = "quiz.Grade"
+
+	byQuestion := make(map[int]views.Answer, len(answers))
+	for _, a := range answers {
+		if a.Question < 0 || a.Question >= len(qs) {
+			return nil, 0, format.Error(op, fmt.Errorf("%w: question %d does not exist", ErrBadAnswer, a.Question))
+		}
+		if _, ok := byQuestion[a.Question]; ok {
+			return nil, 0, format.Error(op, fmt.Errorf("%w: question %d answered twice", ErrBadAnswer, a.Question))
+		}
+		byQuestion[a.Question] = a
+	}
+
+	results := make([]views.QuestionResult, 0, len(qs))
+	var total float64
+	for i, q := range qs {
+		r := views.QuestionResult{
+			Question:    i,
+			Explanation: q.Explanation,
+		}
+		a, answered := byQuestion[i]
+
+		if q.Type == views.QuestionOpen {
+			r.Answer = q.Answer
+			if answered && strings.TrimSpace(a.Text) != "" {
+				score, feedback, err := open.Grade(ctx, q, a.Text)
+				if err != nil {
+					return nil, 0, format.Error(op, err)
+				}
+				r.Score = min(max(score, 0), 1)
+				r.Feedback = feedback
+			}
+			r.Correct = r.Score >= PassScore
+		} else {
+			r.Expected = q.Correct
+			if answered && sameSet(a.Options, q.Correct) {
+				r.Score = 1
+				r.Correct = true
+			}
+		}
+
+		total += r.Score
+		results = append(results, r)
+	}
+
+	return results, total, nil
+}
+
+// LLMCalls return number of answers which Grade sends to LLM with grader g, so they can be
+// counted in AI quota before grading
+func LLMCalls(qs []views.Question, answers []views.Answer, g OpenGrader) int {
+	if _, ok := g.(LLMGrader); !ok {
+		return 0
+	}
+	n := 0
+	for _, a := range answers {
+		if a.Question >= 0 && a.Question < len(qs) && qs[a.Question].Type == views.QuestionOpen && strings.TrimSpace(a.Text) != "" {
+			n++
+		}
+	}
+	return n
+}
+
+func sameSet(a, b []int) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(slices.Compact(a), slices.Compact(b))
+}
+
+// KeywordGrader score open answer by share of keywords found in it. Keywords are taken from
+// question or, if LLM didn't give them, from reference answer. Words are compared by stem,
+// so "энергии" matches "энергия"
+type KeywordGrader struct{}
+
+func (KeywordGrader) Grade(_ context.Context, q views.Question, answer string) (float64, string, error) {
+	keywords := q.Keywords
+	if len(keywords) == 0 {
+		keywords = words(q.Answer)
+	}
+	if len(keywords) == 0 {
+		return 0, "", nil
+	}
+
+	got := make(map[string]bool)
+	for _, w := range words(answer) {
+		got[stem(w)] = true
+	}
+
+	var missed []string
+	for _, k := range keywords {
+		// keyword of short words only can't be found, else it would match any answer
+		ws := words(k)
+		found := len(ws) > 0
+		for _, w := range ws {
+			if !got[stem(w)] {
+				found = false
+				break
+			}
+		}
+		if !found {
+			missed = append(missed, k)
+		}
+	}
+
+	score := float64(len(keywords)-len(missed)) / float64(len(keywords))
+	var feedback string
+	if len(missed) > 0 {
+		feedback = "Не упомянуто: " + strings.Join(missed, ", ")
+	}
+	return score, feedback, nil
+}
+
+// minWordLen drop short words like prepositions
+const minWordLen = 3
+
+func words(s string) []string {
+	var res []string
+	seen := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if utf8.RuneCountInString(w) < minWordLen || seen[w] {
+			continue
+		}
+		seen[w] = true
+		res = append(res, w)
+	}
+	return res
+}
+
+// stemLen is length of word prefix compared instead of full word, rough replacement for stemming
+const stemLen = 5
+
+func stem(w string) string {
+	w = strings.ReplaceAll(w, "ё", "е")
+	if r := []rune(w); len(r) > stemLen {
+		return string(r[:stemLen])
+	}
+	return w
+}
+
+// LLMGrader ask LLM to compare answer with reference one. Expected LLM output is
+// {"score": 0..1, "feedback": "..."}. If Fallback is set, it used when LLM fails
+type LLMGrader struct {
+	LLM      LLM
+	Hook     string
+	Fallback OpenGrader
+}
+
+type gradeRequest struct {
+	Question  string   `json:"question"`
+	Reference string   `json:"reference"`
+	Keywords  []string `json:"keywords,omitempty"`
+	Answer    string   `json:"answer"`
+	Format    string   `json:"format"`
+}
+
+type gradeResponse struct {
+	Score    *float64 `json:"score"`
+	Feedback string   `json:"feedback"`
+}
+
+func (g LLMGrader) Grade(ctx context.Context, q views.Question, answer string) (float64, string, error) {
+	const op = "quiz.LLMGrader.Grade"
+
+	score, feedback, err := g.grade(ctx, q, answer)
+	if err != nil && g.Fallback != nil {
+		return g.Fallback.Grade(ctx, q, answer)
+	}
+	if err != nil {
+		return 0, "", format.Error(op, err)
+	}
+	return score, feedback, nil
+}
+
+func (g LLMGrader) grade(ctx context.Context, q views.Question, answer string) (float64, string, error) {
+	out, err := g.LLM.Call(ctx, g.Hook, gradeRequest{
+		Question:  q.Text,
+		Reference: q.Answer,
+		Keywords:  q.Keywords,
+		Answer:    answer,
+		Format:    `{"score": number from 0 to 1, "feedback": string}`,
+	})
+	if err != nil {
+		return 0, "", err
+	}
+
+	js, err := llmjson.Extract(out)
+	if err != nil {
+		return 0, "", err
+	}
+	var r gradeResponse
+	if err := json.Unmarshal([]byte(js), &r); err != nil {
+		return 0, "", fmt.Errorf("%w: %w", ErrInvalidOutput, err)
+	}
+	if r.Score == nil || *r.Score < 0 || *r.Score > 1 {
+		return 0, "", fmt.Errorf("%w: score must be number from 0 to 1", ErrInvalidOutput)
+	}
+	return *r.Score, strings.TrimSpace(r.Feedback), nil
+}
+
+// Stats aggregate submitted attempts of one quiz with n questions. Attempts are expected newest first
+func Stats(attempts []*views.QuizAttempt, n int) views.QuizStats {
+	st := views.QuizStats{
+		Attempts:    len(attempts),
+		PerQuestion: make([]float64, n),
+	}
+
+	var sum float64
+	for _, a := range attempts {
+		if a.Status != views.AttemptSubmitted || a.MaxScore == 0 {
+			continue
+		}
+		pct := a.Score / a.MaxScore * 100
+		if st.Submitted == 0 {
+			st.Last, st.Best, st.Worst = pct, pct, pct
+		}
+		st.Best = max(st.Best, pct)
+		st.Worst = min(st.Worst, pct)
+		sum += pct
+		st.Submitted++
+
+		for _, r := range a.Results {
+			if r.Question >= 0 && r.Question < n {
+				st.PerQuestion[r.Question] += r.Score * 100
+			}
+		}
+	}
+
+	if st.Submitted > 0 {
+		st.Average = sum / float64(st.Submitted)
+		for i := range st.PerQuestion {
+			st.PerQuestion[i] /= float64(st.Submitted)
+		}
+	}
+	return st
+}
@@ -0,0 +1,134 @@
+package quiz
+
+import (
+	"context"
+	"errors"
+	"flicker/internal/views"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var gradeQuestions = []views.Question{
+	{Type: views.QuestionSingle, Text: "2+2?", Options: []string{"3", "4"}, Correct: []int{1}, Difficulty: views.DifficultyEasy},
+	{Type: views.QuestionMulti, Text: "Чётные?", Options: []string{"1", "2", "4"}, Correct: []int{1, 2}, Difficulty: views.DifficultyMedium},
+	{Type: views.QuestionTrueFalse, Text: "Земля круглая", Options: []string{optionTrue, optionFalse}, Correct: []int{0}, Difficulty: views.DifficultyEasy},
+	{Type: views.QuestionOpen, Text: "Что изучает термодинамика?", Answer: "Тепловые процессы", Keywords: []string{"тепловые процессы", "энергия"}, Difficulty: views.DifficultyHard},
+}
+
+func TestGradeClosed(t *testing.T) {
+	t.Parallel()
+
+	results, score, err := Grade(context.Background(), gradeQuestions, []views.Answer{
+		{Question: 0, Options: []int{1}},
+		{Question: 1, Options: []int{2}},
+		{Question: 2, Options: []int{0}},
+	}, KeywordGrader{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2.0, score)
+	if assert.Len(t, results, 4) {
+		assert.True(t, results[0].Correct)
+		assert.False(t, results[1].Correct, "partially right multi answer is wrong")
+		assert.Equal(t, []int{1, 2}, results[1].Expected)
+		assert.True(t, results[2].Correct)
+		assert.False(t, results[3].Correct, "unanswered question")
+		assert.Equal(t, "Тепловые процессы", results[3].Answer)
+	}
+
+	_, score, err = Grade(context.Background(), gradeQuestions, []views.Answer{{Question: 1, Options: []int{2, 1}}}, KeywordGrader{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, score, "order of options doesn't matter")
+}
+
+func TestGradeBadAnswers(t *testing.T) {
+	t.Parallel()
+
+	_, _, err := Grade(context.Background(), gradeQuestions, []views.Answer{{Question: 4}}, KeywordGrader{})
+	assert.ErrorIs(t, err, ErrBadAnswer)
+
+	_, _, err = Grade(context.Background(), gradeQuestions, []views.Answer{{Question: 0}, {Question: 0}}, KeywordGrader{})
+	assert.ErrorIs(t, err, ErrBadAnswer)
+}
+
+func TestKeywordGrader(t *testing.T) {
+	t.Parallel()
+
+	score, feedback, err := KeywordGrader{}.Grade(context.Background(), gradeQuestions[3], "Изучает тепловой процесс и превращения энергии")
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, score)
+	assert.Empty(t, feedback)
+
+	score, feedback, err = KeywordGrader{}.Grade(context.Background(), gradeQuestions[3], "Движение планет и энергию")
+	assert.NoError(t, err)
+	assert.Equal(t, 0.5, score)
+	assert.Equal(t, "Не упомянуто: тепловые процессы", feedback)
+
+	shortKeywords := views.Question{Type: views.QuestionOpen, Text: "?", Keywords: []string{"и в", "энергия"}}
+	score, feedback, err = KeywordGrader{}.Grade(context.Background(), shortKeywords, "не знаю")
+	assert.NoError(t, err)
+	assert.Zero(t, score, "keyword without words to compare is not matched")
+	assert.Equal(t, "Не упомянуто: и в, энергия", feedback)
+
+	noKeywords := views.Question{Type: views.QuestionOpen, Text: "?", Answer: "Сохранение энергии"}
+	score, _, err = KeywordGrader{}.Grade(context.Background(), noKeywords, "закон сохранения")
+	assert.NoError(t, err)
+	assert.Equal(t, 0.5, score)
+}
+
+func TestLLMCalls(t *testing.T) {
+	t.Parallel()
+
+	answers := []views.Answer{{Question: 0, Options: []int{1}}, {Question: 3, Text: "тепло"}}
+	assert.Equal(t, 1, LLMCalls(gradeQuestions, answers, NewGrader("llm", nil)))
+	assert.Zero(t, LLMCalls(gradeQuestions, answers, NewGrader("keyword", nil)))
+	assert.Zero(t, LLMCalls(gradeQuestions, []views.Answer{{Question: 3, Text: " "}, {Question: 9, Text: "x"}}, NewGrader("llm", nil)))
+}
+
+type llmFunc func() (string, error)
+
+func (f llmFunc) Call(context.Context, string, any) (string, error) {
+	return f()
+}
+
+func TestLLMGrader(t *testing.T) {
+	t.Parallel()
+
+	g := LLMGrader{LLM: llmFunc(func() (string, error) {
+		return "```json\n{\"score\": 0.8, \"feedback\": \"Почти\"}\n```", nil
+	}), Hook: "grade"}
+	score, feedback, err := g.Grade(context.Background(), gradeQuestions[3], "тепло")
+	assert.NoError(t, err)
+	assert.Equal(t, 0.8, score)
+	assert.Equal(t, "Почти", feedback)
+
+	g.LLM = llmFunc(func() (string, error) { return `{"score": 8}`, nil })
+	_, _, err = g.Grade(context.Background(), gradeQuestions[3], "тепло")
+	assert.ErrorIs(t, err, ErrInvalidOutput)
+
+	g.LLM = llmFunc(func() (string, error) { return "", errors.New("down") })
+	g.Fallback = KeywordGrader{}
+	score, _, err = g.Grade(context.Background(), gradeQuestions[3], "энергия")
+	assert.NoError(t, err)
+	assert.Equal(t, 0.5, score)
+}
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+
+	st := Stats([]*views.QuizAttempt{
+		{Status: views.AttemptStarted, MaxScore: 2},
+		{Status: views.AttemptSubmitted, Score: 2, MaxScore: 2, Results: []views.QuestionResult{{Question: 0, Score: 1}, {Question: 1, Score: 1}}},
+		{Status: views.AttemptSubmitted, Score: 0.5, MaxScore: 2, Results: []views.QuestionResult{{Question: 0, Score: 0}, {Question: 1, Score: 0.5}}},
+	}, 2)
+
+	assert.Equal(t, 3, st.Attempts)
+	assert.Equal(t, 2, st.Submitted)
+	assert.Equal(t, 100.0, st.Best)
+	assert.Equal(t, 25.0, st.Worst)
+	assert.Equal(t, 62.5, st.Average)
+	assert.Equal(t, 100.0, st.Last)
+	assert.Equal(t, []float64{50, 75}, st.PerQuestion)
+
+	empty := Stats(nil, 3)
+	assert.Equal(t, []float64{0, 0, 0}, empty.PerQuestion)
+}
@@ -0,0 +1,56 @@
+package quiz
+
+import (
+	"flicker/internal/views"
+	"fmt"
+	"strings"
+)
+
+var typeNames = map[string]string{
+	views.QuestionSingle:    "один ответ",
+	views.QuestionMulti:     "несколько ответов",
+	views.QuestionOpen:      "открытый вопрос",
+	views.QuestionTrueFalse: "верно/неверно",
+}
+
+// Label return letter of option like in printed tests: A, B, C...
+func Label(i int) string {
+	if i >= 0 && i < len(latinLabels) {
+		return latinLabels[i : i+1]
+	}
+	return fmt.Sprintf("%d", i+1)
+}
+
+// Markdown render quiz as questions list followed by answers key
+func Markdown(q *views.Quiz) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "# %s\n\n", q.Title)
+	for i, qs := range q.Questions {
+		fmt.Fprintf(&sb, "%d. %s *(%s, %s)*\n", i+1, qs.Text, typeNames[qs.Type], qs.Difficulty)
+		for j, o := range qs.Options {
+			fmt.Fprintf(&sb, "   %s) %s\n", Label(j), o)
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("## Ответы\n\n")
+	for i, qs := range q.Questions {
+		var answer string
+		if qs.Type == views.QuestionOpen {
+			answer = qs.Answer
+		} else {
+			ls := make([]string, 0, len(qs.Correct))
+			for _, c := range qs.Correct {
+				ls = append(ls, Label(c))
+			}
+			answer = strings.Join(ls, ", ")
+		}
+		fmt.Fprintf(&sb, "%d. %s", i+1, answer)
+		if qs.Explanation != "" {
+			fmt.Fprintf(&sb, " — %s", qs.Explanation)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
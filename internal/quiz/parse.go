@@ -0,0 +1,351 @@
+package quiz
+
+import (
+	"encoding/json"
+	"flicker/internal/llmjson"
+	"flicker/internal/views"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var ErrNoJSON = llmjson.ErrNoJSON
+
+const (
+	optionTrue  = "Верно"
+	optionFalse = "Неверно"
+)
+
+type rawQuiz struct {
+	Title     string        `json:"title"`
+	Questions []rawQuestion `json:"questions"`
+	Quiz      *rawQuiz      `json:"quiz"`
+}
+
+type rawQuestion struct {
+	Type        string            `json:"type"`
+	Text        string            `json:"text"`
+	Question    string            `json:"question"`
+	Options     []json.RawMessage `json:"options"`
+	Choices     []json.RawMessage `json:"choices"`
+	Correct     json.RawMessage   `json:"correct"`
+	Answer      json.RawMessage   `json:"answer"`
+	Explanation string            `json:"explanation"`
+	Difficulty  string            `json:"difficulty"`
+	Keywords    []string          `json:"keywords"`
+}
+
+// Parse extract quiz from raw LLM output, repair common deviations from schema and validate result.
+// Return *ValidationError if quiz can't be repaired
+func Parse(raw string) (*views.Quiz, error) {
+	js, err := llmjson.Extract(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var rq rawQuiz
+	if strings.HasPrefix(js, "[") {
+		err = json.Unmarshal([]byte(js), &rq.Questions)
+	} else {
+		err = json.Unmarshal([]byte(js), &rq)
+	}
+	if err != nil {
+		return nil, &ValidationError{Problems: []string{"output is not valid JSON for schema: " + err.Error()}}
+	}
+	if rq.Quiz != nil && len(rq.Questions) == 0 {
+		rq = *rq.Quiz
+	}
+
+	q := repair(rq)
+	if err := Validate(q); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func repair(rq rawQuiz) *views.Quiz {
+	q := &views.Quiz{
+		Title:     strings.TrimSpace(rq.Title),
+		Questions: make([]views.Question, 0, len(rq.Questions)),
+	}
+	if q.Title == "" {
+		q.Title = "Тест"
+	}
+
+	for _, r := range rq.Questions {
+		qs := views.Question{
+			Text:        strings.TrimSpace(r.Text),
+			Explanation: strings.TrimSpace(r.Explanation),
+			Difficulty:  normalizeDifficulty(r.Difficulty),
+		}
+		if qs.Text == "" {
+			qs.Text = strings.TrimSpace(r.Question)
+		}
+
+		rawOptions := r.Options
+		if len(rawOptions) == 0 {
+			rawOptions = r.Choices
+		}
+		options := make([]string, 0, len(rawOptions))
+		for _, o := range rawOptions {
+			options = append(options, optionText(o))
+		}
+		options, labels := stripLabels(options)
+
+		correct := resolveAll(r.Correct, options, labels)
+		answer := plainString(r.Answer)
+		if len(correct) == 0 && answer != "" && len(options) > 0 {
+			correct = resolveAll(r.Answer, options, labels)
+		}
+
+		qs.Type = normalizeType(r.Type, options, correct)
+		switch qs.Type {
+		case views.QuestionTrueFalse:
+			qs.Options = []string{optionTrue, optionFalse}
+			qs.Correct = trueFalseCorrect(r.Correct, options, correct)
+		case views.QuestionOpen:
+			qs.Answer = answer
+			if qs.Answer == "" {
+				qs.Answer = plainString(r.Correct)
+			}
+			for _, k := range r.Keywords {
+				if k = strings.TrimSpace(k); k != "" {
+					qs.Keywords = append(qs.Keywords, k)
+				}
+			}
+		default:
+			qs.Options = options
+			qs.Correct = correct
+		}
+
+		q.Questions = append(q.Questions, qs)
+	}
+	return q
+}
+
+func optionText(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return strings.TrimSpace(s)
+	}
+	var obj map[string]any
+	if err := json.Unmarshal(raw, &obj); err == nil {
+		for _, k := range []string{"text", "option", "value", "label"} {
+			if v, ok := obj[k].(string); ok {
+				return strings.TrimSpace(v)
+			}
+		}
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+// plainString return JSON string value or empty string for other JSON types
+func plainString(raw json.RawMessage) string {
+	var s string
+	if len(raw) == 0 || json.Unmarshal(raw, &s) != nil {
+		return ""
+	}
+	return strings.TrimSpace(s)
+}
+
+var labelRe = regexp.MustCompile(`^\s*([A-Za-zА-Яа-яЁё]|\d{1,2})\s*[\).:]\s*(.+)$`)
+
+const (
+	latinLabels    = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	cyrillicLabels = "АБВГДЕЖЗИКЛМНОПРСТУФХЦЧШЩЭЮЯ"
+)
+
+// stripLabels remove "A) ", "Б. ", "1: " prefixes when every option has them in order
+func stripLabels(options []string) ([]string, bool) {
+	if len(options) == 0 {
+		return options, false
+	}
+	stripped := make([]string, len(options))
+	for i, o := range options {
+		m := labelRe.FindStringSubmatch(o)
+		if m == nil || labelIndex(m[1]) != i {
+			return options, false
+		}
+		stripped[i] = strings.TrimSpace(m[2])
+	}
+	return stripped, true
+}
+
+// labelIndex convert option label (A, б, 3) to zero based index, -1 if it is not label
+func labelIndex(label string) int {
+	if n, err := strconv.Atoi(label); err == nil {
+		return n - 1
+	}
+	l := []rune(strings.ToUpper(label))
+	if len(l) != 1 {
+		return -1
+	}
+	if i := strings.IndexRune(latinLabels, l[0]); i >= 0 {
+		return i
+	}
+	for i, r := range []rune(cyrillicLabels) {
+		if r == l[0] {
+			return i
+		}
+	}
+	return -1
+}
+
+func resolveAll(raw json.RawMessage, options []string, labeled bool) []int {
+	if len(raw) == 0 {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil
+	}
+
+	var items []any
+	switch t := v.(type) {
+	case []any:
+		items = t
+	case string:
+		if strings.Contains(t, ",") && len(options) > 0 && indexOfOption(t, options) < 0 {
+			for _, p := range strings.Split(t, ",") {
+				items = append(items, p)
+			}
+		} else {
+			items = []any{t}
+		}
+	default:
+		items = []any{t}
+	}
+
+	var res []int
+	for _, it := range items {
+		if i, ok := resolveOne(it, options, labeled); ok {
+			res = append(res, i)
+		}
+	}
+	return res
+}
+
+func resolveOne(v any, options []string, labeled bool) (int, bool) {
+	switch t := v.(type) {
+	case float64:
+		return int(t), true
+	case bool:
+		if t {
+			return 0, true
+		}
+		return 1, true
+	case string:
+		s := strings.TrimSpace(t)
+		if i := indexOfOption(s, options); i >= 0 {
+			return i, true
+		}
+		if n, err := strconv.Atoi(s); err == nil {
+			if labeled {
+				return n - 1, true
+			}
+			return n, true
+		}
+		if m := labelRe.FindStringSubmatch(s); m != nil {
+			s = m[1]
+		}
+		s = strings.Trim(s, " ).:")
+		if i := labelIndex(s); i >= 0 && i < len(options) {
+			return i, true
+		}
+		if b, ok := truth(s); ok {
+			if b {
+				return 0, true
+			}
+			return 1, true
+		}
+	}
+	return 0, false
+}
+
+func indexOfOption(s string, options []string) int {
+	for i, o := range options {
+		if strings.EqualFold(strings.TrimSpace(o), strings.TrimSpace(s)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// truth recognize true/false words in english and russian
+func truth(s string) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "true", "верно", "правда", "да", "yes", "истина":
+		return true, true
+	case "false", "неверно", "ложь", "нет", "no", "неправда":
+		return false, true
+	}
+	return false, false
+}
+
+func trueFalseCorrect(raw json.RawMessage, options []string, correct []int) []int {
+	var b bool
+	if err := json.Unmarshal(raw, &b); err == nil {
+		if b {
+			return []int{0}
+		}
+		return []int{1}
+	}
+	if len(correct) != 1 {
+		return correct
+	}
+	// correct points into original options, which may be in any order and language
+	if c := correct[0]; c >= 0 && c < len(options) {
+		if t, ok := truth(options[c]); ok {
+			if t {
+				return []int{0}
+			}
+			return []int{1}
+		}
+	}
+	return correct
+}
+
+func normalizeType(t string, options []string, correct []int) string {
+	switch strings.ToLower(strings.NewReplacer("-", "_", " ", "_").Replace(strings.TrimSpace(t))) {
+	case "single", "single_choice", "singlechoice", "choice", "one":
+		return views.QuestionSingle
+	case "multi", "multiple", "multiple_choice", "multi_choice", "multichoice", "multiselect":
+		return views.QuestionMulti
+	case "open", "open_ended", "text", "short", "short_answer", "free":
+		return views.QuestionOpen
+	case "true_false", "truefalse", "true/false", "bool", "boolean", "tf":
+		return views.QuestionTrueFalse
+	}
+
+	switch {
+	case len(options) == 0:
+		return views.QuestionOpen
+	case len(options) == 2 && isTrueFalse(options):
+		return views.QuestionTrueFalse
+	case len(correct) > 1:
+		return views.QuestionMulti
+	default:
+		return views.QuestionSingle
+	}
+}
+
+func isTrueFalse(options []string) bool {
+	for _, o := range options {
+		if _, ok := truth(o); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func normalizeDifficulty(d string) string {
+	switch strings.ToLower(strings.TrimSpace(d)) {
+	case "easy", "легкий", "лёгкий", "простой", "low":
+		return views.DifficultyEasy
+	case "medium", "normal", "средний", "moderate", "":
+		return views.DifficultyMedium
+	case "hard", "difficult", "сложный", "трудный", "high":
+		return views.DifficultyHard
+	}
+	return d
+}
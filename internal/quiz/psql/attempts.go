@@ -0,0 +1,138 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flicker/internal/views"
+
+	"github.com/autumnterror/breezynotes/pkg/log"
+	"github.com/autumnterror/breezynotes/pkg/utils/format"
+)
+
+// StartAttempt create attempt of quiz a.QuizId. Id and MaxScore must be set by caller.
+// Returns sql.ErrNoRows if quiz of owner not found
+func (d *Driver) StartAttempt(ctx context.Context, owner string, a *views.QuizAttempt) error {
+	const op = "psql.attempts.StartAttempt"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	query := `
+		INSERT INTO quiz_attempts (id, quiz_id, owner, max_score)
+		SELECT $1, id, owner, $2 FROM quizzes
+		WHERE id = $3 AND owner = $4
+		RETURNING status, started_at
+	`
+	if err := d.driver.QueryRowContext(ctx, query, a.Id, a.MaxScore, a.QuizId, owner).Scan(&a.Status, &a.StartedAt); err != nil {
+		return format.Error(op, err)
+	}
+
+	return nil
+}
+
+const attemptColumns = `id, quiz_id, status, answers, results, score, max_score, started_at, submitted_at`
+
+func scanAttempt(row interface{ Scan(dest ...any) error }) (*views.QuizAttempt, error) {
+	var (
+		a                views.QuizAttempt
+		answers, results []byte
+		submittedAt      sql.NullTime
+	)
+	if err := row.Scan(&a.Id, &a.QuizId, &a.Status, &answers, &results, &a.Score, &a.MaxScore, &a.StartedAt, &submittedAt); err != nil {
+		return nil, err
+	}
+	if submittedAt.Valid {
+		a.SubmittedAt = &submittedAt.Time
+	}
+	if answers != nil {
+		if err := json.Unmarshal(answers, &a.Answers); err != nil {
+			return nil, err
+		}
+	}
+	if results != nil {
+		if err := json.Unmarshal(results, &a.Results); err != nil {
+			return nil, err
+		}
+	}
+	return &a, nil
+}
+
+// Attempt return attempt of owner. May send sql.ErrNoRows
+func (d *Driver) Attempt(ctx context.Context, owner, quizId, id string) (*views.QuizAttempt, error) {
+	const op = "psql.attempts.Attempt"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	query := `SELECT ` + attemptColumns + ` FROM quiz_attempts WHERE id = $1 AND quiz_id = $2 AND owner = $3`
+	a, err := scanAttempt(d.driver.QueryRowContext(ctx, query, id, quizId, owner))
+	if err != nil {
+		return nil, format.Error(op, err)
+	}
+
+	return a, nil
+}
+
+// Attempts return all attempts of owner at quiz, newest first
+func (d *Driver) Attempts(ctx context.Context, owner, quizId string) ([]*views.QuizAttempt, error) {
+	const op = "psql.attempts.Attempts"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	query := `SELECT ` + attemptColumns + ` FROM quiz_attempts WHERE quiz_id = $1 AND owner = $2 ORDER BY started_at DESC`
+	rows, err := d.driver.QueryContext(ctx, query, quizId, owner)
+	if err != nil {
+		return nil, format.Error(op, err)
+	}
+	defer rows.Close()
+
+	ls := []*views.QuizAttempt{}
+	for rows.Next() {
+		a, err := scanAttempt(rows)
+		if err != nil {
+			log.Error(op, "rows scan error", err)
+			continue
+		}
+		ls = append(ls, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, format.Error(op, err)
+	}
+
+	return ls, nil
+}
+
+// SubmitAttempt store answers, results and score of started attempt and mark it submitted.
+// Returns sql.ErrNoRows if attempt not found or already submitted
+func (d *Driver) SubmitAttempt(ctx context.Context, owner string, a *views.QuizAttempt) error {
+	const op = "psql.attempts.SubmitAttempt"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	answers, err := json.Marshal(a.Answers)
+	if err != nil {
+		return format.Error(op, err)
+	}
+	results, err := json.Marshal(a.Results)
+	if err != nil {
+		return format.Error(op, err)
+	}
+
+	query := `
+		UPDATE quiz_attempts
+		SET status = $1, answers = $2, results = $3, score = $4, submitted_at = now()
+		WHERE id = $5 AND quiz_id = $6 AND owner = $7 AND status = $8
+		RETURNING status, submitted_at
+	`
+	var submittedAt sql.NullTime
+	if err := d.driver.QueryRowContext(ctx, query, views.AttemptSubmitted, answers, results, a.Score,
+		a.Id, a.QuizId, owner, views.AttemptStarted).Scan(&a.Status, &submittedAt); err != nil {
+		return format.Error(op, err)
+	}
+	a.SubmittedAt = &submittedAt.Time
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"flicker/internal/views"
+	"time"
+)
+
+type SqlRepo interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+const (
+	waitTime = 3 * time.Second
+)
+
+type Driver struct {
+	driver SqlRepo
+}
+
+func NewDriver(driver SqlRepo) *Driver {
+	return &Driver{
+		driver: driver,
+	}
+}
+
+type QuizzesRepo interface {
+	Create(ctx context.Context, q *views.SavedQuiz) error
+	Get(ctx context.Context, owner, id string) (*views.SavedQuiz, error)
+	List(ctx context.Context, owner string) ([]*views.SavedQuiz, error)
+	Delete(ctx context.Context, owner, id string) error
+
+	StartAttempt(ctx context.Context, owner string, a *views.QuizAttempt) error
+	Attempt(ctx context.Context, owner, quizId, id string) (*views.QuizAttempt, error)
+	Attempts(ctx context.Context, owner, quizId string) ([]*views.QuizAttempt, error)
+	SubmitAttempt(ctx context.Context, owner string, a *views.QuizAttempt) error
+}
@@ -0,0 +1,120 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flicker/internal/views"
+
+	"github.com/autumnterror/breezynotes/pkg/log"
+	"github.com/autumnterror/breezynotes/pkg/utils/format"
+)
+
+// Create new quiz. Id and owner must be set by caller
+func (d *Driver) Create(ctx context.Context, q *views.SavedQuiz) error {
+	const op = "psql.quizzes.Create"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	questions, err := json.Marshal(q.Questions)
+	if err != nil {
+		return format.Error(op, err)
+	}
+
+	query := `
+		INSERT INTO quizzes (id, owner, title, questions, prompt_version)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+		RETURNING created_at
+	`
+	if err := d.driver.QueryRowContext(ctx, query, q.Id, q.Owner, q.Title, questions, q.PromptVersion).Scan(&q.CreatedAt); err != nil {
+		return format.Error(op, err)
+	}
+	q.Count = len(q.Questions)
+
+	return nil
+}
+
+// Get quiz of owner with questions. May send sql.ErrNoRows
+func (d *Driver) Get(ctx context.Context, owner, id string) (*views.SavedQuiz, error) {
+	const op = "psql.quizzes.Get"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	query := `
+		SELECT id, owner, title, questions, COALESCE(prompt_version, ''), created_at
+		FROM quizzes
+		WHERE id = $1 AND owner = $2
+	`
+	var (
+		q         views.SavedQuiz
+		questions []byte
+	)
+	if err := d.driver.QueryRowContext(ctx, query, id, owner).
+		Scan(&q.Id, &q.Owner, &q.Title, &questions, &q.PromptVersion, &q.CreatedAt); err != nil {
+		return nil, format.Error(op, err)
+	}
+	if err := json.Unmarshal(questions, &q.Questions); err != nil {
+		return nil, format.Error(op, err)
+	}
+	q.Count = len(q.Questions)
+
+	return &q, nil
+}
+
+// List quizzes of owner without questions, newest first
+func (d *Driver) List(ctx context.Context, owner string) ([]*views.SavedQuiz, error) {
+	const op = "psql.quizzes.List"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	query := `
+		SELECT id, owner, title, jsonb_array_length(questions), COALESCE(prompt_version, ''), created_at
+		FROM quizzes
+		WHERE owner = $1
+		ORDER BY created_at DESC
+	`
+	rows, err := d.driver.QueryContext(ctx, query, owner)
+	if err != nil {
+		return nil, format.Error(op, err)
+	}
+	defer rows.Close()
+
+	ls := []*views.SavedQuiz{}
+	for rows.Next() {
+		var q views.SavedQuiz
+		if err := rows.Scan(&q.Id, &q.Owner, &q.Title, &q.Count, &q.PromptVersion, &q.CreatedAt); err != nil {
+			log.Error(op, "rows scan error", err)
+			continue
+		}
+		ls = append(ls, &q)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, format.Error(op, err)
+	}
+
+	return ls, nil
+}
+
+// Delete quiz with all its attempts. May send sql.ErrNoRows
+func (d *Driver) Delete(ctx context.Context, owner, id string) error {
+	const op = "psql.quizzes.Delete"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	res, err := d.driver.ExecContext(ctx, `DELETE FROM quizzes WHERE id = $1 AND owner = $2`, id, owner)
+	if err != nil {
+		return format.Error(op, err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return format.Error(op, err)
+	}
+	if rows == 0 {
+		return format.Error(op, sql.ErrNoRows)
+	}
+	return nil
+}
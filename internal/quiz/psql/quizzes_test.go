@@ -0,0 +1,124 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	authpsql "flicker/internal/auth/psql"
+	"flicker/internal/config"
+	"flicker/internal/views"
+	"testing"
+
+	"github.com/autumnterror/breezynotes/pkg/utils/id"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuizzesOperations(t *testing.T) {
+	t.Parallel()
+
+	repo, owner, cleanup := setupTestTx(t)
+	defer cleanup()
+
+	q := &views.SavedQuiz{
+		Id:    id.New(),
+		Owner: owner,
+		Title: "test quiz",
+		Questions: []views.Question{
+			{Type: views.QuestionSingle, Text: "2+2?", Options: []string{"3", "4"}, Correct: []int{1}, Difficulty: views.DifficultyEasy},
+			{Type: views.QuestionOpen, Text: "Почему?", Answer: "Потому", Keywords: []string{"потому"}, Difficulty: views.DifficultyHard},
+		},
+	}
+
+	t.Run("create", func(t *testing.T) {
+		assert.NoError(t, repo.Create(context.TODO(), q))
+		assert.False(t, q.CreatedAt.IsZero())
+		assert.Equal(t, 2, q.Count)
+	})
+
+	t.Run("get", func(t *testing.T) {
+		got, err := repo.Get(context.TODO(), owner, q.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, q.Questions, got.Questions)
+		assert.Equal(t, 2, got.Count)
+
+		_, err = repo.Get(context.TODO(), id.New(), q.Id)
+		assert.True(t, errors.Is(err, sql.ErrNoRows))
+	})
+
+	t.Run("list", func(t *testing.T) {
+		ls, err := repo.List(context.TODO(), owner)
+		assert.NoError(t, err)
+		if assert.Len(t, ls, 1) {
+			assert.Equal(t, q.Id, ls[0].Id)
+			assert.Equal(t, 2, ls[0].Count)
+			assert.Nil(t, ls[0].Questions)
+		}
+	})
+
+	a := &views.QuizAttempt{
+		Id:       id.New(),
+		QuizId:   q.Id,
+		MaxScore: 2,
+	}
+
+	t.Run("start attempt", func(t *testing.T) {
+		assert.NoError(t, repo.StartAttempt(context.TODO(), owner, a))
+		assert.Equal(t, views.AttemptStarted, a.Status)
+
+		err := repo.StartAttempt(context.TODO(), id.New(), &views.QuizAttempt{Id: id.New(), QuizId: q.Id, MaxScore: 2})
+		assert.True(t, errors.Is(err, sql.ErrNoRows))
+	})
+
+	t.Run("submit attempt", func(t *testing.T) {
+		a.Answers = []views.Answer{{Question: 0, Options: []int{1}}, {Question: 1, Text: "потому что"}}
+		a.Results = []views.QuestionResult{{Question: 0, Score: 1, Correct: true}, {Question: 1, Score: 0.5, Correct: true}}
+		a.Score = 1.5
+		assert.NoError(t, repo.SubmitAttempt(context.TODO(), owner, a))
+		assert.Equal(t, views.AttemptSubmitted, a.Status)
+		assert.NotNil(t, a.SubmittedAt)
+
+		err := repo.SubmitAttempt(context.TODO(), owner, a)
+		assert.True(t, errors.Is(err, sql.ErrNoRows))
+	})
+
+	t.Run("attempts", func(t *testing.T) {
+		got, err := repo.Attempt(context.TODO(), owner, q.Id, a.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, a.Answers, got.Answers)
+		assert.Equal(t, a.Results, got.Results)
+		assert.Equal(t, 1.5, got.Score)
+
+		ls, err := repo.Attempts(context.TODO(), owner, q.Id)
+		assert.NoError(t, err)
+		assert.Len(t, ls, 1)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.NoError(t, repo.Delete(context.TODO(), owner, q.Id))
+		assert.True(t, errors.Is(repo.Delete(context.TODO(), owner, q.Id), sql.ErrNoRows))
+
+		_, err := repo.Attempt(context.TODO(), owner, q.Id, a.Id)
+		assert.True(t, errors.Is(err, sql.ErrNoRows))
+	})
+}
+
+// setupTestTx return driver inside transaction with one created user
+func setupTestTx(t *testing.T) (*Driver, string, func()) {
+	pdb := authpsql.MustConnect(config.Test())
+
+	tx, err := pdb.Driver.Begin()
+	assert.NoError(t, err)
+
+	owner := id.New()
+	assert.NoError(t, authpsql.NewDriver(tx).Create(context.TODO(), &views.User{
+		Id:       owner,
+		Login:    owner[:10],
+		Email:    owner[:10] + "@example.com",
+		Password: "password",
+	}))
+
+	return NewDriver(tx), owner, func() {
+		assert.NoError(t, tx.Rollback())
+		assert.NoError(t, pdb.Disconnect())
+	}
+}
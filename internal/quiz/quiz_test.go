@@ -0,0 +1,213 @@
+package quiz
+
+import (
+	"context"
+	"errors"
+	"flicker/internal/views"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseValid(t *testing.T) {
+	t.Parallel()
+
+	raw := `{
+		"title": "Физика",
+		"questions": [
+			{"type": "single", "text": "2+2?", "options": ["3", "4"], "correct": [1], "difficulty": "easy"},
+			{"type": "multi", "text": "Чётные?", "options": ["1", "2", "4"], "correct": [1, 2], "difficulty": "medium"},
+			{"type": "open", "text": "Что такое энергия?", "answer": "Мера движения", "difficulty": "hard"},
+			{"type": "true_false", "text": "Земля круглая", "options": ["Верно", "Неверно"], "correct": [0], "difficulty": "easy"}
+		]
+	}`
+
+	q, err := Parse(raw)
+	assert.NoError(t, err)
+	assert.Equal(t, "Физика", q.Title)
+	assert.Len(t, q.Questions, 4)
+	assert.Equal(t, []int{1}, q.Questions[0].Correct)
+	assert.Equal(t, []int{1, 2}, q.Questions[1].Correct)
+	assert.Equal(t, "Мера движения", q.Questions[2].Answer)
+	assert.Equal(t, []int{0}, q.Questions[3].Correct)
+}
+
+func TestParseRepair(t *testing.T) {
+	t.Parallel()
+
+	raw := "Вот ваш тест:\n```json\n" + `{
+		"quiz": {
+			"title": "Тест",
+			"questions": [
+				{"type": "single-choice", "question": "Столица Франции?", "options": ["A) Берлин", "B) Париж", "C) Рим"], "correct": "B", "difficulty": "Лёгкий",},
+				{"type": "multiple", "text": "Простые числа?", "options": ["2", "4", "5"], "correct": "2, 5"},
+				{"type": "boolean", "text": "Вода мокрая", "options": ["False", "True"], "correct": 1},
+				{"text": "Что такое LLM?", "correct": "Большая языковая модель"},
+			]
+		}
+	}` + "\n```\nУдачи!"
+
+	q, err := Parse(raw)
+	assert.NoError(t, err)
+	if !assert.Len(t, q.Questions, 4) {
+		return
+	}
+
+	assert.Equal(t, views.QuestionSingle, q.Questions[0].Type)
+	assert.Equal(t, "Столица Франции?", q.Questions[0].Text)
+	assert.Equal(t, []string{"Берлин", "Париж", "Рим"}, q.Questions[0].Options)
+	assert.Equal(t, []int{1}, q.Questions[0].Correct)
+	assert.Equal(t, views.DifficultyEasy, q.Questions[0].Difficulty)
+
+	assert.Equal(t, views.QuestionMulti, q.Questions[1].Type)
+	assert.Equal(t, []int{0, 2}, q.Questions[1].Correct)
+	assert.Equal(t, views.DifficultyMedium, q.Questions[1].Difficulty)
+
+	assert.Equal(t, views.QuestionTrueFalse, q.Questions[2].Type)
+	assert.Equal(t, []string{optionTrue, optionFalse}, q.Questions[2].Options)
+	assert.Equal(t, []int{0}, q.Questions[2].Correct)
+
+	assert.Equal(t, views.QuestionOpen, q.Questions[3].Type)
+	assert.Equal(t, "Большая языковая модель", q.Questions[3].Answer)
+}
+
+func TestParseQuestionsArray(t *testing.T) {
+	t.Parallel()
+
+	q, err := Parse(`[{"type": "true_false", "text": "Солнце — звезда", "correct": true, "difficulty": "easy"}]`)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0}, q.Questions[0].Correct)
+}
+
+func TestParseInvalid(t *testing.T) {
+	t.Parallel()
+
+	t.Run("no json", func(t *testing.T) {
+		t.Parallel()
+		_, err := Parse("1. Вопрос\n   A) ...")
+		assert.ErrorIs(t, err, ErrNoJSON)
+	})
+
+	t.Run("schema violations", func(t *testing.T) {
+		t.Parallel()
+		_, err := Parse(`{"questions": [
+			{"type": "single", "text": "", "options": ["a"], "correct": [3], "difficulty": "easy"},
+			{"type": "open", "text": "Почему?", "difficulty": "impossible"}
+		]}`)
+
+		var ve *ValidationError
+		if assert.True(t, errors.As(err, &ve)) {
+			assert.Contains(t, ve.Problems, "question 1: empty text")
+			assert.Contains(t, ve.Problems, "question 1: need at least 2 options, got 1")
+			assert.Contains(t, ve.Problems, "question 1: correct option index 3 out of range")
+			assert.Contains(t, ve.Problems, "question 2: unknown difficulty \"impossible\"")
+			assert.Contains(t, ve.Problems, "question 2: open question has no reference answer")
+		}
+	})
+
+	t.Run("empty quiz", func(t *testing.T) {
+		t.Parallel()
+		_, err := Parse(`{"title": "x", "questions": []}`)
+		var ve *ValidationError
+		assert.True(t, errors.As(err, &ve))
+	})
+}
+
+type fakeLLM struct {
+	outputs  []string
+	payloads []request
+}
+
+func (f *fakeLLM) Call(_ context.Context, _ string, payload any) (string, error) {
+	f.payloads = append(f.payloads, payload.(request))
+	out := f.outputs[0]
+	f.outputs = f.outputs[1:]
+	return out, nil
+}
+
+func TestGenerateRetry(t *testing.T) {
+	t.Parallel()
+
+	llm := &fakeLLM{outputs: []string{
+		"не могу",
+		`{"questions": [{"type": "single", "text": "?", "options": ["a", "b"], "correct": [5], "difficulty": "easy"}]}`,
+		`{"questions": [{"type": "single", "text": "?", "options": ["a", "b"], "correct": [1], "difficulty": "easy"}]}`,
+	}}
+
+	q, err := Generate(context.Background(), llm, "gentest", "text", views.QuizOptions{}, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, q.Questions[0].Correct)
+
+	assert.Len(t, llm.payloads, 3)
+	assert.Equal(t, "", llm.payloads[0].Problems)
+	assert.Contains(t, llm.payloads[2].Problems, "out of range")
+	assert.Contains(t, llm.payloads[2].Previous, `"correct": [5]`)
+}
+
+func TestGenerateGiveUp(t *testing.T) {
+	t.Parallel()
+
+	llm := &fakeLLM{outputs: []string{"нет", "нет"}}
+
+	_, err := Generate(context.Background(), llm, "gentest", "text", views.QuizOptions{}, 2)
+	assert.ErrorIs(t, err, ErrInvalidOutput)
+	assert.ErrorIs(t, err, ErrNoJSON)
+}
+
+func TestGenerateOptions(t *testing.T) {
+	t.Parallel()
+
+	one := `{"type": "single", "text": "?", "options": ["a", "b"], "correct": [1], "difficulty": "%s"}`
+	llm := &fakeLLM{outputs: []string{
+		`{"questions": [` + fmt.Sprintf(one, "easy") + `]}`,
+		`{"questions": [` + fmt.Sprintf(one, "hard") + "," + fmt.Sprintf(one, "hard") + "," + fmt.Sprintf(one, "hard") + `]}`,
+	}}
+	o := views.QuizOptions{Questions: 2, Difficulty: views.DifficultyHard, Types: []string{views.QuestionSingle}, Language: "en"}
+
+	q, err := Generate(context.Background(), llm, "gentest", "text", o, 2)
+	assert.NoError(t, err)
+	assert.Len(t, q.Questions, 2)
+
+	assert.Len(t, llm.payloads, 2)
+	assert.Equal(t, &o, llm.payloads[0].Options)
+	assert.Contains(t, llm.payloads[0].Instructions, "exactly 2 questions")
+	assert.Contains(t, llm.payloads[0].Instructions, `"single"`)
+	assert.Contains(t, llm.payloads[0].Instructions, `"en"`)
+	assert.Contains(t, llm.payloads[1].Problems, "has 1 questions, want 2")
+	assert.Contains(t, llm.payloads[1].Problems, `difficulty "easy", want "hard"`)
+}
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+
+	q := &views.Quiz{Questions: []views.Question{
+		{Type: views.QuestionOpen, Difficulty: views.DifficultyEasy},
+		{Type: views.QuestionSingle, Difficulty: views.DifficultyHard},
+	}}
+	assert.NoError(t, Check(q, views.QuizOptions{Difficulty: views.DifficultyMixed}))
+
+	err := Check(q, views.QuizOptions{Types: []string{views.QuestionSingle, views.QuestionMulti}})
+	var ve *ValidationError
+	if assert.ErrorAs(t, err, &ve) {
+		assert.Equal(t, []string{`question 1: type "open" is not allowed, want one of single, multi`}, ve.Problems)
+	}
+
+	assert.NoError(t, Check(q, views.QuizOptions{Questions: 1, Types: []string{views.QuestionOpen}}))
+	assert.Len(t, q.Questions, 1)
+}
+
+func TestMarkdown(t *testing.T) {
+	t.Parallel()
+
+	md := Markdown(&views.Quiz{
+		Title: "Тест",
+		Questions: []views.Question{
+			{Type: views.QuestionSingle, Text: "2+2?", Options: []string{"3", "4"}, Correct: []int{1}, Difficulty: "easy", Explanation: "арифметика"},
+			{Type: views.QuestionOpen, Text: "Почему?", Answer: "Потому", Difficulty: "hard"},
+		},
+	})
+
+	assert.Contains(t, md, "1. 2+2? *(один ответ, easy)*\n   A) 3\n   B) 4\n")
+	assert.Contains(t, md, "1. B — арифметика\n2. Потому\n")
+}
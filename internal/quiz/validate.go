@@ -0,0 +1,92 @@
+package quiz
+
+import (
+	"flicker/internal/views"
+	"fmt"
+	"strings"
+)
+
+// ValidationError list every problem found in quiz, so LLM can fix all of them at once
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid quiz: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate check quiz against schema. Return *ValidationError if quiz is invalid
+func Validate(q *views.Quiz) error {
+	var ps []string
+	add := func(i int, f string, args ...any) {
+		ps = append(ps, fmt.Sprintf("question %d: ", i+1)+fmt.Sprintf(f, args...))
+	}
+
+	if len(q.Questions) == 0 {
+		ps = append(ps, "quiz has no questions")
+	}
+
+	for i, qs := range q.Questions {
+		if strings.TrimSpace(qs.Text) == "" {
+			add(i, "empty text")
+		}
+
+		switch qs.Difficulty {
+		case views.DifficultyEasy, views.DifficultyMedium, views.DifficultyHard:
+		default:
+			add(i, "unknown difficulty %q", qs.Difficulty)
+		}
+
+		switch qs.Type {
+		case views.QuestionSingle, views.QuestionMulti, views.QuestionTrueFalse:
+			if qs.Type == views.QuestionTrueFalse && len(qs.Options) != 2 {
+				add(i, "true_false question must have exactly 2 options")
+			}
+			if len(qs.Options) < 2 {
+				add(i, "need at least 2 options, got %d", len(qs.Options))
+			}
+			seen := make(map[string]bool, len(qs.Options))
+			for j, o := range qs.Options {
+				o = strings.ToLower(strings.TrimSpace(o))
+				if o == "" {
+					add(i, "option %d is empty", j+1)
+				}
+				if seen[o] {
+					add(i, "option %d duplicates another option", j+1)
+				}
+				seen[o] = true
+			}
+
+			if len(qs.Correct) == 0 {
+				add(i, "no correct option")
+			}
+			if qs.Type != views.QuestionMulti && len(qs.Correct) > 1 {
+				add(i, "%s question must have exactly one correct option", qs.Type)
+			}
+			picked := make(map[int]bool, len(qs.Correct))
+			for _, c := range qs.Correct {
+				if c < 0 || c >= len(qs.Options) {
+					add(i, "correct option index %d out of range", c)
+				}
+				if picked[c] {
+					add(i, "correct option %d repeated", c)
+				}
+				picked[c] = true
+			}
+		case views.QuestionOpen:
+			if len(qs.Options) != 0 {
+				add(i, "open question must not have options")
+			}
+			if strings.TrimSpace(qs.Answer) == "" {
+				add(i, "open question has no reference answer")
+			}
+		default:
+			add(i, "unknown type %q", qs.Type)
+		}
+	}
+
+	if len(ps) > 0 {
+		return &ValidationError{Problems: ps}
+	}
+	return nil
+}
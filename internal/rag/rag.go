@@ -0,0 +1,150 @@
+// Package rag answer questions about user documents: relevant chunks are retrieved from
+// vector store and given to LLM as numbered context, which answer cites by number
+package rag
+
+import (
+	"context"
+	"errors"
+	"flicker/internal/views"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/autumnterror/breezynotes/pkg/utils/format"
+)
+
+var ErrNoContext = errors.New("no indexed chunks match question")
+
+// Chunk is piece of indexed document. Start and End are rune offsets in document text
+type Chunk struct {
+	DocumentId string  `json:"document_id"`
+	Source     string  `json:"source"`
+	Index      int     `json:"chunk"`
+	Start      int     `json:"start"`
+	End        int     `json:"end"`
+	Text       string  `json:"text"`
+	Score      float64 `json:"score"`
+}
+
+// Retriever return up to k chunks of owner documents most similar to query, best first
+type Retriever interface {
+	Search(ctx context.Context, owner, query string, k int) ([]Chunk, error)
+}
+
+// LLM run prompt webhook and return raw text answer, e.g. *n8n.Client
+type LLM interface {
+	Call(ctx context.Context, hook string, payload any) (string, error)
+}
+
+const instructions = `Answer the question using only the numbered context fragments. ` +
+	`After every statement put the number of fragment it is based on in square brackets, e.g. [1] or [2][3]. ` +
+	`If the context doesn't contain the answer, say so and don't make it up. Answer in the language of the question.`
+
+type request struct {
+	Question     string `json:"question"`
+	Context      string `json:"context"`
+	Instructions string `json:"instructions"`
+}
+
+// snippetLen limit text of citation, full chunk is available by document id and offsets
+const snippetLen = 300
+
+// Ask retrieve k chunks for question and ask LLM to answer with them. Upstream errors are returned as is
+func Ask(ctx context.Context, llm LLM, hook string, r Retriever, owner, question string, k int) (*views.AskResponse, error) {
+	const op = "rag.Ask"
+
+	chunks, err := r.Search(ctx, owner, question, k)
+	if err != nil {
+		return nil, format.Error(op, err)
+	}
+	if len(chunks) == 0 {
+		return nil, format.Error(op, ErrNoContext)
+	}
+
+	out, err := llm.Call(ctx, hook, request{
+		Question:     question,
+		Context:      Context(chunks),
+		Instructions: instructions,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	answer, citations := Cite(out, chunks)
+	return &views.AskResponse{Answer: answer, Citations: citations}, nil
+}
+
+// Context number chunks starting from 1 and join them with their sources
+func Context(chunks []Chunk) string {
+	var sb strings.Builder
+	for i, c := range chunks {
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+		fmt.Fprintf(&sb, "[%d] %s, chunk %d:\n%s", i+1, c.Source, c.Index, strings.TrimSpace(c.Text))
+	}
+	return sb.String()
+}
+
+var markerRe = regexp.MustCompile(`\[(\d+(?:\s*,\s*\d+)*)\]`)
+
+// Cite collect chunks referenced by [n] markers in order of first mention. Markers of
+// fragments which were not in context are removed from answer. If answer cites nothing,
+// all chunks are returned, because answer was still built from them
+func Cite(answer string, chunks []Chunk) (string, []views.Citation) {
+	var (
+		order []int
+		seen  = map[int]bool{}
+	)
+	answer = markerRe.ReplaceAllStringFunc(answer, func(m string) string {
+		var valid []string
+		for _, s := range strings.Split(markerRe.FindStringSubmatch(m)[1], ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil || n < 1 || n > len(chunks) {
+				continue
+			}
+			valid = append(valid, strconv.Itoa(n))
+			if !seen[n] {
+				seen[n] = true
+				order = append(order, n)
+			}
+		}
+		if len(valid) == 0 {
+			return ""
+		}
+		return "[" + strings.Join(valid, ", ") + "]"
+	})
+	answer = strings.TrimSpace(answer)
+
+	if len(order) == 0 {
+		for i := range chunks {
+			order = append(order, i+1)
+		}
+	}
+
+	citations := make([]views.Citation, 0, len(order))
+	for _, n := range order {
+		c := chunks[n-1]
+		citations = append(citations, views.Citation{
+			N:          n,
+			Source:     c.Source,
+			DocumentId: c.DocumentId,
+			Chunk:      c.Index,
+			Start:      c.Start,
+			End:        c.End,
+			Score:      c.Score,
+			Text:       snippet(c.Text),
+		})
+	}
+	return answer, citations
+}
+
+func snippet(s string) string {
+	s = strings.TrimSpace(s)
+	r := []rune(s)
+	if len(r) <= snippetLen {
+		return s
+	}
+	return strings.TrimSpace(string(r[:snippetLen])) + "…"
+}
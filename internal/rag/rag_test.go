@@ -0,0 +1,101 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"flicker/internal/views"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeLLM struct {
+	output string
+	err    error
+	hook   string
+	req    any
+}
+
+func (f *fakeLLM) Call(_ context.Context, hook string, payload any) (string, error) {
+	f.hook, f.req = hook, payload
+	return f.output, f.err
+}
+
+type fakeRetriever struct {
+	chunks []Chunk
+	owner  string
+	k      int
+}
+
+func (f *fakeRetriever) Search(_ context.Context, owner, _ string, k int) ([]Chunk, error) {
+	f.owner, f.k = owner, k
+	return f.chunks, nil
+}
+
+var chunks = []Chunk{
+	{DocumentId: "d1", Source: "thermo.pdf", Index: 2, Start: 1000, End: 1500, Text: "Изотермический процесс идёт при постоянной температуре.", Score: 0.9},
+	{DocumentId: "d1", Source: "thermo.pdf", Index: 5, Start: 2500, End: 3000, Text: "Адиабатный процесс протекает без теплообмена.", Score: 0.8},
+	{DocumentId: "d2", Source: "notes.md", Index: 0, Start: 0, End: 400, Text: "Посторонний фрагмент.", Score: 0.4},
+}
+
+func TestAsk(t *testing.T) {
+	t.Parallel()
+
+	llm := &fakeLLM{output: "При постоянной температуре [1], без теплообмена [2][7]."}
+	r := &fakeRetriever{chunks: chunks}
+	res, err := Ask(context.Background(), llm, "ask", r, "u1", "Чем отличаются процессы?", 3)
+	require.NoError(t, err)
+
+	assert.Equal(t, "u1", r.owner)
+	assert.Equal(t, 3, r.k)
+	assert.Equal(t, "ask", llm.hook)
+	req := llm.req.(request)
+	assert.Equal(t, "Чем отличаются процессы?", req.Question)
+	assert.True(t, strings.HasPrefix(req.Context, "[1] thermo.pdf, chunk 2:\nИзотермический"))
+	assert.Contains(t, req.Context, "\n\n[3] notes.md, chunk 0:\n")
+
+	assert.Equal(t, "При постоянной температуре [1], без теплообмена [2].", res.Answer)
+	assert.Equal(t, []views.Citation{
+		{N: 1, Source: "thermo.pdf", DocumentId: "d1", Chunk: 2, Start: 1000, End: 1500, Score: 0.9, Text: chunks[0].Text},
+		{N: 2, Source: "thermo.pdf", DocumentId: "d1", Chunk: 5, Start: 2500, End: 3000, Score: 0.8, Text: chunks[1].Text},
+	}, res.Citations)
+}
+
+func TestAskErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := Ask(context.Background(), &fakeLLM{}, "ask", &fakeRetriever{}, "u1", "q", 5)
+	assert.ErrorIs(t, err, ErrNoContext)
+
+	upstream := errors.New("down")
+	_, err = Ask(context.Background(), &fakeLLM{err: upstream}, "ask", &fakeRetriever{chunks: chunks}, "u1", "q", 5)
+	assert.ErrorIs(t, err, upstream)
+}
+
+func TestCite(t *testing.T) {
+	t.Parallel()
+
+	t.Run("grouped and repeated", func(t *testing.T) {
+		t.Parallel()
+		answer, cs := Cite("a [3, 1] b [1] c [0, 9]", chunks)
+		assert.Equal(t, "a [3, 1] b [1] c", answer)
+		require.Len(t, cs, 2)
+		assert.Equal(t, 3, cs[0].N)
+		assert.Equal(t, 1, cs[1].N)
+	})
+
+	t.Run("nothing cited", func(t *testing.T) {
+		t.Parallel()
+		answer, cs := Cite("Ответа в документах нет", chunks)
+		assert.Equal(t, "Ответа в документах нет", answer)
+		assert.Len(t, cs, 3)
+	})
+
+	t.Run("long snippet", func(t *testing.T) {
+		t.Parallel()
+		_, cs := Cite("[1]", []Chunk{{Text: strings.Repeat("я", snippetLen+10)}})
+		assert.Equal(t, snippetLen+1, len([]rune(cs[0].Text)))
+	})
+}
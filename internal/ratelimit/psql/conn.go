@@ -0,0 +1,26 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type SqlRepo interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+const waitTime = 3 * time.Second
+
+// Driver is ratelimit.Store on top of postgres, buckets are shared by all instances of service
+type Driver struct {
+	driver SqlRepo
+}
+
+func NewDriver(driver SqlRepo) *Driver {
+	return &Driver{
+		driver: driver,
+	}
+}
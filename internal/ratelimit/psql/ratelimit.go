@@ -0,0 +1,62 @@
+package psql
+
+import (
+	"context"
+	"flicker/internal/ratelimit"
+	"strings"
+
+	"github.com/autumnterror/breezynotes/pkg/utils/format"
+)
+
+func (d *Driver) Name() string {
+	return ratelimit.StorePostgres
+}
+
+// Take refill and take token in one statement, so concurrent requests of instances don't race.
+// Time is taken from database, clocks of instances may differ
+func (d *Driver) Take(ctx context.Context, key string, r ratelimit.Rate) (ratelimit.Result, error) {
+	const op = "psql.ratelimit.Take"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	// $2 is limit, $3 is tokens per second, right side of SET sees bucket before update
+	query := strings.ReplaceAll(`
+		INSERT INTO rate_limits AS b (key, tokens, allowed, updated_at, expires_at)
+		VALUES ($1, $2::float8 - 1, true, now(), now() + $4::bigint * interval '1 millisecond')
+		ON CONFLICT (key) DO UPDATE
+		SET tokens     = CASE WHEN refilled >= 1 THEN refilled - 1 ELSE refilled END,
+		    allowed    = refilled >= 1,
+		    updated_at = now(),
+		    expires_at = now() + $4::bigint * interval '1 millisecond'
+		RETURNING tokens, allowed
+	`, "refilled", `least($2::float8, b.tokens + extract(epoch FROM now() - b.updated_at) * $3::float8)`)
+
+	var (
+		tokens  float64
+		allowed bool
+	)
+	// bucket is full at latest period after last request, then row can be deleted
+	err := d.driver.QueryRowContext(ctx, query, key, r.Limit, r.PerSecond(), r.Period.Milliseconds()).Scan(&tokens, &allowed)
+	if err != nil {
+		return ratelimit.Result{}, format.Error(op, err)
+	}
+	return ratelimit.NewResult(r, allowed, tokens), nil
+}
+
+func (d *Driver) Expire(ctx context.Context) (int64, error) {
+	const op = "psql.ratelimit.Expire"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	res, err := d.driver.ExecContext(ctx, `DELETE FROM rate_limits WHERE expires_at <= now()`)
+	if err != nil {
+		return 0, format.Error(op, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, format.Error(op, err)
+	}
+	return n, nil
+}
@@ -0,0 +1,63 @@
+package psql
+
+import (
+	"context"
+	authpsql "flicker/internal/auth/psql"
+	"flicker/internal/config"
+	"flicker/internal/ratelimit"
+	"testing"
+	"time"
+
+	"github.com/autumnterror/breezynotes/pkg/utils/id"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitOperations(t *testing.T) {
+	t.Parallel()
+
+	repo, cleanup := setupTestTx(t)
+	defer cleanup()
+
+	key := id.New()
+	r := ratelimit.Rate{Limit: 3, Period: time.Hour}
+
+	t.Run("burst", func(t *testing.T) {
+		// now() is fixed inside transaction, so bucket is not refilled between calls
+		for i := range 3 {
+			res, err := repo.Take(context.TODO(), key, r)
+			assert.NoError(t, err)
+			assert.True(t, res.Allowed)
+			assert.Equal(t, 2-i, res.Remaining)
+		}
+
+		res, err := repo.Take(context.TODO(), key, r)
+		assert.NoError(t, err)
+		assert.False(t, res.Allowed)
+		assert.Equal(t, 0, res.Remaining)
+		assert.InDelta(t, (20 * time.Minute).Seconds(), res.RetryAfter.Seconds(), 1)
+	})
+
+	t.Run("other key", func(t *testing.T) {
+		res, err := repo.Take(context.TODO(), id.New(), r)
+		assert.NoError(t, err)
+		assert.True(t, res.Allowed)
+	})
+
+	t.Run("expire", func(t *testing.T) {
+		n, err := repo.Expire(context.TODO())
+		assert.NoError(t, err)
+		assert.EqualValues(t, 0, n)
+	})
+}
+
+func setupTestTx(t *testing.T) (*Driver, func()) {
+	pdb := authpsql.MustConnect(config.Test())
+
+	tx, err := pdb.Driver.Begin()
+	assert.NoError(t, err)
+
+	return NewDriver(tx), func() {
+		assert.NoError(t, tx.Rollback())
+		assert.NoError(t, pdb.Disconnect())
+	}
+}
@@ -0,0 +1,94 @@
+// Package ratelimit throttle requests with token buckets: bucket holds up to Limit tokens,
+// every request takes one and tokens are refilled evenly, Limit per Period
+package ratelimit
+
+import (
+	"context"
+	"flicker/internal/config"
+	"math"
+	"time"
+
+	"github.com/autumnterror/breezynotes/pkg/log"
+)
+
+const (
+	StoreMemory   = "memory"
+	StorePostgres = "postgres"
+	StoreOff      = "off"
+)
+
+// groups of routes which have own limits in config
+const (
+	GroupGlobal = "global"
+	GroupAuth   = "auth"
+	GroupAI     = "ai"
+)
+
+type Rate struct {
+	Limit  int
+	Period time.Duration
+}
+
+func FromConfig(r config.RateLimit) Rate {
+	return Rate{Limit: r.Limit, Period: r.Period}
+}
+
+// PerSecond is refill speed of bucket
+func (r Rate) PerSecond() float64 {
+	return float64(r.Limit) / r.Period.Seconds()
+}
+
+// Result of taking token from bucket
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is time until bucket is full again
+	Reset time.Duration
+	// RetryAfter is time until next token when request is not allowed
+	RetryAfter time.Duration
+}
+
+// NewResult build Result from tokens left in bucket
+func NewResult(r Rate, allowed bool, tokens float64) Result {
+	res := Result{
+		Allowed:   allowed,
+		Limit:     r.Limit,
+		Remaining: max(int(math.Floor(tokens)), 0),
+		Reset:     seconds((float64(r.Limit) - tokens) / r.PerSecond()),
+	}
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / r.PerSecond())
+	}
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(max(s, 0) * float64(time.Second))
+}
+
+type Store interface {
+	// Take token from bucket of key
+	Take(ctx context.Context, key string, r Rate) (Result, error)
+	// Expire delete buckets which are full by now, they are the same as missing ones
+	Expire(ctx context.Context) (int64, error)
+	Name() string
+}
+
+// Sweep expire full buckets every period until ctx is done
+func Sweep(ctx context.Context, s Store, every time.Duration) {
+	const op = "ratelimit.Sweep"
+
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if _, err := s.Expire(ctx); err != nil {
+				log.Error(op, s.Name(), err)
+			}
+		}
+	}
+}
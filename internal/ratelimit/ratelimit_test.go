@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func memory(now *time.Time) *Memory {
+	m := NewMemory()
+	m.now = func() time.Time { return *now }
+	return m
+}
+
+func TestTake(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	m := memory(&now)
+	r := Rate{Limit: 3, Period: 3 * time.Second}
+
+	// full bucket allows burst of limit requests
+	for i := range 3 {
+		res, err := m.Take(context.TODO(), "k", r)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 3, res.Limit)
+		assert.Equal(t, 2-i, res.Remaining)
+		assert.Equal(t, time.Duration(i+1)*time.Second, res.Reset)
+	}
+
+	res, _ := m.Take(context.TODO(), "k", r)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	assert.Equal(t, time.Second, res.RetryAfter)
+
+	// other key has own bucket
+	res, _ = m.Take(context.TODO(), "other", r)
+	assert.True(t, res.Allowed)
+
+	// token per second is refilled
+	now = now.Add(500 * time.Millisecond)
+	res, _ = m.Take(context.TODO(), "k", r)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 500*time.Millisecond, res.RetryAfter)
+
+	now = now.Add(500 * time.Millisecond)
+	res, _ = m.Take(context.TODO(), "k", r)
+	assert.True(t, res.Allowed)
+
+	// bucket is not filled over limit
+	now = now.Add(time.Hour)
+	res, _ = m.Take(context.TODO(), "k", r)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 2, res.Remaining)
+}
+
+func TestTakeConcurrent(t *testing.T) {
+	t.Parallel()
+
+	m := NewMemory()
+	r := Rate{Limit: 50, Period: time.Hour}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		allowed int
+	)
+	for range 200 {
+		wg.Go(func() {
+			res, err := m.Take(context.TODO(), "k", r)
+			assert.NoError(t, err)
+			mu.Lock()
+			defer mu.Unlock()
+			if res.Allowed {
+				allowed++
+			}
+		})
+	}
+	wg.Wait()
+	assert.Equal(t, 50, allowed)
+}
+
+func TestExpire(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	m := memory(&now)
+	r := Rate{Limit: 10, Period: 10 * time.Second}
+
+	for i := range 5 {
+		m.Take(context.TODO(), fmt.Sprint(i), r)
+	}
+	m.Take(context.TODO(), "0", r)
+	require.Equal(t, 5, m.Len())
+
+	// buckets with one token taken are full again in one second
+	now = now.Add(time.Second)
+	n, err := m.Expire(context.TODO())
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, n)
+	assert.Equal(t, 1, m.Len())
+}
@@ -0,0 +1,89 @@
+// Package reqid is id of request to flicker. It is carried by context.Context, so logs, queries
+// to postgres and requests to upstreams made while serving request can be correlated
+package reqid
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/autumnterror/breezynotes/pkg/utils/id"
+)
+
+// Header carries id of request from client and to upstreams, and is sent back in answer
+const Header = "X-Request-ID"
+
+// maxLen of id taken from client, longer ids are replaced
+const maxLen = 128
+
+type ctxKey struct{}
+
+// New return generated id
+func New() string {
+	return id.New()
+}
+
+// Valid report if id of client may be used as is. Only letters, digits and "-_.:" are allowed,
+// so id is safe to put into logs and SQL comments
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLen {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// With return context carrying id
+func With(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// From return id carried by context, empty outside of request
+func From(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+type SqlRepo interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// DB append id of request to queries as comment, so it is seen in pg_stat_activity and logs of
+// postgres. Queries made outside of request are sent as is
+type DB struct {
+	SqlRepo
+}
+
+// SQL wrap db, result is passed to drivers of stores instead of db
+func SQL(db SqlRepo) *DB {
+	return &DB{SqlRepo: db}
+}
+
+func (d *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return d.SqlRepo.QueryContext(ctx, comment(ctx, query), args...)
+}
+
+func (d *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return d.SqlRepo.ExecContext(ctx, comment(ctx, query), args...)
+}
+
+func (d *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return d.SqlRepo.QueryRowContext(ctx, comment(ctx, query), args...)
+}
+
+// comment is put on its own line: query may end with "--" comment
+func comment(ctx context.Context, query string) string {
+	id := From(ctx)
+	if !Valid(id) {
+		return query
+	}
+	return query + "\n/* request_id=" + id + " */"
+}
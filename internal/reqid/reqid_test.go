@@ -0,0 +1,62 @@
+package reqid
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recorder struct {
+	queries []string
+}
+
+func (r *recorder) QueryContext(_ context.Context, query string, _ ...any) (*sql.Rows, error) {
+	r.queries = append(r.queries, query)
+	return nil, nil
+}
+
+func (r *recorder) ExecContext(_ context.Context, query string, _ ...any) (sql.Result, error) {
+	r.queries = append(r.queries, query)
+	return nil, nil
+}
+
+func (r *recorder) QueryRowContext(_ context.Context, query string, _ ...any) *sql.Row {
+	r.queries = append(r.queries, query)
+	return nil
+}
+
+func TestValid(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, Valid(New()))
+	assert.True(t, Valid("trace-1:span_2.a"))
+
+	assert.False(t, Valid(""))
+	assert.False(t, Valid(strings.Repeat("a", maxLen+1)))
+	assert.False(t, Valid("a b"))
+	assert.False(t, Valid("*/ DROP TABLE notes; /*"))
+	assert.False(t, Valid("id\nforged log line"))
+}
+
+func TestSQL(t *testing.T) {
+	t.Parallel()
+
+	rec := &recorder{}
+	db := SQL(rec)
+	const query = "SELECT 1 -- one"
+
+	db.QueryRowContext(context.Background(), query)
+	_, _ = db.ExecContext(With(context.Background(), "req-1"), query)
+	_, _ = db.QueryContext(With(context.Background(), "*/ bad"), query)
+
+	assert.Equal(t, []string{
+		query,
+		query + "\n/* request_id=req-1 */",
+		query,
+	}, rec.queries)
+	assert.Equal(t, "req-1", From(With(context.Background(), "req-1")))
+	assert.Empty(t, From(context.Background()))
+}
@@ -0,0 +1,95 @@
+// Package srs implement SM-2 spaced repetition scheduler.
+// https://super-memory.com/english/ol/sm2.htm
+package srs
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+var ErrBadGrade = errors.New("grade must be from 0 to 5")
+
+// Grade is quality of recall from 0 (complete blackout) to 5 (perfect response)
+type Grade int
+
+const (
+	GradeBlackout Grade = iota
+	GradeWrong
+	GradeWrongFamiliar
+	GradeHard
+	GradeGood
+	GradeEasy
+)
+
+// PassGrade is minimal grade at which card counts as recalled
+const PassGrade = GradeHard
+
+const (
+	InitialEase = 2.5
+	MinEase     = 1.3
+
+	firstInterval  = 1
+	secondInterval = 6
+)
+
+// MaxInterval in days, about 100 years. Longer intervals overflow time.Duration and interval_days
+const MaxInterval = 36500
+
+const day = 24 * time.Hour
+
+// State of card in schedule. Interval is in days
+type State struct {
+	Repetitions int
+	Interval    int
+	Ease        float64
+	Lapses      int
+	Due         time.Time
+}
+
+// New return state of never reviewed card, due immediately
+func New(now time.Time) State {
+	return State{
+		Ease: InitialEase,
+		Due:  now,
+	}
+}
+
+// Review apply answer with grade g given at now and return next state.
+// Failed card (grade below PassGrade) starts repetitions from scratch, but keep its ease
+// lowered so it appear more often
+func Review(s State, g Grade, now time.Time) (State, error) {
+	if g < GradeBlackout || g > GradeEasy {
+		return s, ErrBadGrade
+	}
+	if s.Ease < MinEase {
+		s.Ease = InitialEase
+	}
+
+	q := float64(GradeEasy - g)
+	s.Ease = math.Max(MinEase, s.Ease+0.1-q*(0.08+q*0.02))
+
+	if g < PassGrade {
+		s.Repetitions = 0
+		s.Interval = firstInterval
+		s.Lapses++
+	} else {
+		switch s.Repetitions {
+		case 0:
+			s.Interval = firstInterval
+		case 1:
+			s.Interval = secondInterval
+		default:
+			s.Interval = min(int(math.Round(float64(min(s.Interval, MaxInterval))*s.Ease)), MaxInterval)
+		}
+		s.Repetitions++
+	}
+
+	s.Due = now.Add(time.Duration(s.Interval) * day)
+	return s, nil
+}
+
+// IsDue report if card should be reviewed at now
+func (s State) IsDue(now time.Time) bool {
+	return !s.Due.After(now)
+}
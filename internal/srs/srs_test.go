@@ -0,0 +1,149 @@
+package srs
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var start = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	s := New(start)
+	assert.Equal(t, InitialEase, s.Ease)
+	assert.Equal(t, 0, s.Repetitions)
+	assert.True(t, s.IsDue(start))
+}
+
+func TestReviewIntervals(t *testing.T) {
+	t.Parallel()
+
+	s := New(start)
+	now := start
+
+	var intervals []int
+	for range 5 {
+		var err error
+		s, err = Review(s, GradeGood, now)
+		assert.NoError(t, err)
+		intervals = append(intervals, s.Interval)
+		now = s.Due
+	}
+
+	// grade 4 keeps ease at 2.5: 1, 6, 15, 38, 95 (rounded 37.5 -> 38)
+	assert.Equal(t, []int{1, 6, 15, 38, 95}, intervals)
+	assert.Equal(t, 5, s.Repetitions)
+	assert.Equal(t, InitialEase, s.Ease)
+	assert.Equal(t, start.Add(time.Duration(1+6+15+38+95)*day), s.Due)
+}
+
+func TestReviewEase(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		grade Grade
+		ease  float64
+	}{
+		{GradeEasy, 2.6},
+		{GradeGood, 2.5},
+		{GradeHard, 2.36},
+		{GradeWrongFamiliar, 2.18},
+		{GradeWrong, 1.96},
+		{GradeBlackout, 1.7},
+	}
+
+	for _, tt := range tests {
+		s, err := Review(New(start), tt.grade, start)
+		assert.NoError(t, err)
+		assert.InDelta(t, tt.ease, s.Ease, 1e-9, "grade %d", tt.grade)
+	}
+}
+
+func TestReviewMinEase(t *testing.T) {
+	t.Parallel()
+
+	s := New(start)
+	for range 10 {
+		s, _ = Review(s, GradeBlackout, start)
+	}
+	assert.Equal(t, MinEase, s.Ease)
+
+	// interval still grows after recovery, but slowly
+	s, _ = Review(s, GradeHard, start)
+	s, _ = Review(s, GradeHard, start)
+	s, _ = Review(s, GradeHard, start)
+	assert.Equal(t, int(math.Round(6*MinEase)), s.Interval)
+}
+
+func TestReviewMaxInterval(t *testing.T) {
+	t.Parallel()
+
+	s := New(start)
+	now := start
+	for range 100 {
+		var err error
+		s, err = Review(s, GradeEasy, now)
+		assert.NoError(t, err)
+		assert.LessOrEqual(t, s.Interval, MaxInterval)
+		assert.True(t, s.Due.After(now), "due %s is not after %s", s.Due, now)
+		now = s.Due
+	}
+	assert.Equal(t, MaxInterval, s.Interval)
+
+	// interval saved before cap is not grown further
+	s.Interval = 206276
+	s, _ = Review(s, GradeEasy, start)
+	assert.Equal(t, MaxInterval, s.Interval)
+	assert.Equal(t, start.Add(MaxInterval*day), s.Due)
+}
+
+func TestReviewLapse(t *testing.T) {
+	t.Parallel()
+
+	s := New(start)
+	s, _ = Review(s, GradeGood, start)
+	s, _ = Review(s, GradeGood, start)
+	s, _ = Review(s, GradeGood, start)
+	assert.Equal(t, 15, s.Interval)
+
+	now := start.Add(20 * day)
+	s, err := Review(s, GradeWrong, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, s.Repetitions)
+	assert.Equal(t, 1, s.Interval)
+	assert.Equal(t, 1, s.Lapses)
+	assert.Equal(t, now.Add(day), s.Due)
+	assert.False(t, s.IsDue(now))
+	assert.True(t, s.IsDue(now.Add(day)))
+
+	// relearning starts from first interval again
+	s, _ = Review(s, GradeGood, now)
+	assert.Equal(t, 1, s.Interval)
+	s, _ = Review(s, GradeGood, now)
+	assert.Equal(t, 6, s.Interval)
+}
+
+func TestReviewBadGrade(t *testing.T) {
+	t.Parallel()
+
+	s := New(start)
+	for _, g := range []Grade{-1, 6} {
+		got, err := Review(s, g, start)
+		assert.ErrorIs(t, err, ErrBadGrade)
+		assert.Equal(t, s, got)
+	}
+}
+
+func TestReviewZeroState(t *testing.T) {
+	t.Parallel()
+
+	// state loaded without ease, e.g. from old row, behaves as new card
+	s, err := Review(State{}, GradeGood, start)
+	assert.NoError(t, err)
+	assert.Equal(t, InitialEase, s.Ease)
+	assert.Equal(t, 1, s.Interval)
+}
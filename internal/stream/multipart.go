@@ -0,0 +1,37 @@
+// Package stream build request bodies for upstream services without holding files in memory
+package stream
+
+import (
+	"io"
+	"mime/multipart"
+)
+
+// Multipart return multipart/form-data body with file read from r in field, and its content type.
+// Body is produced by goroutine through io.Pipe while it is read, so memory doesn't depend
+// on file size. Error of r is returned from Read of body. Body must be closed, HTTP client does it
+func Multipart(field, filename string, r io.Reader, fields map[string]string) (io.ReadCloser, string) {
+	pr, pw := io.Pipe()
+	w := multipart.NewWriter(pw)
+
+	go func() {
+		pw.CloseWithError(writeMultipart(w, field, filename, r, fields))
+	}()
+
+	return pr, w.FormDataContentType()
+}
+
+func writeMultipart(w *multipart.Writer, field, filename string, r io.Reader, fields map[string]string) error {
+	for k, v := range fields {
+		if err := w.WriteField(k, v); err != nil {
+			return err
+		}
+	}
+	part, err := w.CreateFormFile(field, filename)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, r); err != nil {
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,158 @@
+package stream
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// synthetic is reader of n pseudo random bytes without allocation
+type synthetic struct {
+	n, pos int64
+}
+
+func (s *synthetic) Read(p []byte) (int, error) {
+	if s.pos >= s.n {
+		return 0, io.EOF
+	}
+	p = p[:min(int64(len(p)), s.n-s.pos)]
+	for i := range p {
+		p[i] = byte((s.pos + int64(i)) * 2654435761 >> 13)
+	}
+	s.pos += int64(len(p))
+	return len(p), nil
+}
+
+func checksum(r io.Reader) []byte {
+	h := sha256.New()
+	_, _ = io.Copy(h, r)
+	return h.Sum(nil)
+}
+
+func TestMultipart(t *testing.T) {
+	t.Parallel()
+
+	body, contentType := Multipart("file", "лекция.mp3", bytes.NewReader([]byte("audio")), map[string]string{"language": "ru"})
+	defer body.Close()
+
+	_, params, err := mime.ParseMediaType(contentType)
+	require.NoError(t, err)
+	form, err := multipart.NewReader(body, params["boundary"]).ReadForm(1 << 20)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"ru"}, form.Value["language"])
+	if assert.Len(t, form.File["file"], 1) {
+		fh := form.File["file"][0]
+		assert.Equal(t, "лекция.mp3", fh.Filename)
+		f, err := fh.Open()
+		require.NoError(t, err)
+		b, _ := io.ReadAll(f)
+		assert.Equal(t, "audio", string(b))
+	}
+}
+
+type failing struct{}
+
+func (failing) Read([]byte) (int, error) { return 0, errors.New("disk error") }
+
+func TestMultipartSourceError(t *testing.T) {
+	t.Parallel()
+
+	body, _ := Multipart("file", "a", failing{}, nil)
+	defer body.Close()
+	_, err := io.ReadAll(body)
+	assert.EqualError(t, err, "disk error")
+}
+
+// counting is synthetic reader safe to watch from other goroutine
+type counting struct {
+	synthetic
+	read atomic.Int64
+}
+
+func (c *counting) Read(p []byte) (int, error) {
+	n, err := c.synthetic.Read(p)
+	c.read.Add(int64(n))
+	return n, err
+}
+
+func TestMultipartReaderClosed(t *testing.T) {
+	t.Parallel()
+
+	// client gave up: writer goroutine must stop reading file instead of blocking or copying it to the end
+	src := &counting{synthetic: synthetic{n: 1 << 30}}
+	body, _ := Multipart("file", "a", src, nil)
+	_, err := io.CopyN(io.Discard, body, 1<<20)
+	require.NoError(t, err)
+	require.NoError(t, body.Close())
+
+	assert.Eventually(t, func() bool {
+		n := src.read.Load()
+		time.Sleep(10 * time.Millisecond)
+		return src.read.Load() == n
+	}, time.Second, 10*time.Millisecond)
+	assert.Less(t, src.read.Load(), int64(2<<20))
+}
+
+// TestMultipartConstantMemory send 512 MiB file to HTTP server and check that heap
+// allocated during request is a small fraction of file size
+func TestMultipartConstantMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("large upload")
+	}
+
+	const size = 512 << 20
+
+	var got hash.Hash
+	var received int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mr, err := r.MultipartReader()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		part, err := mr.NextPart()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		got = sha256.New()
+		received, _ = io.Copy(got, part)
+	}))
+	defer srv.Close()
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	body, contentType := Multipart("file", "big.bin", &synthetic{n: size}, nil)
+	req, err := http.NewRequest(http.MethodPost, srv.URL, body)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	runtime.ReadMemStats(&after)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int64(size), received)
+	assert.Equal(t, checksum(&synthetic{n: size}), got.Sum(nil))
+
+	allocated := after.TotalAlloc - before.TotalAlloc
+	t.Logf("allocated %d KiB for %d MiB file", allocated>>10, size>>20)
+	assert.Less(t, allocated, uint64(16<<20), "memory must not grow with file")
+}
@@ -0,0 +1,80 @@
+package summary
+
+import (
+	"strings"
+)
+
+// Normalize give note consistent heading structure: single first level heading with title on
+// top, sections start from second level and no level is skipped. Headings in code blocks are
+// not touched. If title is empty, first level heading of note or DefaultTitle is used
+func Normalize(md, title string) string {
+	lines := strings.Split(strings.TrimSpace(md), "\n")
+
+	type heading struct{ line, level int }
+	var (
+		headings []heading
+		fence    string
+	)
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			continue
+		}
+		if level := headingLevel(line); level > 0 {
+			headings = append(headings, heading{i, level})
+		}
+	}
+
+	// note's own title is dropped, it is replaced by given one or becomes the title
+	if len(headings) > 0 && headings[0].level == 1 && strings.TrimSpace(strings.Join(lines[:headings[0].line], "")) == "" {
+		if strings.TrimSpace(title) == "" {
+			title = strings.TrimSpace(lines[headings[0].line][1:])
+		}
+		lines = lines[headings[0].line+1:]
+		shift := headings[0].line + 1
+		headings = headings[1:]
+		for i := range headings {
+			headings[i].line -= shift
+		}
+	}
+	title = strings.TrimSpace(title)
+	if title == "" {
+		title = DefaultTitle
+	}
+
+	top := 7
+	for _, h := range headings {
+		top = min(top, h.level)
+	}
+	prev := 1
+	for _, h := range headings {
+		level := min(h.level-top+2, prev+1, 6)
+		lines[h.line] = strings.Repeat("#", level) + lines[h.line][h.level:]
+		prev = level
+	}
+
+	body := strings.TrimSpace(strings.Join(lines, "\n"))
+	if body == "" {
+		return "# " + title + "\n"
+	}
+	return "# " + title + "\n\n" + body + "\n"
+}
+
+// headingLevel return level of ATX heading or 0 if line is not heading
+func headingLevel(line string) int {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || (level < len(line) && line[level] != ' ') {
+		return 0
+	}
+	return level
+}
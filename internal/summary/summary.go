@@ -0,0 +1,360 @@
+// Package summary make markdown note from text of any length. Text longer than LLM context is
+// split into overlapping chunks, chunks are summarized in parallel (map) and their notes are
+// rewritten by LLM into one note with common heading structure (reduce)
+package summary
+
+import (
+	"context"
+	"errors"
+	"flicker/internal/config"
+	"flicker/internal/upstream"
+	"flicker/internal/views"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/autumnterror/breezynotes/pkg/utils/format"
+)
+
+var (
+	ErrSummary     = errors.New("summarization failed")
+	ErrEmptyOutput = errors.New("llm returned empty note")
+)
+
+const (
+	// MapHook make markdown note from text
+	MapHook = "generatemd"
+	// ReduceHook rewrite notes of consecutive parts of text into one note by instructions
+	ReduceHook = "reducemd"
+
+	callTimeout = 2 * time.Minute
+)
+
+// DefaultTitle is heading of merged note when title is not given
+const DefaultTitle = "Конспект"
+
+const instructions = `These are notes of consecutive parts of one text, separated by "---". ` +
+	`Rewrite them into one markdown note. The first line is "# " and the title; sections are "## ", subsections "### ", ` +
+	`deeper headings are not used and there are no other first level headings. ` +
+	`Merge topics repeated in several parts, drop duplicates caused by overlap of parts, keep all facts, definitions, formulas and examples.`
+
+var (
+	details = map[string]string{
+		views.DetailBrief:    `Be brief: keep only main ideas, key definitions and formulas, about a third of the usual length.`,
+		views.DetailDetailed: `Be detailed: keep every fact, definition, formula, example and step of reasoning.`,
+	}
+	styles = map[string]string{
+		views.StyleCornell: `Use Cornell notes layout: every "## " section is a table with two columns, key questions on the left ` +
+			`and notes on the right, followed by a summary of the section in one or two sentences; the last section is "## Summary" of the whole text.`,
+		views.StyleMindMap: `Write the note as a mind map: the title is the central topic, every "## " section is a main branch ` +
+			`and its content is a nested bulleted list of short phrases, up to four levels deep, without paragraphs.`,
+	}
+)
+
+// Instructions tell LLM how to follow options, empty for default ones
+func Instructions(o views.SummaryOptions) string {
+	var ls []string
+	if s := styles[o.Style]; s != "" {
+		ls = append(ls, s)
+	}
+	if d := details[o.Detail]; d != "" {
+		ls = append(ls, d)
+	}
+	if o.Language != "" {
+		ls = append(ls, fmt.Sprintf(`Write in the language with BCP 47 tag %q, translate if the text is in other language.`, o.Language))
+	}
+	return strings.Join(ls, " ")
+}
+
+// reduceInstructions are instructions of reduce with options, notes keep their language by default
+func reduceInstructions(o views.SummaryOptions) string {
+	ls := []string{instructions}
+	if extra := Instructions(o); extra != "" {
+		ls = append(ls, extra)
+	}
+	if o.Language == "" {
+		ls = append(ls, "Write in the language of the notes.")
+	}
+	return strings.Join(ls, " ")
+}
+
+// payloadOptions return options sent to LLM, nil for default ones
+func payloadOptions(o views.SummaryOptions) *views.SummaryOptions {
+	if o == (views.SummaryOptions{}) {
+		return nil
+	}
+	return &o
+}
+
+const separator = "\n\n---\n\n"
+
+// LLM run prompt webhook and return raw text answer, e.g. *n8n.Client
+type LLM interface {
+	Call(ctx context.Context, hook string, payload any) (string, error)
+}
+
+type mapRequest struct {
+	Content      string                `json:"content"`
+	Instructions string                `json:"instructions,omitempty"`
+	Options      *views.SummaryOptions `json:"options,omitempty"`
+}
+
+type reduceRequest struct {
+	Content      string                `json:"content"`
+	Title        string                `json:"title,omitempty"`
+	Instructions string                `json:"instructions"`
+	Options      *views.SummaryOptions `json:"options,omitempty"`
+}
+
+func newMapRequest(text string, o views.SummaryOptions) mapRequest {
+	return mapRequest{Content: text, Instructions: Instructions(o), Options: payloadOptions(o)}
+}
+
+func newReduceRequest(text, title string, o views.SummaryOptions) reduceRequest {
+	return reduceRequest{Content: text, Title: title, Instructions: reduceInstructions(o), Options: payloadOptions(o)}
+}
+
+type Summarizer struct {
+	LLM LLM
+	// ChunkTokens is max estimated size of text given to LLM at once
+	ChunkTokens   int
+	OverlapTokens int
+	// Concurrency limit LLM calls running at the same time
+	Concurrency int
+	// Retries of failed call, with backoff doubled after every attempt. Summarization has no
+	// side effects, so calls failed after upstream got them (5xx, timeout) are repeated here,
+	// while client of upstream repeats only calls which didn't reach it
+	Retries int
+	Backoff time.Duration
+	// MaxFailed is share of chunks which may stay not summarized, note is made from the rest
+	MaxFailed float64
+}
+
+func New(llm LLM, cfg *config.Config) *Summarizer {
+	return &Summarizer{
+		LLM:           llm,
+		ChunkTokens:   cfg.SummaryChunkTokens,
+		OverlapTokens: cfg.SummaryOverlapTokens,
+		Concurrency:   cfg.SummaryConcurrency,
+		Retries:       cfg.SummaryRetries,
+		Backoff:       time.Second,
+		MaxFailed:     cfg.SummaryMaxFailed,
+	}
+}
+
+// Result is note with details of how it was made
+type Result struct {
+	Markdown string
+	Chunks   int
+	// Failed are indexes of chunks left without summary
+	Failed   []int
+	Warnings []string
+}
+
+// Summarize make note from text by options. Text which fits into one chunk is summarized by
+// single call and note is returned as LLM wrote it
+func (s *Summarizer) Summarize(ctx context.Context, title, text string, o views.SummaryOptions) (*Result, error) {
+	const op = "summary.Summarizer.Summarize"
+
+	if Tokens(text) <= s.ChunkTokens {
+		md, err := s.call(ctx, MapHook, newMapRequest(text, o))
+		if err != nil {
+			return nil, format.Error(op, err)
+		}
+		return &Result{Markdown: md, Chunks: 1}, nil
+	}
+
+	chunks := Split(text, s.ChunkTokens, s.OverlapTokens)
+	summaries, failed, err := s.Map(ctx, chunks, o, nil)
+	if err != nil {
+		return nil, format.Error(op, err)
+	}
+
+	res := &Result{Chunks: len(chunks), Failed: failed}
+	if len(failed) > 0 {
+		res.Warnings = append(res.Warnings, fmt.Sprintf("%d of %d chunks are not summarized", len(failed), len(chunks)))
+	}
+	summaries = Succeeded(summaries, failed)
+
+	res.Markdown, err = s.Reduce(ctx, title, summaries, o)
+	if err != nil {
+		// notes of chunks are still worth returning
+		res.Warnings = append(res.Warnings, "notes of chunks are joined without rewriting: "+ErrSummary.Error())
+		res.Markdown = Normalize(strings.Join(summaries, "\n\n"), title)
+	}
+	return res, nil
+}
+
+// Map summarize every text, at most Concurrency at once. It returns notes in order of texts and
+// indexes of texts which failed after all retries; their notes are empty. Error is returned when
+// share of failed texts is more than MaxFailed or all of them failed. progress may be nil
+func (s *Summarizer) Map(ctx context.Context, texts []string, o views.SummaryOptions, progress func(done, total int)) ([]string, []int, error) {
+	const op = "summary.Summarizer.Map"
+
+	notes, failed, err := s.parallel(ctx, texts, func(ctx context.Context, text string) (string, error) {
+		return s.call(ctx, MapHook, newMapRequest(text, o))
+	}, progress)
+	if err != nil {
+		return nil, failed, format.Error(op, err)
+	}
+	return notes, failed, nil
+}
+
+// Reduce rewrite notes into one note. Notes which together don't fit into chunk are first reduced
+// by groups which fit, until the rest fit
+func (s *Summarizer) Reduce(ctx context.Context, title string, notes []string, o views.SummaryOptions) (string, error) {
+	const op = "summary.Summarizer.Reduce"
+
+	reduce := func(ctx context.Context, text string) (string, error) {
+		return s.call(ctx, ReduceHook, newReduceRequest(text, "", o))
+	}
+	for len(notes) > 1 && Tokens(strings.Join(notes, separator)) > s.ChunkTokens {
+		groups := s.group(notes)
+		if len(groups) == len(notes) {
+			// every note fills chunk alone
+			break
+		}
+		reduced, failed, err := s.parallel(ctx, groups, reduce, nil)
+		if err != nil {
+			return "", format.Error(op, err)
+		}
+		for _, i := range failed {
+			reduced[i] = groups[i]
+		}
+		notes = reduced
+	}
+
+	md, err := s.call(ctx, ReduceHook, newReduceRequest(strings.Join(notes, separator), title, o))
+	if err != nil {
+		return "", format.Error(op, err)
+	}
+	return Normalize(md, title), nil
+}
+
+// group join consecutive notes into texts which fit into chunk
+func (s *Summarizer) group(notes []string) []string {
+	var (
+		groups []string
+		cur    []string
+		tokens int
+	)
+	for _, n := range notes {
+		t := Tokens(n + separator)
+		if len(cur) > 0 && tokens+t > s.ChunkTokens {
+			groups = append(groups, strings.Join(cur, separator))
+			cur, tokens = nil, 0
+		}
+		cur = append(cur, n)
+		tokens += t
+	}
+	if len(cur) > 0 {
+		groups = append(groups, strings.Join(cur, separator))
+	}
+	return groups
+}
+
+// parallel run fn for every text with bounded concurrency and check share of failures
+func (s *Summarizer) parallel(ctx context.Context, texts []string, fn func(context.Context, string) (string, error), progress func(done, total int)) ([]string, []int, error) {
+	out := make([]string, len(texts))
+	errs := make([]error, len(texts))
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		done int
+	)
+	sem := make(chan struct{}, max(s.Concurrency, 1))
+	for i, text := range texts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				if errs[i] = ctx.Err(); errs[i] == nil {
+					out[i], errs[i] = fn(ctx, text)
+				}
+				<-sem
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			done++
+			if progress != nil {
+				progress(done, len(texts))
+			}
+		}()
+	}
+	wg.Wait()
+
+	var (
+		failed []int
+		first  error
+	)
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, i)
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	if len(failed) > 0 && (len(failed) == len(texts) || float64(len(failed)) > s.MaxFailed*float64(len(texts))) {
+		return nil, failed, fmt.Errorf("%w: %d of %d chunks failed: %w", ErrSummary, len(failed), len(texts), first)
+	}
+	return out, failed, nil
+}
+
+// call run hook, retrying failed calls until ctx is done. Calls refused by open breaker are not
+// retried: breaker stays open longer than backoff
+func (s *Summarizer) call(ctx context.Context, hook string, payload any) (string, error) {
+	var err error
+	for attempt := 0; attempt <= s.Retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(s.Backoff << (attempt - 1)):
+			case <-ctx.Done():
+				return "", err
+			}
+		}
+
+		var md string
+		if md, err = s.once(ctx, hook, payload); err == nil {
+			return md, nil
+		}
+		if errors.Is(err, upstream.ErrOpen) || ctx.Err() != nil {
+			break
+		}
+	}
+	return "", err
+}
+
+func (s *Summarizer) once(ctx context.Context, hook string, payload any) (string, error) {
+	ctx, done := context.WithTimeout(ctx, callTimeout)
+	defer done()
+
+	md, err := s.LLM.Call(ctx, hook, payload)
+	if err != nil {
+		return "", err
+	}
+	md = strings.TrimSpace(md)
+	if md == "" {
+		return "", ErrEmptyOutput
+	}
+	return md, nil
+}
+
+// Succeeded return notes except failed ones
+func Succeeded(notes []string, failed []int) []string {
+	res := make([]string, 0, len(notes)-len(failed))
+	for i, n := range notes {
+		if len(failed) > 0 && failed[0] == i {
+			failed = failed[1:]
+			continue
+		}
+		res = append(res, n)
+	}
+	return res
+}
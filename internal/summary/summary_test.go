@@ -0,0 +1,296 @@
+package summary
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"flicker/internal/upstream"
+	"flicker/internal/views"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokens(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, 0, Tokens(""))
+	assert.Equal(t, 1, Tokens("abc"))
+	assert.Equal(t, 2, Tokens("abcdefgh"))
+	assert.Equal(t, 3, Tokens("привет"))
+}
+
+func TestSplit(t *testing.T) {
+	t.Parallel()
+
+	var sb strings.Builder
+	for i := range 40 {
+		fmt.Fprintf(&sb, "Предложение номер %d про термодинамику. ", i)
+		if i%5 == 4 {
+			sb.WriteString("\n\n")
+		}
+	}
+	text := sb.String()
+
+	chunks := Split(text, 100, 20)
+	assert.Greater(t, len(chunks), 5)
+	for i, c := range chunks {
+		assert.LessOrEqual(t, Tokens(c), 100)
+		assert.NotEqual(t, ' ', rune(c[0]))
+		if i > 0 {
+			// neighbours share text
+			assert.Contains(t, chunks[i-1], string([]rune(c)[:15]), "chunk %d", i)
+		}
+	}
+	assert.True(t, strings.HasPrefix(text, chunks[0]))
+	assert.True(t, strings.HasSuffix(strings.TrimSpace(text), chunks[len(chunks)-1]))
+
+	assert.Equal(t, []string{"коротко"}, Split("  коротко  ", 100, 20))
+	assert.Nil(t, Split("   ", 100, 20))
+}
+
+func TestNormalize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name, md, title, want string
+	}{
+		{"title replaced", "# Старый\n\n## Раздел\nтекст", "Новый", "# Новый\n\n## Раздел\nтекст\n"},
+		{"own title kept", "# Свой\n\n### Раздел\n#### Пункт", "", "# Свой\n\n## Раздел\n### Пункт\n"},
+		{"several first level", "# Один\nа\n# Два\nб", "Общий", "# Общий\n\nа\n## Два\nб\n"},
+		{"skipped levels", "## А\n#### Б\n###### В", "T", "# T\n\n## А\n### Б\n#### В\n"},
+		{"code untouched", "## А\n```\n# комментарий\n```\n#тег", "", "# Конспект\n\n## А\n```\n# комментарий\n```\n#тег\n"},
+		{"text before title", "введение\n# А", "", "# Конспект\n\nвведение\n## А\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Normalize(tt.md, tt.title))
+		})
+	}
+}
+
+// fakeLLM summarize chunk as "## <first word>" with some text, reduce by joining headings under title.
+// Chunks containing words from fail fail every time, flaky fail with 5xx on first call,
+// empty is answered with empty note on first call
+type fakeLLM struct {
+	mu        sync.Mutex
+	calls     map[string]int
+	fail      []string
+	flaky     string
+	empty     string
+	reduceErr error
+
+	running, peak atomic.Int32
+}
+
+func (f *fakeLLM) Call(_ context.Context, hook string, payload any) (string, error) {
+	n := f.running.Add(1)
+	defer f.running.Add(-1)
+	for p := f.peak.Load(); n > p && !f.peak.CompareAndSwap(p, n); p = f.peak.Load() {
+	}
+	time.Sleep(time.Millisecond)
+
+	b, _ := json.Marshal(payload)
+	var req reduceRequest
+	_ = json.Unmarshal(b, &req)
+
+	f.mu.Lock()
+	if f.calls == nil {
+		f.calls = map[string]int{}
+	}
+	f.calls[hook]++
+	first := f.calls[hook+req.Content] == 0
+	f.calls[hook+req.Content]++
+	f.mu.Unlock()
+
+	if hook == ReduceHook {
+		if f.reduceErr != nil {
+			return "", f.reduceErr
+		}
+		var heads []string
+		for _, line := range strings.Split(req.Content, "\n") {
+			if strings.HasPrefix(line, "## ") {
+				heads = append(heads, line)
+			}
+		}
+		return "# " + req.Title + "\n\n" + strings.Join(heads, "\n"), nil
+	}
+
+	for _, w := range f.fail {
+		if strings.Contains(req.Content, w) {
+			return "", errors.New("upstream down")
+		}
+	}
+	if f.flaky != "" && strings.Contains(req.Content, f.flaky) && first {
+		return "", errors.New("n8n returned non-200: status: 502 Bad Gateway")
+	}
+	if f.empty != "" && strings.Contains(req.Content, f.empty) && first {
+		return "", nil
+	}
+	return "## " + strings.Fields(req.Content)[0] + "\n\n" + strings.Repeat("y", 60), nil
+}
+
+// text of n paragraphs, each fills chunk of 50 tokens
+func paragraphs(n int) string {
+	ps := make([]string, n)
+	for i := range ps {
+		ps[i] = fmt.Sprintf("p%02d %s", i, strings.Repeat("x", 150))
+	}
+	return strings.Join(ps, "\n\n")
+}
+
+func newSummarizer(llm LLM) *Summarizer {
+	return &Summarizer{LLM: llm, ChunkTokens: 50, Concurrency: 3, Retries: 1, Backoff: time.Millisecond, MaxFailed: 0.25}
+}
+
+func TestSummarizeShort(t *testing.T) {
+	t.Parallel()
+
+	llm := &fakeLLM{}
+	res, err := newSummarizer(llm).Summarize(context.Background(), "Т", "короткий текст", views.SummaryOptions{})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(res.Markdown, "## короткий\n"))
+	assert.Equal(t, 1, res.Chunks)
+	assert.Equal(t, 1, llm.calls[MapHook])
+	assert.Zero(t, llm.calls[ReduceHook])
+}
+
+func TestSummarizeMapReduce(t *testing.T) {
+	t.Parallel()
+
+	llm := &fakeLLM{flaky: "p03", empty: "p06"}
+	s := newSummarizer(llm)
+	res, err := s.Summarize(context.Background(), "Лекция", paragraphs(8), views.SummaryOptions{})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 8, res.Chunks)
+	assert.Empty(t, res.Failed)
+	assert.Equal(t, "# Лекция\n\n## p00\n## p01\n## p02\n## p03\n## p04\n## p05\n## p06\n## p07\n", res.Markdown)
+	// p03 failed with 5xx and p06 with empty note are retried once
+	assert.Equal(t, 10, llm.calls[MapHook])
+	assert.LessOrEqual(t, llm.peak.Load(), int32(3))
+	assert.Greater(t, llm.peak.Load(), int32(1))
+	// notes don't fit into one chunk, so they are reduced by groups first
+	assert.Greater(t, llm.calls[ReduceHook], 1)
+}
+
+func TestSummarizePartialFailure(t *testing.T) {
+	t.Parallel()
+
+	llm := &fakeLLM{fail: []string{"p05"}}
+	res, err := newSummarizer(llm).Summarize(context.Background(), "Лекция", paragraphs(8), views.SummaryOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{5}, res.Failed)
+	assert.NotContains(t, res.Markdown, "p05")
+	assert.Contains(t, res.Markdown, "## p06")
+	assert.Len(t, res.Warnings, 1)
+	// failing chunk is retried once
+	assert.Equal(t, 9, llm.calls[MapHook])
+
+	llm = &fakeLLM{fail: []string{"p01", "p02", "p05"}}
+	_, err = newSummarizer(llm).Summarize(context.Background(), "Лекция", paragraphs(8), views.SummaryOptions{})
+	assert.ErrorIs(t, err, ErrSummary)
+}
+
+func TestSummarizeReduceFailure(t *testing.T) {
+	t.Parallel()
+
+	llm := &fakeLLM{reduceErr: errors.New("down")}
+	res, err := newSummarizer(llm).Summarize(context.Background(), "Лекция", paragraphs(3), views.SummaryOptions{})
+	assert.NoError(t, err)
+	y := strings.Repeat("y", 60)
+	assert.Equal(t, "# Лекция\n\n## p00\n\n"+y+"\n\n## p01\n\n"+y+"\n\n## p02\n\n"+y+"\n", res.Markdown)
+	assert.Len(t, res.Warnings, 1)
+}
+
+// openLLM is n8n with open breaker
+type openLLM struct {
+	calls atomic.Int32
+}
+
+func (o *openLLM) Call(context.Context, string, any) (string, error) {
+	o.calls.Add(1)
+	return "", &upstream.OpenError{Upstream: upstream.N8n, RetryAfter: time.Minute}
+}
+
+func TestSummarizeBreakerOpen(t *testing.T) {
+	t.Parallel()
+
+	llm := &openLLM{}
+	_, err := newSummarizer(llm).Summarize(context.Background(), "Т", "короткий текст", views.SummaryOptions{})
+	assert.ErrorIs(t, err, upstream.ErrOpen)
+	assert.EqualValues(t, 1, llm.calls.Load(), "call refused by breaker is not retried")
+}
+
+func TestMapCanceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var progress []int
+	_, failed, err := newSummarizer(&fakeLLM{}).Map(ctx, []string{"a", "b"}, views.SummaryOptions{}, func(done, total int) {
+		progress = append(progress, done)
+	})
+	assert.ErrorIs(t, err, ErrSummary)
+	assert.Len(t, failed, 2)
+	assert.Equal(t, []int{1, 2}, progress)
+}
+
+// payloadLLM keep raw payloads of calls by hook
+type payloadLLM struct {
+	mu       sync.Mutex
+	payloads map[string][]string
+}
+
+func (p *payloadLLM) Call(_ context.Context, hook string, payload any) (string, error) {
+	b, _ := json.Marshal(payload)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.payloads == nil {
+		p.payloads = map[string][]string{}
+	}
+	p.payloads[hook] = append(p.payloads[hook], string(b))
+	return "## note\n\n" + strings.Repeat("y", 150), nil
+}
+
+func TestSummarizeOptions(t *testing.T) {
+	t.Parallel()
+
+	llm := &payloadLLM{}
+	_, err := newSummarizer(llm).Summarize(context.Background(), "Т", "короткий текст", views.SummaryOptions{})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"content":"короткий текст"}`, llm.payloads[MapHook][0])
+
+	o := views.SummaryOptions{Language: "en", Detail: views.DetailBrief, Style: views.StyleCornell}
+	llm = &payloadLLM{}
+	_, err = newSummarizer(llm).Summarize(context.Background(), "Лекция", paragraphs(3), o)
+	assert.NoError(t, err)
+
+	var req reduceRequest
+	for _, p := range append(llm.payloads[MapHook], llm.payloads[ReduceHook]...) {
+		assert.NoError(t, json.Unmarshal([]byte(p), &req))
+		assert.Equal(t, &o, req.Options)
+		assert.Contains(t, req.Instructions, "Cornell")
+		assert.Contains(t, req.Instructions, "Be brief")
+		assert.Contains(t, req.Instructions, `"en"`)
+		assert.NotContains(t, req.Instructions, "language of the notes")
+	}
+	assert.True(t, strings.HasPrefix(req.Instructions, instructions))
+
+	assert.Equal(t, "", Instructions(views.SummaryOptions{Detail: views.DetailStandard, Style: views.StyleOutline}))
+	assert.Equal(t, instructions+" Write in the language of the notes.", reduceInstructions(views.SummaryOptions{}))
+}
+
+func TestSucceeded(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{"a", "c"}, Succeeded([]string{"a", "b", "c", "d"}, []int{1, 3}))
+	assert.Equal(t, []string{"a"}, Succeeded([]string{"a"}, nil))
+}
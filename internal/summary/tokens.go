@@ -0,0 +1,113 @@
+package summary
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Tokens estimate number of LLM tokens in text without tokenizer: BPE vocabularies spend about
+// one token on 4 latin characters and on 2 characters of cyrillic and other scripts
+func Tokens(s string) int {
+	q := 0
+	for _, r := range s {
+		q += quarters(r)
+	}
+	return (q + 3) / 4
+}
+
+// quarters is cost of rune in quarters of token
+func quarters(r rune) int {
+	if r < 0x80 {
+		return 1
+	}
+	return 2
+}
+
+// Split cut text into chunks of at most maxTokens estimated tokens, neighbours share about
+// overlap tokens. Chunk is ended at paragraph break, sentence end or space found in its last third
+func Split(text string, maxTokens, overlap int) []string {
+	maxTokens = max(maxTokens, 1)
+	overlap = min(max(overlap, 0), maxTokens/2)
+
+	r := []rune(text)
+	// cost[i] is cost of r[:i] in quarters
+	cost := make([]int, len(r)+1)
+	for i, c := range r {
+		cost[i+1] = cost[i] + quarters(c)
+	}
+
+	var chunks []string
+	for start := skipSpace(r, 0); start < len(r); {
+		end := start
+		for end < len(r) && cost[end+1]-cost[start] <= maxTokens*4 {
+			end++
+		}
+		end = max(end, start+1)
+		if end < len(r) {
+			end = boundary(r, end-(end-start)/3, end)
+		}
+
+		s, e := trim(r, start, end)
+		if s < e {
+			chunks = append(chunks, string(r[s:e]))
+		}
+		if end >= len(r) {
+			break
+		}
+
+		next := end
+		for next > start && cost[end]-cost[next-1] <= overlap*4 {
+			next--
+		}
+		if next < end {
+			// start overlap from word beginning, if there is one
+			j := next
+			for j < end && j > 0 && !unicode.IsSpace(r[j-1]) {
+				j++
+			}
+			if j < end {
+				next = j
+			}
+		}
+		start = skipSpace(r, max(next, start+1))
+	}
+	return chunks
+}
+
+// boundary find best place to end chunk in r[from:to]: after paragraph, sentence or word
+func boundary(r []rune, from, to int) int {
+	from = max(from, 1)
+	best := [3]int{} // paragraph, sentence, space
+	for i := to; i >= from; i-- {
+		prev := r[i-1]
+		switch {
+		case best[0] == 0 && prev == '\n' && i >= 2 && r[i-2] == '\n':
+			best[0] = i
+		case best[1] == 0 && (prev == '\n' || (i < len(r) && unicode.IsSpace(r[i]) && strings.ContainsRune(".!?…;", prev))):
+			best[1] = i
+		case best[2] == 0 && unicode.IsSpace(prev):
+			best[2] = i
+		}
+	}
+	for _, b := range best {
+		if b > 0 {
+			return b
+		}
+	}
+	return to
+}
+
+func skipSpace(r []rune, i int) int {
+	for i < len(r) && unicode.IsSpace(r[i]) {
+		i++
+	}
+	return i
+}
+
+func trim(r []rune, s, e int) (int, int) {
+	s = skipSpace(r, s)
+	for e > s && unicode.IsSpace(r[e-1]) {
+		e--
+	}
+	return s, e
+}
@@ -0,0 +1,70 @@
+package transcript
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flicker/internal/config"
+	"flicker/internal/stream"
+	"flicker/internal/upstream"
+	"flicker/internal/views"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/autumnterror/breezynotes/pkg/utils/format"
+)
+
+var (
+	ErrRequest  = errors.New("transcriber request error")
+	ErrStatus   = errors.New("transcriber returned non-200")
+	ErrResponse = errors.New("bad transcriber response")
+)
+
+// Client call Python transcription service
+type Client struct {
+	url  string
+	http *upstream.Client
+}
+
+func NewClient(cfg *config.Config) *Client {
+	return &Client{
+		url:  strings.TrimRight(cfg.TranscriberURL, "/"),
+		http: upstream.New(upstream.Transcriber, upstream.FromConfig(cfg, upstream.Transcriber)),
+	}
+}
+
+// Transcribe send audio of r to /transcribe as multipart file. Audio is streamed, not buffered
+func (c *Client) Transcribe(ctx context.Context, filename string, r io.Reader) (*views.TranscriberServiceResponse, error) {
+	const op = "transcript.Client.Transcribe"
+
+	body, contentType := stream.Multipart("file", filename, r, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url+"/transcribe", body)
+	if err != nil {
+		body.Close()
+		return nil, format.Error(op, err)
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, format.Error(op, fmt.Errorf("%w: %w", ErrRequest, err))
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, format.Error(op, fmt.Errorf("%w: %w", ErrRequest, err))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, format.Error(op, fmt.Errorf("%w: status: %s, body: %s", ErrStatus, resp.Status, string(respBody)))
+	}
+
+	var out views.TranscriberServiceResponse
+	if err := json.Unmarshal(respBody, &out); err != nil {
+		return nil, format.Error(op, fmt.Errorf("%w: %w", ErrResponse, err))
+	}
+
+	return &out, nil
+}
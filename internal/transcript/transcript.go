@@ -0,0 +1,185 @@
+// Package transcript render timed transcription as subtitles (SRT, WebVTT) or text with timestamps.
+// Transcriber returns long segments, so they are split into short cues at sentence or word
+// boundaries and time of each cue is estimated in proportion to its length
+package transcript
+
+import (
+	"flicker/internal/views"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// subtitle cue is at most two lines of lineChars
+	cueChars  = 84
+	lineChars = 42
+	// paragraph of text format
+	paragraphChars = 600
+)
+
+// Cue is piece of text shown from Start to End seconds
+type Cue struct {
+	Start, End float64
+	Text       string
+}
+
+// Segments return segments of transcription, or whole text as one segment if transcriber
+// didn't send them. Segments without text are dropped
+func Segments(segments []views.TranscriptSegment, text string, duration float64) []views.TranscriptSegment {
+	if len(segments) == 0 {
+		if strings.TrimSpace(text) == "" {
+			return nil
+		}
+		return []views.TranscriptSegment{{StartSec: 0, EndSec: duration, Text: text}}
+	}
+
+	res := make([]views.TranscriptSegment, 0, len(segments))
+	for _, s := range segments {
+		if strings.TrimSpace(s.Text) != "" {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// Cues split segments into cues of at most maxChars runes, longer words are not broken
+func Cues(segments []views.TranscriptSegment, maxChars int) []Cue {
+	var cues []Cue
+	for _, s := range segments {
+		pieces := split(strings.Join(strings.Fields(s.Text), " "), maxChars)
+
+		total := 0
+		for _, p := range pieces {
+			total += utf8.RuneCountInString(p)
+		}
+		start, end := s.StartSec, math.Max(s.EndSec, s.StartSec)
+		at := func(done int) float64 {
+			return start + (end-start)*float64(done)/float64(total)
+		}
+
+		done := 0
+		for _, p := range pieces {
+			n := utf8.RuneCountInString(p)
+			cues = append(cues, Cue{Start: at(done), End: at(done + n), Text: p})
+			done += n
+		}
+	}
+	return cues
+}
+
+// split text into pieces of at most limit runes, preferring end of sentence in second half of piece
+func split(text string, limit int) []string {
+	var pieces []string
+	for text != "" {
+		runes := []rune(text)
+		if len(runes) <= limit {
+			pieces = append(pieces, text)
+			break
+		}
+
+		cut := -1
+		for i := limit; i >= limit/2; i-- {
+			if runes[i] == ' ' && strings.ContainsRune(".!?…", runes[i-1]) {
+				cut = i
+				break
+			}
+		}
+		if cut < 0 {
+			for i := limit; i > 0; i-- {
+				if runes[i] == ' ' {
+					cut = i
+					break
+				}
+			}
+		}
+		if cut < 0 {
+			// no space before limit: word is longer than piece
+			cut = limit
+			for cut < len(runes) && runes[cut] != ' ' {
+				cut++
+			}
+		}
+
+		pieces = append(pieces, strings.TrimSpace(string(runes[:cut])))
+		text = strings.TrimSpace(string(runes[cut:]))
+	}
+	return pieces
+}
+
+// wrap break cue text into two lines near the middle if it is longer than one line
+func wrap(text string) string {
+	runes := []rune(text)
+	if len(runes) <= lineChars {
+		return text
+	}
+	best := -1
+	for i, r := range runes {
+		if r == ' ' && (best < 0 || abs(i-len(runes)/2) < abs(best-len(runes)/2)) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return text
+	}
+	return string(runes[:best]) + "\n" + string(runes[best+1:])
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// timestamp format seconds as HH:MM:SS with milliseconds after sep, or without them if sep is 0
+func timestamp(sec float64, sep rune) string {
+	ms := int64(math.Round(math.Max(sec, 0) * 1000))
+	h, m, s := ms/3_600_000, ms/60_000%60, ms/1000%60
+	if sep == 0 {
+		return fmt.Sprintf("%02d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%02d:%02d:%02d%c%03d", h, m, s, sep, ms%1000)
+}
+
+// SRT render segments as SubRip subtitles
+func SRT(segments []views.TranscriptSegment) string {
+	var sb strings.Builder
+	for i, c := range Cues(segments, cueChars) {
+		fmt.Fprintf(&sb, "%d\n%s --> %s\n%s\n\n", i+1, timestamp(c.Start, ','), timestamp(c.End, ','), wrap(c.Text))
+	}
+	return sb.String()
+}
+
+var vttEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// VTT render segments as WebVTT subtitles
+func VTT(segments []views.TranscriptSegment) string {
+	var sb strings.Builder
+	sb.WriteString("WEBVTT\n\n")
+	for _, c := range Cues(segments, cueChars) {
+		fmt.Fprintf(&sb, "%s --> %s\n%s\n\n", timestamp(c.Start, '.'), timestamp(c.End, '.'), vttEscaper.Replace(wrap(c.Text)))
+	}
+	return sb.String()
+}
+
+// Text render segments as paragraphs, each prefixed by time it starts at
+func Text(segments []views.TranscriptSegment) string {
+	var sb strings.Builder
+	for i, c := range Cues(segments, paragraphChars) {
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+		fmt.Fprintf(&sb, "[%s] %s", timestamp(c.Start, 0), c.Text)
+	}
+	if sb.Len() > 0 {
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// Clock format seconds as HH:MM:SS
+func Clock(sec float64) string {
+	return timestamp(sec, 0)
+}
@@ -0,0 +1,86 @@
+package transcript
+
+import (
+	"flicker/internal/views"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSegments(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []views.TranscriptSegment{{StartSec: 0, EndSec: 12.5, Text: "всё"}}, Segments(nil, "всё", 12.5))
+	assert.Empty(t, Segments(nil, "  ", 3))
+
+	got := Segments([]views.TranscriptSegment{{Index: 0, EndSec: 600, Text: "a"}, {Index: 1, StartSec: 600, EndSec: 700, Text: " "}}, "a", 700)
+	assert.Len(t, got, 1)
+}
+
+func TestCues(t *testing.T) {
+	t.Parallel()
+
+	text := "Первое предложение лекции. Второе предложение чуть длиннее первого! А третье совсем короткое? " +
+		"Дальше идёт очень длинное рассуждение без точек которое придётся резать по пробелам между словами"
+	cues := Cues([]views.TranscriptSegment{{StartSec: 60, EndSec: 120, Text: text}}, 50)
+
+	assert.Greater(t, len(cues), 2)
+	assert.Equal(t, 60.0, cues[0].Start)
+	assert.InDelta(t, 120.0, cues[len(cues)-1].End, 1e-9)
+	assert.Equal(t, "Первое предложение лекции.", cues[0].Text, "sentence boundary preferred")
+
+	var joined []string
+	for i, c := range cues {
+		assert.LessOrEqual(t, utf8.RuneCountInString(c.Text), 50)
+		assert.Less(t, c.Start, c.End)
+		if i > 0 {
+			assert.InDelta(t, cues[i-1].End, c.Start, 1e-9, "cues are contiguous")
+		}
+		joined = append(joined, c.Text)
+	}
+	assert.Equal(t, text, strings.Join(joined, " "))
+
+	long := Cues([]views.TranscriptSegment{{EndSec: 1, Text: strings.Repeat("ы", 100) + " конец"}}, 10)
+	assert.Equal(t, []string{strings.Repeat("ы", 100), "конец"}, []string{long[0].Text, long[1].Text}, "word is not broken")
+}
+
+func TestTimestamp(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "00:00:00,000", timestamp(0, ','))
+	assert.Equal(t, "01:02:03.457", timestamp(3723.4567, '.'))
+	assert.Equal(t, "10:00:00", timestamp(36000, 0))
+	assert.Equal(t, "00:00:00.000", timestamp(-1, '.'))
+}
+
+func TestSRT(t *testing.T) {
+	t.Parallel()
+
+	got := SRT([]views.TranscriptSegment{
+		{StartSec: 0, EndSec: 4, Text: "Привет."},
+		{StartSec: 4, EndSec: 10, Text: "Сегодня поговорим о втором начале термодинамики и энтропии"},
+	})
+	assert.Equal(t, "1\n00:00:00,000 --> 00:00:04,000\nПривет.\n\n"+
+		"2\n00:00:04,000 --> 00:00:10,000\nСегодня поговорим о втором\nначале термодинамики и энтропии\n\n", got)
+}
+
+func TestVTT(t *testing.T) {
+	t.Parallel()
+
+	got := VTT([]views.TranscriptSegment{{StartSec: 1.5, EndSec: 3, Text: "a < b && c -->\n\nd"}})
+	assert.Equal(t, "WEBVTT\n\n00:00:01.500 --> 00:00:03.000\na &lt; b &amp;&amp; c --&gt; d\n\n", got)
+	assert.Equal(t, "WEBVTT\n\n", VTT(nil))
+}
+
+func TestText(t *testing.T) {
+	t.Parallel()
+
+	got := Text([]views.TranscriptSegment{
+		{StartSec: 0, EndSec: 600, Text: "Первая часть."},
+		{StartSec: 600, EndSec: 1200, Text: "Вторая часть."},
+	})
+	assert.Equal(t, "[00:00:00] Первая часть.\n\n[00:10:00] Вторая часть.\n", got)
+	assert.Empty(t, Text(nil))
+}
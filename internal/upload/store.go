@@ -0,0 +1,393 @@
+// Package upload store resumable uploads (tus.io protocol 1.0.0) on disk. Every upload is
+// a data file growing by PATCH requests and a JSON info file with its length, offset and metadata.
+// Completed upload is referenced by id from endpoints which consume files
+package upload
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/autumnterror/breezynotes/pkg/log"
+	"github.com/autumnterror/breezynotes/pkg/utils/format"
+	"github.com/autumnterror/breezynotes/pkg/utils/id"
+)
+
+var (
+	ErrNotFound   = errors.New("upload not found")
+	ErrOffset     = errors.New("upload offset mismatch")
+	ErrTooLarge   = errors.New("upload too large")
+	ErrChecksum   = errors.New("checksum mismatch")
+	ErrLocked     = errors.New("upload is being written")
+	ErrIncomplete = errors.New("upload is not completed")
+)
+
+// Info is state of upload, saved next to its data
+type Info struct {
+	Id       string            `json:"id"`
+	Owner    string            `json:"owner"`
+	Length   int64             `json:"length"`
+	Offset   int64             `json:"offset"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Checksum is sha256 of whole file, set when upload is completed
+	Checksum  string    `json:"checksum,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Completed report whether all bytes are received
+func (i *Info) Completed() bool {
+	return i.Offset == i.Length
+}
+
+// Filename from metadata, tus clients send it as filename or name
+func (i *Info) Filename() string {
+	if f := i.Metadata["filename"]; f != "" {
+		return f
+	}
+	return i.Metadata["name"]
+}
+
+type Store struct {
+	Dir     string
+	MaxSize int64
+	TTL     time.Duration
+
+	mu    sync.Mutex
+	locks map[string]bool
+}
+
+// NewStore create directory for uploads if it doesn't exist
+func NewStore(dir string, maxSize int64, ttl time.Duration) (*Store, error) {
+	const op = "upload.NewStore"
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, format.Error(op, err)
+	}
+	return &Store{Dir: dir, MaxSize: maxSize, TTL: ttl, locks: map[string]bool{}}, nil
+}
+
+// MustNewStore return store and panic if error
+func MustNewStore(dir string, maxSize int64, ttl time.Duration) *Store {
+	s, err := NewStore(dir, maxSize, ttl)
+	if err != nil {
+		log.Panic(err)
+	}
+	return s
+}
+
+var idRe = regexp.MustCompile(`^[0-9a-zA-Z-]{1,64}$`)
+
+func (s *Store) dataPath(uploadId string) string {
+	return filepath.Join(s.Dir, uploadId+".bin")
+}
+
+func (s *Store) infoPath(uploadId string) string {
+	return filepath.Join(s.Dir, uploadId+".json")
+}
+
+// Create register upload of length bytes with empty data file
+func (s *Store) Create(owner string, length int64, metadata map[string]string) (*Info, error) {
+	const op = "upload.Store.Create"
+
+	if length < 0 {
+		return nil, format.Error(op, fmt.Errorf("negative length %d", length))
+	}
+	if s.MaxSize > 0 && length > s.MaxSize {
+		return nil, format.Error(op, ErrTooLarge)
+	}
+
+	now := time.Now().UTC()
+	info := &Info{
+		Id:        id.New(),
+		Owner:     owner,
+		Length:    length,
+		Metadata:  metadata,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.TTL),
+	}
+	f, err := os.OpenFile(s.dataPath(info.Id), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, format.Error(op, err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, format.Error(op, err)
+	}
+	if length == 0 {
+		info.Checksum = hex.EncodeToString(sha256.New().Sum(nil))
+	}
+	if err := s.saveInfo(info); err != nil {
+		return nil, format.Error(op, err)
+	}
+
+	return info, nil
+}
+
+// Get return upload of owner. Expired upload is not found
+func (s *Store) Get(owner, uploadId string) (*Info, error) {
+	const op = "upload.Store.Get"
+
+	info, err := s.info(uploadId)
+	if err != nil {
+		return nil, format.Error(op, err)
+	}
+	if info.Owner != owner || time.Now().After(info.ExpiresAt) {
+		return nil, format.Error(op, ErrNotFound)
+	}
+	return info, nil
+}
+
+func (s *Store) info(uploadId string) (*Info, error) {
+	if !idRe.MatchString(uploadId) {
+		return nil, ErrNotFound
+	}
+	b, err := os.ReadFile(s.infoPath(uploadId))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	var info Info
+	if err := json.Unmarshal(b, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// saveInfo write info atomically, so crash during write doesn't lose offset
+func (s *Store) saveInfo(info *Info) error {
+	b, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	tmp := s.infoPath(info.Id) + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o640); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.infoPath(info.Id))
+}
+
+func (s *Store) lock(uploadId string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.locks[uploadId] {
+		return false
+	}
+	s.locks[uploadId] = true
+	return true
+}
+
+func (s *Store) unlock(uploadId string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.locks, uploadId)
+}
+
+// Write append data of r at offset, which must be equal to current offset of upload.
+// When checksum is set, it covers only this chunk: on mismatch chunk is discarded and ErrChecksum
+// returned. Without checksum bytes received before connection break are kept, so client can resume
+// from them. Returned info is current even if error is returned
+func (s *Store) Write(owner, uploadId string, offset int64, r io.Reader, checksum *Checksum) (*Info, error) {
+	const op = "upload.Store.Write"
+
+	info, err := s.Get(owner, uploadId)
+	if err != nil {
+		return nil, err
+	}
+	if !s.lock(uploadId) {
+		return info, format.Error(op, ErrLocked)
+	}
+	defer s.unlock(uploadId)
+
+	// reread under lock, concurrent write may have finished
+	if info, err = s.info(uploadId); err != nil {
+		return nil, format.Error(op, err)
+	}
+	if offset != info.Offset {
+		return info, format.Error(op, ErrOffset)
+	}
+
+	f, err := os.OpenFile(s.dataPath(uploadId), os.O_WRONLY, 0)
+	if err != nil {
+		return info, format.Error(op, err)
+	}
+	defer f.Close()
+	// data file may be longer than offset if previous write failed before info was saved
+	if err := f.Truncate(offset); err != nil {
+		return info, format.Error(op, err)
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return info, format.Error(op, err)
+	}
+
+	var w io.Writer = f
+	if checksum != nil {
+		w = io.MultiWriter(f, checksum.hash)
+	}
+	// one byte more than left to detect body longer than upload
+	n, copyErr := io.Copy(w, io.LimitReader(r, info.Length-offset+1))
+	if copyErr == nil && offset+n > info.Length {
+		copyErr = ErrTooLarge
+	}
+	if copyErr == nil && checksum != nil && !checksum.valid() {
+		copyErr = ErrChecksum
+	}
+	if errors.Is(copyErr, ErrTooLarge) || (copyErr != nil && checksum != nil) {
+		// chunk is rejected as a whole
+		if err := f.Truncate(offset); err != nil {
+			log.Error(op, "truncate rejected chunk", err)
+		}
+		return info, format.Error(op, copyErr)
+	}
+
+	info.Offset = offset + n
+	if info.Completed() {
+		if info.Checksum, err = fileChecksum(f); err != nil {
+			return info, format.Error(op, err)
+		}
+	}
+	if err := s.saveInfo(info); err != nil {
+		return info, format.Error(op, err)
+	}
+	if copyErr != nil {
+		return info, format.Error(op, copyErr)
+	}
+
+	return info, nil
+}
+
+func fileChecksum(f *os.File) (string, error) {
+	h := sha256.New()
+	// f is opened write only, read through new descriptor
+	r, err := os.Open(f.Name())
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Open return data of completed upload. Caller must close file
+func (s *Store) Open(owner, uploadId string) (*os.File, *Info, error) {
+	const op = "upload.Store.Open"
+
+	info, err := s.Get(owner, uploadId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !info.Completed() {
+		return nil, info, format.Error(op, ErrIncomplete)
+	}
+	f, err := os.Open(s.dataPath(uploadId))
+	if err != nil {
+		return nil, info, format.Error(op, err)
+	}
+	return f, info, nil
+}
+
+// Delete upload of owner with its data
+func (s *Store) Delete(owner, uploadId string) error {
+	const op = "upload.Store.Delete"
+
+	if _, err := s.Get(owner, uploadId); err != nil {
+		return err
+	}
+	if !s.lock(uploadId) {
+		return format.Error(op, ErrLocked)
+	}
+	defer s.unlock(uploadId)
+
+	if err := s.remove(uploadId); err != nil {
+		return format.Error(op, err)
+	}
+	return nil
+}
+
+func (s *Store) remove(uploadId string) error {
+	// info goes first, upload without info is not visible
+	if err := os.Remove(s.infoPath(uploadId)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.Remove(s.dataPath(uploadId)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Expire remove uploads expired before now and return their count
+func (s *Store) Expire(now time.Time) (int, error) {
+	const op = "upload.Store.Expire"
+
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return 0, format.Error(op, err)
+	}
+	n := 0
+	for _, e := range entries {
+		uploadId, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok {
+			continue
+		}
+		info, err := s.info(uploadId)
+		if err != nil {
+			log.Error(op, "read info "+uploadId, err)
+			continue
+		}
+		if now.Before(info.ExpiresAt) || !s.lock(uploadId) {
+			continue
+		}
+		err = s.remove(uploadId)
+		s.unlock(uploadId)
+		if err != nil {
+			log.Error(op, "remove "+uploadId, err)
+			continue
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Sweep remove expired uploads every interval until ctx is done
+func (s *Store) Sweep(ctx context.Context, every time.Duration) {
+	const op = "upload.Store.Sweep"
+
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			if n, err := s.Expire(now); err != nil {
+				log.Error(op, "", err)
+			} else if n > 0 {
+				log.Info(op, fmt.Sprintf("%d expired uploads removed", n))
+			}
+		}
+	}
+}
+
+// Checksum of one PATCH body, from Upload-Checksum header
+type Checksum struct {
+	hash     hash.Hash
+	expected []byte
+}
+
+func (c *Checksum) valid() bool {
+	return string(c.hash.Sum(nil)) == string(c.expected)
+}
@@ -0,0 +1,110 @@
+package upload
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"sort"
+	"strings"
+)
+
+// Protocol headers and values of tus 1.0.0
+const (
+	Version            = "1.0.0"
+	Extensions         = "creation,creation-with-upload,termination,checksum,expiration"
+	ChecksumAlgorithms = "sha1,sha256,md5"
+	ContentType        = "application/offset+octet-stream"
+
+	HeaderResumable = "Tus-Resumable"
+	HeaderVersion   = "Tus-Version"
+	HeaderExtension = "Tus-Extension"
+	HeaderMaxSize   = "Tus-Max-Size"
+	HeaderAlgorithm = "Tus-Checksum-Algorithm"
+	HeaderLength    = "Upload-Length"
+	HeaderOffset    = "Upload-Offset"
+	HeaderMetadata  = "Upload-Metadata"
+	HeaderChecksum  = "Upload-Checksum"
+	HeaderExpires   = "Upload-Expires"
+)
+
+// StatusChecksumMismatch is tus response status for chunk with wrong checksum
+const StatusChecksumMismatch = 460
+
+var (
+	ErrBadMetadata = errors.New("bad Upload-Metadata")
+	ErrBadChecksum = errors.New("bad Upload-Checksum")
+)
+
+var algorithms = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"md5":    md5.New,
+}
+
+// ParseMetadata decode Upload-Metadata: comma separated pairs of key and base64 value,
+// value may be omitted
+func ParseMetadata(header string) (map[string]string, error) {
+	meta := map[string]string{}
+	if strings.TrimSpace(header) == "" {
+		return meta, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, fmt.Errorf("%w: empty key", ErrBadMetadata)
+		}
+		v, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("%w: value of %q: %w", ErrBadMetadata, key, err)
+		}
+		meta[key] = string(v)
+	}
+	return meta, nil
+}
+
+// EncodeMetadata is reverse of ParseMetadata with keys in stable order
+func EncodeMetadata(meta map[string]string) string {
+	keys := make([]string, 0, len(meta))
+	for k := range meta {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k
+		if meta[k] != "" {
+			pairs[i] += " " + base64.StdEncoding.EncodeToString([]byte(meta[k]))
+		}
+	}
+	return strings.Join(pairs, ",")
+}
+
+// ParseChecksum decode Upload-Checksum: algorithm name and base64 digest of request body.
+// Empty header return nil checksum
+func ParseChecksum(header string) (*Checksum, error) {
+	if header == "" {
+		return nil, nil
+	}
+	name, digest, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrBadChecksum, header)
+	}
+	newHash, ok := algorithms[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrBadChecksum, name)
+	}
+	expected, err := base64.StdEncoding.DecodeString(digest)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBadChecksum, err)
+	}
+	h := newHash()
+	if len(expected) != h.Size() {
+		return nil, fmt.Errorf("%w: digest of %s must be %d bytes", ErrBadChecksum, name, h.Size())
+	}
+	return &Checksum{hash: h, expected: expected}, nil
+}
@@ -0,0 +1,209 @@
+package upload
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newStore(t *testing.T) *Store {
+	s, err := NewStore(t.TempDir(), 100, time.Hour)
+	require.NoError(t, err)
+	return s
+}
+
+func TestStoreResume(t *testing.T) {
+	t.Parallel()
+
+	s := newStore(t)
+	data := []byte("лекция по термодинамике")
+
+	info, err := s.Create("owner", int64(len(data)), map[string]string{"filename": "a.mp3"})
+	require.NoError(t, err)
+	assert.Equal(t, "a.mp3", info.Filename())
+	assert.False(t, info.Completed())
+
+	info, err = s.Write("owner", info.Id, 0, bytes.NewReader(data[:10]), nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), info.Offset)
+
+	_, err = s.Write("owner", info.Id, 0, bytes.NewReader(data[:10]), nil)
+	assert.ErrorIs(t, err, ErrOffset)
+
+	_, _, err = s.Open("owner", info.Id)
+	assert.ErrorIs(t, err, ErrIncomplete)
+
+	info, err = s.Write("owner", info.Id, 10, bytes.NewReader(data[10:]), nil)
+	require.NoError(t, err)
+	assert.True(t, info.Completed())
+	sum := sha256.Sum256(data)
+	assert.Equal(t, hex.EncodeToString(sum[:]), info.Checksum)
+
+	f, got, err := s.Open("owner", info.Id)
+	require.NoError(t, err)
+	defer f.Close()
+	b, err := io.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, data, b)
+	assert.Equal(t, info.Checksum, got.Checksum)
+}
+
+// brokenReader return some bytes and then error, like dropped connection
+type brokenReader struct {
+	data []byte
+}
+
+func (r *brokenReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, errors.New("connection reset")
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestStoreBrokenConnection(t *testing.T) {
+	t.Parallel()
+
+	s := newStore(t)
+	info, err := s.Create("owner", 10, nil)
+	require.NoError(t, err)
+
+	info, err = s.Write("owner", info.Id, 0, &brokenReader{data: []byte("0123")}, nil)
+	assert.Error(t, err)
+	assert.Equal(t, int64(4), info.Offset, "received bytes are kept")
+
+	info, err = s.Write("owner", info.Id, 4, bytes.NewReader([]byte("456789")), nil)
+	require.NoError(t, err)
+	assert.True(t, info.Completed())
+}
+
+func TestStoreChecksum(t *testing.T) {
+	t.Parallel()
+
+	s := newStore(t)
+	info, err := s.Create("owner", 6, nil)
+	require.NoError(t, err)
+
+	good := sha1.Sum([]byte("abc"))
+	c, err := ParseChecksum("sha1 " + base64.StdEncoding.EncodeToString(good[:]))
+	require.NoError(t, err)
+	info, err = s.Write("owner", info.Id, 0, bytes.NewReader([]byte("abc")), c)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), info.Offset)
+
+	c, err = ParseChecksum("sha1 " + base64.StdEncoding.EncodeToString(good[:]))
+	require.NoError(t, err)
+	info, err = s.Write("owner", info.Id, 3, bytes.NewReader([]byte("xyz")), c)
+	assert.ErrorIs(t, err, ErrChecksum)
+	assert.Equal(t, int64(3), info.Offset, "chunk discarded")
+
+	c, err = ParseChecksum("sha1 " + base64.StdEncoding.EncodeToString(good[:]))
+	require.NoError(t, err)
+	_, err = s.Write("owner", info.Id, 3, &brokenReader{data: []byte("a")}, c)
+	assert.Error(t, err)
+
+	info, err = s.Get("owner", info.Id)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), info.Offset, "partial chunk with checksum discarded")
+
+	for _, h := range []string{"sha1", "crc32 AAAA", "sha1 !!", "sha256 " + base64.StdEncoding.EncodeToString(good[:])} {
+		_, err := ParseChecksum(h)
+		assert.ErrorIs(t, err, ErrBadChecksum, h)
+	}
+	c, err = ParseChecksum("")
+	assert.NoError(t, err)
+	assert.Nil(t, c)
+}
+
+func TestStoreLimits(t *testing.T) {
+	t.Parallel()
+
+	s := newStore(t)
+	_, err := s.Create("owner", 101, nil)
+	assert.ErrorIs(t, err, ErrTooLarge)
+
+	info, err := s.Create("owner", 3, nil)
+	require.NoError(t, err)
+	info, err = s.Write("owner", info.Id, 0, bytes.NewReader([]byte("abcd")), nil)
+	assert.ErrorIs(t, err, ErrTooLarge)
+	assert.Equal(t, int64(0), info.Offset)
+
+	_, err = s.Get("other", info.Id)
+	assert.ErrorIs(t, err, ErrNotFound, "other owner")
+	_, err = s.Get("owner", "../../etc/passwd")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	empty, err := s.Create("owner", 0, nil)
+	require.NoError(t, err)
+	assert.True(t, empty.Completed())
+	assert.NotEmpty(t, empty.Checksum)
+}
+
+func TestStoreLocked(t *testing.T) {
+	t.Parallel()
+
+	s := newStore(t)
+	info, err := s.Create("owner", 3, nil)
+	require.NoError(t, err)
+
+	require.True(t, s.lock(info.Id))
+	_, err = s.Write("owner", info.Id, 0, bytes.NewReader([]byte("abc")), nil)
+	assert.ErrorIs(t, err, ErrLocked)
+	assert.ErrorIs(t, s.Delete("owner", info.Id), ErrLocked)
+	s.unlock(info.Id)
+
+	assert.NoError(t, s.Delete("owner", info.Id))
+	_, err = s.Get("owner", info.Id)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestStoreExpire(t *testing.T) {
+	t.Parallel()
+
+	s := newStore(t)
+	old, err := s.Create("owner", 3, nil)
+	require.NoError(t, err)
+	s.TTL = 3 * time.Hour
+	fresh, err := s.Create("owner", 3, nil)
+	require.NoError(t, err)
+
+	n, err := s.Expire(time.Now().Add(2 * time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	_, err = s.Get("owner", old.Id)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = s.Get("owner", fresh.Id)
+	assert.NoError(t, err)
+}
+
+func TestMetadata(t *testing.T) {
+	t.Parallel()
+
+	meta, err := ParseMetadata("filename bGVjdHVyZS5tcDM=, is_confidential,filetype YXVkaW8vbXBlZw==")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"filename": "lecture.mp3", "is_confidential": "", "filetype": "audio/mpeg"}, meta)
+
+	back, err := ParseMetadata(EncodeMetadata(meta))
+	require.NoError(t, err)
+	assert.Equal(t, meta, back)
+
+	_, err = ParseMetadata("filename !!!")
+	assert.ErrorIs(t, err, ErrBadMetadata)
+	_, err = ParseMetadata(",")
+	assert.ErrorIs(t, err, ErrBadMetadata)
+
+	meta, err = ParseMetadata("")
+	assert.NoError(t, err)
+	assert.Empty(t, meta)
+}
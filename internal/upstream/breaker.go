@@ -0,0 +1,97 @@
+package upstream
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	StateClosed   = "closed"
+	StateOpen     = "open"
+	StateHalfOpen = "half_open"
+)
+
+// breaker open after failures in a row and reject requests for cooldown. Then one probe request
+// is let through: its success close breaker, failure open it again
+type breaker struct {
+	mu        sync.Mutex
+	failures  int // threshold
+	cooldown  time.Duration
+	state     string
+	inRow     int
+	openUntil time.Time
+	probing   bool
+}
+
+func newBreaker(failures int, cooldown time.Duration) *breaker {
+	return &breaker{failures: max(failures, 1), cooldown: cooldown, state: StateClosed}
+}
+
+// allow report if request may go to upstream now, or how long to wait if it may not
+func (b *breaker) allow(now time.Time) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if now.Before(b.openUntil) {
+			return b.openUntil.Sub(now), false
+		}
+		b.state = StateHalfOpen
+		b.probing = true
+		return 0, true
+	case StateHalfOpen:
+		if b.probing {
+			// wait for result of probe
+			return time.Second, false
+		}
+		b.probing = true
+		return 0, true
+	}
+	return 0, true
+}
+
+// record result of request let through by allow
+func (b *breaker) record(now time.Time, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateHalfOpen {
+		b.probing = false
+		if failed {
+			b.open(now)
+		} else {
+			b.state, b.inRow = StateClosed, 0
+		}
+		return
+	}
+
+	if !failed {
+		b.inRow = 0
+		return
+	}
+	b.inRow++
+	if b.state == StateClosed && b.inRow >= b.failures {
+		b.open(now)
+	}
+}
+
+// cancel return probe slot of request which ended without result, e.g. canceled by client
+func (b *breaker) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == StateHalfOpen {
+		b.probing = false
+	}
+}
+
+func (b *breaker) open(now time.Time) {
+	b.state = StateOpen
+	b.openUntil = now.Add(b.cooldown)
+}
+
+func (b *breaker) snapshot() (state string, failures int, openUntil time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state, b.inRow, b.openUntil
+}
@@ -0,0 +1,315 @@
+// Package upstream is HTTP client for services flicker depends on (n8n, transcriber, embeddings).
+// Failed requests are retried with jittered exponential backoff, every upstream has circuit
+// breaker which fails fast while upstream is down, and number of concurrent requests is limited
+package upstream
+
+import (
+	"context"
+	"errors"
+	"flicker/internal/config"
+	"flicker/internal/metrics"
+	"flicker/internal/reqid"
+	"flicker/internal/views"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptrace"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// names of upstreams, also keys of upstream_concurrency in config
+const (
+	N8n         = "n8n"
+	Transcriber = "transcriber"
+	Embeddings  = "embeddings"
+)
+
+var ErrOpen = errors.New("circuit breaker is open")
+
+// OpenError is returned while breaker of upstream is open
+type OpenError struct {
+	Upstream   string
+	RetryAfter time.Duration
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("%s: %s, retry after %s", e.Upstream, ErrOpen, e.RetryAfter.Round(time.Second))
+}
+
+func (e *OpenError) Unwrap() error {
+	return ErrOpen
+}
+
+type Options struct {
+	// Retries of failed request, request body must be replayable (http.Request.GetBody)
+	Retries    int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// RetryPost allow to repeat failed POST requests, for upstreams where it has no side effects.
+	// Without it POST is repeated only when upstream surely didn't run it: connection failed
+	// before request was sent, or upstream answered 429 or 503 with Retry-After
+	RetryPost bool
+
+	// Failures in a row open breaker for Cooldown
+	Failures int
+	Cooldown time.Duration
+
+	// MaxConcurrent requests, 0 is no limit
+	MaxConcurrent int
+}
+
+// FromConfig return options of upstream name from config
+func FromConfig(cfg *config.Config, name string) Options {
+	return Options{
+		Retries:       cfg.UpstreamRetries,
+		Backoff:       cfg.UpstreamBackoff,
+		MaxBackoff:    cfg.UpstreamMaxBackoff,
+		RetryPost:     cfg.UpstreamRetryPost[name],
+		Failures:      cfg.BreakerFailures,
+		Cooldown:      cfg.BreakerCooldown,
+		MaxConcurrent: cfg.UpstreamConcurrency[name],
+	}
+}
+
+type Client struct {
+	name    string
+	opts    Options
+	http    *http.Client
+	breaker *breaker
+	slots   chan struct{}
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]*Client{}
+)
+
+// New return client of upstream and register it for States. Client with same name replaces previous one
+func New(name string, opts Options) *Client {
+	c := &Client{
+		name:    name,
+		opts:    opts,
+		http:    &http.Client{Transport: transport},
+		breaker: newBreaker(opts.Failures, opts.Cooldown),
+	}
+	if opts.MaxConcurrent > 0 {
+		c.slots = make(chan struct{}, opts.MaxConcurrent)
+	}
+
+	registryMu.Lock()
+	registry[name] = c
+	registryMu.Unlock()
+	return c
+}
+
+// transport is shared by upstreams: they are few hosts with many requests each
+var transport = func() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.MaxIdleConnsPerHost = 32
+	t.IdleConnTimeout = 90 * time.Second
+	return t
+}()
+
+// Do send request like http.Client.Do. Connection errors, 5xx and 429 answers are retried while
+// attempts last and breaker is closed. Answer of last attempt is returned as is. Id of request
+// carried by context is forwarded in reqid.Header
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if id := reqid.From(ctx); id != "" && req.Header.Get(reqid.Header) == "" {
+		req.Header.Set(reqid.Header, id)
+	}
+	if err := c.acquire(ctx); err != nil {
+		closeBody(req)
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		if wait, ok := c.breaker.allow(time.Now()); !ok {
+			metrics.UpstreamErrors.WithLabelValues(c.name, metrics.ErrorOpen).Inc()
+			c.release()
+			closeBody(req)
+			return nil, &OpenError{Upstream: c.name, RetryAfter: wait}
+		}
+		if attempt > 0 {
+			body, err := req.GetBody()
+			if err != nil {
+				c.breaker.cancel()
+				c.release()
+				closeBody(req)
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		start := time.Now()
+		// trace hooks may run in goroutine of transport
+		var sent atomic.Bool
+		trace := &httptrace.ClientTrace{WroteRequest: func(i httptrace.WroteRequestInfo) {
+			if i.Err == nil {
+				sent.Store(true)
+			}
+		}}
+		resp, err := c.http.Do(req.WithContext(httptrace.WithClientTrace(ctx, trace)))
+		if ctx.Err() != nil {
+			// client is gone, this tells nothing about upstream
+			c.breaker.cancel()
+		} else {
+			c.breaker.record(time.Now(), failed(resp, err))
+			c.observe(start, resp, err)
+		}
+
+		if !failed(resp, err) || attempt >= c.opts.Retries || !c.retryable(req, resp, err, sent.Load()) || ctx.Err() != nil {
+			if err != nil {
+				c.release()
+				return nil, err
+			}
+			resp.Body = &releaseBody{ReadCloser: resp.Body, release: c.release}
+			return resp, nil
+		}
+
+		delay := c.backoff(attempt, resp)
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			c.release()
+			closeBody(req)
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// closeBody close body of request which won't be sent, as http.Client.Do does, so writer of
+// piped body doesn't wait forever. Body sent by transport is already closed, closing it again is
+// harmless
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
+
+// observe record attempt in metrics. Duration is time until headers of answer, streamed body
+// isn't waited for
+func (c *Client) observe(start time.Time, resp *http.Response, err error) {
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	metrics.UpstreamDuration.WithLabelValues(c.name, status).Observe(time.Since(start).Seconds())
+
+	switch {
+	case err != nil:
+		metrics.UpstreamErrors.WithLabelValues(c.name, metrics.ErrorConnection).Inc()
+	case failed(resp, nil):
+		metrics.UpstreamErrors.WithLabelValues(c.name, metrics.ErrorStatus).Inc()
+	}
+}
+
+// failed report if attempt failed because of upstream
+func failed(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+}
+
+// retryable report if failed attempt may be repeated. sent is whether request was written
+// to upstream
+func (c *Client) retryable(req *http.Request, resp *http.Response, err error, sent bool) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	case http.MethodPost:
+		if c.opts.RetryPost {
+			return true
+		}
+		if err != nil {
+			return !sent
+		}
+		return (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) &&
+			resp.Header.Get("Retry-After") != ""
+	}
+	return false
+}
+
+// backoff return random delay up to exponentially growing limit (full jitter),
+// or Retry-After of answer if upstream asked for it
+func (c *Client) backoff(attempt int, resp *http.Response) time.Duration {
+	limit := c.opts.MaxBackoff
+	if resp != nil {
+		if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s >= 0 {
+			return min(time.Duration(s)*time.Second, limit)
+		}
+	}
+	d := min(c.opts.Backoff<<attempt, limit)
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d) + 1
+}
+
+func (c *Client) acquire(ctx context.Context) error {
+	if c.slots == nil {
+		return nil
+	}
+	select {
+	case c.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Client) release() {
+	if c.slots != nil {
+		<-c.slots
+	}
+}
+
+// releaseBody free slot of concurrency limit when answer is read and closed
+type releaseBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// State of upstream and its breaker for health check
+func (c *Client) State() views.UpstreamState {
+	s := views.UpstreamState{Name: c.name, InFlight: len(c.slots), Limit: c.opts.MaxConcurrent}
+	var until time.Time
+	s.State, s.Failures, until = c.breaker.snapshot()
+	if s.State == StateOpen {
+		s.OpenUntil = &until
+	}
+	return s
+}
+
+// States return state of every upstream ordered by name
+func States() []views.UpstreamState {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	res := make([]views.UpstreamState, 0, len(registry))
+	for _, c := range registry {
+		res = append(res, c.State())
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
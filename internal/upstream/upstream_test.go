@@ -0,0 +1,391 @@
+package upstream
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flicker/internal/metrics"
+	"flicker/internal/reqid"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// server answer with statuses in turn, the last one is repeated
+func server(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(statuses[min(n, len(statuses))-1])
+		w.Write([]byte("body"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func post(t *testing.T, c *Client, url string) (*http.Response, error) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader([]byte(`{"a":1}`)))
+	require.NoError(t, err)
+	return c.Do(req)
+}
+
+func quick() Options {
+	return Options{
+		Retries:    2,
+		Backoff:    time.Millisecond,
+		MaxBackoff: 5 * time.Millisecond,
+		RetryPost:  true,
+		Failures:   100,
+		Cooldown:   time.Minute,
+	}
+}
+
+func TestDoRetry(t *testing.T) {
+	t.Parallel()
+
+	srv, calls := server(t, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK)
+	c := New(t.Name(), quick())
+
+	resp, err := post(t, c, srv.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.EqualValues(t, 3, calls.Load())
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "body", string(body))
+}
+
+func TestDoRequestId(t *testing.T) {
+	t.Parallel()
+
+	var ids []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids = append(ids, r.Header.Get(reqid.Header))
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+	c := New(t.Name(), quick())
+
+	for _, ctx := range []context.Context{reqid.With(context.Background(), "req-1"), context.Background()} {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+		require.NoError(t, err)
+		resp, err := c.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+	assert.Equal(t, []string{"req-1", ""}, ids)
+}
+
+func TestDoRetriesExhausted(t *testing.T) {
+	t.Parallel()
+
+	srv, calls := server(t, http.StatusBadGateway)
+	c := New(t.Name(), quick())
+
+	// answer of last attempt is returned as is
+	resp, err := post(t, c, srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	assert.EqualValues(t, 3, calls.Load())
+}
+
+func TestDoNoRetry(t *testing.T) {
+	t.Parallel()
+
+	t.Run("client error", func(t *testing.T) {
+		t.Parallel()
+		srv, calls := server(t, http.StatusBadRequest)
+		resp, err := post(t, New(t.Name(), quick()), srv.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.EqualValues(t, 1, calls.Load())
+	})
+
+	t.Run("post not allowed", func(t *testing.T) {
+		t.Parallel()
+		srv, calls := server(t, http.StatusBadGateway)
+		opts := quick()
+		opts.RetryPost = false
+		resp, err := post(t, New(t.Name(), opts), srv.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.EqualValues(t, 1, calls.Load())
+	})
+
+	t.Run("streamed body", func(t *testing.T) {
+		t.Parallel()
+		srv, calls := server(t, http.StatusBadGateway)
+		pr, pw := io.Pipe()
+		go func() {
+			pw.Write([]byte("audio"))
+			pw.Close()
+		}()
+		req, err := http.NewRequest(http.MethodPost, srv.URL, pr)
+		require.NoError(t, err)
+
+		resp, err := New(t.Name(), quick()).Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.EqualValues(t, 1, calls.Load())
+	})
+}
+
+func TestDoRetryUnsentPost(t *testing.T) {
+	t.Parallel()
+
+	opts := quick()
+	opts.RetryPost = false
+
+	t.Run("retry after", func(t *testing.T) {
+		t.Parallel()
+		var calls atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		t.Cleanup(srv.Close)
+
+		resp, err := post(t, New(t.Name(), opts), srv.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.EqualValues(t, 2, calls.Load())
+	})
+
+	t.Run("connection refused", func(t *testing.T) {
+		t.Parallel()
+		srv := httptest.NewServer(http.NotFoundHandler())
+		srv.Close()
+		refused := metrics.UpstreamErrors.WithLabelValues(t.Name(), metrics.ErrorConnection)
+		before := testutil.ToFloat64(refused)
+
+		_, err := post(t, New(t.Name(), opts), srv.URL)
+		require.Error(t, err)
+		assert.EqualValues(t, opts.Retries+1, testutil.ToFloat64(refused)-before)
+	})
+
+	t.Run("connection lost after send", func(t *testing.T) {
+		t.Parallel()
+		var calls atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			io.Copy(io.Discard, r.Body)
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+		}))
+		t.Cleanup(srv.Close)
+
+		_, err := post(t, New(t.Name(), opts), srv.URL)
+		require.Error(t, err)
+		assert.EqualValues(t, 1, calls.Load())
+	})
+}
+
+func TestBreaker(t *testing.T) {
+	t.Parallel()
+
+	srv, calls := server(t, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusOK)
+	opts := quick()
+	opts.Retries = 0
+	opts.Failures = 3
+	opts.Cooldown = 50 * time.Millisecond
+	c := New(t.Name(), opts)
+
+	for range 3 {
+		resp, err := post(t, c, srv.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+	assert.Equal(t, StateOpen, c.State().State)
+	require.NotNil(t, c.State().OpenUntil)
+
+	// open breaker fails fast without calling upstream
+	_, err := post(t, c, srv.URL)
+	require.ErrorIs(t, err, ErrOpen)
+	var open *OpenError
+	require.True(t, errors.As(err, &open))
+	assert.Equal(t, t.Name(), open.Upstream)
+	assert.Greater(t, open.RetryAfter, time.Duration(0))
+	assert.EqualValues(t, 3, calls.Load())
+	assert.Equal(t, 3.0, testutil.ToFloat64(metrics.UpstreamErrors.WithLabelValues(t.Name(), metrics.ErrorStatus)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.UpstreamErrors.WithLabelValues(t.Name(), metrics.ErrorOpen)))
+
+	// after cooldown one probe is let through, its success closes breaker
+	time.Sleep(60 * time.Millisecond)
+	resp, err := post(t, c, srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, StateClosed, c.State().State)
+	assert.Equal(t, 0, c.State().Failures)
+}
+
+func TestBreakerClosesBody(t *testing.T) {
+	t.Parallel()
+
+	srv, _ := server(t, http.StatusBadGateway)
+	opts := quick()
+	opts.Retries = 0
+	opts.Failures = 1
+	c := New(t.Name(), opts)
+	_, err := post(t, c, srv.URL)
+	require.NoError(t, err)
+	require.Equal(t, StateOpen, c.State().State)
+
+	// writers of piped bodies, like stream.Multipart, end when request isn't sent
+	var writers sync.WaitGroup
+	for range 50 {
+		pr, pw := io.Pipe()
+		writers.Add(1)
+		go func() {
+			defer writers.Done()
+			pw.Write([]byte("audio"))
+			pw.Close()
+		}()
+		req, err := http.NewRequest(http.MethodPost, srv.URL, pr)
+		require.NoError(t, err)
+		_, err = c.Do(req)
+		require.ErrorIs(t, err, ErrOpen)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		writers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("writers of request bodies are left blocked")
+	}
+}
+
+func TestBreakerProbeFails(t *testing.T) {
+	t.Parallel()
+
+	srv, calls := server(t, http.StatusBadGateway)
+	opts := quick()
+	opts.Failures = 2
+	opts.Cooldown = 30 * time.Millisecond
+	c := New(t.Name(), opts)
+
+	// retries of one request count as failures too and stop when breaker opens
+	_, err := post(t, c, srv.URL)
+	require.ErrorIs(t, err, ErrOpen)
+	assert.EqualValues(t, 2, calls.Load())
+
+	time.Sleep(40 * time.Millisecond)
+	_, err = post(t, c, srv.URL)
+	require.ErrorIs(t, err, ErrOpen)
+	assert.EqualValues(t, 3, calls.Load(), "only probe is sent")
+	assert.Equal(t, StateOpen, c.State().State)
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	t.Parallel()
+
+	b := newBreaker(1, time.Second)
+	now := time.Now()
+	b.record(now, true)
+
+	_, ok := b.allow(now)
+	assert.False(t, ok)
+
+	later := now.Add(2 * time.Second)
+	_, ok = b.allow(later)
+	assert.True(t, ok, "probe")
+	_, ok = b.allow(later)
+	assert.False(t, ok, "only one probe at time")
+
+	// canceled probe gives slot to next request
+	b.cancel()
+	_, ok = b.allow(later)
+	assert.True(t, ok)
+	b.record(later, false)
+
+	state, _, _ := b.snapshot()
+	assert.Equal(t, StateClosed, state)
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	t.Parallel()
+
+	srv, _ := server(t, http.StatusOK)
+	opts := quick()
+	opts.MaxConcurrent = 1
+	c := New(t.Name(), opts)
+
+	first, err := post(t, c, srv.URL)
+	require.NoError(t, err)
+	assert.Equal(t, 1, c.State().InFlight)
+
+	// slot is held until body of first answer is closed
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+	_, err = c.Do(req)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	first.Body.Close()
+	first.Body.Close()
+	assert.Equal(t, 0, c.State().InFlight)
+
+	second, err := post(t, c, srv.URL)
+	require.NoError(t, err)
+	second.Body.Close()
+	assert.Equal(t, 0, c.State().InFlight)
+}
+
+func TestBackoff(t *testing.T) {
+	t.Parallel()
+
+	c := New(t.Name(), Options{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second})
+	for attempt := range 6 {
+		limit := min(100*time.Millisecond<<attempt, time.Second)
+		for range 50 {
+			d := c.backoff(attempt, nil)
+			assert.Greater(t, d, time.Duration(0))
+			assert.LessOrEqual(t, d, limit)
+		}
+	}
+
+	resp := &http.Response{Header: http.Header{"Retry-After": {"0"}}}
+	assert.Equal(t, time.Duration(0), c.backoff(3, resp))
+	resp.Header.Set("Retry-After", "120")
+	assert.Equal(t, time.Second, c.backoff(0, resp), "capped by max backoff")
+}
+
+func TestStates(t *testing.T) {
+	t.Parallel()
+
+	New(t.Name(), Options{MaxConcurrent: 3})
+	var found bool
+	for _, s := range States() {
+		if s.Name == t.Name() {
+			found = true
+			assert.Equal(t, StateClosed, s.State)
+			assert.Equal(t, 3, s.Limit)
+		}
+	}
+	assert.True(t, found)
+}
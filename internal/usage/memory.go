@@ -0,0 +1,115 @@
+package usage
+
+import (
+	"context"
+	"flicker/internal/views"
+	"sort"
+	"sync"
+	"time"
+)
+
+// keep is how long memory ledger keeps records, longer than any period
+const keep = 62 * 24 * time.Hour
+
+// Memory is usage ledger of one instance, for development and tests
+type Memory struct {
+	mu      sync.Mutex
+	records []views.UsageRecord
+	lastId  int64
+	plans   map[string]string
+}
+
+func NewMemory() *Memory {
+	return &Memory{plans: map[string]string{}}
+}
+
+func (m *Memory) Record(_ context.Context, r *views.UsageRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// records are appended in time order, so old ones are in front
+	old := 0
+	for old < len(m.records) && r.CreatedAt.Sub(m.records[old].CreatedAt) > keep {
+		old++
+	}
+	m.lastId++
+	r.Id = m.lastId
+	m.records = append(m.records[old:], *r)
+	return nil
+}
+
+func (m *Memory) Update(_ context.Context, r *views.UsageRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if i := m.find(r.Id); i >= 0 {
+		created := m.records[i].CreatedAt
+		m.records[i] = *r
+		m.records[i].CreatedAt = created
+	}
+	return nil
+}
+
+func (m *Memory) Delete(_ context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if i := m.find(id); i >= 0 {
+		m.records = append(m.records[:i], m.records[i+1:]...)
+	}
+	return nil
+}
+
+// find return index of record, recent ones are looked for first
+func (m *Memory) find(id int64) int {
+	for i := len(m.records) - 1; i >= 0; i-- {
+		if m.records[i].Id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *Memory) Totals(_ context.Context, user string, since time.Time) ([]views.UsageTotals, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	byEndpoint := map[string]*views.UsageTotals{}
+	for _, r := range m.records {
+		if r.UserId != user || r.CreatedAt.Before(since) || !Counted(r.Outcome) {
+			continue
+		}
+		t, ok := byEndpoint[r.Endpoint]
+		if !ok {
+			t = &views.UsageTotals{Endpoint: r.Endpoint}
+			byEndpoint[r.Endpoint] = t
+		}
+		t.Requests += r.Units
+		if r.Outcome == views.UsageError {
+			t.Failed += r.Units
+		}
+		t.InputBytes += r.InputBytes
+		t.AudioSeconds += r.AudioSeconds
+		t.Tokens += r.Tokens
+	}
+
+	res := make([]views.UsageTotals, 0, len(byEndpoint))
+	for _, t := range byEndpoint {
+		res = append(res, *t)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Endpoint < res[j].Endpoint })
+	return res, nil
+}
+
+func (m *Memory) Plan(_ context.Context, user string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.plans[user], nil
+}
+
+// SetPlan change plan of user, plans of postgres ledger are kept in users table
+func (m *Memory) SetPlan(user, plan string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.plans[user] = plan
+}
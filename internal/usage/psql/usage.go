@@ -0,0 +1,110 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flicker/internal/views"
+	"time"
+
+	"github.com/autumnterror/breezynotes/pkg/utils/format"
+)
+
+func (d *Driver) Record(ctx context.Context, r *views.UsageRecord) error {
+	const op = "psql.usage.Record"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	query := `
+		INSERT INTO ai_usage (user_id, endpoint, units, input_bytes, audio_seconds, tokens, latency_ms, status, outcome, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id
+	`
+	if err := d.driver.QueryRowContext(ctx, query, r.UserId, r.Endpoint, r.Units, r.InputBytes, r.AudioSeconds, r.Tokens,
+		r.LatencyMs, r.Status, r.Outcome, r.CreatedAt).Scan(&r.Id); err != nil {
+		return format.Error(op, err)
+	}
+	return nil
+}
+
+func (d *Driver) Update(ctx context.Context, r *views.UsageRecord) error {
+	const op = "psql.usage.Update"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	query := `
+		UPDATE ai_usage
+		SET units = $2, input_bytes = $3, audio_seconds = $4, tokens = $5, latency_ms = $6, status = $7, outcome = $8
+		WHERE id = $1
+	`
+	if _, err := d.driver.ExecContext(ctx, query, r.Id, r.Units, r.InputBytes, r.AudioSeconds, r.Tokens,
+		r.LatencyMs, r.Status, r.Outcome); err != nil {
+		return format.Error(op, err)
+	}
+	return nil
+}
+
+func (d *Driver) Delete(ctx context.Context, id int64) error {
+	const op = "psql.usage.Delete"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	if _, err := d.driver.ExecContext(ctx, `DELETE FROM ai_usage WHERE id = $1`, id); err != nil {
+		return format.Error(op, err)
+	}
+	return nil
+}
+
+func (d *Driver) Totals(ctx context.Context, user string, since time.Time) ([]views.UsageTotals, error) {
+	const op = "psql.usage.Totals"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	query := `
+		SELECT endpoint, sum(units), coalesce(sum(units) FILTER (WHERE outcome = $3), 0),
+		       sum(input_bytes), sum(audio_seconds), sum(tokens)
+		FROM ai_usage
+		WHERE user_id = $1 AND created_at >= $2 AND outcome IN ($4, $3, $5)
+		GROUP BY endpoint
+		ORDER BY endpoint
+	`
+	rows, err := d.driver.QueryContext(ctx, query, user, since, views.UsageError, views.UsageOK, views.UsagePending)
+	if err != nil {
+		return nil, format.Error(op, err)
+	}
+	defer rows.Close()
+
+	res := []views.UsageTotals{}
+	for rows.Next() {
+		var t views.UsageTotals
+		if err := rows.Scan(&t.Endpoint, &t.Requests, &t.Failed, &t.InputBytes, &t.AudioSeconds, &t.Tokens); err != nil {
+			return nil, format.Error(op, err)
+		}
+		res = append(res, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, format.Error(op, err)
+	}
+	return res, nil
+}
+
+func (d *Driver) Plan(ctx context.Context, user string) (string, error) {
+	const op = "psql.usage.Plan"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	var plan sql.NullString
+	err := d.driver.QueryRowContext(ctx, `SELECT plan FROM users WHERE id = $1`, user).Scan(&plan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", format.Error(op, err)
+	}
+	return plan.String, nil
+}
@@ -0,0 +1,98 @@
+package psql
+
+import (
+	"context"
+	authpsql "flicker/internal/auth/psql"
+	"flicker/internal/config"
+	"flicker/internal/views"
+	"testing"
+	"time"
+
+	"github.com/autumnterror/breezynotes/pkg/utils/id"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsageOperations(t *testing.T) {
+	t.Parallel()
+
+	repo, cleanup := setupTestTx(t)
+	defer cleanup()
+
+	user := id.New()
+	now := time.Now().UTC()
+
+	t.Run("empty", func(t *testing.T) {
+		totals, err := repo.Totals(context.TODO(), user, now.Add(-time.Hour))
+		assert.NoError(t, err)
+		assert.Empty(t, totals)
+
+		plan, err := repo.Plan(context.TODO(), user)
+		assert.NoError(t, err)
+		assert.Equal(t, "", plan)
+	})
+
+	t.Run("record and totals", func(t *testing.T) {
+		for _, r := range []views.UsageRecord{
+			{Endpoint: "/api/ai/generatemd", InputBytes: 100, Tokens: 50, Status: 200, Outcome: views.UsageOK},
+			{Endpoint: "/api/ai/generatemd", InputBytes: 10, Status: 502, Outcome: views.UsageError},
+			{Endpoint: "/api/ai/generatemd", InputBytes: 100, Status: 200, Outcome: views.UsageCached},
+			{Endpoint: "/api/ai/transcribe", InputBytes: 5000, AudioSeconds: 61.5, Status: 200, Outcome: views.UsageOK},
+			{Endpoint: "/api/ai/transcribe", Status: 400, Outcome: views.UsageInvalid},
+		} {
+			r.UserId, r.Units, r.CreatedAt = user, 1, now
+			assert.NoError(t, repo.Record(context.TODO(), &r))
+		}
+		old := views.UsageRecord{UserId: user, Endpoint: "/api/ai/ask", Units: 1, Status: 200, Outcome: views.UsageOK, CreatedAt: now.Add(-48 * time.Hour)}
+		assert.NoError(t, repo.Record(context.TODO(), &old))
+
+		totals, err := repo.Totals(context.TODO(), user, now.Add(-time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, []views.UsageTotals{
+			{Endpoint: "/api/ai/generatemd", Requests: 2, Failed: 1, InputBytes: 110, Tokens: 50},
+			{Endpoint: "/api/ai/transcribe", Requests: 1, InputBytes: 5000, AudioSeconds: 61.5},
+		}, totals)
+
+		totals, err = repo.Totals(context.TODO(), user, now.Add(-72*time.Hour))
+		assert.NoError(t, err)
+		assert.Len(t, totals, 3)
+	})
+
+	t.Run("reserve, update and delete", func(t *testing.T) {
+		other := id.New()
+		pending := views.UsageRecord{UserId: other, Endpoint: "/api/ai/ask", Units: 1, Outcome: views.UsagePending, CreatedAt: now}
+		assert.NoError(t, repo.Record(context.TODO(), &pending))
+		assert.NotZero(t, pending.Id)
+		rejected := pending
+		assert.NoError(t, repo.Record(context.TODO(), &rejected))
+		assert.NotEqual(t, pending.Id, rejected.Id)
+
+		totals, err := repo.Totals(context.TODO(), other, now.Add(-time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, []views.UsageTotals{{Endpoint: "/api/ai/ask", Requests: 2}}, totals)
+
+		assert.NoError(t, repo.Delete(context.TODO(), rejected.Id))
+		pending.Units, pending.Tokens, pending.Status, pending.Outcome = 3, 30, 200, views.UsageOK
+		assert.NoError(t, repo.Update(context.TODO(), &pending))
+		totals, err = repo.Totals(context.TODO(), other, now.Add(-time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, []views.UsageTotals{{Endpoint: "/api/ai/ask", Requests: 3, Tokens: 30}}, totals)
+
+		pending.Status, pending.Outcome = 503, views.UsageUnavailable
+		assert.NoError(t, repo.Update(context.TODO(), &pending))
+		totals, err = repo.Totals(context.TODO(), other, now.Add(-time.Hour))
+		assert.NoError(t, err)
+		assert.Empty(t, totals)
+	})
+}
+
+func setupTestTx(t *testing.T) (*Driver, func()) {
+	pdb := authpsql.MustConnect(config.Test())
+
+	tx, err := pdb.Driver.Begin()
+	assert.NoError(t, err)
+
+	return NewDriver(tx), func() {
+		assert.NoError(t, tx.Rollback())
+		assert.NoError(t, pdb.Disconnect())
+	}
+}
@@ -0,0 +1,298 @@
+// Package usage keep ledger of AI calls and check daily and monthly quotas of user plan
+package usage
+
+import (
+	"context"
+	"errors"
+	"flicker/internal/config"
+	"flicker/internal/views"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/autumnterror/breezynotes/pkg/utils/format"
+)
+
+const (
+	StoreMemory   = "memory"
+	StorePostgres = "postgres"
+	StoreOff      = "off"
+)
+
+// PlanAnonymous is plan of callers without token, they are counted by IP
+const PlanAnonymous = "anonymous"
+
+const anonymousPrefix = "ip:"
+
+const (
+	PeriodDay   = "daily"
+	PeriodMonth = "monthly"
+)
+
+var ErrQuota = errors.New("quota exceeded")
+
+// QuotaError tell which quota is exceeded and when it is reset
+type QuotaError struct {
+	Period   string
+	Resource string
+	Limit    float64
+	ResetAt  time.Time
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%s %s quota of %v exceeded", e.Period, e.Resource, e.Limit)
+}
+
+func (e *QuotaError) Unwrap() error {
+	return ErrQuota
+}
+
+// Store is usage ledger
+type Store interface {
+	// Record add r to ledger and set its Id
+	Record(ctx context.Context, r *views.UsageRecord) error
+	// Update set result of request to record with Id of r
+	Update(ctx context.Context, r *views.UsageRecord) error
+	// Delete remove record, e.g. reservation of rejected request
+	Delete(ctx context.Context, id int64) error
+	// Totals return usage of user since time by endpoint. Only outcomes of Counted are counted
+	Totals(ctx context.Context, user string, since time.Time) ([]views.UsageTotals, error)
+	// Plan return plan of user, empty if it is not set
+	Plan(ctx context.Context, user string) (string, error)
+}
+
+// Anonymous return ledger user of caller without token
+func Anonymous(ip string) string {
+	return anonymousPrefix + ip
+}
+
+// Counted report if outcome is counted in quota: requests answered from cache, rejected as
+// invalid or not sent to unavailable upstream cost nothing. Pending requests are counted, so
+// concurrent requests see each other
+func Counted(outcome string) bool {
+	return outcome == views.UsageOK || outcome == views.UsageError || outcome == views.UsagePending
+}
+
+type Meter struct {
+	Store       Store
+	Plans       map[string]config.Plan
+	DefaultPlan string
+	now         func() time.Time
+}
+
+func New(store Store, cfg *config.Config) *Meter {
+	return &Meter{
+		Store:       store,
+		Plans:       cfg.UsagePlans,
+		DefaultPlan: cfg.UsageDefaultPlan,
+		now:         time.Now,
+	}
+}
+
+// Plan return plan of user, default one if user has none
+func (m *Meter) Plan(ctx context.Context, user string) (string, error) {
+	if strings.HasPrefix(user, anonymousPrefix) {
+		return PlanAnonymous, nil
+	}
+	plan, err := m.Store.Plan(ctx, user)
+	if err != nil {
+		return "", err
+	}
+	if plan == "" {
+		plan = m.DefaultPlan
+	}
+	return plan, nil
+}
+
+// Check return *QuotaError if user has used daily or monthly quota of his plan
+func (m *Meter) Check(ctx context.Context, user string) error {
+	return m.check(ctx, user, false)
+}
+
+// Reserve add pending record of request to ledger before it is served, or return *QuotaError
+// if user has used his quota. Pending record is counted in quota, so of concurrent requests
+// only ones which fit into quota pass. Request is completed with Finish
+func (m *Meter) Reserve(ctx context.Context, user, endpoint string) (*views.UsageRecord, error) {
+	const op = "usage.Meter.Reserve"
+
+	r := &views.UsageRecord{UserId: user, Endpoint: endpoint, Units: 1, Outcome: views.UsagePending, CreatedAt: m.now().UTC()}
+	if err := m.Store.Record(ctx, r); err != nil {
+		return nil, format.Error(op, err)
+	}
+	if err := m.check(ctx, user, true); err != nil {
+		// rejected request costs nothing
+		if derr := m.Store.Delete(context.WithoutCancel(ctx), r.Id); derr != nil {
+			err = errors.Join(err, format.Error(op, derr))
+		}
+		return nil, err
+	}
+	return r, nil
+}
+
+// Resize change number of requests reserved by r to units, e.g. to number of LLM calls made by
+// request, or return *QuotaError if they don't fit into quota, then reservation is kept as is.
+// Record without Id, made when ledger was down on Reserve, is only changed
+func (m *Meter) Resize(ctx context.Context, r *views.UsageRecord, units int64) error {
+	const op = "usage.Meter.Resize"
+
+	if r.Id == 0 {
+		r.Units = units
+		return nil
+	}
+	// request is being served, so record stays pending until Finish
+	p := *r
+	p.Units, p.Outcome = units, views.UsagePending
+	if err := m.Store.Update(ctx, &p); err != nil {
+		return format.Error(op, err)
+	}
+	if units > r.Units {
+		if err := m.check(ctx, r.UserId, true); err != nil {
+			p.Units = r.Units
+			if uerr := m.Store.Update(context.WithoutCancel(ctx), &p); uerr != nil {
+				err = errors.Join(err, format.Error(op, uerr))
+			}
+			return err
+		}
+	}
+	r.Units = units
+	return nil
+}
+
+// Finish write result of request to its reserved record. Record without Id, made when ledger
+// was down on Reserve, is added
+func (m *Meter) Finish(ctx context.Context, r *views.UsageRecord) error {
+	const op = "usage.Meter.Finish"
+
+	if r.Id == 0 {
+		return m.Record(ctx, r)
+	}
+	if err := m.Store.Update(ctx, r); err != nil {
+		return format.Error(op, err)
+	}
+	return nil
+}
+
+// check quotas of user. reserved is set when ledger already has record of request being checked:
+// then quota is exceeded only when usage is over limit, not at it
+func (m *Meter) check(ctx context.Context, user string, reserved bool) error {
+	const op = "usage.Meter.Check"
+
+	plan, err := m.Plan(ctx, user)
+	if err != nil {
+		return format.Error(op, err)
+	}
+	p := m.Plans[plan]
+	now := m.now().UTC()
+
+	for _, period := range []struct {
+		name  string
+		quota config.Quota
+		start time.Time
+		reset time.Time
+	}{
+		{PeriodDay, p.Daily, dayStart(now), dayStart(now).AddDate(0, 0, 1)},
+		{PeriodMonth, p.Monthly, monthStart(now), monthStart(now).AddDate(0, 1, 0)},
+	} {
+		if period.quota == (config.Quota{}) {
+			continue
+		}
+		totals, err := m.Store.Totals(ctx, user, period.start)
+		if err != nil {
+			return format.Error(op, err)
+		}
+		used := Sum(totals)
+		if reserved {
+			used.Requests--
+		}
+		if err := exceeded(used, period.quota); err != nil {
+			err.Period, err.ResetAt = period.name, period.reset
+			return err
+		}
+	}
+	return nil
+}
+
+// exceeded check if usage has reached any limit of quota. Tokens and audio are known after call,
+// so the request that crossed limit is served and the next one is rejected
+func exceeded(used views.UsageTotals, q config.Quota) *QuotaError {
+	switch {
+	case q.Requests > 0 && used.Requests >= q.Requests:
+		return &QuotaError{Resource: "requests", Limit: float64(q.Requests)}
+	case q.Tokens > 0 && used.Tokens >= q.Tokens:
+		return &QuotaError{Resource: "tokens", Limit: float64(q.Tokens)}
+	case q.AudioSeconds > 0 && used.AudioSeconds >= q.AudioSeconds:
+		return &QuotaError{Resource: "audio seconds", Limit: q.AudioSeconds}
+	}
+	return nil
+}
+
+// Record add request to ledger
+func (m *Meter) Record(ctx context.Context, r *views.UsageRecord) error {
+	const op = "usage.Meter.Record"
+
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = m.now().UTC()
+	}
+	if err := m.Store.Record(ctx, r); err != nil {
+		return format.Error(op, err)
+	}
+	return nil
+}
+
+// Summary return usage of user in current day and month with limits of his plan
+func (m *Meter) Summary(ctx context.Context, user string) (*views.UsageSummary, error) {
+	const op = "usage.Meter.Summary"
+
+	plan, err := m.Plan(ctx, user)
+	if err != nil {
+		return nil, format.Error(op, err)
+	}
+	p := m.Plans[plan]
+	now := m.now().UTC()
+
+	res := &views.UsageSummary{Plan: plan}
+	for _, period := range []struct {
+		res   *views.UsagePeriod
+		quota config.Quota
+		start time.Time
+		reset time.Time
+	}{
+		{&res.Day, p.Daily, dayStart(now), dayStart(now).AddDate(0, 0, 1)},
+		{&res.Month, p.Monthly, monthStart(now), monthStart(now).AddDate(0, 1, 0)},
+	} {
+		totals, err := m.Store.Totals(ctx, user, period.start)
+		if err != nil {
+			return nil, format.Error(op, err)
+		}
+		*period.res = views.UsagePeriod{
+			Start:     period.start,
+			ResetAt:   period.reset,
+			Used:      Sum(totals),
+			Limit:     views.UsageLimit(period.quota),
+			Endpoints: totals,
+		}
+	}
+	return res, nil
+}
+
+// Sum return totals of all endpoints
+func Sum(totals []views.UsageTotals) views.UsageTotals {
+	var s views.UsageTotals
+	for _, t := range totals {
+		s.Requests += t.Requests
+		s.Failed += t.Failed
+		s.InputBytes += t.InputBytes
+		s.AudioSeconds += t.AudioSeconds
+		s.Tokens += t.Tokens
+	}
+	return s
+}
+
+// periods are in UTC, so reset time is the same for all users
+func dayStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
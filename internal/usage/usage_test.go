@@ -0,0 +1,261 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"flicker/internal/config"
+	"flicker/internal/views"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func meter(now time.Time) (*Meter, *Memory) {
+	store := NewMemory()
+	m := New(store, config.Test())
+	m.now = func() time.Time { return now }
+	return m, store
+}
+
+func record(t *testing.T, m *Meter, user, outcome string, at time.Time, tokens int64, audio float64) {
+	t.Helper()
+	require.NoError(t, m.Record(context.TODO(), &views.UsageRecord{
+		UserId:       user,
+		Endpoint:     "/api/ai/generatemd",
+		Units:        1,
+		InputBytes:   10,
+		Tokens:       tokens,
+		AudioSeconds: audio,
+		Status:       200,
+		Outcome:      outcome,
+		CreatedAt:    at,
+	}))
+}
+
+func TestPlan(t *testing.T) {
+	t.Parallel()
+
+	m, store := meter(time.Now())
+	store.SetPlan("u2", "pro")
+
+	plan, err := m.Plan(context.TODO(), "u1")
+	require.NoError(t, err)
+	assert.Equal(t, "free", plan)
+
+	plan, _ = m.Plan(context.TODO(), "u2")
+	assert.Equal(t, "pro", plan)
+
+	plan, _ = m.Plan(context.TODO(), Anonymous("10.0.0.1"))
+	assert.Equal(t, PlanAnonymous, plan)
+}
+
+func TestCheckRequests(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	m, _ := meter(now)
+	user := Anonymous("10.0.0.1")
+
+	for range 19 {
+		record(t, m, user, views.UsageOK, now, 0, 0)
+	}
+	// cached and invalid requests are not counted
+	record(t, m, user, views.UsageCached, now, 0, 0)
+	record(t, m, user, views.UsageInvalid, now, 0, 0)
+	require.NoError(t, m.Check(context.TODO(), user))
+
+	record(t, m, user, views.UsageError, now, 0, 0)
+	err := m.Check(context.TODO(), user)
+	require.ErrorIs(t, err, ErrQuota)
+
+	var qe *QuotaError
+	require.True(t, errors.As(err, &qe))
+	assert.Equal(t, PeriodDay, qe.Period)
+	assert.Equal(t, "requests", qe.Resource)
+	assert.Equal(t, time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC), qe.ResetAt)
+
+	// next day daily quota is reset
+	m.now = func() time.Time { return now.Add(24 * time.Hour) }
+	assert.NoError(t, m.Check(context.TODO(), user))
+	// other callers have own quota
+	m.now = func() time.Time { return now }
+	assert.NoError(t, m.Check(context.TODO(), Anonymous("10.0.0.2")))
+}
+
+func TestCheckMonthly(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 31, 23, 0, 0, 0, time.UTC)
+	m, _ := meter(now)
+
+	// tokens and audio are known after call, request that crossed limit is already served
+	for day := range 10 {
+		record(t, m, "u1", views.UsageOK, now.AddDate(0, 0, -day-1), 0, 2*3600)
+	}
+	err := m.Check(context.TODO(), "u1")
+	var qe *QuotaError
+	require.True(t, errors.As(err, &qe))
+	assert.Equal(t, PeriodMonth, qe.Period)
+	assert.Equal(t, "audio seconds", qe.Resource)
+	assert.Equal(t, time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), qe.ResetAt)
+
+	record(t, m, "u2", views.UsageOK, now, 200_000, 0)
+	err = m.Check(context.TODO(), "u2")
+	require.True(t, errors.As(err, &qe))
+	assert.Equal(t, PeriodDay, qe.Period)
+	assert.Equal(t, "tokens", qe.Resource)
+}
+
+func TestCheckUnlimited(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	m, store := meter(now)
+	store.SetPlan("u1", "pro")
+	m.Plans["unknown"] = config.Plan{}
+	store.SetPlan("u2", "unknown")
+
+	for range 200 {
+		record(t, m, "u1", views.UsageOK, now, 1_000_000, 3600)
+		record(t, m, "u2", views.UsageOK, now, 1_000_000, 3600)
+	}
+	assert.NoError(t, m.Check(context.TODO(), "u1"))
+	assert.NoError(t, m.Check(context.TODO(), "u2"))
+}
+
+func TestReserve(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	m, store := meter(now)
+	user := Anonymous("10.0.0.1")
+
+	// concurrent requests see reservations of each other
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		reserved []*views.UsageRecord
+		rejected int
+	)
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, err := m.Reserve(context.TODO(), user, "/api/ai/generatemd")
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				assert.ErrorIs(t, err, ErrQuota)
+				rejected++
+				return
+			}
+			reserved = append(reserved, r)
+		}()
+	}
+	wg.Wait()
+	require.Len(t, reserved, 20)
+	assert.Equal(t, 30, rejected)
+	// rejected requests leave nothing in ledger
+	assert.Len(t, store.records, 20)
+	assert.ErrorIs(t, m.Check(context.TODO(), user), ErrQuota)
+
+	// requests not sent to unavailable upstream and answered from cache free their reservation
+	for i, outcome := range []string{views.UsageOK, views.UsageUnavailable, views.UsageCached} {
+		r := reserved[i]
+		r.Status, r.Outcome, r.Tokens = 200, outcome, 10
+		require.NoError(t, m.Finish(context.TODO(), r))
+	}
+	for range 2 {
+		_, err := m.Reserve(context.TODO(), user, "/api/ai/generatemd")
+		require.NoError(t, err)
+	}
+	_, err := m.Reserve(context.TODO(), user, "/api/ai/generatemd")
+	assert.ErrorIs(t, err, ErrQuota)
+
+	totals, err := store.Totals(context.TODO(), user, now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, views.UsageTotals{Endpoint: "/api/ai/generatemd", Requests: 20, Tokens: 10}, totals[0])
+}
+
+func TestResize(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	m, store := meter(now)
+	user := Anonymous("10.0.0.1")
+	for range 10 {
+		record(t, m, user, views.UsageOK, now, 0, 0)
+	}
+
+	r, err := m.Reserve(context.TODO(), user, "/api/quizzes/:id/attempts/:attempt/submit")
+	require.NoError(t, err)
+	// 10 more requests don't fit into 20 of anonymous, reservation is kept
+	err = m.Resize(context.TODO(), r, 11)
+	assert.ErrorIs(t, err, ErrQuota)
+	assert.EqualValues(t, 1, r.Units)
+	require.NoError(t, m.Resize(context.TODO(), r, 10))
+	assert.ErrorIs(t, m.Check(context.TODO(), user), ErrQuota)
+
+	// request without LLM calls costs nothing
+	require.NoError(t, m.Resize(context.TODO(), r, 0))
+	r.Status, r.Outcome = 200, views.UsageOK
+	require.NoError(t, m.Finish(context.TODO(), r))
+	totals, err := store.Totals(context.TODO(), user, now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.EqualValues(t, 10, Sum(totals).Requests)
+}
+
+func TestFinishWithoutReserve(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	m, store := meter(now)
+
+	// reservation failed when ledger was down, record is added on finish
+	r := &views.UsageRecord{UserId: "u1", Endpoint: "/api/ai/ask", Units: 1, Status: 200, Outcome: views.UsageOK}
+	require.NoError(t, m.Finish(context.TODO(), r))
+	require.Len(t, store.records, 1)
+	assert.NotZero(t, store.records[0].Id)
+	assert.False(t, store.records[0].CreatedAt.IsZero())
+}
+
+func TestSummary(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	m, _ := meter(now)
+
+	record(t, m, "u1", views.UsageOK, now, 100, 0)
+	record(t, m, "u1", views.UsageError, now.Add(-time.Hour), 0, 0)
+	record(t, m, "u1", views.UsageOK, now.AddDate(0, 0, -3), 50, 30)
+	record(t, m, "u1", views.UsageOK, now.AddDate(0, -1, 0), 1000, 0)
+	record(t, m, "u2", views.UsageOK, now, 100, 0)
+
+	s, err := m.Summary(context.TODO(), "u1")
+	require.NoError(t, err)
+	assert.Equal(t, "free", s.Plan)
+
+	assert.Equal(t, time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC), s.Day.Start)
+	assert.Equal(t, time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC), s.Day.ResetAt)
+	assert.Equal(t, views.UsageTotals{Requests: 2, Failed: 1, InputBytes: 20, Tokens: 100}, s.Day.Used)
+	assert.Equal(t, views.UsageLimit{Requests: 100, Tokens: 200_000, AudioSeconds: 7200}, s.Day.Limit)
+	require.Len(t, s.Day.Endpoints, 1)
+	assert.Equal(t, "/api/ai/generatemd", s.Day.Endpoints[0].Endpoint)
+
+	assert.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), s.Month.Start)
+	assert.Equal(t, time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), s.Month.ResetAt)
+	assert.Equal(t, views.UsageTotals{Requests: 3, Failed: 1, InputBytes: 30, AudioSeconds: 30, Tokens: 150}, s.Month.Used)
+}
+
+func TestMemoryDropsOld(t *testing.T) {
+	t.Parallel()
+
+	m, store := meter(time.Now())
+	now := time.Now()
+	record(t, m, "u1", views.UsageOK, now.Add(-100*24*time.Hour), 0, 0)
+	record(t, m, "u1", views.UsageOK, now, 0, 0)
+	assert.Len(t, store.records, 1)
+}
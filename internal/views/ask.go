@@ -0,0 +1,29 @@
+package views
+
+// AskRequest — вопрос по проиндексированным документам пользователя
+type AskRequest struct {
+	Question string        `json:"question" example:"Чем отличается изотермический процесс от адиабатного?"`
+	TopK     int           `json:"top_k,omitempty" example:"5" minimum:"1" maximum:"20"`
+	Prompt   PromptOptions `json:"prompt"`
+}
+
+// Citation — фрагмент документа, на который ссылается ответ. N совпадает с номером [N] в тексте ответа,
+// Start и End — смещения фрагмента в тексте документа в символах
+type Citation struct {
+	N          int     `json:"n" example:"1"`
+	Source     string  `json:"source" example:"lecture.pdf"`
+	DocumentId string  `json:"document_id,omitempty" example:"d4c1b3c2-..."`
+	Chunk      int     `json:"chunk" example:"3"`
+	Start      int     `json:"start" example:"4200"`
+	End        int     `json:"end" example:"5400"`
+	Score      float64 `json:"score" example:"0.83"`
+	Text       string  `json:"text" example:"Изотермический процесс протекает при постоянной температуре..."`
+}
+
+type AskResponse struct {
+	Answer    string     `json:"answer" example:"Изотермический процесс идёт при постоянной температуре [1], а адиабатный — без теплообмена [2]."`
+	Citations []Citation `json:"citations"`
+
+	// PromptVersion — версия промпта, которым получен ответ
+	PromptVersion string `json:"prompt_version" example:"ask=t1"`
+}
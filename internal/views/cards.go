@@ -0,0 +1,40 @@
+package views
+
+import "time"
+
+// Card — флеш-карточка вопрос/ответ с состоянием расписания повторений SM-2
+type Card struct {
+	Id          string     `json:"id" example:"d4c1b3c2-..."`
+	Owner       string     `json:"owner,omitempty"`
+	NoteId      string     `json:"note_id,omitempty" example:"d4c1b3c2-..."`
+	Front       string     `json:"front" example:"Что изучает термодинамика?"`
+	Back        string     `json:"back" example:"Тепловые процессы и превращения энергии"`
+	Repetitions int        `json:"repetitions" example:"2"`
+	Interval    int        `json:"interval_days" example:"6"`
+	Ease        float64    `json:"ease" example:"2.5"`
+	Lapses      int        `json:"lapses" example:"0"`
+	DueAt       time.Time  `json:"due_at"`
+	ReviewedAt  *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+
+	// PromptVersion — версия промпта, которым сгенерирована карточка
+	PromptVersion string `json:"prompt_version,omitempty" example:"flashcards=t1"`
+}
+
+type CardRequest struct {
+	Front string `json:"front" example:"Что изучает термодинамика?"`
+	Back  string `json:"back" example:"Тепловые процессы и превращения энергии"`
+}
+
+// GenerateCardsRequest — источник карточек: заметка пользователя или произвольный текст
+type GenerateCardsRequest struct {
+	NoteId  string        `json:"note_id,omitempty" example:"d4c1b3c2-..."`
+	Content string        `json:"content,omitempty" example:"# Конспект ..."`
+	Count   int           `json:"count,omitempty" example:"10" minimum:"1" maximum:"50"`
+	Prompt  PromptOptions `json:"prompt"`
+}
+
+// CardReview — оценка вспоминания по шкале SM-2: 0 — не вспомнил совсем, 3 — вспомнил с трудом, 5 — легко
+type CardReview struct {
+	Grade *int `json:"grade" example:"4" minimum:"0" maximum:"5"`
+}
@@ -0,0 +1,26 @@
+package views
+
+import "time"
+
+const (
+	DocumentIndexing = "indexing"
+	DocumentIndexed  = "indexed"
+	DocumentFailed   = "failed"
+)
+
+// Document — загруженный пользователем файл и состояние его индексации. Report есть только
+// у проиндексированного документа и не возвращается в списке
+type Document struct {
+	Id        string        `json:"id" example:"d4c1b3c2-..."`
+	Owner     string        `json:"owner,omitempty"`
+	Filename  string        `json:"filename" example:"lecture.pdf"`
+	Size      int64         `json:"size" example:"183204"`
+	Checksum  string        `json:"checksum" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	MimeType  string        `json:"mime_type" example:"application/pdf"`
+	Status    string        `json:"status" example:"indexed" enums:"indexing,indexed,failed"`
+	Chunks    int           `json:"chunks" example:"48"`
+	Error     string        `json:"error,omitempty" example:"no text in file"`
+	Report    *IngestReport `json:"report,omitempty"`
+	CreatedAt time.Time     `json:"created_at"`
+	IndexedAt *time.Time    `json:"indexed_at,omitempty"`
+}
@@ -0,0 +1,15 @@
+package views
+
+const (
+	ExportPDF  = "pdf"
+	ExportDOCX = "docx"
+	ExportApkg = "apkg"
+)
+
+// ExportRequest — документ для выгрузки: markdown конспект или тест из gentest.
+// Должно быть заполнено ровно одно из полей markdown и quiz
+type ExportRequest struct {
+	Title    string `json:"title,omitempty" example:"Лекция 1. Введение"`
+	Markdown string `json:"markdown,omitempty" example:"# Конспект ..."`
+	Quiz     *Quiz  `json:"quiz,omitempty"`
+}
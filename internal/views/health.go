@@ -0,0 +1,27 @@
+package views
+
+import "time"
+
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+)
+
+// UpstreamState — состояние внешнего сервиса (n8n, транскрибатор, эмбеддинги) и его circuit breaker
+type UpstreamState struct {
+	Name  string `json:"name" example:"n8n"`
+	State string `json:"state" example:"closed" enums:"closed,open,half_open"`
+	// Failures — неудачных запросов подряд
+	Failures int `json:"failures" example:"0"`
+	// OpenUntil — до какого времени запросы к сервису сразу отклоняются с 503
+	OpenUntil *time.Time `json:"open_until,omitempty"`
+	InFlight  int        `json:"in_flight" example:"3"`
+	Limit     int        `json:"limit,omitempty" example:"16"`
+}
+
+// Health — ответ проверки здоровья: degraded, если breaker какого-то сервиса открыт
+type Health struct {
+	Message   string          `json:"message" example:"HEALTHZ"`
+	Status    string          `json:"status" example:"ok" enums:"ok,degraded"`
+	Upstreams []UpstreamState `json:"upstreams"`
+}
@@ -0,0 +1,18 @@
+package views
+
+// IngestReport — результат индексации документа: что извлечено, как нарезано и куда сохранено
+type IngestReport struct {
+	DocumentId   string   `json:"document_id" example:"d4c1b3c2-..."`
+	Filename     string   `json:"filename" example:"lecture.pdf"`
+	Format       string   `json:"format" example:"pdf" enums:"pdf,docx,txt,md"`
+	Size         int64    `json:"size" example:"183204"`
+	Characters   int      `json:"characters" example:"48210"`
+	Chunks       int      `json:"chunks" example:"48"`
+	ChunkSize    int      `json:"chunk_size" example:"1200"`
+	ChunkOverlap int      `json:"chunk_overlap" example:"200"`
+	Embedder     string   `json:"embedder" example:"openai:text-embedding-3-small"`
+	Dimensions   int      `json:"dimensions" example:"1536"`
+	Store        string   `json:"store" example:"pgvector" enums:"pgvector,memory"`
+	DurationMs   int64    `json:"duration_ms" example:"2350"`
+	Warnings     []string `json:"warnings,omitempty" example:"page 3 skipped: malformed content"`
+}
@@ -0,0 +1,52 @@
+package views
+
+const (
+	StageTranscribe = "transcribe"
+	StageChunk      = "chunk"
+	StageSummarize  = "summarize"
+	StageMerge      = "merge"
+	StageQuiz       = "quiz"
+	StageSave       = "save"
+)
+
+const (
+	StageRunning = "running"
+	StageDone    = "done"
+	StageFailed  = "failed"
+)
+
+// LectureStage — состояние этапа обработки лекции, отправляется как событие progress
+type LectureStage struct {
+	Name       string `json:"name" example:"summarize" enums:"transcribe,chunk,summarize,merge,quiz,save"`
+	Status     string `json:"status" example:"running" enums:"running,done,failed"`
+	Done       int    `json:"done,omitempty" example:"2"`
+	Total      int    `json:"total,omitempty" example:"5"`
+	DurationMs int64  `json:"duration_ms,omitempty" example:"2350"`
+	Error      string `json:"error,omitempty" example:"n8n request error"`
+}
+
+// LectureChunk — часть расшифровки, которая конспектируется отдельно
+type LectureChunk struct {
+	Index      int     `json:"index" example:"0"`
+	StartSec   float64 `json:"start_sec" example:"0"`
+	EndSec     float64 `json:"end_sec" example:"1260"`
+	Characters int     `json:"characters" example:"11840"`
+	Summary    string  `json:"summary" example:"# Первое начало термодинамики ..."`
+	// Failed — часть не удалось законспектировать, в конспекте на её месте заглушка
+	Failed bool `json:"failed,omitempty" example:"false"`
+}
+
+// LectureResponse — конспект лекции, расшифровка, из которой он сделан, и тест, если его просили
+type LectureResponse struct {
+	Markdown   string             `json:"markdown" example:"# Лекция 3 ..."`
+	Transcript TranscribeResponse `json:"transcript"`
+	Chunks     []LectureChunk     `json:"chunks"`
+	Quiz       *Quiz              `json:"quiz,omitempty"`
+	NoteId     string             `json:"note_id,omitempty" example:"d4c1b3c2-..."`
+	QuizId     string             `json:"quiz_id,omitempty" example:"d4c1b3c2-..."`
+	Stages     []LectureStage     `json:"stages"`
+	Warnings   []string           `json:"warnings,omitempty" example:"1 of 5 parts are not summarized"`
+
+	// PromptVersion — версии промптов, которыми сгенерированы конспект и тест
+	PromptVersion string `json:"prompt_version" example:"generatemd=t3;gentest=1"`
+}
@@ -36,11 +36,23 @@ type Tokens struct {
 }
 
 type GenerateMDRequest struct {
-	Content string `json:"content" example:"Текст документа, который нужно законспектировать"`
+	Content string         `json:"content" example:"Текст документа, который нужно законспектировать"`
+	Options SummaryOptions `json:"options"`
+	Prompt  PromptOptions  `json:"prompt"`
+	SaveAsNote
 }
 
 type MarkdownResponse struct {
 	Markdown string `json:"markdown" example:"# Конспект ..."`
+	NoteId   string `json:"note_id,omitempty" example:"d4c1b3c2-..."`
+
+	// Chunks — на сколько частей был разрезан длинный текст, FailedChunks — части без конспекта
+	Chunks       int      `json:"chunks,omitempty" example:"4"`
+	FailedChunks []int    `json:"failed_chunks,omitempty" example:"2"`
+	Warnings     []string `json:"warnings,omitempty" example:"1 of 4 chunks are not summarized"`
+
+	// PromptVersion — версии промптов, которыми сгенерирован конспект
+	PromptVersion string `json:"prompt_version" example:"generatemd=t3;reducemd=1"`
 }
 
 type N8nResponse struct {
@@ -54,8 +66,26 @@ type TranscribeResponse struct {
 	DurationSeconds float64 `json:"duration_seconds,omitempty" example:"123.45"`
 	Language        string  `json:"language,omitempty" example:"ru"`
 	Model           string  `json:"model,omitempty" example:"nova-2-general"`
+	NoteId          string  `json:"note_id,omitempty" example:"d4c1b3c2-..."`
+
+	Segments []TranscriptSegment `json:"segments,omitempty"`
+}
+
+// TranscriptSegment — кусок расшифровки с временем начала и конца в секундах от начала записи
+type TranscriptSegment struct {
+	Index    int     `json:"segment_index" example:"0"`
+	StartSec float64 `json:"start_sec" example:"0"`
+	EndSec   float64 `json:"end_sec" example:"600"`
+	Text     string  `json:"text" example:"Сегодня поговорим о термодинамике"`
 }
 
+const (
+	TranscriptJSON = "json"
+	TranscriptSRT  = "srt"
+	TranscriptVTT  = "vtt"
+	TranscriptText = "text"
+)
+
 // Вспомогательная структура для ответа Python-сервиса.
 type TranscriberServiceResponse struct {
 	Filename        string  `json:"filename"`
@@ -65,22 +95,16 @@ type TranscriberServiceResponse struct {
 	Language        string  `json:"language"`
 	Model           string  `json:"model"`
 	Text            string  `json:"text"`
-	// Segments можно добавить при необходимости
-	// Segments []struct {
-	// 	SegmentIndex int    `json:"segment_index"`
-	// 	StartSec     int    `json:"start_sec"`
-	// 	EndSec       int    `json:"end_sec"`
-	// 	Text         string `json:"text"`
-	// } `json:"segments"`
+	// время сегментов целое, но читаем его как дробное, если сервис станет точнее
+	Segments []TranscriptSegment `json:"segments"`
 }
 
 type File2DBResponse map[string]interface{}
 
 type GenerateTasksRequest struct {
-	Content string `json:"content" example:"Контекст и/или промт для генерации заданий"`
-}
-
-// TasksMarkdownResponse — ответ с заданиями в формате Markdown.
-type TasksMarkdownResponse struct {
-	Markdown string `json:"markdown" example:"1. Вопрос...\n   A) ...\n   B) ..."`
+	Content  string        `json:"content" example:"Контекст и/или промт для генерации заданий"`
+	SaveQuiz bool          `json:"save_quiz,omitempty" example:"false"`
+	Options  QuizOptions   `json:"options"`
+	Prompt   PromptOptions `json:"prompt"`
+	SaveAsNote
 }
@@ -0,0 +1,67 @@
+package views
+
+import "time"
+
+const (
+	NoteSourceText       = "text"
+	NoteSourceFile       = "file"
+	NoteSourceTranscript = "transcript"
+)
+
+type Note struct {
+	Id         string    `json:"id" example:"d4c1b3c2-..."`
+	Owner      string    `json:"owner,omitempty"`
+	Title      string    `json:"title" example:"Лекция 1. Введение"`
+	Body       string    `json:"body" example:"# Конспект ..."`
+	SourceType string    `json:"source_type" example:"text" enums:"text,file,transcript"`
+	SourceRef  string    `json:"source_ref,omitempty" example:"lecture.mp3"`
+	Revision   int       `json:"revision" example:"3"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+
+	// PromptVersion — версии промптов, которыми сгенерирована заметка
+	PromptVersion string `json:"prompt_version,omitempty" example:"generatemd=t3;reducemd=1"`
+}
+
+// NoteRevision — неизменяемый снимок заметки. Body пустой в списке ревизий
+type NoteRevision struct {
+	Number       int       `json:"number" example:"2"`
+	Title        string    `json:"title" example:"Лекция 1. Введение"`
+	Body         string    `json:"body,omitempty" example:"# Конспект ..."`
+	RestoredFrom int       `json:"restored_from,omitempty" example:"1"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type NoteDiff struct {
+	From int    `json:"from" example:"1"`
+	To   int    `json:"to" example:"2"`
+	Diff string `json:"diff" example:"--- revision 1\n+++ revision 2\n@@ -1 +1 @@\n-# old\n+# new\n"`
+}
+
+type NoteRequest struct {
+	Title      string `json:"title" example:"Лекция 1. Введение"`
+	Body       string `json:"body" example:"# Конспект ..."`
+	SourceType string `json:"source_type,omitempty" example:"text" enums:"text,file,transcript"`
+	SourceRef  string `json:"source_ref,omitempty" example:"lecture.mp3"`
+}
+
+type NoteId struct {
+	Id string `json:"id" example:"d4c1b3c2-..."`
+}
+
+// SaveAsNote — опции сохранения результата генерации как заметки.
+type SaveAsNote struct {
+	Save       bool   `json:"save,omitempty" example:"false"`
+	Title      string `json:"title,omitempty" example:"Лекция 1. Введение"`
+	SourceType string `json:"source_type,omitempty" example:"text" enums:"text,file,transcript"`
+	SourceRef  string `json:"source_ref,omitempty" example:"lecture.mp3"`
+}
+
+// ValidNoteSource сообщает, известен ли тип источника. Пустой тип допустим и означает значение по умолчанию
+func ValidNoteSource(t string) bool {
+	switch t {
+	case "", NoteSourceText, NoteSourceFile, NoteSourceTranscript:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package views
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"golang.org/x/text/language"
+)
+
+const (
+	DetailBrief    = "brief"
+	DetailStandard = "standard"
+	DetailDetailed = "detailed"
+)
+
+const (
+	StyleOutline = "outline"
+	StyleCornell = "cornell"
+	StyleMindMap = "mindmap"
+)
+
+// DifficultyMixed — вопросы всех уровней сложности
+const DifficultyMixed = "mixed"
+
+// MaxQuestions — наибольшее число вопросов в тесте (question_count)
+const MaxQuestions = 30
+
+// SummaryOptions — параметры конспекта. Пустое поле — значение по умолчанию: язык исходного текста,
+// обычная подробность и конспект-план
+type SummaryOptions struct {
+	// Language — язык конспекта, тег BCP 47
+	Language string `json:"language,omitempty" example:"en"`
+	Detail   string `json:"detail,omitempty" example:"brief" enums:"brief,standard,detailed"`
+	// Style: outline — план с разделами, cornell — конспект Корнелла (вопросы, заметки, итог),
+	// mindmap — карта памяти вложенными списками Markdown
+	Style string `json:"style,omitempty" example:"cornell" enums:"outline,cornell,mindmap"`
+}
+
+// QuizOptions — параметры теста. Пустое поле — на усмотрение LLM
+type QuizOptions struct {
+	// Language — язык вопросов, тег BCP 47
+	Language   string   `json:"language,omitempty" example:"en"`
+	Questions  int      `json:"question_count,omitempty" example:"10" minimum:"1" maximum:"30"`
+	Difficulty string   `json:"difficulty,omitempty" example:"mixed" enums:"easy,medium,hard,mixed"`
+	Types      []string `json:"question_types,omitempty" example:"single,open" enums:"single,multi,open,true_false"`
+}
+
+// Validate проверяет параметры и приводит тег языка к каноничному виду. Текст ошибки предназначен для клиента
+func (o *SummaryOptions) Validate() error {
+	var err error
+	if o.Language, err = languageTag(o.Language); err != nil {
+		return err
+	}
+	switch o.Detail {
+	case "", DetailBrief, DetailStandard, DetailDetailed:
+	default:
+		return fmt.Errorf("unknown detail %q, want brief, standard or detailed", o.Detail)
+	}
+	switch o.Style {
+	case "", StyleOutline, StyleCornell, StyleMindMap:
+	default:
+		return fmt.Errorf("unknown style %q, want outline, cornell or mindmap", o.Style)
+	}
+	return nil
+}
+
+// IsZero сообщает, что ни один параметр не задан
+func (o QuizOptions) IsZero() bool {
+	return o.Language == "" && o.Questions == 0 && o.Difficulty == "" && len(o.Types) == 0
+}
+
+// Validate проверяет параметры и приводит тег языка к каноничному виду. Текст ошибки предназначен для клиента
+func (o *QuizOptions) Validate() error {
+	var err error
+	if o.Language, err = languageTag(o.Language); err != nil {
+		return err
+	}
+	if o.Questions < 0 || o.Questions > MaxQuestions {
+		return fmt.Errorf("question_count must be from 1 to %d", MaxQuestions)
+	}
+	switch o.Difficulty {
+	case "", DifficultyEasy, DifficultyMedium, DifficultyHard, DifficultyMixed:
+	default:
+		return fmt.Errorf("unknown difficulty %q, want easy, medium, hard or mixed", o.Difficulty)
+	}
+	for i, t := range o.Types {
+		switch t {
+		case QuestionSingle, QuestionMulti, QuestionOpen, QuestionTrueFalse:
+		default:
+			return fmt.Errorf("unknown question type %q, want single, multi, open or true_false", t)
+		}
+		if slices.Contains(o.Types[:i], t) {
+			return fmt.Errorf("question type %q is repeated", t)
+		}
+	}
+	return nil
+}
+
+var errLanguage = errors.New("language must be BCP 47 tag, e.g. en or pt-BR")
+
+func languageTag(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	tag, err := language.Parse(s)
+	if err != nil {
+		return "", errLanguage
+	}
+	return tag.String(), nil
+}
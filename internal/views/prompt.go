@@ -0,0 +1,51 @@
+package views
+
+import "time"
+
+// PromptTemplate — версия шаблона промпта (Go text/template). Name совпадает с n8n webhook, которому
+// отправляется промпт. Версии неизменяемы, активна не больше одной версии шаблона
+type PromptTemplate struct {
+	Name      string    `json:"name" example:"generatemd"`
+	Version   int       `json:"version" example:"3"`
+	Body      string    `json:"body" example:"Составь конспект на языке {{.Language}} для {{.Audience}}"`
+	Comment   string    `json:"comment,omitempty" example:"shorter sections"`
+	Active    bool      `json:"active" example:"true"`
+	Author    string    `json:"author,omitempty" example:"d4c1b3c2-..."`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PromptTemplateRequest — новая версия шаблона. При activate=true она сразу становится активной
+type PromptTemplateRequest struct {
+	Body     string `json:"body" example:"Составь конспект на языке {{.Language}} для {{.Audience}}"`
+	Comment  string `json:"comment,omitempty" example:"shorter sections"`
+	Activate bool   `json:"activate,omitempty" example:"true"`
+}
+
+// PromptActivateRequest — версия шаблона, которая станет активной. 0 отключает шаблон,
+// и n8n использует промпт своего workflow
+type PromptActivateRequest struct {
+	Version int `json:"version" example:"3" minimum:"0"`
+}
+
+// PromptOptions — выбор шаблонов и значения переменных шаблонов для запроса генерации
+type PromptOptions struct {
+	// Templates — версия шаблона по имени, для не указанных используется активная версия
+	Templates map[string]int `json:"templates,omitempty"`
+	Language  string         `json:"language,omitempty" example:"ru"`
+	Detail    string         `json:"detail,omitempty" example:"brief"`
+	Audience  string         `json:"audience,omitempty" example:"first-year students"`
+	Format    string         `json:"format,omitempty" example:"outline"`
+}
+
+// PromptPreviewRequest — отрисовка шаблона без вызова LLM. Body проверяет ещё не сохранённый шаблон,
+// иначе берётся version или активная версия. Input — поля запроса к webhook, например content
+type PromptPreviewRequest struct {
+	Body    string         `json:"body,omitempty" example:"Составь конспект на языке {{.Language}}"`
+	Version int            `json:"version,omitempty" example:"3"`
+	Prompt  PromptOptions  `json:"prompt"`
+	Input   map[string]any `json:"input,omitempty"`
+}
+
+type PromptPreview struct {
+	Prompt string `json:"prompt" example:"Составь конспект на языке ru"`
+}
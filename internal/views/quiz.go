@@ -0,0 +1,121 @@
+package views
+
+import "time"
+
+const (
+	QuestionSingle    = "single"
+	QuestionMulti     = "multi"
+	QuestionOpen      = "open"
+	QuestionTrueFalse = "true_false"
+)
+
+const (
+	DifficultyEasy   = "easy"
+	DifficultyMedium = "medium"
+	DifficultyHard   = "hard"
+)
+
+// Question — вопрос теста. Correct хранит индексы правильных вариантов из Options,
+// для открытых вопросов эталонный ответ лежит в Answer
+type Question struct {
+	Type        string   `json:"type" example:"single" enums:"single,multi,open,true_false"`
+	Text        string   `json:"text" example:"Что изучает термодинамика?"`
+	Options     []string `json:"options,omitempty" example:"Тепловые процессы,Движение планет"`
+	Correct     []int    `json:"correct,omitempty" example:"0"`
+	Answer      string   `json:"answer,omitempty" example:"Тепловые процессы и превращения энергии"`
+	Explanation string   `json:"explanation,omitempty" example:"Термодинамика — раздел физики о тепловых явлениях"`
+	Difficulty  string   `json:"difficulty" example:"easy" enums:"easy,medium,hard"`
+	Keywords    []string `json:"keywords,omitempty" example:"тепло,энергия"`
+}
+
+type Quiz struct {
+	Title     string     `json:"title" example:"Тест по термодинамике"`
+	Questions []Question `json:"questions"`
+}
+
+type QuizResponse struct {
+	Quiz   Quiz   `json:"quiz"`
+	NoteId string `json:"note_id,omitempty" example:"d4c1b3c2-..."`
+	QuizId string `json:"quiz_id,omitempty" example:"d4c1b3c2-..."`
+
+	// PromptVersion — версии промптов, которыми сгенерирован тест
+	PromptVersion string `json:"prompt_version" example:"gentest=t2"`
+}
+
+const (
+	AttemptStarted   = "started"
+	AttemptSubmitted = "submitted"
+)
+
+// SavedQuiz — сохранённый тест пользователя
+type SavedQuiz struct {
+	Id        string     `json:"id" example:"d4c1b3c2-..."`
+	Owner     string     `json:"owner,omitempty"`
+	Title     string     `json:"title" example:"Тест по термодинамике"`
+	Questions []Question `json:"questions,omitempty"`
+	Count     int        `json:"count" example:"10"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	// PromptVersion — версии промптов, которыми сгенерирован тест
+	PromptVersion string `json:"prompt_version,omitempty" example:"gentest=t2"`
+}
+
+type QuizId struct {
+	Id string `json:"id" example:"d4c1b3c2-..."`
+}
+
+// AttemptQuestion — вопрос в том виде, в котором его видит проходящий тест, без ответов
+type AttemptQuestion struct {
+	Type       string   `json:"type" example:"single" enums:"single,multi,open,true_false"`
+	Text       string   `json:"text" example:"Что изучает термодинамика?"`
+	Options    []string `json:"options,omitempty" example:"Тепловые процессы,Движение планет"`
+	Difficulty string   `json:"difficulty" example:"easy" enums:"easy,medium,hard"`
+}
+
+// Answer — ответ на вопрос с индексом Question. Для закрытых вопросов заполняется Options, для открытых Text
+type Answer struct {
+	Question int    `json:"question" example:"0"`
+	Options  []int  `json:"options,omitempty" example:"1"`
+	Text     string `json:"text,omitempty" example:"Наука о тепловых процессах"`
+}
+
+type AttemptSubmit struct {
+	Answers []Answer `json:"answers"`
+}
+
+// QuestionResult — оценка ответа на один вопрос. Score от 0 до 1
+type QuestionResult struct {
+	Question    int     `json:"question" example:"0"`
+	Score       float64 `json:"score" example:"1"`
+	Correct     bool    `json:"correct" example:"true"`
+	Feedback    string  `json:"feedback,omitempty" example:"Не упомянута энергия"`
+	Expected    []int   `json:"expected,omitempty" example:"0"`
+	Answer      string  `json:"answer,omitempty" example:"Тепловые процессы и превращения энергии"`
+	Explanation string  `json:"explanation,omitempty" example:"Термодинамика — раздел физики о тепловых явлениях"`
+}
+
+// QuizAttempt — попытка прохождения теста. Questions отдаются только у начатой попытки,
+// Results — только у отправленной
+type QuizAttempt struct {
+	Id          string            `json:"id" example:"d4c1b3c2-..."`
+	QuizId      string            `json:"quiz_id" example:"d4c1b3c2-..."`
+	Status      string            `json:"status" example:"submitted" enums:"started,submitted"`
+	Questions   []AttemptQuestion `json:"questions,omitempty"`
+	Answers     []Answer          `json:"answers,omitempty"`
+	Results     []QuestionResult  `json:"results,omitempty"`
+	Score       float64           `json:"score" example:"7.5"`
+	MaxScore    float64           `json:"max_score" example:"10"`
+	StartedAt   time.Time         `json:"started_at"`
+	SubmittedAt *time.Time        `json:"submitted_at,omitempty"`
+}
+
+// QuizStats — статистика отправленных попыток. Проценты от 0 до 100
+type QuizStats struct {
+	Attempts    int       `json:"attempts" example:"3"`
+	Submitted   int       `json:"submitted" example:"2"`
+	Best        float64   `json:"best" example:"90"`
+	Worst       float64   `json:"worst" example:"60"`
+	Average     float64   `json:"average" example:"75"`
+	Last        float64   `json:"last" example:"90"`
+	PerQuestion []float64 `json:"per_question" example:"100,50"`
+}
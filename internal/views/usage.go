@@ -0,0 +1,66 @@
+package views
+
+import "time"
+
+const (
+	UsageOK          = "ok"
+	UsageError       = "error"
+	UsageInvalid     = "invalid"
+	UsageCached      = "cached"
+	UsageUnavailable = "unavailable"
+	UsagePending     = "pending"
+)
+
+// UsageRecord — запись журнала использования AI: один запрос к AI-эндпоинту
+type UsageRecord struct {
+	// Id записи журнала, 0 пока запись не добавлена
+	Id           int64   `json:"-"`
+	UserId       string  `json:"user_id" example:"d4c1b3c2-..."`
+	Endpoint     string  `json:"endpoint" example:"/api/ai/generatemd"`
+	InputBytes   int64   `json:"input_bytes" example:"48210"`
+	AudioSeconds float64 `json:"audio_seconds,omitempty" example:"3600"`
+	// Units — сколько запросов засчитывается в квоту: 1, а для проверки попытки теста — число
+	// открытых ответов, проверяемых LLM
+	Units int64 `json:"units" example:"1"`
+	// Tokens — оценка токенов входа и выхода LLM, 0 если неизвестно
+	Tokens    int64 `json:"tokens,omitempty" example:"15200"`
+	LatencyMs int64 `json:"latency_ms" example:"5320"`
+	Status    int   `json:"status" example:"200"`
+	// Outcome: ok, error (ошибка сервера или внешнего сервиса), invalid (ошибка в запросе), cached (ответ из кеша),
+	// unavailable (внешний сервис не вызывался, так как недоступен), pending (запрос ещё выполняется).
+	// В квоту засчитываются ok, error и pending
+	Outcome   string    `json:"outcome" example:"ok" enums:"ok,error,invalid,cached,unavailable,pending"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UsageTotals — сумма использования за период, всего или по эндпоинту
+type UsageTotals struct {
+	Endpoint     string  `json:"endpoint,omitempty" example:"/api/ai/generatemd"`
+	Requests     int64   `json:"requests" example:"12"`
+	Failed       int64   `json:"failed" example:"1"`
+	InputBytes   int64   `json:"input_bytes" example:"480210"`
+	AudioSeconds float64 `json:"audio_seconds" example:"5400"`
+	Tokens       int64   `json:"tokens" example:"152000"`
+}
+
+// UsageLimit — квота на период, 0 — без ограничения
+type UsageLimit struct {
+	Requests     int64   `json:"requests" example:"100"`
+	Tokens       int64   `json:"tokens" example:"200000"`
+	AudioSeconds float64 `json:"audio_seconds" example:"7200"`
+}
+
+type UsagePeriod struct {
+	Start     time.Time     `json:"start"`
+	ResetAt   time.Time     `json:"reset_at"`
+	Used      UsageTotals   `json:"used"`
+	Limit     UsageLimit    `json:"limit"`
+	Endpoints []UsageTotals `json:"endpoints"`
+}
+
+// UsageSummary — использование AI пользователем за текущие сутки и месяц (UTC) и квоты его тарифа
+type UsageSummary struct {
+	Plan  string      `json:"plan" example:"free"`
+	Day   UsagePeriod `json:"day"`
+	Month UsagePeriod `json:"month"`
+}
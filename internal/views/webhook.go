@@ -0,0 +1,70 @@
+package views
+
+import "time"
+
+// События webhook: задача завершилась успешно или с ошибкой
+const (
+	EventTranscriptionCompleted = "transcription.completed"
+	EventTranscriptionFailed    = "transcription.failed"
+	EventDocumentIndexed        = "document.indexed"
+	EventDocumentFailed         = "document.failed"
+	EventLectureCompleted       = "lecture.completed"
+	EventLectureFailed          = "lecture.failed"
+	// EventPing отправляется только запросом проверки webhook
+	EventPing = "ping"
+)
+
+// WebhookEvents — события, на которые может подписаться вебхук
+var WebhookEvents = []string{
+	EventTranscriptionCompleted, EventTranscriptionFailed,
+	EventDocumentIndexed, EventDocumentFailed,
+	EventLectureCompleted, EventLectureFailed,
+}
+
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook — callback URL пользователя. Secret подписывает уведомления и возвращается только при создании
+type Webhook struct {
+	Id        string    `json:"id" example:"d4c1b3c2-..."`
+	Owner     string    `json:"owner,omitempty"`
+	URL       string    `json:"url" example:"https://lms.example.com/flicker/callback"`
+	Events    []string  `json:"events" example:"lecture.completed,lecture.failed"`
+	Secret    string    `json:"secret,omitempty" example:"whsec_3f1c..."`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookRequest — регистрация webhook. Пустой events — подписка на все события
+type WebhookRequest struct {
+	URL    string   `json:"url" example:"https://lms.example.com/flicker/callback"`
+	Events []string `json:"events,omitempty" example:"lecture.completed,lecture.failed" enums:"transcription.completed,transcription.failed,document.indexed,document.failed,lecture.completed,lecture.failed"`
+}
+
+// WebhookEvent — тело уведомления. Subject — объект задачи: id документа или заметки, для расшифровки
+// без заметки — имя файла. Error — причина неудачи для событий *.failed, Data — результат задачи
+type WebhookEvent struct {
+	Id        string    `json:"id" example:"d4c1b3c2-..."`
+	Event     string    `json:"event" example:"document.indexed"`
+	Subject   string    `json:"subject,omitempty" example:"d4c1b3c2-..."`
+	Error     string    `json:"error,omitempty" example:"embedding error"`
+	Data      any       `json:"data,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookDelivery — запись журнала доставки одного уведомления. ResponseStatus и Error относятся
+// к последней попытке
+type WebhookDelivery struct {
+	Id             string     `json:"id" example:"d4c1b3c2-..."`
+	WebhookId      string     `json:"webhook_id" example:"d4c1b3c2-..."`
+	Event          string     `json:"event" example:"document.indexed"`
+	Payload        string     `json:"payload" example:"{\"id\":\"...\",\"event\":\"document.indexed\"}"`
+	Status         string     `json:"status" example:"delivered" enums:"pending,delivered,failed"`
+	Attempts       int        `json:"attempts" example:"2"`
+	ResponseStatus int        `json:"response_status,omitempty" example:"200"`
+	Error          string     `json:"error,omitempty" example:"status 503"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
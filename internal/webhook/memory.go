@@ -0,0 +1,110 @@
+package webhook
+
+import (
+	"context"
+	"flicker/internal/views"
+	"slices"
+	"sync"
+	"time"
+)
+
+// keepDeliveries is how many deliveries memory log keeps
+const keepDeliveries = 1000
+
+// Memory keep webhooks and deliveries of one instance, for development and tests
+type Memory struct {
+	mu         sync.Mutex
+	hooks      []views.Webhook
+	deliveries []views.WebhookDelivery
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Create(_ context.Context, h *views.Webhook) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h.CreatedAt = time.Now()
+	c := *h
+	c.Events = slices.Clone(h.Events)
+	m.hooks = append(m.hooks, c)
+	return nil
+}
+
+func (m *Memory) Get(_ context.Context, owner, id string) (*views.Webhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, h := range m.hooks {
+		if h.Id == id && h.Owner == owner {
+			return &h, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *Memory) List(_ context.Context, owner string) ([]*views.Webhook, error) {
+	return m.filter(func(h *views.Webhook) bool { return h.Owner == owner }), nil
+}
+
+func (m *Memory) Delete(_ context.Context, owner, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := slices.IndexFunc(m.hooks, func(h views.Webhook) bool { return h.Id == id && h.Owner == owner })
+	if i < 0 {
+		return ErrNotFound
+	}
+	m.hooks = slices.Delete(m.hooks, i, i+1)
+	m.deliveries = slices.DeleteFunc(m.deliveries, func(d views.WebhookDelivery) bool { return d.WebhookId == id })
+	return nil
+}
+
+func (m *Memory) Subscribed(_ context.Context, owner, event string) ([]*views.Webhook, error) {
+	return m.filter(func(h *views.Webhook) bool {
+		return h.Owner == owner && slices.Contains(h.Events, event)
+	}), nil
+}
+
+func (m *Memory) filter(keep func(h *views.Webhook) bool) []*views.Webhook {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ls := []*views.Webhook{}
+	for _, h := range m.hooks {
+		if keep(&h) {
+			ls = append(ls, &h)
+		}
+	}
+	return ls
+}
+
+func (m *Memory) SaveDelivery(_ context.Context, d *views.WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if i := slices.IndexFunc(m.deliveries, func(x views.WebhookDelivery) bool { return x.Id == d.Id }); i >= 0 {
+		m.deliveries[i] = *d
+		return nil
+	}
+	m.deliveries = append(m.deliveries, *d)
+	if over := len(m.deliveries) - keepDeliveries; over > 0 {
+		m.deliveries = m.deliveries[over:]
+	}
+	return nil
+}
+
+func (m *Memory) Deliveries(_ context.Context, webhookId string, limit int) ([]*views.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ls := []*views.WebhookDelivery{}
+	for i := len(m.deliveries) - 1; i >= 0 && len(ls) < limit; i-- {
+		if d := m.deliveries[i]; d.WebhookId == webhookId {
+			ls = append(ls, &d)
+		}
+	}
+	return ls, nil
+}
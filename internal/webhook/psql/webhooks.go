@@ -0,0 +1,174 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flicker/internal/views"
+	"flicker/internal/webhook"
+
+	"github.com/autumnterror/breezynotes/pkg/utils/format"
+	"github.com/lib/pq"
+)
+
+const webhookColumns = `id, owner, url, events, secret, created_at`
+
+func scanWebhook(row interface{ Scan(dest ...any) error }) (*views.Webhook, error) {
+	var h views.Webhook
+	if err := row.Scan(&h.Id, &h.Owner, &h.URL, pq.Array(&h.Events), &h.Secret, &h.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// Create webhook. Id, owner and secret must be set by caller
+func (d *Driver) Create(ctx context.Context, h *views.Webhook) error {
+	const op = "psql.webhook.Create"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	query := `INSERT INTO webhooks (id, owner, url, events, secret) VALUES ($1, $2, $3, $4, $5) RETURNING created_at`
+	if err := d.driver.QueryRowContext(ctx, query, h.Id, h.Owner, h.URL, pq.Array(h.Events), h.Secret).
+		Scan(&h.CreatedAt); err != nil {
+		return format.Error(op, err)
+	}
+	return nil
+}
+
+func (d *Driver) Get(ctx context.Context, owner, id string) (*views.Webhook, error) {
+	const op = "psql.webhook.Get"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = $1 AND owner = $2`
+	h, err := scanWebhook(d.driver.QueryRowContext(ctx, query, id, owner))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, format.Error(op, webhook.ErrNotFound)
+	}
+	if err != nil {
+		return nil, format.Error(op, err)
+	}
+	return h, nil
+}
+
+func (d *Driver) List(ctx context.Context, owner string) ([]*views.Webhook, error) {
+	const op = "psql.webhook.List"
+
+	return d.list(ctx, op, `SELECT `+webhookColumns+` FROM webhooks WHERE owner = $1 ORDER BY created_at`, owner)
+}
+
+func (d *Driver) Subscribed(ctx context.Context, owner, event string) ([]*views.Webhook, error) {
+	const op = "psql.webhook.Subscribed"
+
+	return d.list(ctx, op, `SELECT `+webhookColumns+` FROM webhooks WHERE owner = $1 AND $2 = ANY(events) ORDER BY created_at`, owner, event)
+}
+
+func (d *Driver) list(ctx context.Context, op, query string, args ...any) ([]*views.Webhook, error) {
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	rows, err := d.driver.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, format.Error(op, err)
+	}
+	defer rows.Close()
+
+	ls := []*views.Webhook{}
+	for rows.Next() {
+		h, err := scanWebhook(rows)
+		if err != nil {
+			return nil, format.Error(op, err)
+		}
+		ls = append(ls, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, format.Error(op, err)
+	}
+	return ls, nil
+}
+
+// Delete webhook, its deliveries are deleted by cascade
+func (d *Driver) Delete(ctx context.Context, owner, id string) error {
+	const op = "psql.webhook.Delete"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	res, err := d.driver.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1 AND owner = $2`, id, owner)
+	if err != nil {
+		return format.Error(op, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return format.Error(op, err)
+	}
+	if n == 0 {
+		return format.Error(op, webhook.ErrNotFound)
+	}
+	return nil
+}
+
+func (d *Driver) SaveDelivery(ctx context.Context, dl *views.WebhookDelivery) error {
+	const op = "psql.webhook.SaveDelivery"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	query := `
+		INSERT INTO webhook_deliveries (id, webhook_id, event, payload, status, attempts, response_status, error, created_at, delivered_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (id) DO UPDATE SET
+			status = EXCLUDED.status,
+			attempts = EXCLUDED.attempts,
+			response_status = EXCLUDED.response_status,
+			error = EXCLUDED.error,
+			delivered_at = EXCLUDED.delivered_at
+	`
+	if _, err := d.driver.ExecContext(ctx, query, dl.Id, dl.WebhookId, dl.Event, dl.Payload, dl.Status,
+		dl.Attempts, dl.ResponseStatus, dl.Error, dl.CreatedAt, dl.DeliveredAt); err != nil {
+		return format.Error(op, err)
+	}
+	return nil
+}
+
+func (d *Driver) Deliveries(ctx context.Context, webhookId string, limit int) ([]*views.WebhookDelivery, error) {
+	const op = "psql.webhook.Deliveries"
+
+	ctx, done := context.WithTimeout(ctx, waitTime)
+	defer done()
+
+	query := `
+		SELECT id, webhook_id, event, payload, status, attempts, response_status, error, created_at, delivered_at
+		FROM webhook_deliveries
+		WHERE webhook_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+	rows, err := d.driver.QueryContext(ctx, query, webhookId, limit)
+	if err != nil {
+		return nil, format.Error(op, err)
+	}
+	defer rows.Close()
+
+	ls := []*views.WebhookDelivery{}
+	for rows.Next() {
+		var (
+			dl          views.WebhookDelivery
+			deliveredAt sql.NullTime
+		)
+		if err := rows.Scan(&dl.Id, &dl.WebhookId, &dl.Event, &dl.Payload, &dl.Status, &dl.Attempts,
+			&dl.ResponseStatus, &dl.Error, &dl.CreatedAt, &deliveredAt); err != nil {
+			return nil, format.Error(op, err)
+		}
+		if deliveredAt.Valid {
+			dl.DeliveredAt = &deliveredAt.Time
+		}
+		ls = append(ls, &dl)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, format.Error(op, err)
+	}
+	return ls, nil
+}
@@ -0,0 +1,112 @@
+package psql
+
+import (
+	"context"
+	authpsql "flicker/internal/auth/psql"
+	"flicker/internal/config"
+	"flicker/internal/views"
+	"flicker/internal/webhook"
+	"testing"
+	"time"
+
+	"github.com/autumnterror/breezynotes/pkg/utils/id"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookOperations(t *testing.T) {
+	t.Parallel()
+
+	repo, owner, cleanup := setupTestTx(t)
+	defer cleanup()
+
+	h := &views.Webhook{
+		Id:     id.New(),
+		Owner:  owner,
+		URL:    "https://lms.example.com/hook",
+		Events: []string{views.EventLectureCompleted, views.EventLectureFailed},
+		Secret: "whsec_test",
+	}
+
+	t.Run("create", func(t *testing.T) {
+		assert.NoError(t, repo.Create(context.TODO(), h))
+		assert.False(t, h.CreatedAt.IsZero())
+
+		got, err := repo.Get(context.TODO(), owner, h.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, h.Events, got.Events)
+		assert.Equal(t, h.Secret, got.Secret)
+
+		_, err = repo.Get(context.TODO(), id.New(), h.Id)
+		assert.ErrorIs(t, err, webhook.ErrNotFound)
+	})
+
+	t.Run("subscribed", func(t *testing.T) {
+		ls, err := repo.Subscribed(context.TODO(), owner, views.EventLectureFailed)
+		assert.NoError(t, err)
+		assert.Len(t, ls, 1)
+
+		ls, err = repo.Subscribed(context.TODO(), owner, views.EventDocumentIndexed)
+		assert.NoError(t, err)
+		assert.Empty(t, ls)
+
+		ls, err = repo.List(context.TODO(), owner)
+		assert.NoError(t, err)
+		assert.Len(t, ls, 1)
+	})
+
+	t.Run("deliveries", func(t *testing.T) {
+		dl := &views.WebhookDelivery{
+			Id:        id.New(),
+			WebhookId: h.Id,
+			Event:     views.EventLectureCompleted,
+			Payload:   `{"event":"lecture.completed"}`,
+			Status:    views.DeliveryPending,
+			Attempts:  1,
+			Error:     "status 503: ",
+			CreatedAt: time.Now().UTC(),
+		}
+		assert.NoError(t, repo.SaveDelivery(context.TODO(), dl))
+
+		now := time.Now().UTC()
+		dl.Status, dl.Attempts, dl.ResponseStatus, dl.Error, dl.DeliveredAt = views.DeliveryDelivered, 2, 200, "", &now
+		assert.NoError(t, repo.SaveDelivery(context.TODO(), dl))
+
+		ds, err := repo.Deliveries(context.TODO(), h.Id, 10)
+		assert.NoError(t, err)
+		if assert.Len(t, ds, 1) {
+			assert.Equal(t, views.DeliveryDelivered, ds[0].Status)
+			assert.Equal(t, 2, ds[0].Attempts)
+			assert.Equal(t, 200, ds[0].ResponseStatus)
+			assert.NotNil(t, ds[0].DeliveredAt)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.NoError(t, repo.Delete(context.TODO(), owner, h.Id))
+		assert.ErrorIs(t, repo.Delete(context.TODO(), owner, h.Id), webhook.ErrNotFound)
+
+		ds, err := repo.Deliveries(context.TODO(), h.Id, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, ds)
+	})
+}
+
+func setupTestTx(t *testing.T) (*Driver, string, func()) {
+	pdb := authpsql.MustConnect(config.Test())
+
+	tx, err := pdb.Driver.Begin()
+	assert.NoError(t, err)
+
+	owner := id.New()
+	assert.NoError(t, authpsql.NewDriver(tx).Create(context.TODO(), &views.User{
+		Id:       owner,
+		Login:    owner[:10],
+		Email:    owner[:10] + "@example.com",
+		Password: "password",
+	}))
+
+	return NewDriver(tx), owner, func() {
+		assert.NoError(t, tx.Rollback())
+		assert.NoError(t, pdb.Disconnect())
+	}
+}
@@ -0,0 +1,337 @@
+// Package webhook notify integrators about finished AI jobs. User registers callback URL and
+// receives POST signed by secret of webhook for every event it is subscribed to. Failed deliveries
+// are retried with backoff and every delivery is kept in log
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flicker/internal/config"
+	"flicker/internal/views"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/autumnterror/breezynotes/pkg/log"
+	"github.com/autumnterror/breezynotes/pkg/utils/format"
+	"github.com/autumnterror/breezynotes/pkg/utils/id"
+)
+
+const (
+	StoreMemory   = "memory"
+	StorePostgres = "postgres"
+)
+
+const (
+	// HeaderSignature is "sha256=" and hex HMAC-SHA256 of timestamp, "." and body by secret of webhook
+	HeaderSignature = "X-Flicker-Signature"
+	HeaderTimestamp = "X-Flicker-Timestamp"
+	HeaderEvent     = "X-Flicker-Event"
+	HeaderDelivery  = "X-Flicker-Delivery"
+)
+
+const (
+	// MaxWebhooks limit webhooks of one user
+	MaxWebhooks = 10
+	maxURL      = 2000
+	maxBackoff  = 10 * time.Minute
+	// responseSnippet is how much of failed response is kept in log
+	responseSnippet = 500
+	secretPrefix    = "whsec_"
+)
+
+var (
+	ErrNotFound  = errors.New("webhook not found")
+	ErrURL       = errors.New("bad webhook url")
+	ErrSignature = errors.New("bad webhook signature")
+)
+
+// Store keep webhooks of users and log of deliveries
+type Store interface {
+	Create(ctx context.Context, h *views.Webhook) error
+	// Get webhook of owner with secret. Sends ErrNotFound
+	Get(ctx context.Context, owner, id string) (*views.Webhook, error)
+	// List webhooks of owner with secrets, oldest first
+	List(ctx context.Context, owner string) ([]*views.Webhook, error)
+	// Delete webhook of owner with its deliveries. Sends ErrNotFound
+	Delete(ctx context.Context, owner, id string) error
+	// Subscribed return webhooks of owner subscribed to event
+	Subscribed(ctx context.Context, owner, event string) ([]*views.Webhook, error)
+	// SaveDelivery insert delivery or update it by id
+	SaveDelivery(ctx context.Context, d *views.WebhookDelivery) error
+	// Deliveries return last deliveries of webhook, newest first
+	Deliveries(ctx context.Context, webhookId string, limit int) ([]*views.WebhookDelivery, error)
+}
+
+// Sign return value of HeaderSignature for body sent at unix timestamp
+func Sign(secret, timestamp string, body []byte) string {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write([]byte(timestamp))
+	m.Write([]byte("."))
+	m.Write(body)
+	return "sha256=" + hex.EncodeToString(m.Sum(nil))
+}
+
+// Verify check signature of received notification and that it is not older than tolerance,
+// as receiver should do. Sends ErrSignature
+func Verify(secret, timestamp, signature string, body []byte, now time.Time, tolerance time.Duration) error {
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: bad timestamp", ErrSignature)
+	}
+	if d := now.Sub(time.Unix(sec, 0)); d > tolerance || d < -tolerance {
+		return fmt.Errorf("%w: timestamp is out of tolerance", ErrSignature)
+	}
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body))) {
+		return fmt.Errorf("%w: signature mismatch", ErrSignature)
+	}
+	return nil
+}
+
+// NewSecret generate secret of webhook
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return secretPrefix + hex.EncodeToString(b), nil
+}
+
+// ValidateURL check callback URL. Unless allowPrivate, loopback and private hosts are rejected.
+// Error is meant for client
+func ValidateURL(raw string, allowPrivate bool) error {
+	if len(raw) > maxURL {
+		return fmt.Errorf("%w: longer than %d characters", ErrURL, maxURL)
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("%w: absolute http or https url is required", ErrURL)
+	}
+	if u.User != nil {
+		return fmt.Errorf("%w: credentials in url are not allowed", ErrURL)
+	}
+	if allowPrivate {
+		return nil
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); strings.EqualFold(host, "localhost") || ip != nil && private(ip) {
+		return fmt.Errorf("%w: private hosts are not allowed", ErrURL)
+	}
+	return nil
+}
+
+func private(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast()
+}
+
+// NewClient return HTTP client for deliveries. Redirects are not followed, and unless
+// allowPrivate, connections to private addresses are refused after DNS resolution
+func NewClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || private(ip) {
+				return fmt.Errorf("%w: private address %s", ErrURL, host)
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// proxy would connect instead of dialer and skip the check
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Dispatcher deliver events to webhooks in background
+type Dispatcher struct {
+	Store  Store
+	Client *http.Client
+	// Retries of failed delivery, with backoff doubled after every attempt
+	Retries int
+	Backoff time.Duration
+
+	ctx  context.Context
+	stop context.CancelFunc
+	wg   sync.WaitGroup
+	now  func() time.Time
+}
+
+func New(store Store, cfg *config.Config) *Dispatcher {
+	ctx, stop := context.WithCancel(context.Background())
+	return &Dispatcher{
+		Store:   store,
+		Client:  NewClient(cfg.WebhookTimeout, cfg.WebhookAllowPrivate),
+		Retries: cfg.WebhookRetries,
+		Backoff: cfg.WebhookBackoff,
+		ctx:     ctx,
+		stop:    stop,
+		now:     time.Now,
+	}
+}
+
+// Notify send event to every webhook of owner subscribed to it. Id and time of event are set here,
+// deliveries run in background and ctx is used only to find webhooks
+func (d *Dispatcher) Notify(ctx context.Context, owner string, ev views.WebhookEvent) error {
+	const op = "webhook.Dispatcher.Notify"
+
+	hooks, err := d.Store.Subscribed(ctx, owner, ev.Event)
+	if err != nil {
+		return format.Error(op, err)
+	}
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	ev.Id, ev.CreatedAt = id.New(), d.now().UTC()
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return format.Error(op, err)
+	}
+	for _, h := range hooks {
+		dl := d.newDelivery(h, ev.Event, body)
+		if err := d.Store.SaveDelivery(ctx, dl); err != nil {
+			return format.Error(op, err)
+		}
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			d.deliver(h, dl)
+		}()
+	}
+	return nil
+}
+
+// Ping send ping event to webhook once and wait for result, so user can check receiver
+func (d *Dispatcher) Ping(ctx context.Context, h *views.Webhook) (*views.WebhookDelivery, error) {
+	const op = "webhook.Dispatcher.Ping"
+
+	body, err := json.Marshal(views.WebhookEvent{Id: id.New(), Event: views.EventPing, CreatedAt: d.now().UTC()})
+	if err != nil {
+		return nil, format.Error(op, err)
+	}
+	dl := d.newDelivery(h, views.EventPing, body)
+	d.attempt(ctx, h, dl)
+	if dl.Status != views.DeliveryDelivered {
+		dl.Status = views.DeliveryFailed
+	}
+	if err := d.Store.SaveDelivery(ctx, dl); err != nil {
+		return nil, format.Error(op, err)
+	}
+	return dl, nil
+}
+
+// Wait for deliveries running in background to finish with all their retries
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
+
+// Stop cancel retries and wait for deliveries running in background. Interrupted deliveries are
+// logged as failed
+func (d *Dispatcher) Stop() {
+	d.stop()
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) newDelivery(h *views.Webhook, event string, body []byte) *views.WebhookDelivery {
+	return &views.WebhookDelivery{
+		Id:        id.New(),
+		WebhookId: h.Id,
+		Event:     event,
+		Payload:   string(body),
+		Status:    views.DeliveryPending,
+		CreatedAt: d.now().UTC(),
+	}
+}
+
+// deliver make attempts until receiver accepts delivery, retries are over or dispatcher is stopped.
+// Delivery is saved after every attempt
+func (d *Dispatcher) deliver(h *views.Webhook, dl *views.WebhookDelivery) {
+	backoff := d.Backoff
+	for {
+		d.attempt(d.ctx, h, dl)
+		if dl.Status != views.DeliveryDelivered && dl.Attempts > d.Retries {
+			dl.Status = views.DeliveryFailed
+		}
+		d.save(dl)
+		if dl.Status != views.DeliveryPending {
+			return
+		}
+
+		select {
+		case <-d.ctx.Done():
+			dl.Status, dl.Error = views.DeliveryFailed, "delivery stopped: "+dl.Error
+			d.save(dl)
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// save delivery in background, log is kept even if dispatcher is stopped
+func (d *Dispatcher) save(dl *views.WebhookDelivery) {
+	const op = "webhook.Dispatcher.save"
+
+	ctx, done := context.WithTimeout(context.WithoutCancel(d.ctx), 3*time.Second)
+	defer done()
+	if err := d.Store.SaveDelivery(ctx, dl); err != nil {
+		log.Error(op, "", err)
+	}
+}
+
+// attempt send delivery once and record outcome in it
+func (d *Dispatcher) attempt(ctx context.Context, h *views.Webhook, dl *views.WebhookDelivery) {
+	dl.Attempts++
+	ts := strconv.FormatInt(d.now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, strings.NewReader(dl.Payload))
+	if err != nil {
+		dl.ResponseStatus, dl.Error = 0, err.Error()
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "flicker-webhook")
+	req.Header.Set(HeaderEvent, dl.Event)
+	req.Header.Set(HeaderDelivery, dl.Id)
+	req.Header.Set(HeaderTimestamp, ts)
+	req.Header.Set(HeaderSignature, Sign(h.Secret, ts, []byte(dl.Payload)))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		dl.ResponseStatus, dl.Error = 0, err.Error()
+		return
+	}
+	defer resp.Body.Close()
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, responseSnippet))
+
+	dl.ResponseStatus = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		dl.Error = fmt.Sprintf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(snippet)))
+		return
+	}
+	now := d.now().UTC()
+	dl.Status, dl.Error, dl.DeliveredAt = views.DeliveryDelivered, "", &now
+}
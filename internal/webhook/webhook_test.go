@@ -0,0 +1,169 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"flicker/internal/config"
+	"flicker/internal/views"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sink is local receiver of notifications which answers with statuses in turn, then with 200
+type sink struct {
+	mu       sync.Mutex
+	statuses []int
+	received []*http.Request
+	bodies   [][]byte
+}
+
+func (s *sink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.received = append(s.received, r)
+	s.bodies = append(s.bodies, b)
+	if len(s.statuses) > 0 {
+		w.WriteHeader(s.statuses[0])
+		_, _ = w.Write([]byte("try later"))
+		s.statuses = s.statuses[1:]
+	}
+}
+
+func newDispatcher(t *testing.T, s *sink) (*Dispatcher, *views.Webhook) {
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+
+	d := New(NewMemory(), config.Test())
+	t.Cleanup(d.Stop)
+	h := &views.Webhook{Id: "h1", Owner: "u1", URL: srv.URL, Events: []string{views.EventDocumentIndexed}, Secret: "whsec_test"}
+	assert.NoError(t, d.Store.Create(context.TODO(), h))
+	return d, h
+}
+
+func TestNotify(t *testing.T) {
+	t.Parallel()
+
+	s := &sink{statuses: []int{http.StatusServiceUnavailable}}
+	d, h := newDispatcher(t, s)
+
+	assert.NoError(t, d.Notify(context.TODO(), "u1", views.WebhookEvent{Event: views.EventDocumentFailed}))
+	assert.NoError(t, d.Notify(context.TODO(), "u2", views.WebhookEvent{Event: views.EventDocumentIndexed}))
+	assert.NoError(t, d.Notify(context.TODO(), "u1", views.WebhookEvent{
+		Event:   views.EventDocumentIndexed,
+		Subject: "doc1",
+		Data:    views.IngestReport{Chunks: 3},
+	}))
+	d.Wait()
+
+	assert.Len(t, s.received, 2)
+	r, body := s.received[1], s.bodies[1]
+	assert.Equal(t, views.EventDocumentIndexed, r.Header.Get(HeaderEvent))
+	assert.NoError(t, Verify(h.Secret, r.Header.Get(HeaderTimestamp), r.Header.Get(HeaderSignature), body, time.Now(), time.Minute))
+	assert.ErrorIs(t, Verify("other", r.Header.Get(HeaderTimestamp), r.Header.Get(HeaderSignature), body, time.Now(), time.Minute), ErrSignature)
+
+	var ev views.WebhookEvent
+	assert.NoError(t, json.Unmarshal(body, &ev))
+	assert.Equal(t, "doc1", ev.Subject)
+	assert.NotEmpty(t, ev.Id)
+
+	ds, err := d.Store.Deliveries(context.TODO(), h.Id, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, ds, 1) {
+		assert.Equal(t, r.Header.Get(HeaderDelivery), ds[0].Id)
+		assert.Equal(t, views.DeliveryDelivered, ds[0].Status)
+		assert.Equal(t, 2, ds[0].Attempts)
+		assert.Equal(t, http.StatusOK, ds[0].ResponseStatus)
+		assert.NotNil(t, ds[0].DeliveredAt)
+	}
+}
+
+func TestNotifyGiveUp(t *testing.T) {
+	t.Parallel()
+
+	s := &sink{statuses: []int{500, 500, 500, 500}}
+	d, h := newDispatcher(t, s)
+
+	assert.NoError(t, d.Notify(context.TODO(), "u1", views.WebhookEvent{Event: views.EventDocumentIndexed}))
+	d.Wait()
+
+	assert.Len(t, s.received, d.Retries+1)
+	ds, err := d.Store.Deliveries(context.TODO(), h.Id, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, ds, 1) {
+		assert.Equal(t, views.DeliveryFailed, ds[0].Status)
+		assert.Equal(t, d.Retries+1, ds[0].Attempts)
+		assert.Equal(t, "status 500: try later", ds[0].Error)
+	}
+}
+
+func TestStop(t *testing.T) {
+	t.Parallel()
+
+	s := &sink{statuses: []int{500, 500, 500, 500}}
+	d, h := newDispatcher(t, s)
+	d.Backoff = time.Hour
+
+	assert.NoError(t, d.Notify(context.TODO(), "u1", views.WebhookEvent{Event: views.EventDocumentIndexed}))
+	assert.Eventually(t, func() bool {
+		ds, _ := d.Store.Deliveries(context.TODO(), h.Id, 10)
+		return len(ds) == 1 && ds[0].Attempts == 1
+	}, time.Second, 5*time.Millisecond)
+	d.Stop()
+
+	ds, err := d.Store.Deliveries(context.TODO(), h.Id, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, ds, 1) {
+		assert.Equal(t, views.DeliveryFailed, ds[0].Status)
+		assert.Equal(t, "delivery stopped: status 500: try later", ds[0].Error)
+	}
+}
+
+func TestPing(t *testing.T) {
+	t.Parallel()
+
+	s := &sink{}
+	d, h := newDispatcher(t, s)
+
+	dl, err := d.Ping(context.TODO(), h)
+	assert.NoError(t, err)
+	assert.Equal(t, views.DeliveryDelivered, dl.Status)
+	assert.Equal(t, views.EventPing, s.received[0].Header.Get(HeaderEvent))
+
+	h.URL = "http://127.0.0.1:1"
+	dl, err = d.Ping(context.TODO(), h)
+	assert.NoError(t, err)
+	assert.Equal(t, views.DeliveryFailed, dl.Status)
+	assert.NotEmpty(t, dl.Error)
+}
+
+func TestPrivate(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(&sink{})
+	defer srv.Close()
+
+	_, err := NewClient(time.Second, false).Post(srv.URL, "application/json", nil)
+	assert.ErrorIs(t, err, ErrURL)
+	_, err = NewClient(time.Second, true).Post(srv.URL, "application/json", nil)
+	assert.NoError(t, err)
+}
+
+func TestValidateURL(t *testing.T) {
+	t.Parallel()
+
+	for _, u := range []string{"https://lms.example.com/hook", "http://93.184.216.34:8080/x"} {
+		assert.NoError(t, ValidateURL(u, false), u)
+	}
+	for _, u := range []string{"", "lms.example.com/hook", "ftp://lms.example.com", "https://user:pw@lms.example.com",
+		"http://localhost:8080", "http://127.0.0.1/x", "http://10.0.0.5/x", "http://[::1]/x", "http://169.254.169.254/latest"} {
+		assert.ErrorIs(t, ValidateURL(u, false), ErrURL, u)
+	}
+	assert.NoError(t, ValidateURL("http://localhost:8080", true))
+}
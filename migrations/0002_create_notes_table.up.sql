@@ -0,0 +1,12 @@
+CREATE TABLE notes
+(
+    id          VARCHAR(50) PRIMARY KEY,
+    owner       VARCHAR(50)  NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    title       VARCHAR(255) NOT NULL,
+    body        TEXT         NOT NULL,
+    source_type VARCHAR(20)  NOT NULL,
+    source_ref  TEXT,
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+CREATE INDEX notes_owner_idx ON notes (owner, updated_at DESC);
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP TABLE note_revisions;
+ALTER TABLE notes DROP COLUMN revision;
\ No newline at end of file
@@ -0,0 +1,14 @@
+ALTER TABLE notes ADD COLUMN revision INT NOT NULL DEFAULT 1;
+CREATE TABLE note_revisions
+(
+    note_id       VARCHAR(50)  NOT NULL REFERENCES notes (id) ON DELETE CASCADE,
+    number        INT          NOT NULL,
+    title         VARCHAR(255) NOT NULL,
+    body          TEXT         NOT NULL,
+    restored_from INT,
+    created_at    TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    PRIMARY KEY (note_id, number)
+);
+INSERT INTO note_revisions (note_id, number, title, body, created_at)
+SELECT id, 1, title, body, updated_at
+FROM notes;
\ No newline at end of file
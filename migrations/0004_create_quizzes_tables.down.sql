@@ -0,0 +1,2 @@
+DROP TABLE quiz_attempts;
+DROP TABLE quizzes;
\ No newline at end of file
@@ -0,0 +1,24 @@
+CREATE TABLE quizzes
+(
+    id         VARCHAR(50) PRIMARY KEY,
+    owner      VARCHAR(50)  NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    title      VARCHAR(255) NOT NULL,
+    questions  JSONB        NOT NULL,
+    created_at TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+CREATE INDEX quizzes_owner_idx ON quizzes (owner, created_at DESC);
+
+CREATE TABLE quiz_attempts
+(
+    id           VARCHAR(50) PRIMARY KEY,
+    quiz_id      VARCHAR(50) NOT NULL REFERENCES quizzes (id) ON DELETE CASCADE,
+    owner        VARCHAR(50) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    status       VARCHAR(20) NOT NULL DEFAULT 'started',
+    answers      JSONB,
+    results      JSONB,
+    score        REAL        NOT NULL DEFAULT 0,
+    max_score    REAL        NOT NULL,
+    started_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    submitted_at TIMESTAMPTZ
+);
+CREATE INDEX quiz_attempts_quiz_idx ON quiz_attempts (quiz_id, started_at DESC);
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE cards;
\ No newline at end of file
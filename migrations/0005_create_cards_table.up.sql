@@ -0,0 +1,16 @@
+CREATE TABLE cards
+(
+    id            VARCHAR(50)      PRIMARY KEY,
+    owner         VARCHAR(50)      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    note_id       VARCHAR(50)      REFERENCES notes (id) ON DELETE SET NULL,
+    front         TEXT             NOT NULL,
+    back          TEXT             NOT NULL,
+    repetitions   INT              NOT NULL DEFAULT 0,
+    interval_days INT              NOT NULL DEFAULT 0,
+    ease          DOUBLE PRECISION NOT NULL DEFAULT 2.5,
+    lapses        INT              NOT NULL DEFAULT 0,
+    due_at        TIMESTAMPTZ      NOT NULL DEFAULT now(),
+    reviewed_at   TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ      NOT NULL DEFAULT now()
+);
+CREATE INDEX cards_owner_due_idx ON cards (owner, due_at);
\ No newline at end of file
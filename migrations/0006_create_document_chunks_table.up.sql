@@ -0,0 +1,2 @@
+-- document_chunks needs pgvector and is created by migrations/pgvector when vector_store is "pgvector"
+SELECT 1;
\ No newline at end of file
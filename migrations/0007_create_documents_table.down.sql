@@ -0,0 +1 @@
+DROP TABLE documents;
\ No newline at end of file
@@ -0,0 +1,17 @@
+CREATE TABLE documents
+(
+    id         VARCHAR(50)  PRIMARY KEY,
+    owner      VARCHAR(50)  NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    filename   TEXT         NOT NULL,
+    size       BIGINT       NOT NULL,
+    checksum   VARCHAR(64)  NOT NULL,
+    mime_type  VARCHAR(100) NOT NULL,
+    status     VARCHAR(20)  NOT NULL DEFAULT 'indexing',
+    chunks     INT          NOT NULL DEFAULT 0,
+    error      TEXT         NOT NULL DEFAULT '',
+    report     JSONB,
+    content    BYTEA        NOT NULL,
+    created_at TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    indexed_at TIMESTAMPTZ
+);
+CREATE INDEX documents_owner_created_idx ON documents (owner, created_at);
\ No newline at end of file
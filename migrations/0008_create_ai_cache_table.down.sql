@@ -0,0 +1 @@
+DROP TABLE ai_cache;
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE TABLE ai_cache
+(
+    key        VARCHAR(64)  PRIMARY KEY,
+    prompt     VARCHAR(100) NOT NULL,
+    version    VARCHAR(100) NOT NULL,
+    value      BYTEA        NOT NULL,
+    created_at TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ  NOT NULL
+);
+CREATE INDEX ai_cache_prompt_idx ON ai_cache (prompt, version);
+CREATE INDEX ai_cache_expires_idx ON ai_cache (expires_at);
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP TABLE ai_usage;
+ALTER TABLE users DROP COLUMN plan;
\ No newline at end of file
@@ -0,0 +1,16 @@
+ALTER TABLE users ADD COLUMN plan VARCHAR(50);
+
+CREATE TABLE ai_usage
+(
+    id            BIGSERIAL    PRIMARY KEY,
+    user_id       VARCHAR(100) NOT NULL,
+    endpoint      VARCHAR(100) NOT NULL,
+    input_bytes   BIGINT       NOT NULL DEFAULT 0,
+    audio_seconds DOUBLE PRECISION NOT NULL DEFAULT 0,
+    tokens        BIGINT       NOT NULL DEFAULT 0,
+    latency_ms    BIGINT       NOT NULL DEFAULT 0,
+    status        INT          NOT NULL,
+    outcome       VARCHAR(20)  NOT NULL,
+    created_at    TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+CREATE INDEX ai_usage_user_idx ON ai_usage (user_id, created_at);
\ No newline at end of file
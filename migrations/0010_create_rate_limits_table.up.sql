@@ -0,0 +1,9 @@
+CREATE TABLE rate_limits
+(
+    key        VARCHAR(200)     PRIMARY KEY,
+    tokens     DOUBLE PRECISION NOT NULL,
+    allowed    BOOLEAN          NOT NULL,
+    updated_at TIMESTAMPTZ      NOT NULL,
+    expires_at TIMESTAMPTZ      NOT NULL
+);
+CREATE INDEX rate_limits_expires_idx ON rate_limits (expires_at);
\ No newline at end of file
@@ -0,0 +1,4 @@
+DROP TABLE prompt_templates;
+ALTER TABLE cards DROP COLUMN prompt_version;
+ALTER TABLE quizzes DROP COLUMN prompt_version;
+ALTER TABLE notes DROP COLUMN prompt_version;
\ No newline at end of file
@@ -0,0 +1,15 @@
+ALTER TABLE notes ADD COLUMN prompt_version VARCHAR(200);
+ALTER TABLE quizzes ADD COLUMN prompt_version VARCHAR(200);
+ALTER TABLE cards ADD COLUMN prompt_version VARCHAR(200);
+
+CREATE TABLE prompt_templates
+(
+    name       VARCHAR(100) NOT NULL,
+    version    INT          NOT NULL,
+    body       TEXT         NOT NULL,
+    comment    TEXT         NOT NULL DEFAULT '',
+    active     BOOLEAN      NOT NULL DEFAULT false,
+    author     VARCHAR(100),
+    created_at TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    PRIMARY KEY (name, version)
+);
\ No newline at end of file
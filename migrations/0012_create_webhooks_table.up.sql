@@ -0,0 +1,25 @@
+CREATE TABLE webhooks
+(
+    id         VARCHAR(50)  PRIMARY KEY,
+    owner      VARCHAR(50)  NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    url        TEXT         NOT NULL,
+    events     TEXT[]       NOT NULL,
+    secret     VARCHAR(100) NOT NULL,
+    created_at TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+CREATE INDEX webhooks_owner_idx ON webhooks (owner, created_at);
+
+CREATE TABLE webhook_deliveries
+(
+    id              VARCHAR(50)  PRIMARY KEY,
+    webhook_id      VARCHAR(50)  NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event           VARCHAR(50)  NOT NULL,
+    payload         TEXT         NOT NULL,
+    status          VARCHAR(20)  NOT NULL,
+    attempts        INT          NOT NULL DEFAULT 0,
+    response_status INT          NOT NULL DEFAULT 0,
+    error           TEXT         NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    delivered_at    TIMESTAMPTZ
+);
+CREATE INDEX webhook_deliveries_webhook_idx ON webhook_deliveries (webhook_id, created_at);
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE ai_usage DROP COLUMN units;
\ No newline at end of file
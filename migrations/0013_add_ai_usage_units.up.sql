@@ -0,0 +1 @@
+ALTER TABLE ai_usage ADD COLUMN units INT NOT NULL DEFAULT 1;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS document_chunks;
\ No newline at end of file
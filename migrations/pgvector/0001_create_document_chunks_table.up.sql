@@ -0,0 +1,15 @@
+CREATE EXTENSION IF NOT EXISTS vector;
+
+CREATE TABLE IF NOT EXISTS document_chunks
+(
+    id           BIGSERIAL   PRIMARY KEY,
+    owner        VARCHAR(50) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    document_id  VARCHAR(50) NOT NULL,
+    source       TEXT        NOT NULL,
+    chunk        INT         NOT NULL,
+    start_offset INT         NOT NULL,
+    end_offset   INT         NOT NULL,
+    text         TEXT        NOT NULL,
+    embedding    vector      NOT NULL
+);
+CREATE INDEX IF NOT EXISTS document_chunks_owner_document_idx ON document_chunks (owner, document_id);
\ No newline at end of file